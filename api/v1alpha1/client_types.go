@@ -25,15 +25,104 @@ import (
 type ClientSpec struct {
 	Server ClientSpec_Server `json:"server"`
 	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	// Replicas is the number of frpc pods, TCP, HTTP and TCPMUX upstreams are
+	// load balanced across replicas with a frp load balancer group, other upstreams
+	// are left out of the configuration
+	Replicas *int32 `json:"replicas,omitempty"`
+	// +optional
 	// PodTemplate allows customization of the FRP client pod
 	PodTemplate *ClientSpec_PodTemplate `json:"podTemplate,omitempty"`
+	// +optional
+	// AllowedNamespaces lists the other namespaces whose Upstreams and Visitors
+	// may reference this Client with clientRef
+	AllowedNamespaces *ClientSpec_AllowedNamespaces `json:"allowedNamespaces,omitempty"`
+	// +optional
+	// Failover lists the servers frpc switches to when spec.server is unreachable.
+	// Servers are probed over TCP, so failover can't be used with the kcp and quic
+	// protocols.
+	Failover *ClientSpec_Failover `json:"failover,omitempty"`
+	// +optional
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	// RevisionHistoryLimit is the number of rendered configurations kept as
+	// immutable revision Secrets
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{16}$`
+	// ConfigRevision pins frpc to a revision of its configuration, the value of the
+	// frp.zufardhiyaulhaq.com/config-revision label of a revision Secret. Changes of
+	// Upstreams and Visitors aren't applied while the Client is pinned, and a
+	// revision rendered for other admin credentials or another server is refused.
+	ConfigRevision string `json:"configRevision,omitempty"`
+	// +optional
+	// Suspend scales the frpc pods to zero, the configuration is kept and applied
+	// again when the Client is resumed
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// ClientSpec_Failover configures the servers the operator probes and fails over to
+type ClientSpec_Failover struct {
+	// +kubebuilder:validation:MinItems=1
+	// Servers are tried in order after spec.server, frpc connects to the first
+	// server that accepts TCP connections. The servers are probed from the
+	// operator pod, so its network has to reach them like the frpc pods do. The
+	// kcp and quic protocols are rejected since their UDP port can't be probed.
+	Servers []ClientSpec_FailoverServer `json:"servers"`
+	// +optional
+	// +kubebuilder:default=30
+	// +kubebuilder:validation:Minimum=5
+	// ProbeInterval is the number of seconds between probes of the servers
+	ProbeInterval int `json:"probeInterval,omitempty"`
+	// +optional
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	// ProbeTimeout is the number of seconds a probe waits for a connection
+	ProbeTimeout int `json:"probeTimeout,omitempty"`
+}
+
+// ClientSpec_FailoverServer is a server frpc fails over to, the protocol,
+// authentication, TLS and transport it doesn't set are taken from spec.server
+type ClientSpec_FailoverServer struct {
+	// +optional
+	Host string `json:"host,omitempty"`
+	// +optional
+	Port int `json:"port,omitempty"`
+	// +optional
+	// ServerRef points to a Server in the same namespace instead of host and port
+	ServerRef *ClientSpec_Server_ServerRef `json:"serverRef,omitempty"`
+	// +kubebuilder:validation:Enum=tcp;kcp;quic;websocket;wss
+	// +optional
+	Protocol *string `json:"protocol,omitempty"`
+	// +optional
+	Authentication *ClientSpec_Server_Authentication `json:"authentication,omitempty"`
+	// +optional
+	TLS *ClientSpec_Server_TLS `json:"tls,omitempty"`
+	// +optional
+	Transport *ClientSpec_Server_Transport `json:"transport,omitempty"`
+}
+
+type ClientSpec_AllowedNamespaces struct {
+	// +optional
+	// Names of allowed namespaces
+	Names []string `json:"names,omitempty"`
+	// +optional
+	// Selector matches the labels of allowed namespaces
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 }
 
 type ClientSpec_Server struct {
-	Host string `json:"host"`
-	Port int    `json:"port"`
+	// +optional
+	Host string `json:"host,omitempty"`
+	// +optional
+	Port int `json:"port,omitempty"`
+	// +optional
+	// ServerRef points to a Server in the same namespace instead of host and port
+	ServerRef *ClientSpec_Server_ServerRef `json:"serverRef,omitempty"`
 	// +kubebuilder:validation:Enum=tcp;kcp;quic;websocket;wss
 	// +optional
+	// Protocol is the transport frpc dials the server with, kcp and quic use UDP
 	Protocol       *string                          `json:"protocol,omitempty"`
 	Authentication ClientSpec_Server_Authentication `json:"authentication"`
 	AdminServer    *ClientSpec_Server_AdminServer   `json:"adminServer,omitempty"`
@@ -64,6 +153,29 @@ type ClientSpec_Server_Transport struct {
 	// +optional
 	// ConnectServerLocalIP binds the outbound connection to a specific local IP
 	ConnectServerLocalIP string `json:"connectServerLocalIP,omitempty"`
+	// +optional
+	// QUIC tunes the QUIC connection, it requires the quic protocol
+	QUIC *ClientSpec_Server_Transport_QUIC `json:"quic,omitempty"`
+}
+
+// ClientSpec_Server_Transport_QUIC configures the QUIC connection to the server
+type ClientSpec_Server_Transport_QUIC struct {
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// KeepalivePeriod is the interval in seconds between keepalive packets
+	KeepalivePeriod int `json:"keepalivePeriod,omitempty"`
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// MaxIdleTimeout is the time in seconds after which an idle connection is closed
+	MaxIdleTimeout int `json:"maxIdleTimeout,omitempty"`
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// MaxIncomingStreams is the maximum number of concurrent streams the server may open
+	MaxIncomingStreams int `json:"maxIncomingStreams,omitempty"`
+}
+
+type ClientSpec_Server_ServerRef struct {
+	Name string `json:"name"`
 }
 
 type ClientSpec_Server_TLS struct {
@@ -164,7 +276,7 @@ type ClientSpec_PodTemplate struct {
 // ClientStatus defines the observed state of Client
 type ClientStatus struct {
 	// +optional
-	// Phase indicates the current state: Pending, Running, Failed, Unknown
+	// Phase indicates the current state: Pending, Running, Failed, Suspended, Unknown
 	Phase string `json:"phase,omitempty"`
 	// +optional
 	// Message provides human-readable status information
@@ -181,11 +293,36 @@ type ClientStatus struct {
 	// +optional
 	// VisitorCount is the number of visitors associated with this client
 	VisitorCount int `json:"visitorCount,omitempty"`
+	// +optional
+	// ReadyReplicas is the number of ready frpc pods
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// +optional
+	// ActiveServer is the address of the server frpc connects to
+	ActiveServer string `json:"activeServer,omitempty"`
+	// +optional
+	// FailoverHistory lists the latest switches between servers, newest first
+	FailoverHistory []ClientStatus_Failover `json:"failoverHistory,omitempty"`
+	// +optional
+	// ConfigRevision is the revision of the configuration frpc runs with
+	ConfigRevision string `json:"configRevision,omitempty"`
+}
+
+// ClientStatus_Failover records a switch of frpc to another server
+type ClientStatus_Failover struct {
+	Time metav1.Time `json:"time"`
+	// +optional
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+	// +optional
+	Reason string `json:"reason,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+//+kubebuilder:printcolumn:name="Server",type=string,JSONPath=`.status.activeServer`,priority=1
+//+kubebuilder:printcolumn:name="Revision",type=string,JSONPath=`.status.configRevision`,priority=1
 //+kubebuilder:printcolumn:name="Upstreams",type=integer,JSONPath=`.status.upstreamCount`
 //+kubebuilder:printcolumn:name="Visitors",type=integer,JSONPath=`.status.visitorCount`
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
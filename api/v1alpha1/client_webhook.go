@@ -0,0 +1,249 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the Client defaulting and validating webhooks
+func (r *Client) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&ClientDefaulter{}).
+		WithValidator(&ClientValidator{Reader: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-frp-zufardhiyaulhaq-com-v1alpha1-client,mutating=true,failurePolicy=fail,sideEffects=None,groups=frp.zufardhiyaulhaq.com,resources=clients,verbs=create;update,versions=v1alpha1,name=mclient.frp.zufardhiyaulhaq.com,admissionReviewVersions=v1
+
+// ClientDefaulter applies the Client defaults at admission
+// +kubebuilder:object:generate=false
+type ClientDefaulter struct{}
+
+var _ admission.CustomDefaulter = &ClientDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *ClientDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	frpClient, ok := obj.(*Client)
+	if !ok {
+		return fmt.Errorf("expected a Client but got a %T", obj)
+	}
+
+	server := &frpClient.Spec.Server
+	if server.Transport == nil {
+		server.Transport = &ClientSpec_Server_Transport{}
+	}
+	if server.Transport.TCPMux == nil {
+		tcpMux := DefaultTCPMux
+		server.Transport.TCPMux = &tcpMux
+	}
+
+	if server.AdminServer != nil && server.AdminServer.Port == 0 {
+		server.AdminServer.Port = DefaultAdminPort
+	}
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-frp-zufardhiyaulhaq-com-v1alpha1-client,mutating=false,failurePolicy=fail,sideEffects=None,groups=frp.zufardhiyaulhaq.com,resources=clients,verbs=create;update,versions=v1alpha1,name=vclient.frp.zufardhiyaulhaq.com,admissionReviewVersions=v1
+
+// ClientValidator rejects Clients the operator can't render a configuration for
+// +kubebuilder:object:generate=false
+type ClientValidator struct {
+	Reader client.Reader
+}
+
+var _ admission.CustomValidator = &ClientValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *ClientValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(ctx, obj)
+}
+
+// ValidateUpdate implements admission.CustomValidator
+func (v *ClientValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(ctx, newObj)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *ClientValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ClientValidator) validate(ctx context.Context, obj runtime.Object) error {
+	frpClient, ok := obj.(*Client)
+	if !ok {
+		return fmt.Errorf("expected a Client but got a %T", obj)
+	}
+
+	var errs field.ErrorList
+	server := frpClient.Spec.Server
+	serverPath := field.NewPath("spec", "server")
+
+	if server.ServerRef == nil {
+		if server.Host == "" {
+			errs = append(errs, field.Required(serverPath.Child("host"), "either host or serverRef is required"))
+		}
+		errs = append(errs, validatePort(serverPath.Child("port"), server.Port)...)
+	}
+
+	errs = append(errs, validateClientAuthentication(serverPath.Child("authentication"), server.Authentication)...)
+
+	if server.AdminServer != nil {
+		errs = append(errs, validatePort(serverPath.Child("adminServer", "port"), server.AdminServer.Port)...)
+	}
+
+	if server.Transport != nil && server.Transport.QUIC != nil && (server.Protocol == nil || *server.Protocol != "quic") {
+		errs = append(errs, field.Forbidden(serverPath.Child("transport", "quic"), "quic settings require the quic protocol"))
+	}
+
+	protocolErrs, err := validateServerRefProtocol(ctx, v.Reader, frpClient.Namespace, serverPath.Child("protocol"), server.ServerRef, server.Protocol)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, protocolErrs...)
+
+	if failover := frpClient.Spec.Failover; failover != nil {
+		if udpProtocol(server.Protocol) {
+			errs = append(errs, field.Forbidden(serverPath.Child("protocol"), failoverUDPMessage))
+		}
+		for i, failoverServer := range failover.Servers {
+			failoverPath := field.NewPath("spec", "failover", "servers").Index(i)
+			if failoverServer.ServerRef == nil {
+				if failoverServer.Host == "" {
+					errs = append(errs, field.Required(failoverPath.Child("host"), "either host or serverRef is required"))
+				}
+				errs = append(errs, validatePort(failoverPath.Child("port"), failoverServer.Port)...)
+			}
+			if failoverServer.Authentication != nil {
+				errs = append(errs, validateClientAuthentication(failoverPath.Child("authentication"), *failoverServer.Authentication)...)
+			}
+
+			protocol := server.Protocol
+			if failoverServer.Protocol != nil {
+				protocol = failoverServer.Protocol
+				if udpProtocol(protocol) {
+					errs = append(errs, field.Forbidden(failoverPath.Child("protocol"), failoverUDPMessage))
+				}
+			}
+			protocolErrs, err := validateServerRefProtocol(ctx, v.Reader, frpClient.Namespace, failoverPath.Child("protocol"), failoverServer.ServerRef, protocol)
+			if err != nil {
+				return err
+			}
+			errs = append(errs, protocolErrs...)
+		}
+	}
+
+	if allowed := frpClient.Spec.AllowedNamespaces; allowed != nil && allowed.Selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(allowed.Selector); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "allowedNamespaces", "selector"), allowed.Selector, err.Error()))
+		}
+	}
+
+	secretErrs, err := validateSecrets(ctx, v.Reader, frpClient.Namespace, clientSecretFields(frpClient))
+	if err != nil {
+		return err
+	}
+	errs = append(errs, secretErrs...)
+
+	return invalid("Client", frpClient.Name, errs)
+}
+
+// failoverUDPMessage explains why failover rejects the kcp and quic protocols
+const failoverUDPMessage = "failover probes servers over TCP, kcp and quic servers listen on UDP and can't be probed"
+
+// udpProtocol reports whether frpc dials the server over UDP
+func udpProtocol(protocol *string) bool {
+	return protocol != nil && (*protocol == "kcp" || *protocol == "quic")
+}
+
+// validateClientAuthentication checks that exactly one authentication method is set
+func validateClientAuthentication(authPath *field.Path, authentication ClientSpec_Server_Authentication) field.ErrorList {
+	var errs field.ErrorList
+	if authentication.Token == nil && authentication.OIDC == nil {
+		errs = append(errs, field.Required(authPath, "either token or oidc authentication is required"))
+	}
+	if authentication.Token != nil && authentication.OIDC != nil {
+		errs = append(errs, field.Forbidden(authPath, "token and oidc authentication are mutually exclusive"))
+	}
+
+	return errs
+}
+
+// clientSecretFields returns the Secrets referenced by a Client
+func clientSecretFields(frpClient *Client) secretFields {
+	secrets := secretFields{}
+	server := frpClient.Spec.Server
+	serverPath := field.NewPath("spec", "server")
+
+	secrets.addAuthentication(serverPath.Child("authentication"), server.Authentication)
+
+	if server.AdminServer != nil {
+		adminPath := serverPath.Child("adminServer")
+		if server.AdminServer.Username != nil {
+			secrets.add(adminPath.Child("username", "secret"), server.AdminServer.Username.Secret)
+		}
+		if server.AdminServer.Password != nil {
+			secrets.add(adminPath.Child("password", "secret"), server.AdminServer.Password.Secret)
+		}
+	}
+
+	secrets.addTLS(serverPath.Child("tls"), server.TLS)
+
+	if frpClient.Spec.Failover != nil {
+		for i, failoverServer := range frpClient.Spec.Failover.Servers {
+			failoverPath := field.NewPath("spec", "failover", "servers").Index(i)
+			if failoverServer.Authentication != nil {
+				secrets.addAuthentication(failoverPath.Child("authentication"), *failoverServer.Authentication)
+			}
+			secrets.addTLS(failoverPath.Child("tls"), failoverServer.TLS)
+		}
+	}
+
+	return secrets
+}
+
+func (s *secretFields) addAuthentication(authPath *field.Path, authentication ClientSpec_Server_Authentication) {
+	if authentication.Token != nil {
+		s.add(authPath.Child("token", "secret"), authentication.Token.Secret)
+	}
+	if authentication.OIDC != nil {
+		s.addRef(authPath.Child("oidc", "clientId"), &authentication.OIDC.ClientID)
+		s.addRef(authPath.Child("oidc", "clientSecret"), &authentication.OIDC.ClientSecret)
+	}
+}
+
+func (s *secretFields) addTLS(tlsPath *field.Path, tls *ClientSpec_Server_TLS) {
+	if tls == nil {
+		return
+	}
+
+	s.addRef(tlsPath.Child("certFile"), tls.CertFile)
+	s.addRef(tlsPath.Child("keyFile"), tls.KeyFile)
+	if tls.TrustedCAFile != nil && tls.TrustedCAFile.Secret != nil {
+		s.add(tlsPath.Child("trustedCaFile", "secret"), *tls.TrustedCAFile.Secret)
+	}
+}
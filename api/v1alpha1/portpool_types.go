@@ -0,0 +1,99 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PortPoolSpec defines the remote ports allocated to the Upstreams of the Clients
+// connecting to a server
+type PortPoolSpec struct {
+	// Server is the frps the ports are allocated on, it matches the Clients of every
+	// namespace connecting to the same address and bind port, the active server of
+	// a Client with failover
+	Server PortPoolSpec_Server `json:"server"`
+	// +kubebuilder:validation:MinItems=1
+	// Ranges are the remote ports the server allows
+	Ranges []ServerSpec_PortRange `json:"ranges"`
+}
+
+type PortPoolSpec_Server struct {
+	// +optional
+	// Host matches Clients connecting to the server by host
+	Host string `json:"host,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	// Port is the bind port of the server reached by host, defaults to 7000
+	Port int `json:"port,omitempty"`
+	// +optional
+	// ServerRef matches Clients connecting to the in-cluster Server in the namespace
+	// of the PortPool
+	ServerRef *ClientSpec_Server_ServerRef `json:"serverRef,omitempty"`
+}
+
+// PortPoolStatus defines the observed state of PortPool
+type PortPoolStatus struct {
+	// +optional
+	// Phase indicates the current state: Ready, Exhausted
+	Phase string `json:"phase,omitempty"`
+	// +optional
+	// Message provides human-readable status information
+	Message string `json:"message,omitempty"`
+	// +optional
+	// Allocations are the ports allocated to Upstreams
+	Allocations []PortPoolStatus_Allocation `json:"allocations,omitempty"`
+	// +optional
+	// Available is the number of ports left in the pool
+	Available int `json:"available,omitempty"`
+}
+
+type PortPoolStatus_Allocation struct {
+	Port int `json:"port"`
+	// Upstream is the namespaced name of the Upstream the port is allocated to
+	Upstream string `json:"upstream"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Available",type=integer,JSONPath=`.status.available`
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// PortPool is the Schema for the portpools API, it allocates the remote ports of
+// TCP and UDP Upstreams that omit server.port
+type PortPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PortPoolSpec   `json:"spec,omitempty"`
+	Status PortPoolStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PortPoolList contains a list of PortPool
+type PortPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PortPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PortPool{}, &PortPoolList{})
+}
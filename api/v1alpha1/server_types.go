@@ -0,0 +1,182 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServerSpec defines the desired state of Server
+type ServerSpec struct {
+	// +optional
+	// +kubebuilder:default=7000
+	// BindPort is the port frps listens on for frpc connections
+	BindPort int `json:"bindPort,omitempty"`
+	// +optional
+	// KCPBindPort is the UDP port frps listens on for KCP connections
+	KCPBindPort int `json:"kcpBindPort,omitempty"`
+	// +optional
+	// QUICBindPort is the UDP port frps listens on for QUIC connections
+	QUICBindPort int `json:"quicBindPort,omitempty"`
+	// +optional
+	// VhostHTTPPort is the port used by HTTP upstreams
+	VhostHTTPPort int `json:"vhostHTTPPort,omitempty"`
+	// +optional
+	// VhostHTTPSPort is the port used by HTTPS upstreams
+	VhostHTTPSPort int `json:"vhostHTTPSPort,omitempty"`
+	// +optional
+	// TCPMuxHTTPConnectPort is the port used by TCPMUX upstreams
+	TCPMuxHTTPConnectPort int `json:"tcpmuxHTTPConnectPort,omitempty"`
+	// +optional
+	// SubdomainHost is the base domain for upstreams that use subdomain
+	SubdomainHost string `json:"subdomainHost,omitempty"`
+	// +optional
+	// AllowPorts restricts the remote ports that clients can request
+	AllowPorts     []ServerSpec_PortRange    `json:"allowPorts,omitempty"`
+	Authentication ServerSpec_Authentication `json:"authentication"`
+	// +optional
+	// Dashboard enables the frps web dashboard
+	Dashboard *ServerSpec_Dashboard `json:"dashboard,omitempty"`
+	// +optional
+	// TLS configures TLS for connections from frpc
+	TLS *ServerSpec_TLS `json:"tls,omitempty"`
+	// +optional
+	// Service customizes the Service that exposes frps
+	Service *ServerSpec_Service `json:"service,omitempty"`
+	// +optional
+	// PodTemplate allows customization of the FRP server pod
+	PodTemplate *ClientSpec_PodTemplate `json:"podTemplate,omitempty"`
+}
+
+// ServerSpec_PortRange is either a single port or an inclusive range of ports
+type ServerSpec_PortRange struct {
+	// +optional
+	Single int `json:"single,omitempty"`
+	// +optional
+	Start int `json:"start,omitempty"`
+	// +optional
+	End int `json:"end,omitempty"`
+}
+
+type ServerSpec_Authentication struct {
+	// +optional
+	// Token authentication using a shared secret
+	Token *ServerSpec_Authentication_Token `json:"token,omitempty"`
+	// +optional
+	// OIDC authentication for enterprise SSO
+	OIDC *ServerSpec_Authentication_OIDC `json:"oidc,omitempty"`
+}
+
+type ServerSpec_Authentication_Token struct {
+	Secret Secret `json:"secret"`
+}
+
+type ServerSpec_Authentication_OIDC struct {
+	// Issuer is the OIDC issuer used to verify client tokens
+	Issuer string `json:"issuer"`
+	// +optional
+	// Audience is the expected audience of client tokens
+	Audience string `json:"audience,omitempty"`
+	// +optional
+	// SkipExpiryCheck disables the token expiry check
+	SkipExpiryCheck bool `json:"skipExpiryCheck,omitempty"`
+	// +optional
+	// SkipIssuerCheck disables the token issuer check
+	SkipIssuerCheck bool `json:"skipIssuerCheck,omitempty"`
+}
+
+type ServerSpec_Dashboard struct {
+	// +kubebuilder:default=7500
+	Port int `json:"port"`
+	// +optional
+	Username *SecretRef `json:"username,omitempty"`
+	// +optional
+	Password *SecretRef `json:"password,omitempty"`
+}
+
+type ServerSpec_TLS struct {
+	// +optional
+	// Force rejects frpc connections that don't use TLS
+	Force bool `json:"force,omitempty"`
+	// +optional
+	// CertFile is a reference to the server certificate
+	CertFile *SecretRef `json:"certFile,omitempty"`
+	// +optional
+	// KeyFile is a reference to the server private key
+	KeyFile *SecretRef `json:"keyFile,omitempty"`
+	// +optional
+	// TrustedCAFile is a reference to the CA certificate used to verify clients
+	TrustedCAFile *ConfigMapOrSecretRef `json:"trustedCaFile,omitempty"`
+}
+
+type ServerSpec_Service struct {
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +kubebuilder:default=ClusterIP
+	// +optional
+	Type corev1.ServiceType `json:"type,omitempty"`
+	// +optional
+	// Annotations are additional annotations to add to the service
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// +optional
+	// LoadBalancerIP requests a specific IP for LoadBalancer services
+	LoadBalancerIP string `json:"loadBalancerIP,omitempty"`
+}
+
+// ServerStatus defines the observed state of Server
+type ServerStatus struct {
+	// +optional
+	// Phase indicates the current state: Pending, Running, Failed
+	Phase string `json:"phase,omitempty"`
+	// +optional
+	// Message provides human-readable status information
+	Message string `json:"message,omitempty"`
+	// +optional
+	// Address is the in-cluster address clients use to reach the server
+	Address string `json:"address,omitempty"`
+	// +optional
+	// Conditions represent the latest available observations
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Address",type=string,JSONPath=`.status.address`
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Server is the Schema for the servers API
+type Server struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ServerSpec   `json:"spec,omitempty"`
+	Status ServerStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ServerList contains a list of Server
+type ServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Server `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Server{}, &ServerList{})
+}
@@ -1,5 +1,10 @@
 package v1alpha1
 
+import (
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
 type Secret struct {
 	Name string `json:"name"`
 	Key  string `json:"key"`
@@ -20,3 +25,36 @@ type ConfigMapOrSecretRef struct {
 	// +optional
 	ConfigMap *ConfigMapRef `json:"configMap,omitempty"`
 }
+
+// ClientRef references a Client, optionally in another namespace
+type ClientRef struct {
+	Name string `json:"name"`
+	// +optional
+	// Namespace of the Client, defaults to the namespace of the referencing object.
+	// The Client must allow the namespace in spec.allowedNamespaces
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// clientKey resolves the Client an object is bound to. spec.clientRef takes
+// precedence over spec.client, and both default to the object namespace.
+func clientKey(name string, ref *ClientRef, namespace string) types.NamespacedName {
+	if ref != nil {
+		if ref.Namespace != "" {
+			namespace = ref.Namespace
+		}
+		return types.NamespacedName{Name: ref.Name, Namespace: namespace}
+	}
+
+	return types.NamespacedName{Name: name, Namespace: namespace}
+}
+
+// ServiceRef references a Service port the operator resolves into the local
+// address of an Upstream, instead of a hard-coded host and port
+type ServiceRef struct {
+	Name string `json:"name"`
+	// +optional
+	// Namespace of the Service, defaults to the namespace of the Upstream
+	Namespace string `json:"namespace,omitempty"`
+	// Port is the name or number of a Service port
+	Port intstr.IntOrString `json:"port"`
+}
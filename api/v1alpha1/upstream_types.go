@@ -18,11 +18,17 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // UpstreamSpec defines the desired state of Upstream
 type UpstreamSpec struct {
-	Client string `json:"client"`
+	// +optional
+	// Client is the name of a Client in the same namespace
+	Client string `json:"client,omitempty"`
+	// +optional
+	// ClientRef references a Client in another namespace
+	ClientRef *ClientRef `json:"clientRef,omitempty"`
 	// +optional
 	TCP *UpstreamSpec_TCP `json:"tcp,omitempty"`
 	// +optional
@@ -37,12 +43,48 @@ type UpstreamSpec struct {
 	HTTPS *UpstreamSpec_HTTPS `json:"https,omitempty"`
 	// +optional
 	TCPMUX *UpstreamSpec_TCPMUX `json:"tcpmux,omitempty"`
+	// +optional
+	SUDP *UpstreamSpec_SUDP `json:"sudp,omitempty"`
+	// +optional
+	// +kubebuilder:default=true
+	// Enabled set to false drops the proxy from the configuration of the Client
+	// without deleting the Upstream, an allocated port is kept
+	Enabled *bool `json:"enabled,omitempty"`
+	// +optional
+	// Schedule renders the proxy only during activation windows and until it expires
+	Schedule *UpstreamSpec_Schedule `json:"schedule,omitempty"`
+}
+
+// UpstreamSpec_Schedule limits when the proxy of an Upstream is rendered
+type UpstreamSpec_Schedule struct {
+	// +optional
+	// Windows during which the proxy is rendered, it is always rendered when empty
+	Windows []UpstreamSpec_Schedule_Window `json:"windows,omitempty"`
+	// +optional
+	// TimeZone of the window start expressions, such as Europe/Berlin, defaults to UTC
+	TimeZone string `json:"timeZone,omitempty"`
+	// +optional
+	// ExpiresAt removes the proxy from the configuration for good
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// UpstreamSpec_Schedule_Window is an activation window opened by a cron expression
+type UpstreamSpec_Schedule_Window struct {
+	// Start is a standard five field cron expression opening the window, such as "0 9 * * 1-5"
+	Start string `json:"start"`
+	// Duration the window stays open, such as 8h or 30m
+	Duration metav1.Duration `json:"duration"`
 }
 
 // UpstreamSpec_TCPMUX exposes a service using TCP multiplexing over HTTP CONNECT
 type UpstreamSpec_TCPMUX struct {
-	Host string `json:"host"`
-	Port int    `json:"port"`
+	// +optional
+	Host string `json:"host,omitempty"`
+	// +optional
+	Port int `json:"port,omitempty"`
+	// +optional
+	// ServiceRef resolves host and port from a Service
+	ServiceRef *ServiceRef `json:"serviceRef,omitempty"`
 	// +kubebuilder:validation:Enum=httpconnect
 	Multiplexer   string   `json:"multiplexer"`
 	CustomDomains []string `json:"customDomains"`
@@ -51,9 +93,14 @@ type UpstreamSpec_TCPMUX struct {
 }
 
 type UpstreamSpec_STCP struct {
-	Host      string                      `json:"host"`
-	Port      int                         `json:"port"`
-	SecretKey UpstreamSpec_STCP_SecretKey `json:"secretKey"`
+	// +optional
+	Host string `json:"host,omitempty"`
+	// +optional
+	Port int `json:"port,omitempty"`
+	// +optional
+	// ServiceRef resolves host and port from a Service
+	ServiceRef *ServiceRef                 `json:"serviceRef,omitempty"`
+	SecretKey  UpstreamSpec_STCP_SecretKey `json:"secretKey"`
 	// +kubebuilder:validation:Enum=v1;v2
 	// +optional
 	ProxyProtocol *string `json:"proxyProtocol"`
@@ -72,9 +119,14 @@ type UpstreamSpec_STCP_SecretKey struct {
 }
 
 type UpstreamSpec_XTCP struct {
-	Host      string                      `json:"host"`
-	Port      int                         `json:"port"`
-	SecretKey UpstreamSpec_XTCP_SecretKey `json:"secretKey"`
+	// +optional
+	Host string `json:"host,omitempty"`
+	// +optional
+	Port int `json:"port,omitempty"`
+	// +optional
+	// ServiceRef resolves host and port from a Service
+	ServiceRef *ServiceRef                 `json:"serviceRef,omitempty"`
+	SecretKey  UpstreamSpec_XTCP_SecretKey `json:"secretKey"`
 	// +kubebuilder:validation:Enum=v1;v2
 	// +optional
 	ProxyProtocol *string `json:"proxyProtocol"`
@@ -93,8 +145,13 @@ type UpstreamSpec_XTCP_SecretKey struct {
 }
 
 type UpstreamSpec_HTTP struct {
-	Host string `json:"host"`
-	Port int    `json:"port"`
+	// +optional
+	Host string `json:"host,omitempty"`
+	// +optional
+	Port int `json:"port,omitempty"`
+	// +optional
+	// ServiceRef resolves host and port from a Service
+	ServiceRef *ServiceRef `json:"serviceRef,omitempty"`
 	// +optional
 	Subdomain string `json:"subdomain,omitempty"`
 	// +optional
@@ -131,14 +188,24 @@ type UpstreamSpec_HTTP_HealthCheck struct {
 }
 
 type UpstreamSpec_HTTPS struct {
-	Host          string   `json:"host"`
-	Port          int      `json:"port"`
-	CustomDomains []string `json:"customDomains"`
+	// +optional
+	Host string `json:"host,omitempty"`
+	// +optional
+	Port int `json:"port,omitempty"`
+	// +optional
+	// ServiceRef resolves host and port from a Service
+	ServiceRef    *ServiceRef `json:"serviceRef,omitempty"`
+	CustomDomains []string    `json:"customDomains"`
 	// +kubebuilder:validation:Enum=v1;v2
 	// +optional
 	ProxyProtocol *string `json:"proxyProtocol,omitempty"`
 	// +optional
 	Transport *UpstreamSpec_TCP_Transport `json:"transport,omitempty"`
+	// +optional
+	// TLSSecret is the name of a kubernetes.io/tls Secret. frpc terminates TLS with
+	// its certificate through the https2http plugin and forwards plain HTTP to the
+	// backend, without it TLS is passed through to the backend.
+	TLSSecret string `json:"tlsSecret,omitempty"`
 }
 
 // LoadBalancer configures load balancing across multiple upstreams
@@ -184,8 +251,11 @@ type UpstreamSpec_TCP struct {
 	// +optional
 	Host string `json:"host,omitempty"`
 	// +optional
-	Port   int                     `json:"port,omitempty"`
-	Server UpstreamSpec_TCP_Server `json:"server"`
+	Port int `json:"port,omitempty"`
+	// +optional
+	// ServiceRef resolves host and port from a Service
+	ServiceRef *ServiceRef             `json:"serviceRef,omitempty"`
+	Server     UpstreamSpec_TCP_Server `json:"server"`
 	// +kubebuilder:validation:Enum=v1;v2
 	// +optional
 	ProxyProtocol *string `json:"proxyProtocol,omitempty"`
@@ -200,7 +270,10 @@ type UpstreamSpec_TCP struct {
 }
 
 type UpstreamSpec_TCP_Server struct {
-	Port int `json:"port"`
+	// +optional
+	// Port is the remote port on the server, when omitted a port is allocated
+	// from the PortPool of the server
+	Port int `json:"port,omitempty"`
 }
 
 type UpstreamSpec_TCP_HealthCheck struct {
@@ -229,19 +302,50 @@ type UpstreamSpec_TCP_Transport_BandwdithLimit struct {
 }
 
 type UpstreamSpec_UDP struct {
-	Host   string                  `json:"host"`
-	Port   int                     `json:"port"`
-	Server UpstreamSpec_UDP_Server `json:"server"`
+	// +optional
+	Host string `json:"host,omitempty"`
+	// +optional
+	Port int `json:"port,omitempty"`
+	// +optional
+	// ServiceRef resolves host and port from a Service
+	ServiceRef *ServiceRef             `json:"serviceRef,omitempty"`
+	Server     UpstreamSpec_UDP_Server `json:"server"`
 }
 
 type UpstreamSpec_UDP_Server struct {
-	Port int `json:"port"`
+	// +optional
+	// Port is the remote port on the server, when omitted a port is allocated
+	// from the PortPool of the server
+	Port int `json:"port,omitempty"`
+}
+
+// UpstreamSpec_SUDP exposes a UDP service to SUDP visitors sharing its secret key,
+// without a public remote port
+type UpstreamSpec_SUDP struct {
+	// +optional
+	Host string `json:"host,omitempty"`
+	// +optional
+	Port int `json:"port,omitempty"`
+	// +optional
+	// ServiceRef resolves host and port from a Service
+	ServiceRef *ServiceRef                 `json:"serviceRef,omitempty"`
+	SecretKey  UpstreamSpec_SUDP_SecretKey `json:"secretKey"`
+	// +optional
+	Transport *UpstreamSpec_TCP_Transport `json:"transport,omitempty"`
+	// +optional
+	// AllowUsers specifies which FRP users can connect to this tunnel.
+	// Use "*" to allow any user. Empty means only the same user.
+	AllowUsers []string `json:"allowUsers,omitempty"`
+}
+
+type UpstreamSpec_SUDP_SecretKey struct {
+	Secret Secret `json:"secret"`
 }
 
 // UpstreamStatus defines the observed state of Upstream
 type UpstreamStatus struct {
 	// +optional
-	// Phase indicates the current state: Pending, Active, Failed
+	// Phase indicates the current state: Pending, Active, Failed, Disabled, Inactive, Expired
 	Phase string `json:"phase,omitempty"`
 	// +optional
 	// Message provides human-readable status information
@@ -249,12 +353,26 @@ type UpstreamStatus struct {
 	// +optional
 	// RegisteredAt is when the proxy was registered with the server
 	RegisteredAt *metav1.Time `json:"registeredAt,omitempty"`
+	// +optional
+	// RemoteAddress is the address the server exposes the proxy on
+	RemoteAddress string `json:"remoteAddress,omitempty"`
+	// +optional
+	// AllocatedPort is the remote port allocated from a PortPool when server.port is omitted
+	AllocatedPort int `json:"allocatedPort,omitempty"`
+	// +optional
+	// NextScheduleTransition is when the schedule next adds or removes the proxy
+	NextScheduleTransition *metav1.Time `json:"nextScheduleTransition,omitempty"`
+	// +optional
+	// Conditions represent the latest available observations
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Client",type=string,JSONPath=`.spec.client`
 //+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Remote",type=string,JSONPath=`.status.remoteAddress`
+//+kubebuilder:printcolumn:name="Message",type=string,JSONPath=`.status.message`,priority=1
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Upstream is the Schema for the upstreams API
@@ -275,6 +393,74 @@ type UpstreamList struct {
 	Items           []Upstream `json:"items"`
 }
 
+// ClientKey returns the namespaced name of the Client the Upstream is bound to
+func (in *Upstream) ClientKey() types.NamespacedName {
+	return clientKey(in.Spec.Client, in.Spec.ClientRef, in.Namespace)
+}
+
+// ServiceRef returns the Service reference of the Upstream protocol, or nil when
+// the Upstream forwards to a fixed host and port
+func (in *Upstream) ServiceRef() *ServiceRef {
+	switch {
+	case in.Spec.TCP != nil:
+		return in.Spec.TCP.ServiceRef
+	case in.Spec.UDP != nil:
+		return in.Spec.UDP.ServiceRef
+	case in.Spec.STCP != nil:
+		return in.Spec.STCP.ServiceRef
+	case in.Spec.XTCP != nil:
+		return in.Spec.XTCP.ServiceRef
+	case in.Spec.HTTP != nil:
+		return in.Spec.HTTP.ServiceRef
+	case in.Spec.HTTPS != nil:
+		return in.Spec.HTTPS.ServiceRef
+	case in.Spec.TCPMUX != nil:
+		return in.Spec.TCPMUX.ServiceRef
+	case in.Spec.SUDP != nil:
+		return in.Spec.SUDP.ServiceRef
+	}
+
+	return nil
+}
+
+// RemotePort returns the remote port of a TCP or UDP Upstream, the allocated port
+// when server.port is omitted
+func (in *Upstream) RemotePort() int {
+	port := 0
+	switch {
+	case in.Spec.TCP != nil:
+		port = in.Spec.TCP.Server.Port
+	case in.Spec.UDP != nil:
+		port = in.Spec.UDP.Server.Port
+	default:
+		return 0
+	}
+
+	if port == 0 {
+		return in.Status.AllocatedPort
+	}
+	return port
+}
+
+// AllocatesPort reports whether a TCP or UDP Upstream omits server.port and needs a
+// port allocated from a PortPool
+func (in *Upstream) AllocatesPort() bool {
+	return (in.Spec.TCP != nil && in.Spec.TCP.Server.Port == 0) ||
+		(in.Spec.UDP != nil && in.Spec.UDP.Server.Port == 0)
+}
+
+// Replicable reports whether the proxy of the Upstream can be shared by several frpc
+// replicas, frp only supports load balancer groups for TCP, HTTP and TCPMUX
+func (in *Upstream) Replicable() bool {
+	return in.Spec.TCP != nil || in.Spec.HTTP != nil || in.Spec.TCPMUX != nil
+}
+
+// Enabled reports whether the proxy of the Upstream is rendered, it is unless
+// spec.enabled is false
+func (in *Upstream) Enabled() bool {
+	return in.Spec.Enabled == nil || *in.Spec.Enabled
+}
+
 func init() {
 	SchemeBuilder.Register(&Upstream{}, &UpstreamList{})
 }
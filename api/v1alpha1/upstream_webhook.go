@@ -0,0 +1,257 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the Upstream defaulting and validating webhooks
+func (r *Upstream) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&UpstreamDefaulter{}).
+		WithValidator(&UpstreamValidator{Reader: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-frp-zufardhiyaulhaq-com-v1alpha1-upstream,mutating=true,failurePolicy=fail,sideEffects=None,groups=frp.zufardhiyaulhaq.com,resources=upstreams,verbs=create;update,versions=v1alpha1,name=mupstream.frp.zufardhiyaulhaq.com,admissionReviewVersions=v1
+
+// UpstreamDefaulter applies the Upstream defaults at admission
+// +kubebuilder:object:generate=false
+type UpstreamDefaulter struct{}
+
+var _ admission.CustomDefaulter = &UpstreamDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *UpstreamDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	upstream, ok := obj.(*Upstream)
+	if !ok {
+		return fmt.Errorf("expected an Upstream but got a %T", obj)
+	}
+
+	if upstream.Spec.ClientRef != nil && upstream.Spec.ClientRef.Namespace == "" {
+		upstream.Spec.ClientRef.Namespace = upstream.Namespace
+	}
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-frp-zufardhiyaulhaq-com-v1alpha1-upstream,mutating=false,failurePolicy=fail,sideEffects=None,groups=frp.zufardhiyaulhaq.com,resources=upstreams,verbs=create;update,versions=v1alpha1,name=vupstream.frp.zufardhiyaulhaq.com,admissionReviewVersions=v1
+
+// UpstreamValidator rejects Upstreams the operator can't render a configuration for
+// +kubebuilder:object:generate=false
+type UpstreamValidator struct {
+	Reader client.Reader
+}
+
+var _ admission.CustomValidator = &UpstreamValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *UpstreamValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(ctx, obj)
+}
+
+// ValidateUpdate implements admission.CustomValidator
+func (v *UpstreamValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(ctx, newObj)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *UpstreamValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *UpstreamValidator) validate(ctx context.Context, obj runtime.Object) error {
+	upstream, ok := obj.(*Upstream)
+	if !ok {
+		return fmt.Errorf("expected an Upstream but got a %T", obj)
+	}
+
+	spec := upstream.Spec
+	specPath := field.NewPath("spec")
+	errs := validateClientReference(specPath, spec.Client, spec.ClientRef)
+
+	protocols := 0
+	if spec.TCP != nil {
+		protocols++
+		tcpPath := specPath.Child("tcp")
+		errs = append(errs, validateLocalAddress(tcpPath, spec.TCP.Host, spec.TCP.Port, spec.TCP.ServiceRef, false)...)
+		errs = append(errs, validateOptionalPort(tcpPath.Child("server", "port"), spec.TCP.Server.Port)...)
+		if spec.TCP.Server.Port == 0 && spec.TCP.LoadBalancer != nil {
+			errs = append(errs, field.Required(tcpPath.Child("server", "port"), "upstreams of a load balancer group share a fixed port"))
+		}
+		errs = append(errs, validateTransport(tcpPath.Child("transport"), spec.TCP.Transport)...)
+	}
+	if spec.UDP != nil {
+		protocols++
+		udpPath := specPath.Child("udp")
+		errs = append(errs, validateLocalAddress(udpPath, spec.UDP.Host, spec.UDP.Port, spec.UDP.ServiceRef, true)...)
+		errs = append(errs, validateOptionalPort(udpPath.Child("server", "port"), spec.UDP.Server.Port)...)
+	}
+	if spec.STCP != nil {
+		protocols++
+		errs = append(errs, validateLocalAddress(specPath.Child("stcp"), spec.STCP.Host, spec.STCP.Port, spec.STCP.ServiceRef, true)...)
+		errs = append(errs, validateTransport(specPath.Child("stcp", "transport"), spec.STCP.Transport)...)
+	}
+	if spec.XTCP != nil {
+		protocols++
+		errs = append(errs, validateLocalAddress(specPath.Child("xtcp"), spec.XTCP.Host, spec.XTCP.Port, spec.XTCP.ServiceRef, true)...)
+		errs = append(errs, validateTransport(specPath.Child("xtcp", "transport"), spec.XTCP.Transport)...)
+	}
+	if spec.HTTP != nil {
+		protocols++
+		errs = append(errs, validateLocalAddress(specPath.Child("http"), spec.HTTP.Host, spec.HTTP.Port, spec.HTTP.ServiceRef, true)...)
+		errs = append(errs, validateTransport(specPath.Child("http", "transport"), spec.HTTP.Transport)...)
+	}
+	if spec.HTTPS != nil {
+		protocols++
+		errs = append(errs, validateLocalAddress(specPath.Child("https"), spec.HTTPS.Host, spec.HTTPS.Port, spec.HTTPS.ServiceRef, true)...)
+		errs = append(errs, validateTransport(specPath.Child("https", "transport"), spec.HTTPS.Transport)...)
+	}
+	if spec.TCPMUX != nil {
+		protocols++
+		errs = append(errs, validateLocalAddress(specPath.Child("tcpmux"), spec.TCPMUX.Host, spec.TCPMUX.Port, spec.TCPMUX.ServiceRef, true)...)
+		errs = append(errs, validateTransport(specPath.Child("tcpmux", "transport"), spec.TCPMUX.Transport)...)
+	}
+	if spec.SUDP != nil {
+		protocols++
+		errs = append(errs, validateLocalAddress(specPath.Child("sudp"), spec.SUDP.Host, spec.SUDP.Port, spec.SUDP.ServiceRef, true)...)
+		errs = append(errs, validateTransport(specPath.Child("sudp", "transport"), spec.SUDP.Transport)...)
+	}
+
+	if protocols == 0 {
+		errs = append(errs, field.Required(specPath, "one of tcp, udp, stcp, xtcp, http, https, tcpmux or sudp is required"))
+	} else if protocols > 1 {
+		errs = append(errs, field.Forbidden(specPath, "only one of tcp, udp, stcp, xtcp, http, https, tcpmux or sudp may be set"))
+	}
+
+	errs = append(errs, validateSchedule(specPath.Child("schedule"), spec.Schedule)...)
+
+	portErrs, err := v.validateServerPort(ctx, upstream)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, portErrs...)
+
+	secretErrs, err := validateSecrets(ctx, v.Reader, upstream.Namespace, upstreamSecretFields(upstream))
+	if err != nil {
+		return err
+	}
+	errs = append(errs, secretErrs...)
+
+	return invalid("Upstream", upstream.Name, errs)
+}
+
+// validateServerPort rejects a server port already used by another Upstream of the
+// same Client, unless both Upstreams are in the same load balancer group
+func (v *UpstreamValidator) validateServerPort(ctx context.Context, upstream *Upstream) (field.ErrorList, error) {
+	port, group, path := upstreamServerPort(upstream)
+	if port == 0 {
+		return nil, nil
+	}
+
+	upstreams := &UpstreamList{}
+	if err := v.Reader.List(ctx, upstreams); err != nil {
+		return nil, err
+	}
+
+	clientKey := upstream.ClientKey()
+	for _, other := range upstreams.Items {
+		if other.Namespace == upstream.Namespace && other.Name == upstream.Name {
+			continue
+		}
+		if other.ClientKey() != clientKey {
+			continue
+		}
+
+		otherPort, otherGroup, _ := upstreamServerPort(&other)
+		if otherPort != port || (group != "" && group == otherGroup) {
+			continue
+		}
+
+		return field.ErrorList{field.Duplicate(path, fmt.Sprintf("%d is already used by upstream %s/%s", port, other.Namespace, other.Name))}, nil
+	}
+
+	return nil, nil
+}
+
+// upstreamServerPort returns the server port of a TCP or UDP Upstream and its
+// load balancer group
+func upstreamServerPort(upstream *Upstream) (int, string, *field.Path) {
+	if upstream.Spec.TCP != nil {
+		group := ""
+		if upstream.Spec.TCP.LoadBalancer != nil {
+			group = upstream.Spec.TCP.LoadBalancer.Group
+		}
+		return upstream.Spec.TCP.Server.Port, group, field.NewPath("spec", "tcp", "server", "port")
+	}
+	if upstream.Spec.UDP != nil {
+		return upstream.Spec.UDP.Server.Port, "", field.NewPath("spec", "udp", "server", "port")
+	}
+
+	return 0, "", nil
+}
+
+// upstreamSecretFields returns the Secrets referenced by an Upstream
+func upstreamSecretFields(upstream *Upstream) secretFields {
+	secrets := secretFields{}
+	spec := upstream.Spec
+	specPath := field.NewPath("spec")
+
+	if spec.TCP != nil {
+		tcpPath := specPath.Child("tcp")
+		if spec.TCP.LoadBalancer != nil {
+			secrets.addRef(tcpPath.Child("loadBalancer", "groupKey"), spec.TCP.LoadBalancer.GroupKey)
+		}
+		if spec.TCP.Plugin != nil {
+			pluginPath := tcpPath.Child("plugin")
+			secrets.addRef(pluginPath.Child("username"), spec.TCP.Plugin.Username)
+			secrets.addRef(pluginPath.Child("password"), spec.TCP.Plugin.Password)
+			secrets.addRef(pluginPath.Child("httpUser"), spec.TCP.Plugin.HTTPUser)
+			secrets.addRef(pluginPath.Child("httpPassword"), spec.TCP.Plugin.HTTPPassword)
+		}
+	}
+	if spec.STCP != nil {
+		secrets.add(specPath.Child("stcp", "secretKey", "secret"), spec.STCP.SecretKey.Secret)
+	}
+	if spec.XTCP != nil {
+		secrets.add(specPath.Child("xtcp", "secretKey", "secret"), spec.XTCP.SecretKey.Secret)
+	}
+	if spec.SUDP != nil {
+		secrets.add(specPath.Child("sudp", "secretKey", "secret"), spec.SUDP.SecretKey.Secret)
+	}
+	if spec.HTTP != nil {
+		secrets.addRef(specPath.Child("http", "httpUser"), spec.HTTP.HTTPUser)
+		secrets.addRef(specPath.Child("http", "httpPassword"), spec.HTTP.HTTPPassword)
+	}
+	if spec.HTTPS != nil && spec.HTTPS.TLSSecret != "" {
+		tlsPath := specPath.Child("https", "tlsSecret")
+		secrets.add(tlsPath, Secret{Name: spec.HTTPS.TLSSecret, Key: corev1.TLSCertKey})
+		secrets.add(tlsPath, Secret{Name: spec.HTTPS.TLSSecret, Key: corev1.TLSPrivateKeyKey})
+	}
+
+	return secrets
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VirtualNetworkSpec defines the desired state of VirtualNetwork
+type VirtualNetworkSpec struct {
+	// CIDR is the IPv4 network the member addresses are taken from, e.g. 100.86.0.0/24
+	CIDR string `json:"cidr"`
+	// SecretKey is shared by the tunnels between the members
+	SecretKey VirtualNetworkSpec_SecretKey `json:"secretKey"`
+	// +kubebuilder:validation:MinItems=1
+	Members []VirtualNetworkSpec_Member `json:"members"`
+}
+
+type VirtualNetworkSpec_SecretKey struct {
+	Secret Secret `json:"secret"`
+}
+
+// VirtualNetworkSpec_Member is a frpc instance joining the network
+type VirtualNetworkSpec_Member struct {
+	// Name identifies the member on the frp server, every cluster declaring the
+	// network must use the same names
+	Name string `json:"name"`
+	// +optional
+	// Client is the Client in the namespace of the VirtualNetwork joining the network,
+	// members without Client are run by another cluster
+	Client string `json:"client,omitempty"`
+	// +optional
+	// Address pins the address of the member, it is required for members without Client
+	Address string `json:"address,omitempty"`
+}
+
+// VirtualNetworkStatus defines the observed state of VirtualNetwork
+type VirtualNetworkStatus struct {
+	// +optional
+	// Phase indicates the current state: Pending, Ready, Failed
+	Phase string `json:"phase,omitempty"`
+	// +optional
+	// Message provides human-readable status information
+	Message string `json:"message,omitempty"`
+	// +optional
+	// Members are the addresses assigned to the members
+	Members []VirtualNetworkStatus_Member `json:"members,omitempty"`
+}
+
+type VirtualNetworkStatus_Member struct {
+	Name string `json:"name"`
+	// +optional
+	Client  string `json:"client,omitempty"`
+	Address string `json:"address"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="CIDR",type=string,JSONPath=`.spec.cidr`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// VirtualNetwork is the Schema for the virtualnetworks API, it connects Clients
+// with the frp virtual_net plugin
+type VirtualNetwork struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VirtualNetworkSpec   `json:"spec,omitempty"`
+	Status VirtualNetworkStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// VirtualNetworkList contains a list of VirtualNetwork
+type VirtualNetworkList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VirtualNetwork `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VirtualNetwork{}, &VirtualNetworkList{})
+}
@@ -18,15 +18,28 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // VisitorSpec defines the desired state of Visitor
 type VisitorSpec struct {
-	Client string `json:"client"`
+	// +optional
+	// Client is the name of a Client in the same namespace
+	Client string `json:"client,omitempty"`
+	// +optional
+	// ClientRef references a Client in another namespace
+	ClientRef *ClientRef `json:"clientRef,omitempty"`
 	// +optional
 	STCP *VisitorSpec_STCP `json:"stcp"`
 	// +optional
 	XTCP *VisitorSpec_XTCP `json:"xtcp"`
+	// +optional
+	SUDP *VisitorSpec_SUDP `json:"sudp,omitempty"`
+	// +optional
+	// +kubebuilder:default=true
+	// Enabled set to false drops the visitor from the configuration of the Client
+	// without deleting the Visitor
+	Enabled *bool `json:"enabled,omitempty"`
 }
 
 type VisitorSpec_STCP struct {
@@ -59,6 +72,18 @@ type VisitorSpec_XTCP_ServerSecretKey struct {
 	Secret Secret `json:"secret"`
 }
 
+// VisitorSpec_SUDP binds a UDP port in the frpc pod forwarding to an SUDP Upstream
+type VisitorSpec_SUDP struct {
+	Host            string                           `json:"host"`
+	Port            int                              `json:"port"`
+	ServerName      string                           `json:"serverName"`
+	ServerSecretKey VisitorSpec_SUDP_ServerSecretKey `json:"serverSecretKey"`
+}
+
+type VisitorSpec_SUDP_ServerSecretKey struct {
+	Secret Secret `json:"secret"`
+}
+
 type VisitorSpec_Fallback struct {
 	ServerName string `json:"serverName"`
 	Timeout    int    `json:"timeout"`
@@ -67,7 +92,7 @@ type VisitorSpec_Fallback struct {
 // VisitorStatus defines the observed state of Visitor
 type VisitorStatus struct {
 	// +optional
-	// Phase indicates the current state: Pending, Active, Failed
+	// Phase indicates the current state: Pending, Active, Failed, Disabled
 	Phase string `json:"phase,omitempty"`
 	// +optional
 	// Message provides human-readable status information
@@ -101,6 +126,16 @@ type VisitorList struct {
 	Items           []Visitor `json:"items"`
 }
 
+// ClientKey returns the namespaced name of the Client the Visitor is bound to
+func (in *Visitor) ClientKey() types.NamespacedName {
+	return clientKey(in.Spec.Client, in.Spec.ClientRef, in.Namespace)
+}
+
+// Enabled reports whether the Visitor is rendered, it is unless spec.enabled is false
+func (in *Visitor) Enabled() bool {
+	return in.Spec.Enabled == nil || *in.Spec.Enabled
+}
+
 func init() {
 	SchemeBuilder.Register(&Visitor{}, &VisitorList{})
 }
@@ -0,0 +1,191 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the Visitor defaulting and validating webhooks
+func (r *Visitor) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&VisitorDefaulter{}).
+		WithValidator(&VisitorValidator{Reader: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-frp-zufardhiyaulhaq-com-v1alpha1-visitor,mutating=true,failurePolicy=fail,sideEffects=None,groups=frp.zufardhiyaulhaq.com,resources=visitors,verbs=create;update,versions=v1alpha1,name=mvisitor.frp.zufardhiyaulhaq.com,admissionReviewVersions=v1
+
+// VisitorDefaulter applies the Visitor defaults at admission
+// +kubebuilder:object:generate=false
+type VisitorDefaulter struct{}
+
+var _ admission.CustomDefaulter = &VisitorDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *VisitorDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	visitor, ok := obj.(*Visitor)
+	if !ok {
+		return fmt.Errorf("expected a Visitor but got a %T", obj)
+	}
+
+	if visitor.Spec.ClientRef != nil && visitor.Spec.ClientRef.Namespace == "" {
+		visitor.Spec.ClientRef.Namespace = visitor.Namespace
+	}
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-frp-zufardhiyaulhaq-com-v1alpha1-visitor,mutating=false,failurePolicy=fail,sideEffects=None,groups=frp.zufardhiyaulhaq.com,resources=visitors,verbs=create;update,versions=v1alpha1,name=vvisitor.frp.zufardhiyaulhaq.com,admissionReviewVersions=v1
+
+// VisitorValidator rejects Visitors the operator can't render a configuration for
+// +kubebuilder:object:generate=false
+type VisitorValidator struct {
+	Reader client.Reader
+}
+
+var _ admission.CustomValidator = &VisitorValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *VisitorValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(ctx, obj)
+}
+
+// ValidateUpdate implements admission.CustomValidator
+func (v *VisitorValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(ctx, newObj)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *VisitorValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *VisitorValidator) validate(ctx context.Context, obj runtime.Object) error {
+	visitor, ok := obj.(*Visitor)
+	if !ok {
+		return fmt.Errorf("expected a Visitor but got a %T", obj)
+	}
+
+	spec := visitor.Spec
+	specPath := field.NewPath("spec")
+	errs := validateClientReference(specPath, spec.Client, spec.ClientRef)
+
+	protocols := 0
+	if spec.STCP != nil {
+		protocols++
+		errs = append(errs, validatePort(specPath.Child("stcp", "port"), spec.STCP.Port)...)
+	}
+	if spec.XTCP != nil {
+		protocols++
+		errs = append(errs, validatePort(specPath.Child("xtcp", "port"), spec.XTCP.Port)...)
+	}
+	if spec.SUDP != nil {
+		protocols++
+		errs = append(errs, validatePort(specPath.Child("sudp", "port"), spec.SUDP.Port)...)
+	}
+
+	if protocols == 0 {
+		errs = append(errs, field.Required(specPath, "one of stcp, xtcp or sudp is required"))
+	} else if protocols > 1 {
+		errs = append(errs, field.Forbidden(specPath, "only one of stcp, xtcp or sudp may be set"))
+	}
+
+	portErrs, err := v.validatePort(ctx, visitor)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, portErrs...)
+
+	secretErrs, err := validateSecrets(ctx, v.Reader, visitor.Namespace, visitorSecretFields(visitor))
+	if err != nil {
+		return err
+	}
+	errs = append(errs, secretErrs...)
+
+	return invalid("Visitor", visitor.Name, errs)
+}
+
+// validatePort rejects a port already bound by another Visitor of the same Client
+func (v *VisitorValidator) validatePort(ctx context.Context, visitor *Visitor) (field.ErrorList, error) {
+	port, path := visitorPort(visitor)
+	if port == 0 {
+		return nil, nil
+	}
+
+	visitors := &VisitorList{}
+	if err := v.Reader.List(ctx, visitors); err != nil {
+		return nil, err
+	}
+
+	clientKey := visitor.ClientKey()
+	for _, other := range visitors.Items {
+		if other.Namespace == visitor.Namespace && other.Name == visitor.Name {
+			continue
+		}
+		if other.ClientKey() != clientKey {
+			continue
+		}
+
+		if otherPort, _ := visitorPort(&other); otherPort == port {
+			return field.ErrorList{field.Duplicate(path, fmt.Sprintf("%d is already used by visitor %s/%s", port, other.Namespace, other.Name))}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// visitorPort returns the local port a Visitor binds
+func visitorPort(visitor *Visitor) (int, *field.Path) {
+	if visitor.Spec.STCP != nil {
+		return visitor.Spec.STCP.Port, field.NewPath("spec", "stcp", "port")
+	}
+	if visitor.Spec.XTCP != nil {
+		return visitor.Spec.XTCP.Port, field.NewPath("spec", "xtcp", "port")
+	}
+	if visitor.Spec.SUDP != nil {
+		return visitor.Spec.SUDP.Port, field.NewPath("spec", "sudp", "port")
+	}
+
+	return 0, nil
+}
+
+// visitorSecretFields returns the Secrets referenced by a Visitor
+func visitorSecretFields(visitor *Visitor) secretFields {
+	secrets := secretFields{}
+	specPath := field.NewPath("spec")
+
+	if visitor.Spec.STCP != nil {
+		secrets.add(specPath.Child("stcp", "serverSecretKey", "secret"), visitor.Spec.STCP.ServerSecretKey.Secret)
+	}
+	if visitor.Spec.XTCP != nil {
+		secrets.add(specPath.Child("xtcp", "serverSecretKey", "secret"), visitor.Spec.XTCP.ServerSecretKey.Secret)
+	}
+	if visitor.Spec.SUDP != nil {
+		secrets.add(specPath.Child("sudp", "serverSecretKey", "secret"), visitor.Spec.SUDP.ServerSecretKey.Secret)
+	}
+
+	return secrets
+}
@@ -0,0 +1,455 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestReader(objects ...client.Object) client.Reader {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = AddToScheme(scheme)
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func newTestSecret(namespace, name string, keys ...string) *corev1.Secret {
+	data := map[string][]byte{}
+	for _, key := range keys {
+		data[key] = []byte("value")
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       data,
+	}
+}
+
+func newTestClient() *Client {
+	return &Client{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge", Namespace: "default"},
+		Spec: ClientSpec{
+			Server: ClientSpec_Server{
+				Host: "frp.example.com",
+				Port: 7000,
+				Authentication: ClientSpec_Server_Authentication{
+					Token: &ClientSpec_Server_Authentication_Token{
+						Secret: Secret{Name: "token", Key: "token"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newTestTCPUpstream(name string, serverPort int) *Upstream {
+	return &Upstream{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: UpstreamSpec{
+			Client: "edge",
+			TCP: &UpstreamSpec_TCP{
+				Host:   "127.0.0.1",
+				Port:   80,
+				Server: UpstreamSpec_TCP_Server{Port: serverPort},
+			},
+		},
+	}
+}
+
+func expectInvalid(t *testing.T, err error, fields ...string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("expected validation error for %v", fields)
+	}
+	for _, f := range fields {
+		if !strings.Contains(err.Error(), f) {
+			t.Errorf("expected error on %s, got %v", f, err)
+		}
+	}
+}
+
+func TestClientDefaulter(t *testing.T) {
+	frpClient := newTestClient()
+	frpClient.Spec.Server.AdminServer = &ClientSpec_Server_AdminServer{}
+
+	if err := (&ClientDefaulter{}).Default(context.TODO(), frpClient); err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+
+	if frpClient.Spec.Server.Transport == nil || frpClient.Spec.Server.Transport.TCPMux == nil || !*frpClient.Spec.Server.Transport.TCPMux {
+		t.Errorf("Default() expected transport.tcpMux=true")
+	}
+	if frpClient.Spec.Server.AdminServer.Port != DefaultAdminPort {
+		t.Errorf("Default() adminServer.port = %v, want %v", frpClient.Spec.Server.AdminServer.Port, DefaultAdminPort)
+	}
+}
+
+func TestClientDefaulter_KeepsTCPMux(t *testing.T) {
+	tcpMux := false
+	frpClient := newTestClient()
+	frpClient.Spec.Server.Transport = &ClientSpec_Server_Transport{TCPMux: &tcpMux}
+
+	if err := (&ClientDefaulter{}).Default(context.TODO(), frpClient); err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+	if *frpClient.Spec.Server.Transport.TCPMux {
+		t.Errorf("Default() overwrote transport.tcpMux=false")
+	}
+}
+
+func TestClientValidator(t *testing.T) {
+	validator := &ClientValidator{Reader: newTestReader(newTestSecret("default", "token", "token"))}
+
+	if _, err := validator.ValidateCreate(context.TODO(), newTestClient()); err != nil {
+		t.Errorf("ValidateCreate() unexpected error = %v", err)
+	}
+
+	noAuth := newTestClient()
+	noAuth.Spec.Server.Authentication.Token = nil
+	_, err := validator.ValidateCreate(context.TODO(), noAuth)
+	expectInvalid(t, err, "spec.server.authentication")
+
+	bothAuth := newTestClient()
+	bothAuth.Spec.Server.Authentication.OIDC = &ClientSpec_Server_Authentication_OIDC{
+		ClientID:     SecretRef{Secret: Secret{Name: "token", Key: "token"}},
+		ClientSecret: SecretRef{Secret: Secret{Name: "token", Key: "token"}},
+	}
+	_, err = validator.ValidateCreate(context.TODO(), bothAuth)
+	expectInvalid(t, err, "mutually exclusive")
+
+	badPort := newTestClient()
+	badPort.Spec.Server.Port = 70000
+	_, err = validator.ValidateCreate(context.TODO(), badPort)
+	expectInvalid(t, err, "spec.server.port")
+
+	missingSecret := newTestClient()
+	missingSecret.Spec.Server.Authentication.Token.Secret.Name = "missing"
+	_, err = validator.ValidateCreate(context.TODO(), missingSecret)
+	expectInvalid(t, err, "spec.server.authentication.token.secret.name")
+
+	missingKey := newTestClient()
+	missingKey.Spec.Server.Authentication.Token.Secret.Key = "other"
+	_, err = validator.ValidateUpdate(context.TODO(), newTestClient(), missingKey)
+	expectInvalid(t, err, "spec.server.authentication.token.secret.key")
+
+	quicSettings := newTestClient()
+	quicSettings.Spec.Server.Transport = &ClientSpec_Server_Transport{QUIC: &ClientSpec_Server_Transport_QUIC{MaxIdleTimeout: 30}}
+	_, err = validator.ValidateCreate(context.TODO(), quicSettings)
+	expectInvalid(t, err, "spec.server.transport.quic")
+
+	quic := "quic"
+	quicSettings.Spec.Server.Protocol = &quic
+	if _, err := validator.ValidateCreate(context.TODO(), quicSettings); err != nil {
+		t.Errorf("ValidateCreate() unexpected error for quic settings with the quic protocol = %v", err)
+	}
+}
+
+func TestClientValidator_ServerRefProtocol(t *testing.T) {
+	server := &Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "frps", Namespace: "default"},
+		Spec:       ServerSpec{KCPBindPort: 7001},
+	}
+	validator := &ClientValidator{Reader: newTestReader(newTestSecret("default", "token", "token"), server)}
+
+	kcp, quic := "kcp", "quic"
+	kcpClient := newTestClient()
+	kcpClient.Spec.Server.ServerRef = &ClientSpec_Server_ServerRef{Name: "frps"}
+	kcpClient.Spec.Server.Protocol = &kcp
+	if _, err := validator.ValidateCreate(context.TODO(), kcpClient); err != nil {
+		t.Errorf("ValidateCreate() unexpected error = %v", err)
+	}
+
+	quicClient := kcpClient.DeepCopy()
+	quicClient.Spec.Server.Protocol = &quic
+	_, err := validator.ValidateCreate(context.TODO(), quicClient)
+	expectInvalid(t, err, "spec.server.protocol", "quicBindPort")
+
+	quicFailover := kcpClient.DeepCopy()
+	quicFailover.Spec.Failover = &ClientSpec_Failover{
+		Servers: []ClientSpec_FailoverServer{{ServerRef: &ClientSpec_Server_ServerRef{Name: "frps"}, Protocol: &quic}},
+	}
+	_, err = validator.ValidateCreate(context.TODO(), quicFailover)
+	expectInvalid(t, err, "spec.failover.servers[0].protocol")
+
+	missingServer := quicClient.DeepCopy()
+	missingServer.Spec.Server.ServerRef.Name = "missing"
+	if _, err := validator.ValidateCreate(context.TODO(), missingServer); err != nil {
+		t.Errorf("ValidateCreate() unexpected error for a Server that doesn't exist yet = %v", err)
+	}
+}
+
+func TestClientValidator_Failover(t *testing.T) {
+	validator := &ClientValidator{Reader: newTestReader(newTestSecret("default", "token", "token"))}
+
+	failover := newTestClient()
+	failover.Spec.Failover = &ClientSpec_Failover{
+		Servers: []ClientSpec_FailoverServer{
+			{Host: "backup.example.com", Port: 7000},
+			{ServerRef: &ClientSpec_Server_ServerRef{Name: "edge"}},
+		},
+	}
+	if _, err := validator.ValidateCreate(context.TODO(), failover); err != nil {
+		t.Errorf("ValidateCreate() unexpected error = %v", err)
+	}
+
+	noHost := failover.DeepCopy()
+	noHost.Spec.Failover.Servers[0].Host = ""
+	_, err := validator.ValidateCreate(context.TODO(), noHost)
+	expectInvalid(t, err, "spec.failover.servers[0].host")
+
+	missingSecret := failover.DeepCopy()
+	missingSecret.Spec.Failover.Servers[1].Authentication = &ClientSpec_Server_Authentication{
+		Token: &ClientSpec_Server_Authentication_Token{Secret: Secret{Name: "missing", Key: "token"}},
+	}
+	_, err = validator.ValidateCreate(context.TODO(), missingSecret)
+	expectInvalid(t, err, "spec.failover.servers[1].authentication.token.secret.name")
+
+	kcp, quic := "kcp", "quic"
+	kcpPrimary := failover.DeepCopy()
+	kcpPrimary.Spec.Server.Protocol = &kcp
+	_, err = validator.ValidateCreate(context.TODO(), kcpPrimary)
+	expectInvalid(t, err, "spec.server.protocol", "can't be probed")
+
+	quicFailover := failover.DeepCopy()
+	quicFailover.Spec.Failover.Servers[0].Protocol = &quic
+	_, err = validator.ValidateCreate(context.TODO(), quicFailover)
+	expectInvalid(t, err, "spec.failover.servers[0].protocol", "can't be probed")
+}
+
+func TestUpstreamDefaulter(t *testing.T) {
+	upstream := newTestTCPUpstream("web", 8080)
+	upstream.Spec.Client = ""
+	upstream.Spec.ClientRef = &ClientRef{Name: "edge"}
+
+	if err := (&UpstreamDefaulter{}).Default(context.TODO(), upstream); err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+	if upstream.Spec.ClientRef.Namespace != "default" {
+		t.Errorf("Default() clientRef.namespace = %v, want default", upstream.Spec.ClientRef.Namespace)
+	}
+}
+
+func TestUpstreamValidator(t *testing.T) {
+	existing := newTestTCPUpstream("existing", 8080)
+	validator := &UpstreamValidator{Reader: newTestReader(existing)}
+
+	if _, err := validator.ValidateCreate(context.TODO(), newTestTCPUpstream("web", 8081)); err != nil {
+		t.Errorf("ValidateCreate() unexpected error = %v", err)
+	}
+
+	// updating an upstream doesn't conflict with itself
+	if _, err := validator.ValidateUpdate(context.TODO(), existing, existing); err != nil {
+		t.Errorf("ValidateUpdate() unexpected error = %v", err)
+	}
+
+	_, err := validator.ValidateCreate(context.TODO(), newTestTCPUpstream("web", 8080))
+	expectInvalid(t, err, "spec.tcp.server.port", "existing")
+
+	otherClient := newTestTCPUpstream("web", 8080)
+	otherClient.Spec.Client = "other"
+	if _, err := validator.ValidateCreate(context.TODO(), otherClient); err != nil {
+		t.Errorf("ValidateCreate() unexpected error for another client = %v", err)
+	}
+
+	noClient := newTestTCPUpstream("web", 8081)
+	noClient.Spec.Client = ""
+	_, err = validator.ValidateCreate(context.TODO(), noClient)
+	expectInvalid(t, err, "spec.client")
+
+	multiple := newTestTCPUpstream("web", 8081)
+	multiple.Spec.UDP = &UpstreamSpec_UDP{Host: "127.0.0.1", Port: 53, Server: UpstreamSpec_UDP_Server{Port: 5353}}
+	_, err = validator.ValidateCreate(context.TODO(), multiple)
+	expectInvalid(t, err, "only one of")
+
+	none := newTestTCPUpstream("web", 8081)
+	none.Spec.TCP = nil
+	_, err = validator.ValidateCreate(context.TODO(), none)
+	expectInvalid(t, err, "one of tcp")
+
+	bandwidth := newTestTCPUpstream("web", 8081)
+	bandwidth.Spec.TCP.Transport = &UpstreamSpec_TCP_Transport{
+		BandwdithLimit: &UpstreamSpec_TCP_Transport_BandwdithLimit{Enabled: true, Limit: 0, Type: "GB"},
+	}
+	_, err = validator.ValidateCreate(context.TODO(), bandwidth)
+	expectInvalid(t, err, "spec.tcp.transport.bandwidthLimit.limit", "spec.tcp.transport.bandwidthLimit.type")
+
+	stcp := &Upstream{
+		ObjectMeta: metav1.ObjectMeta{Name: "ssh", Namespace: "default"},
+		Spec: UpstreamSpec{
+			Client: "edge",
+			STCP: &UpstreamSpec_STCP{
+				Host:      "127.0.0.1",
+				Port:      22,
+				SecretKey: UpstreamSpec_STCP_SecretKey{Secret: Secret{Name: "missing", Key: "key"}},
+			},
+		},
+	}
+	_, err = validator.ValidateCreate(context.TODO(), stcp)
+	expectInvalid(t, err, "spec.stcp.secretKey.secret.name")
+	sudp := &Upstream{
+		ObjectMeta: metav1.ObjectMeta{Name: "dns", Namespace: "default"},
+		Spec: UpstreamSpec{
+			Client: "edge",
+			SUDP: &UpstreamSpec_SUDP{
+				Host:      "127.0.0.1",
+				SecretKey: UpstreamSpec_SUDP_SecretKey{Secret: Secret{Name: "missing", Key: "key"}},
+			},
+		},
+	}
+	_, err = validator.ValidateCreate(context.TODO(), sudp)
+	expectInvalid(t, err, "spec.sudp.port", "spec.sudp.secretKey.secret.name")
+}
+
+func TestUpstreamValidator_LoadBalancerGroup(t *testing.T) {
+	existing := newTestTCPUpstream("existing", 8080)
+	existing.Spec.TCP.LoadBalancer = &LoadBalancer{Group: "web"}
+	validator := &UpstreamValidator{Reader: newTestReader(existing)}
+
+	upstream := newTestTCPUpstream("web", 8080)
+	upstream.Spec.TCP.LoadBalancer = &LoadBalancer{Group: "web"}
+	if _, err := validator.ValidateCreate(context.TODO(), upstream); err != nil {
+		t.Errorf("ValidateCreate() unexpected error for the same load balancer group = %v", err)
+	}
+
+	allocated := newTestTCPUpstream("web", 0)
+	allocated.Spec.TCP.LoadBalancer = &LoadBalancer{Group: "web"}
+	_, err := validator.ValidateCreate(context.TODO(), allocated)
+	expectInvalid(t, err, "spec.tcp.server.port")
+}
+
+func TestUpstreamValidator_AllocatedPort(t *testing.T) {
+	existing := newTestTCPUpstream("existing", 0)
+	existing.Status.AllocatedPort = 30000
+	validator := &UpstreamValidator{Reader: newTestReader(existing)}
+
+	if _, err := validator.ValidateCreate(context.TODO(), newTestTCPUpstream("web", 0)); err != nil {
+		t.Errorf("ValidateCreate() unexpected error for an upstream without server port = %v", err)
+	}
+
+	if !existing.AllocatesPort() || existing.RemotePort() != 30000 {
+		t.Errorf("RemotePort() = %v, want the allocated 30000", existing.RemotePort())
+	}
+
+	fixed := newTestTCPUpstream("web", 8080)
+	fixed.Status.AllocatedPort = 30000
+	if fixed.AllocatesPort() || fixed.RemotePort() != 8080 {
+		t.Errorf("RemotePort() = %v, want the server port 8080", fixed.RemotePort())
+	}
+}
+
+func TestUpstreamEnabled(t *testing.T) {
+	upstream := newTestTCPUpstream("web", 8080)
+	if !upstream.Enabled() {
+		t.Errorf("Enabled() = false, want upstreams enabled by default")
+	}
+
+	disabled := false
+	upstream.Spec.Enabled = &disabled
+	if upstream.Enabled() {
+		t.Errorf("Enabled() = true, want false")
+	}
+}
+
+func TestUpstreamValidator_Schedule(t *testing.T) {
+	validator := &UpstreamValidator{Reader: newTestReader()}
+
+	upstream := newTestTCPUpstream("support", 8080)
+	upstream.Spec.Schedule = &UpstreamSpec_Schedule{
+		Windows:  []UpstreamSpec_Schedule_Window{{Start: "0 9 * * 1-5", Duration: metav1.Duration{Duration: 8 * time.Hour}}},
+		TimeZone: "Europe/Berlin",
+	}
+	if _, err := validator.ValidateCreate(context.TODO(), upstream); err != nil {
+		t.Errorf("ValidateCreate() unexpected error = %v", err)
+	}
+
+	upstream.Spec.Schedule = &UpstreamSpec_Schedule{
+		Windows:  []UpstreamSpec_Schedule_Window{{Start: "every weekday"}},
+		TimeZone: "Mars/Olympus",
+	}
+	_, err := validator.ValidateCreate(context.TODO(), upstream)
+	expectInvalid(t, err, "spec.schedule.timeZone", "spec.schedule.windows[0].start", "spec.schedule.windows[0].duration")
+}
+
+func TestUpstreamValidator_ServiceRef(t *testing.T) {
+	validator := &UpstreamValidator{Reader: newTestReader()}
+
+	upstream := newTestTCPUpstream("web", 8080)
+	upstream.Spec.TCP.Host = ""
+	upstream.Spec.TCP.Port = 0
+	upstream.Spec.TCP.ServiceRef = &ServiceRef{Name: "web", Port: intstr.FromString("http")}
+	if _, err := validator.ValidateCreate(context.TODO(), upstream); err != nil {
+		t.Errorf("ValidateCreate() unexpected error for a serviceRef = %v", err)
+	}
+
+	both := newTestTCPUpstream("web", 8080)
+	both.Spec.TCP.ServiceRef = &ServiceRef{Name: "web", Port: intstr.FromInt(80)}
+	_, err := validator.ValidateCreate(context.TODO(), both)
+	expectInvalid(t, err, "spec.tcp.host", "spec.tcp.port")
+
+	invalidRef := &Upstream{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: UpstreamSpec{
+			Client: "edge",
+			HTTP: &UpstreamSpec_HTTP{
+				ServiceRef: &ServiceRef{Port: intstr.FromInt(70000)},
+			},
+		},
+	}
+	_, err = validator.ValidateCreate(context.TODO(), invalidRef)
+	expectInvalid(t, err, "spec.http.serviceRef.name", "spec.http.serviceRef.port")
+}
+
+func TestVisitorValidator(t *testing.T) {
+	newVisitor := func(name string, port int) *Visitor {
+		return &Visitor{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: VisitorSpec{
+				Client: "edge",
+				STCP: &VisitorSpec_STCP{
+					Host:            "127.0.0.1",
+					Port:            port,
+					ServerName:      "ssh",
+					ServerSecretKey: VisitorSpec_STCP_ServerSecretKey{Secret: Secret{Name: "stcp", Key: "key"}},
+				},
+			},
+		}
+	}
+
+	validator := &VisitorValidator{Reader: newTestReader(newTestSecret("default", "stcp", "key"), newVisitor("existing", 2222))}
+
+	if _, err := validator.ValidateCreate(context.TODO(), newVisitor("ssh", 2223)); err != nil {
+		t.Errorf("ValidateCreate() unexpected error = %v", err)
+	}
+
+	_, err := validator.ValidateCreate(context.TODO(), newVisitor("ssh", 2222))
+	expectInvalid(t, err, "spec.stcp.port", "existing")
+
+	_, err = validator.ValidateCreate(context.TODO(), newVisitor("ssh", 0))
+	expectInvalid(t, err, "spec.stcp.port")
+
+	none := newVisitor("ssh", 2223)
+	none.Spec.STCP = nil
+	_, err = validator.ValidateCreate(context.TODO(), none)
+	expectInvalid(t, err, "one of stcp, xtcp or sudp")
+
+	sudp := newVisitor("dns", 2222)
+	sudp.Spec.STCP = nil
+	sudp.Spec.SUDP = &VisitorSpec_SUDP{
+		Host:            "0.0.0.0",
+		Port:            2222,
+		ServerName:      "dns",
+		ServerSecretKey: VisitorSpec_SUDP_ServerSecretKey{Secret: Secret{Name: "stcp", Key: "key"}},
+	}
+	_, err = validator.ValidateCreate(context.TODO(), sudp)
+	expectInvalid(t, err, "spec.sudp.port", "existing")
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Defaults applied at admission
+const (
+	DefaultAdminPort = 7400
+	DefaultTCPMux    = true
+)
+
+// secretField is a Secret referenced at a path of an object spec
+// +kubebuilder:object:generate=false
+type secretField struct {
+	path   *field.Path
+	secret Secret
+}
+
+// secretFields collects the Secrets referenced by an object spec
+// +kubebuilder:object:generate=false
+type secretFields []secretField
+
+func (s *secretFields) add(path *field.Path, secret Secret) {
+	*s = append(*s, secretField{path: path, secret: secret})
+}
+
+func (s *secretFields) addRef(path *field.Path, ref *SecretRef) {
+	if ref != nil {
+		s.add(path.Child("secret"), ref.Secret)
+	}
+}
+
+// validateSecrets checks that every referenced Secret exists in the namespace and
+// holds the referenced key
+func validateSecrets(ctx context.Context, reader client.Reader, namespace string, secrets secretFields) (field.ErrorList, error) {
+	var errs field.ErrorList
+
+	for _, ref := range secrets {
+		secret := &corev1.Secret{}
+		err := reader.Get(ctx, types.NamespacedName{Name: ref.secret.Name, Namespace: namespace}, secret)
+		if apierrors.IsNotFound(err) {
+			errs = append(errs, field.NotFound(ref.path.Child("name"), ref.secret.Name))
+			continue
+		} else if err != nil {
+			return errs, err
+		}
+
+		if _, ok := secret.Data[ref.secret.Key]; !ok {
+			errs = append(errs, field.Invalid(ref.path.Child("key"), ref.secret.Key,
+				fmt.Sprintf("key not found in secret %s", ref.secret.Name)))
+		}
+	}
+
+	return errs, nil
+}
+
+// validateServerRefProtocol checks that a referenced Server has the UDP bind port
+// the kcp and quic protocols dial, a Server that doesn't exist yet is left to the
+// Client reconcile
+func validateServerRefProtocol(ctx context.Context, reader client.Reader, namespace string, path *field.Path,
+	serverRef *ClientSpec_Server_ServerRef, protocol *string) (field.ErrorList, error) {
+
+	if serverRef == nil || protocol == nil || (*protocol != "kcp" && *protocol != "quic") {
+		return nil, nil
+	}
+
+	server := &Server{}
+	err := reader.Get(ctx, types.NamespacedName{Name: serverRef.Name, Namespace: namespace}, server)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if (*protocol == "kcp" && server.Spec.KCPBindPort == 0) || (*protocol == "quic" && server.Spec.QUICBindPort == 0) {
+		return field.ErrorList{field.Invalid(path, *protocol,
+			fmt.Sprintf("server %s doesn't set %sBindPort", serverRef.Name, *protocol))}, nil
+	}
+
+	return nil, nil
+}
+
+// validatePort checks that a port is in the valid TCP/UDP port range
+func validatePort(path *field.Path, port int) field.ErrorList {
+	if port < 1 || port > 65535 {
+		return field.ErrorList{field.Invalid(path, port, "must be between 1 and 65535")}
+	}
+
+	return nil
+}
+
+// validateOptionalPort checks the port range of a port that may be left unset
+func validateOptionalPort(path *field.Path, port int) field.ErrorList {
+	if port == 0 {
+		return nil
+	}
+
+	return validatePort(path, port)
+}
+
+// validateLocalAddress checks that an Upstream forwards to either a host and port
+// or a serviceRef. The port of a TCP Upstream may be left unset for plugins.
+func validateLocalAddress(path *field.Path, host string, port int, ref *ServiceRef, portRequired bool) field.ErrorList {
+	if ref == nil {
+		if portRequired {
+			return validatePort(path.Child("port"), port)
+		}
+		return validateOptionalPort(path.Child("port"), port)
+	}
+
+	var errs field.ErrorList
+	refPath := path.Child("serviceRef")
+	if host != "" {
+		errs = append(errs, field.Forbidden(path.Child("host"), "host and serviceRef are mutually exclusive"))
+	}
+	if port != 0 {
+		errs = append(errs, field.Forbidden(path.Child("port"), "port and serviceRef are mutually exclusive"))
+	}
+	if ref.Name == "" {
+		errs = append(errs, field.Required(refPath.Child("name"), "service name is required"))
+	}
+	if ref.Port.Type == intstr.String && ref.Port.StrVal == "" {
+		errs = append(errs, field.Required(refPath.Child("port"), "service port name or number is required"))
+	} else if ref.Port.Type == intstr.Int {
+		errs = append(errs, validatePort(refPath.Child("port"), ref.Port.IntValue())...)
+	}
+
+	return errs
+}
+
+// validateTransport checks the bandwidth limit of an upstream transport
+func validateTransport(path *field.Path, transport *UpstreamSpec_TCP_Transport) field.ErrorList {
+	if transport == nil || transport.BandwdithLimit == nil || !transport.BandwdithLimit.Enabled {
+		return nil
+	}
+
+	limit := transport.BandwdithLimit
+	limitPath := path.Child("bandwidthLimit")
+
+	var errs field.ErrorList
+	if limit.Limit < 1 {
+		errs = append(errs, field.Invalid(limitPath.Child("limit"), limit.Limit, "must be greater than 0"))
+	}
+	if limit.Type != "KB" && limit.Type != "MB" {
+		errs = append(errs, field.NotSupported(limitPath.Child("type"), limit.Type, []string{"KB", "MB"}))
+	}
+
+	return errs
+}
+
+// validateClientReference checks that an Upstream or Visitor names its Client
+func validateClientReference(path *field.Path, name string, ref *ClientRef) field.ErrorList {
+	if name == "" && (ref == nil || ref.Name == "") {
+		return field.ErrorList{field.Required(path.Child("client"), "either client or clientRef is required")}
+	}
+
+	return nil
+}
+
+// validateSchedule checks the time zone, window start expressions and durations of
+// an Upstream schedule
+func validateSchedule(path *field.Path, schedule *UpstreamSpec_Schedule) field.ErrorList {
+	if schedule == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+	if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+		errs = append(errs, field.Invalid(path.Child("timeZone"), schedule.TimeZone, "unknown time zone"))
+	}
+	for i, window := range schedule.Windows {
+		windowPath := path.Child("windows").Index(i)
+		if _, err := cron.ParseStandard(window.Start); err != nil {
+			errs = append(errs, field.Invalid(windowPath.Child("start"), window.Start, err.Error()))
+		}
+		if window.Duration.Duration <= 0 {
+			errs = append(errs, field.Invalid(windowPath.Child("duration"), window.Duration.String(), "must be greater than 0"))
+		}
+	}
+
+	return errs
+}
+
+// invalid wraps field errors into an Invalid API error, or returns nil
+func invalid(kind string, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind(kind).GroupKind(), name, errs)
+}
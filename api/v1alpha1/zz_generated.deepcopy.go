@@ -21,9 +21,9 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -85,15 +85,50 @@ func (in *ClientList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientRef) DeepCopyInto(out *ClientRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientRef.
+func (in *ClientRef) DeepCopy() *ClientRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClientSpec) DeepCopyInto(out *ClientSpec) {
 	*out = *in
 	in.Server.DeepCopyInto(&out.Server)
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
 	if in.PodTemplate != nil {
 		in, out := &in.PodTemplate, &out.PodTemplate
 		*out = new(ClientSpec_PodTemplate)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = new(ClientSpec_AllowedNamespaces)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Failover != nil {
+		in, out := &in.Failover, &out.Failover
+		*out = new(ClientSpec_Failover)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientSpec.
@@ -106,12 +141,99 @@ func (in *ClientSpec) DeepCopy() *ClientSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientSpec_AllowedNamespaces) DeepCopyInto(out *ClientSpec_AllowedNamespaces) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientSpec_AllowedNamespaces.
+func (in *ClientSpec_AllowedNamespaces) DeepCopy() *ClientSpec_AllowedNamespaces {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientSpec_AllowedNamespaces)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientSpec_Failover) DeepCopyInto(out *ClientSpec_Failover) {
+	*out = *in
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]ClientSpec_FailoverServer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientSpec_Failover.
+func (in *ClientSpec_Failover) DeepCopy() *ClientSpec_Failover {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientSpec_Failover)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientSpec_FailoverServer) DeepCopyInto(out *ClientSpec_FailoverServer) {
+	*out = *in
+	if in.ServerRef != nil {
+		in, out := &in.ServerRef, &out.ServerRef
+		*out = new(ClientSpec_Server_ServerRef)
+		**out = **in
+	}
+	if in.Protocol != nil {
+		in, out := &in.Protocol, &out.Protocol
+		*out = new(string)
+		**out = **in
+	}
+	if in.Authentication != nil {
+		in, out := &in.Authentication, &out.Authentication
+		*out = new(ClientSpec_Server_Authentication)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(ClientSpec_Server_TLS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Transport != nil {
+		in, out := &in.Transport, &out.Transport
+		*out = new(ClientSpec_Server_Transport)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientSpec_FailoverServer.
+func (in *ClientSpec_FailoverServer) DeepCopy() *ClientSpec_FailoverServer {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientSpec_FailoverServer)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClientSpec_PodTemplate) DeepCopyInto(out *ClientSpec_PodTemplate) {
 	*out = *in
 	if in.Resources != nil {
 		in, out := &in.Resources, &out.Resources
-		*out = new(v1.ResourceRequirements)
+		*out = new(corev1.ResourceRequirements)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.NodeSelector != nil {
@@ -123,14 +245,14 @@ func (in *ClientSpec_PodTemplate) DeepCopyInto(out *ClientSpec_PodTemplate) {
 	}
 	if in.Tolerations != nil {
 		in, out := &in.Tolerations, &out.Tolerations
-		*out = make([]v1.Toleration, len(*in))
+		*out = make([]corev1.Toleration, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	if in.Affinity != nil {
 		in, out := &in.Affinity, &out.Affinity
-		*out = new(v1.Affinity)
+		*out = new(corev1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.Labels != nil {
@@ -149,12 +271,12 @@ func (in *ClientSpec_PodTemplate) DeepCopyInto(out *ClientSpec_PodTemplate) {
 	}
 	if in.ImagePullSecrets != nil {
 		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
-		*out = make([]v1.LocalObjectReference, len(*in))
+		*out = make([]corev1.LocalObjectReference, len(*in))
 		copy(*out, *in)
 	}
 	if in.SecurityContext != nil {
 		in, out := &in.SecurityContext, &out.SecurityContext
-		*out = new(v1.PodSecurityContext)
+		*out = new(corev1.PodSecurityContext)
 		(*in).DeepCopyInto(*out)
 	}
 }
@@ -172,6 +294,11 @@ func (in *ClientSpec_PodTemplate) DeepCopy() *ClientSpec_PodTemplate {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClientSpec_Server) DeepCopyInto(out *ClientSpec_Server) {
 	*out = *in
+	if in.ServerRef != nil {
+		in, out := &in.ServerRef, &out.ServerRef
+		*out = new(ClientSpec_Server_ServerRef)
+		**out = **in
+	}
 	if in.Protocol != nil {
 		in, out := &in.Protocol, &out.Protocol
 		*out = new(string)
@@ -325,6 +452,21 @@ func (in *ClientSpec_Server_Authentication_Token) DeepCopy() *ClientSpec_Server_
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientSpec_Server_ServerRef) DeepCopyInto(out *ClientSpec_Server_ServerRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientSpec_Server_ServerRef.
+func (in *ClientSpec_Server_ServerRef) DeepCopy() *ClientSpec_Server_ServerRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientSpec_Server_ServerRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClientSpec_Server_TLS) DeepCopyInto(out *ClientSpec_Server_TLS) {
 	*out = *in
@@ -363,6 +505,11 @@ func (in *ClientSpec_Server_Transport) DeepCopyInto(out *ClientSpec_Server_Trans
 		*out = new(bool)
 		**out = **in
 	}
+	if in.QUIC != nil {
+		in, out := &in.QUIC, &out.QUIC
+		*out = new(ClientSpec_Server_Transport_QUIC)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientSpec_Server_Transport.
@@ -375,6 +522,21 @@ func (in *ClientSpec_Server_Transport) DeepCopy() *ClientSpec_Server_Transport {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientSpec_Server_Transport_QUIC) DeepCopyInto(out *ClientSpec_Server_Transport_QUIC) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientSpec_Server_Transport_QUIC.
+func (in *ClientSpec_Server_Transport_QUIC) DeepCopy() *ClientSpec_Server_Transport_QUIC {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientSpec_Server_Transport_QUIC)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClientStatus) DeepCopyInto(out *ClientStatus) {
 	*out = *in
@@ -384,7 +546,14 @@ func (in *ClientStatus) DeepCopyInto(out *ClientStatus) {
 	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]metav1.Condition, len(*in))
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FailoverHistory != nil {
+		in, out := &in.FailoverHistory, &out.FailoverHistory
+		*out = make([]ClientStatus_Failover, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -401,6 +570,22 @@ func (in *ClientStatus) DeepCopy() *ClientStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientStatus_Failover) DeepCopyInto(out *ClientStatus_Failover) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientStatus_Failover.
+func (in *ClientStatus_Failover) DeepCopy() *ClientStatus_Failover {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientStatus_Failover)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigMapOrSecretRef) DeepCopyInto(out *ConfigMapOrSecretRef) {
 	*out = *in
@@ -473,43 +658,464 @@ func (in *LoadBalancer) DeepCopyInto(out *LoadBalancer) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancer.
-func (in *LoadBalancer) DeepCopy() *LoadBalancer {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancer.
+func (in *LoadBalancer) DeepCopy() *LoadBalancer {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortPool) DeepCopyInto(out *PortPool) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortPool.
+func (in *PortPool) DeepCopy() *PortPool {
+	if in == nil {
+		return nil
+	}
+	out := new(PortPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PortPool) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortPoolList) DeepCopyInto(out *PortPoolList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PortPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortPoolList.
+func (in *PortPoolList) DeepCopy() *PortPoolList {
+	if in == nil {
+		return nil
+	}
+	out := new(PortPoolList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PortPoolList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortPoolSpec) DeepCopyInto(out *PortPoolSpec) {
+	*out = *in
+	in.Server.DeepCopyInto(&out.Server)
+	if in.Ranges != nil {
+		in, out := &in.Ranges, &out.Ranges
+		*out = make([]ServerSpec_PortRange, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortPoolSpec.
+func (in *PortPoolSpec) DeepCopy() *PortPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PortPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortPoolSpec_Server) DeepCopyInto(out *PortPoolSpec_Server) {
+	*out = *in
+	if in.ServerRef != nil {
+		in, out := &in.ServerRef, &out.ServerRef
+		*out = new(ClientSpec_Server_ServerRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortPoolSpec_Server.
+func (in *PortPoolSpec_Server) DeepCopy() *PortPoolSpec_Server {
+	if in == nil {
+		return nil
+	}
+	out := new(PortPoolSpec_Server)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortPoolStatus) DeepCopyInto(out *PortPoolStatus) {
+	*out = *in
+	if in.Allocations != nil {
+		in, out := &in.Allocations, &out.Allocations
+		*out = make([]PortPoolStatus_Allocation, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortPoolStatus.
+func (in *PortPoolStatus) DeepCopy() *PortPoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PortPoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortPoolStatus_Allocation) DeepCopyInto(out *PortPoolStatus_Allocation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortPoolStatus_Allocation.
+func (in *PortPoolStatus_Allocation) DeepCopy() *PortPoolStatus_Allocation {
+	if in == nil {
+		return nil
+	}
+	out := new(PortPoolStatus_Allocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Secret) DeepCopyInto(out *Secret) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Secret.
+func (in *Secret) DeepCopy() *Secret {
+	if in == nil {
+		return nil
+	}
+	out := new(Secret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretRef) DeepCopyInto(out *SecretRef) {
+	*out = *in
+	out.Secret = in.Secret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretRef.
+func (in *SecretRef) DeepCopy() *SecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Server) DeepCopyInto(out *Server) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Server.
+func (in *Server) DeepCopy() *Server {
+	if in == nil {
+		return nil
+	}
+	out := new(Server)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Server) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerList) DeepCopyInto(out *ServerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Server, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerList.
+func (in *ServerList) DeepCopy() *ServerList {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ServerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSpec) DeepCopyInto(out *ServerSpec) {
+	*out = *in
+	if in.AllowPorts != nil {
+		in, out := &in.AllowPorts, &out.AllowPorts
+		*out = make([]ServerSpec_PortRange, len(*in))
+		copy(*out, *in)
+	}
+	in.Authentication.DeepCopyInto(&out.Authentication)
+	if in.Dashboard != nil {
+		in, out := &in.Dashboard, &out.Dashboard
+		*out = new(ServerSpec_Dashboard)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(ServerSpec_TLS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(ServerSpec_Service)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodTemplate != nil {
+		in, out := &in.PodTemplate, &out.PodTemplate
+		*out = new(ClientSpec_PodTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSpec.
+func (in *ServerSpec) DeepCopy() *ServerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSpec_Authentication) DeepCopyInto(out *ServerSpec_Authentication) {
+	*out = *in
+	if in.Token != nil {
+		in, out := &in.Token, &out.Token
+		*out = new(ServerSpec_Authentication_Token)
+		**out = **in
+	}
+	if in.OIDC != nil {
+		in, out := &in.OIDC, &out.OIDC
+		*out = new(ServerSpec_Authentication_OIDC)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSpec_Authentication.
+func (in *ServerSpec_Authentication) DeepCopy() *ServerSpec_Authentication {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSpec_Authentication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSpec_Authentication_OIDC) DeepCopyInto(out *ServerSpec_Authentication_OIDC) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSpec_Authentication_OIDC.
+func (in *ServerSpec_Authentication_OIDC) DeepCopy() *ServerSpec_Authentication_OIDC {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSpec_Authentication_OIDC)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSpec_Authentication_Token) DeepCopyInto(out *ServerSpec_Authentication_Token) {
+	*out = *in
+	out.Secret = in.Secret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSpec_Authentication_Token.
+func (in *ServerSpec_Authentication_Token) DeepCopy() *ServerSpec_Authentication_Token {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSpec_Authentication_Token)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSpec_Dashboard) DeepCopyInto(out *ServerSpec_Dashboard) {
+	*out = *in
+	if in.Username != nil {
+		in, out := &in.Username, &out.Username
+		*out = new(SecretRef)
+		**out = **in
+	}
+	if in.Password != nil {
+		in, out := &in.Password, &out.Password
+		*out = new(SecretRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSpec_Dashboard.
+func (in *ServerSpec_Dashboard) DeepCopy() *ServerSpec_Dashboard {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSpec_Dashboard)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSpec_PortRange) DeepCopyInto(out *ServerSpec_PortRange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSpec_PortRange.
+func (in *ServerSpec_PortRange) DeepCopy() *ServerSpec_PortRange {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSpec_PortRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSpec_Service) DeepCopyInto(out *ServerSpec_Service) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSpec_Service.
+func (in *ServerSpec_Service) DeepCopy() *ServerSpec_Service {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSpec_Service)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSpec_TLS) DeepCopyInto(out *ServerSpec_TLS) {
+	*out = *in
+	if in.CertFile != nil {
+		in, out := &in.CertFile, &out.CertFile
+		*out = new(SecretRef)
+		**out = **in
+	}
+	if in.KeyFile != nil {
+		in, out := &in.KeyFile, &out.KeyFile
+		*out = new(SecretRef)
+		**out = **in
+	}
+	if in.TrustedCAFile != nil {
+		in, out := &in.TrustedCAFile, &out.TrustedCAFile
+		*out = new(ConfigMapOrSecretRef)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSpec_TLS.
+func (in *ServerSpec_TLS) DeepCopy() *ServerSpec_TLS {
 	if in == nil {
 		return nil
 	}
-	out := new(LoadBalancer)
+	out := new(ServerSpec_TLS)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Secret) DeepCopyInto(out *Secret) {
+func (in *ServerStatus) DeepCopyInto(out *ServerStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Secret.
-func (in *Secret) DeepCopy() *Secret {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerStatus.
+func (in *ServerStatus) DeepCopy() *ServerStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(Secret)
+	out := new(ServerStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SecretRef) DeepCopyInto(out *SecretRef) {
+func (in *ServiceRef) DeepCopyInto(out *ServiceRef) {
 	*out = *in
-	out.Secret = in.Secret
+	out.Port = in.Port
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretRef.
-func (in *SecretRef) DeepCopy() *SecretRef {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceRef.
+func (in *ServiceRef) DeepCopy() *ServiceRef {
 	if in == nil {
 		return nil
 	}
-	out := new(SecretRef)
+	out := new(ServiceRef)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -611,6 +1217,11 @@ func (in *UpstreamPlugin) DeepCopy() *UpstreamPlugin {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpstreamSpec) DeepCopyInto(out *UpstreamSpec) {
 	*out = *in
+	if in.ClientRef != nil {
+		in, out := &in.ClientRef, &out.ClientRef
+		*out = new(ClientRef)
+		**out = **in
+	}
 	if in.TCP != nil {
 		in, out := &in.TCP, &out.TCP
 		*out = new(UpstreamSpec_TCP)
@@ -619,7 +1230,7 @@ func (in *UpstreamSpec) DeepCopyInto(out *UpstreamSpec) {
 	if in.UDP != nil {
 		in, out := &in.UDP, &out.UDP
 		*out = new(UpstreamSpec_UDP)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.STCP != nil {
 		in, out := &in.STCP, &out.STCP
@@ -646,6 +1257,21 @@ func (in *UpstreamSpec) DeepCopyInto(out *UpstreamSpec) {
 		*out = new(UpstreamSpec_TCPMUX)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.SUDP != nil {
+		in, out := &in.SUDP, &out.SUDP
+		*out = new(UpstreamSpec_SUDP)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = new(UpstreamSpec_Schedule)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamSpec.
@@ -661,6 +1287,11 @@ func (in *UpstreamSpec) DeepCopy() *UpstreamSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpstreamSpec_HTTP) DeepCopyInto(out *UpstreamSpec_HTTP) {
 	*out = *in
+	if in.ServiceRef != nil {
+		in, out := &in.ServiceRef, &out.ServiceRef
+		*out = new(ServiceRef)
+		**out = **in
+	}
 	if in.CustomDomains != nil {
 		in, out := &in.CustomDomains, &out.CustomDomains
 		*out = make([]string, len(*in))
@@ -716,6 +1347,11 @@ func (in *UpstreamSpec_HTTP) DeepCopy() *UpstreamSpec_HTTP {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpstreamSpec_HTTPS) DeepCopyInto(out *UpstreamSpec_HTTPS) {
 	*out = *in
+	if in.ServiceRef != nil {
+		in, out := &in.ServiceRef, &out.ServiceRef
+		*out = new(ServiceRef)
+		**out = **in
+	}
 	if in.CustomDomains != nil {
 		in, out := &in.CustomDomains, &out.CustomDomains
 		*out = make([]string, len(*in))
@@ -761,6 +1397,11 @@ func (in *UpstreamSpec_HTTP_HealthCheck) DeepCopy() *UpstreamSpec_HTTP_HealthChe
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpstreamSpec_STCP) DeepCopyInto(out *UpstreamSpec_STCP) {
 	*out = *in
+	if in.ServiceRef != nil {
+		in, out := &in.ServiceRef, &out.ServiceRef
+		*out = new(ServiceRef)
+		**out = **in
+	}
 	out.SecretKey = in.SecretKey
 	if in.ProxyProtocol != nil {
 		in, out := &in.ProxyProtocol, &out.ProxyProtocol
@@ -810,9 +1451,101 @@ func (in *UpstreamSpec_STCP_SecretKey) DeepCopy() *UpstreamSpec_STCP_SecretKey {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpstreamSpec_SUDP) DeepCopyInto(out *UpstreamSpec_SUDP) {
+	*out = *in
+	if in.ServiceRef != nil {
+		in, out := &in.ServiceRef, &out.ServiceRef
+		*out = new(ServiceRef)
+		**out = **in
+	}
+	out.SecretKey = in.SecretKey
+	if in.Transport != nil {
+		in, out := &in.Transport, &out.Transport
+		*out = new(UpstreamSpec_TCP_Transport)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllowUsers != nil {
+		in, out := &in.AllowUsers, &out.AllowUsers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamSpec_SUDP.
+func (in *UpstreamSpec_SUDP) DeepCopy() *UpstreamSpec_SUDP {
+	if in == nil {
+		return nil
+	}
+	out := new(UpstreamSpec_SUDP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpstreamSpec_SUDP_SecretKey) DeepCopyInto(out *UpstreamSpec_SUDP_SecretKey) {
+	*out = *in
+	out.Secret = in.Secret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamSpec_SUDP_SecretKey.
+func (in *UpstreamSpec_SUDP_SecretKey) DeepCopy() *UpstreamSpec_SUDP_SecretKey {
+	if in == nil {
+		return nil
+	}
+	out := new(UpstreamSpec_SUDP_SecretKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpstreamSpec_Schedule) DeepCopyInto(out *UpstreamSpec_Schedule) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]UpstreamSpec_Schedule_Window, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamSpec_Schedule.
+func (in *UpstreamSpec_Schedule) DeepCopy() *UpstreamSpec_Schedule {
+	if in == nil {
+		return nil
+	}
+	out := new(UpstreamSpec_Schedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpstreamSpec_Schedule_Window) DeepCopyInto(out *UpstreamSpec_Schedule_Window) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamSpec_Schedule_Window.
+func (in *UpstreamSpec_Schedule_Window) DeepCopy() *UpstreamSpec_Schedule_Window {
+	if in == nil {
+		return nil
+	}
+	out := new(UpstreamSpec_Schedule_Window)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpstreamSpec_TCP) DeepCopyInto(out *UpstreamSpec_TCP) {
 	*out = *in
+	if in.ServiceRef != nil {
+		in, out := &in.ServiceRef, &out.ServiceRef
+		*out = new(ServiceRef)
+		**out = **in
+	}
 	out.Server = in.Server
 	if in.ProxyProtocol != nil {
 		in, out := &in.ProxyProtocol, &out.ProxyProtocol
@@ -854,6 +1587,11 @@ func (in *UpstreamSpec_TCP) DeepCopy() *UpstreamSpec_TCP {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpstreamSpec_TCPMUX) DeepCopyInto(out *UpstreamSpec_TCPMUX) {
 	*out = *in
+	if in.ServiceRef != nil {
+		in, out := &in.ServiceRef, &out.ServiceRef
+		*out = new(ServiceRef)
+		**out = **in
+	}
 	if in.CustomDomains != nil {
 		in, out := &in.CustomDomains, &out.CustomDomains
 		*out = make([]string, len(*in))
@@ -949,6 +1687,11 @@ func (in *UpstreamSpec_TCP_Transport_BandwdithLimit) DeepCopy() *UpstreamSpec_TC
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpstreamSpec_UDP) DeepCopyInto(out *UpstreamSpec_UDP) {
 	*out = *in
+	if in.ServiceRef != nil {
+		in, out := &in.ServiceRef, &out.ServiceRef
+		*out = new(ServiceRef)
+		**out = **in
+	}
 	out.Server = in.Server
 }
 
@@ -980,6 +1723,11 @@ func (in *UpstreamSpec_UDP_Server) DeepCopy() *UpstreamSpec_UDP_Server {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpstreamSpec_XTCP) DeepCopyInto(out *UpstreamSpec_XTCP) {
 	*out = *in
+	if in.ServiceRef != nil {
+		in, out := &in.ServiceRef, &out.ServiceRef
+		*out = new(ServiceRef)
+		**out = **in
+	}
 	out.SecretKey = in.SecretKey
 	if in.ProxyProtocol != nil {
 		in, out := &in.ProxyProtocol, &out.ProxyProtocol
@@ -1036,6 +1784,17 @@ func (in *UpstreamStatus) DeepCopyInto(out *UpstreamStatus) {
 		in, out := &in.RegisteredAt, &out.RegisteredAt
 		*out = (*in).DeepCopy()
 	}
+	if in.NextScheduleTransition != nil {
+		in, out := &in.NextScheduleTransition, &out.NextScheduleTransition
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamStatus.
@@ -1048,6 +1807,152 @@ func (in *UpstreamStatus) DeepCopy() *UpstreamStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualNetwork) DeepCopyInto(out *VirtualNetwork) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualNetwork.
+func (in *VirtualNetwork) DeepCopy() *VirtualNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VirtualNetwork) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualNetworkList) DeepCopyInto(out *VirtualNetworkList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VirtualNetwork, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualNetworkList.
+func (in *VirtualNetworkList) DeepCopy() *VirtualNetworkList {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualNetworkList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VirtualNetworkList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualNetworkSpec) DeepCopyInto(out *VirtualNetworkSpec) {
+	*out = *in
+	out.SecretKey = in.SecretKey
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]VirtualNetworkSpec_Member, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualNetworkSpec.
+func (in *VirtualNetworkSpec) DeepCopy() *VirtualNetworkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualNetworkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualNetworkSpec_Member) DeepCopyInto(out *VirtualNetworkSpec_Member) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualNetworkSpec_Member.
+func (in *VirtualNetworkSpec_Member) DeepCopy() *VirtualNetworkSpec_Member {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualNetworkSpec_Member)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualNetworkSpec_SecretKey) DeepCopyInto(out *VirtualNetworkSpec_SecretKey) {
+	*out = *in
+	out.Secret = in.Secret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualNetworkSpec_SecretKey.
+func (in *VirtualNetworkSpec_SecretKey) DeepCopy() *VirtualNetworkSpec_SecretKey {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualNetworkSpec_SecretKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualNetworkStatus) DeepCopyInto(out *VirtualNetworkStatus) {
+	*out = *in
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]VirtualNetworkStatus_Member, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualNetworkStatus.
+func (in *VirtualNetworkStatus) DeepCopy() *VirtualNetworkStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualNetworkStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualNetworkStatus_Member) DeepCopyInto(out *VirtualNetworkStatus_Member) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualNetworkStatus_Member.
+func (in *VirtualNetworkStatus_Member) DeepCopy() *VirtualNetworkStatus_Member {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualNetworkStatus_Member)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Visitor) DeepCopyInto(out *Visitor) {
 	*out = *in
@@ -1110,6 +2015,11 @@ func (in *VisitorList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VisitorSpec) DeepCopyInto(out *VisitorSpec) {
 	*out = *in
+	if in.ClientRef != nil {
+		in, out := &in.ClientRef, &out.ClientRef
+		*out = new(ClientRef)
+		**out = **in
+	}
 	if in.STCP != nil {
 		in, out := &in.STCP, &out.STCP
 		*out = new(VisitorSpec_STCP)
@@ -1120,6 +2030,16 @@ func (in *VisitorSpec) DeepCopyInto(out *VisitorSpec) {
 		*out = new(VisitorSpec_XTCP)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.SUDP != nil {
+		in, out := &in.SUDP, &out.SUDP
+		*out = new(VisitorSpec_SUDP)
+		**out = **in
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VisitorSpec.
@@ -1179,6 +2099,38 @@ func (in *VisitorSpec_STCP_ServerSecretKey) DeepCopy() *VisitorSpec_STCP_ServerS
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VisitorSpec_SUDP) DeepCopyInto(out *VisitorSpec_SUDP) {
+	*out = *in
+	out.ServerSecretKey = in.ServerSecretKey
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VisitorSpec_SUDP.
+func (in *VisitorSpec_SUDP) DeepCopy() *VisitorSpec_SUDP {
+	if in == nil {
+		return nil
+	}
+	out := new(VisitorSpec_SUDP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VisitorSpec_SUDP_ServerSecretKey) DeepCopyInto(out *VisitorSpec_SUDP_ServerSecretKey) {
+	*out = *in
+	out.Secret = in.Secret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VisitorSpec_SUDP_ServerSecretKey.
+func (in *VisitorSpec_SUDP_ServerSecretKey) DeepCopy() *VisitorSpec_SUDP_ServerSecretKey {
+	if in == nil {
+		return nil
+	}
+	out := new(VisitorSpec_SUDP_ServerSecretKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VisitorSpec_XTCP) DeepCopyInto(out *VisitorSpec_XTCP) {
 	*out = *in
@@ -0,0 +1,44 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+)
+
+// namespaceAllowed reports whether Upstreams and Visitors in the namespace may
+// bind to the Client. Namespace labels are only read when the Client uses a selector.
+func namespaceAllowed(ctx context.Context, reader ctrlclient.Reader, client *frpv1alpha1.Client, namespace string) (bool, error) {
+	var namespaceLabels map[string]string
+
+	if namespace != client.Namespace && client.Spec.AllowedNamespaces != nil && client.Spec.AllowedNamespaces.Selector != nil {
+		ns := &corev1.Namespace{}
+		if err := reader.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+			return false, ctrlclient.IgnoreNotFound(err)
+		}
+		namespaceLabels = ns.Labels
+	}
+
+	return models.NamespaceAllowed(client, namespace, namespaceLabels)
+}
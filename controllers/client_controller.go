@@ -17,27 +17,31 @@ limitations under the License.
 package controllers
 
 import (
-	"bytes"
 	"context"
+	goerrors "errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
-	"k8s.io/client-go/tools/remotecommand"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	ctrlhandler "sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
 	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/builder"
@@ -48,61 +52,120 @@ import (
 
 // Event reasons
 const (
-	EventReasonClientConnected    = "ClientConnected"
-	EventReasonConfigReloaded     = "ConfigReloaded"
-	EventReasonConfigReloadFailed = "ConfigReloadFailed"
+	EventReasonClientConnected       = "ClientConnected"
+	EventReasonConfigReloaded        = "ConfigReloaded"
+	EventReasonConfigReloadFailed    = "ConfigReloadFailed"
+	EventReasonRolloutStarted        = "RolloutStarted"
+	EventReasonAdminRotated          = "AdminCredentialsRotated"
+	EventReasonServerFailover        = "ServerFailover"
+	EventReasonConfigRolledBack      = "ConfigRolledBack"
+	EventReasonConfigRevisionInvalid = "ConfigRevisionInvalid"
 )
 
 // ClientReconciler reconciles a Client object
 type ClientReconciler struct {
 	client.Client
-	Scheme    *runtime.Scheme
-	Config    *rest.Config
-	Clientset kubernetes.Interface
-	Recorder  record.EventRecorder
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
-// readPodFile reads a file from a pod container and returns its content
-func (r *ClientReconciler) readPodFile(namespace, podName, containerName, filePath string) (string, error) {
-	req := r.Clientset.CoreV1().RESTClient().Post().
-		Resource("pods").
-		Name(podName).
-		Namespace(namespace).
-		SubResource("exec").
-		VersionedParams(&corev1.PodExecOptions{
-			Container: containerName,
-			Command:   []string{"cat", filePath},
-			Stdout:    true,
-			Stderr:    true,
-		}, scheme.ParameterCodec)
-
-	exec, err := remotecommand.NewSPDYExecutor(r.Config, "POST", req.URL())
-	if err != nil {
-		return "", fmt.Errorf("failed to create executor: %w", err)
+// runningPods returns the frpc pods whose admin API can be reached
+func runningPods(pods []corev1.Pod) []corev1.Pod {
+	running := []corev1.Pod{}
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		running = append(running, pod)
 	}
 
-	var stdout, stderr bytes.Buffer
-	err = exec.StreamWithContext(context.TODO(), remotecommand.StreamOptions{
-		Stdout: &stdout,
-		Stderr: &stderr,
-	})
+	return running
+}
+
+// proxyStartErrors returns the proxies frpc failed to start in any of the pods, and
+// whether frpc is still starting proxies. A pod that can't be asked counts as starting.
+func proxyStartErrors(config models.Config, pods []corev1.Pod) ([]string, bool) {
+	startErrors := []string{}
+	starting := false
+	for _, pod := range pods {
+		config.Common.AdminAddress = pod.Status.PodIP
+		proxies, err := handler.Status(config)
+		if err != nil {
+			starting = true
+			continue
+		}
+
+		for _, proxy := range proxies.StartErrors() {
+			startErrors = append(startErrors, fmt.Sprintf("%s (%s)", proxy.Name, proxy.Err))
+		}
+		starting = starting || proxies.Starting()
+	}
+
+	return startErrors, starting
+}
+
+// rollbackConfig restores the last known good configuration in the config Secret
+// after frpc rejected the applied one, and reports the rejected diff in the
+// ConfigSynced condition. It returns false when there is nothing to roll back to.
+func (r *ClientReconciler) rollbackConfig(ctx context.Context, client *frpv1alpha1.Client, secret *corev1.Secret, cause error) (bool, error) {
+	log := log.FromContext(ctx)
+
+	rejectedConfig := string(secret.Data[builder.ConfigFileKey])
+	lastKnownGood, ok := secret.Data[builder.LastKnownGoodKey]
+	if !ok || string(lastKnownGood) == rejectedConfig {
+		return false, nil
+	}
+
+	log.Info("roll back to the last known good config")
+	secret.Data[builder.ConfigFileKey] = lastKnownGood
+	client.Status.ConfigRevision = models.ConfigHash(string(lastKnownGood))
+	secret.Annotations[builder.RejectedConfigHashAnnotation] = models.ConfigHash(rejectedConfig)
+	secret.Annotations[builder.ReloadPendingAnnotation] = "true"
+	delete(secret.Annotations, builder.VerifyUntilAnnotation)
+	if err := r.Client.Update(ctx, secret, &ctrlclient.UpdateOptions{}); err != nil {
+		return false, err
+	}
+
+	r.Recorder.Event(client, corev1.EventTypeWarning, EventReasonConfigRolledBack,
+		fmt.Sprintf("Rolled back to the last known good configuration: %v", cause))
+	r.setCondition(client, status.ConditionTypeConfigSync, metav1.ConditionFalse, status.ReasonConfigRolledBack,
+		fmt.Sprintf("Configuration rejected, rolled back to the last known good configuration: %v\n%s",
+			cause, models.ConfigDiff(string(lastKnownGood), rejectedConfig)))
+
+	return true, nil
+}
+
+// pushConfig uploads the configuration to the frpc admin API unless frpc already
+// has it, and reloads frpc. Only a refused reload returns a handler.ResponseError.
+func pushConfig(config models.Config, expectedConfig string) error {
+	podConfig, err := handler.Config(config)
 	if err != nil {
-		return "", fmt.Errorf("exec failed: %w, stderr: %s", err, stderr.String())
+		return fmt.Errorf("failed to read config: %v", err)
 	}
 
-	return stdout.String(), nil
+	if podConfig != expectedConfig {
+		if err := handler.PutConfig(config, expectedConfig); err != nil {
+			return fmt.Errorf("failed to upload config: %v", err)
+		}
+	}
+
+	return handler.Reload(config)
 }
 
 //+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=clients,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=clients/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=clients/finalizers,verbs=update
 
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=virtualnetworks,verbs=get;list;watch
+
 //+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 
 func (r *ClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
@@ -117,38 +180,82 @@ func (r *ClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 	log.Info("list upstream configuration")
 	upstreams := &frpv1alpha1.UpstreamList{}
-	err = r.Client.List(ctx, upstreams)
+	err = r.Client.List(ctx, upstreams, ctrlclient.MatchingFields{clientIndexField: req.NamespacedName.String()})
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	filteredUpstreams := []frpv1alpha1.Upstream{}
+	for _, upstream := range upstreams.Items {
+		allowed, err := namespaceAllowed(ctx, r.Client, client, upstream.Namespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !allowed {
+			log.Info(fmt.Sprintf("skip upstream %s/%s, namespace is not allowed", upstream.Namespace, upstream.Name))
+			continue
+		}
+		if rendered, reason := models.UpstreamRendered(client, &upstream, time.Now()); !rendered {
+			log.Info(fmt.Sprintf("skip upstream %s/%s, %s", upstream.Namespace, upstream.Name, reason))
+			continue
+		}
+		filteredUpstreams = append(filteredUpstreams, upstream)
+	}
+	log.Info(fmt.Sprintf("find %d upstream for %s", len(filteredUpstreams), client.Name))
 
 	log.Info("list visitor configuration")
 	visitors := &frpv1alpha1.VisitorList{}
-	err = r.Client.List(ctx, visitors)
+	err = r.Client.List(ctx, visitors, ctrlclient.MatchingFields{clientIndexField: req.NamespacedName.String()})
 	if err != nil {
 		return ctrl.Result{}, err
 	}
-
-	var filteredUpstreams []frpv1alpha1.Upstream
-	for _, upstream := range upstreams.Items {
-		if upstream.Spec.Client == client.Name {
-			filteredUpstreams = append(filteredUpstreams, upstream)
-		}
-	}
-	log.Info(fmt.Sprintf("find %d upstream for %s", len(filteredUpstreams), client.Name))
-
-	var filteredVisitors []frpv1alpha1.Visitor
+	filteredVisitors := []frpv1alpha1.Visitor{}
 	for _, visitor := range visitors.Items {
-		if visitor.Spec.Client == client.Name {
-			filteredVisitors = append(filteredVisitors, visitor)
+		allowed, err := namespaceAllowed(ctx, r.Client, client, visitor.Namespace)
+		if err != nil {
+			return ctrl.Result{}, err
 		}
+		if !allowed {
+			log.Info(fmt.Sprintf("skip visitor %s/%s, namespace is not allowed", visitor.Namespace, visitor.Name))
+			continue
+		}
+		if !visitor.Enabled() {
+			log.Info(fmt.Sprintf("skip visitor %s/%s, visitor is disabled", visitor.Namespace, visitor.Name))
+			continue
+		}
+		filteredVisitors = append(filteredVisitors, visitor)
 	}
 	log.Info(fmt.Sprintf("find %d visitor for %s", len(filteredVisitors), client.Name))
 
-	config, err := models.NewConfig(r.Client, client, filteredUpstreams, filteredVisitors)
+	log.Info("reconcile admin credentials")
+	adminSecret, err := r.reconcileAdminSecret(ctx, client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("select active server")
+	activeServer, err := r.selectServer(ctx, client)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	serverClient := models.WithServer(client, activeServer.Server)
+
+	config, err := models.NewConfig(r.Client, serverClient, adminSecret, filteredUpstreams, filteredVisitors)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("list virtual network configuration")
+	virtualNetworks := &frpv1alpha1.VirtualNetworkList{}
+	err = r.Client.List(ctx, virtualNetworks, ctrlclient.InNamespace(client.Namespace))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	config.VirtualNet, err = models.NewVirtualNet(r.Client, client, virtualNetworks.Items)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	adminCredentialsHash := models.AdminCredentialsHash(config.Common.AdminUsername, config.Common.AdminPassword)
 
 	log.Info("Build configuration")
 	configuration, err := builder.NewConfigurationBuilder().
@@ -158,32 +265,84 @@ func (r *ClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, err
 	}
 
-	log.Info("Build config map")
-	configmap, err := builder.NewConfigMapBuilder().
+	log.Info("record config revision")
+	generations := models.ConfigGenerations(client, filteredUpstreams, filteredVisitors)
+	if err := r.reconcileConfigRevisions(ctx, client, configuration, generations, adminCredentialsHash, activeServer.Endpoint); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if client.Spec.ConfigRevision != "" {
+		log.Info("use pinned config revision", "revision", client.Spec.ConfigRevision)
+		configuration, err = r.pinnedConfiguration(ctx, client, adminCredentialsHash, activeServer.Endpoint)
+		if err != nil && (errors.IsNotFound(err) || errors.IsBadRequest(err)) {
+			message := fmt.Sprintf("Config revision %s can't be applied: %v", client.Spec.ConfigRevision, err)
+			r.Recorder.Event(client, corev1.EventTypeWarning, EventReasonConfigRevisionInvalid, message)
+			r.setCondition(client, status.ConditionTypeConfigSync, metav1.ConditionFalse, status.ReasonConfigRevisionInvalid, message)
+			if statusErr := r.updateClientStatus(ctx, client, status.ClientPhaseFailed, message, len(filteredUpstreams), len(filteredVisitors)); statusErr != nil {
+				log.Error(statusErr, "failed to update client status")
+			}
+			return ctrl.Result{}, nil
+		} else if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	log.Info("Build config secret")
+	configSecret, err := builder.NewSecretBuilder().
 		SetConfig(configuration).
 		SetName(client.Name).
 		SetNamespace(client.Namespace).
+		SetAdminCredentialsHash(adminCredentialsHash).
+		SetActiveServer(activeServer.Endpoint).
+		SetFiles(config.Files).
 		Build()
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	log.Info("set reference config map")
-	if err := controllerutil.SetControllerReference(client, configmap, r.Scheme); err != nil {
+	log.Info("set reference config secret")
+	if err := controllerutil.SetControllerReference(client, configSecret, r.Scheme); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	log.Info("get config map")
-	createdConfigMap := &corev1.ConfigMap{}
-	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: configmap.Name, Namespace: configmap.Namespace}, createdConfigMap)
+	log.Info("get config secret")
+	createdConfigSecret := &corev1.Secret{}
+	err = r.Client.Get(ctx, types.NamespacedName{Name: configSecret.Name, Namespace: configSecret.Namespace}, createdConfigSecret)
 	if err != nil && errors.IsNotFound(err) {
-		log.Info("create config map")
-		err = r.Client.Create(context.TODO(), configmap)
+		log.Info("create config secret")
+		err = r.Client.Create(ctx, configSecret)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
+		createdConfigSecret = configSecret
 	} else if err != nil {
 		return ctrl.Result{}, err
+	} else if createdConfigSecret.Annotations[builder.AdminCredentialsHashAnnotation] != adminCredentialsHash ||
+		createdConfigSecret.Annotations[builder.ActiveServerAnnotation] != activeServer.Endpoint ||
+		createdConfigSecret.Annotations[builder.FilesHashAnnotation] != configSecret.Annotations[builder.FilesHashAnnotation] {
+		// Running frpc pods can't be reloaded with new admin credentials, onto another
+		// server or before the kubelet updated their files, store the configuration right
+		// away so the pods rolled below start with it
+		log.Info("admin credentials, active server or files changed, update config secret")
+		createdConfigSecret.Data = configSecret.Data
+		createdConfigSecret.Annotations = configSecret.Annotations
+		if err := r.Client.Update(ctx, createdConfigSecret); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	client.Status.ConfigRevision = models.ConfigHash(string(createdConfigSecret.Data[builder.ConfigFileKey]))
+
+	// Older releases rendered the configuration, including secrets, into a ConfigMap
+	log.Info("delete legacy config map")
+	legacyConfigMap := &corev1.ConfigMap{}
+	err = r.Client.Get(ctx, types.NamespacedName{Name: configSecret.Name, Namespace: configSecret.Namespace}, legacyConfigMap)
+	if err == nil && metav1.IsControlledBy(legacyConfigMap, client) {
+		if err := r.Client.Delete(ctx, legacyConfigMap); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+	} else if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
 	}
 
 	log.Info("Build service")
@@ -194,11 +353,15 @@ func (r *ClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 	for _, visitor := range filteredVisitors {
 		if visitor.Spec.STCP != nil {
-			serviceBuilder.AddVisitorPort(visitor.Spec.STCP.Port)
+			serviceBuilder.AddVisitorPort(visitor.Spec.STCP.Port, corev1.ProtocolTCP)
 		}
 
 		if visitor.Spec.XTCP != nil {
-			serviceBuilder.AddVisitorPort(visitor.Spec.XTCP.Port)
+			serviceBuilder.AddVisitorPort(visitor.Spec.XTCP.Port, corev1.ProtocolTCP)
+		}
+
+		if visitor.Spec.SUDP != nil {
+			serviceBuilder.AddVisitorPort(visitor.Spec.SUDP.Port, corev1.ProtocolUDP)
 		}
 	}
 	service, err := serviceBuilder.Build()
@@ -229,20 +392,25 @@ func (r *ClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		SetName(client.Name).
 		SetNamespace(client.Namespace).
 		SetImage("fatedier/frpc:v0.65.0").
-		SetPodTemplate(client.Spec.PodTemplate)
-
-	// Wire TLS secret if configured
-	if client.Spec.Server.TLS != nil {
-		if client.Spec.Server.TLS.CertFile != nil {
-			podBuilder.SetTLSSecret(client.Spec.Server.TLS.CertFile.Secret.Name)
-		} else if client.Spec.Server.TLS.KeyFile != nil {
-			podBuilder.SetTLSSecret(client.Spec.Server.TLS.KeyFile.Secret.Name)
-		}
-		if client.Spec.Server.TLS.TrustedCAFile != nil {
-			if client.Spec.Server.TLS.TrustedCAFile.ConfigMap != nil {
-				podBuilder.SetTLSCAConfigMap(client.Spec.Server.TLS.TrustedCAFile.ConfigMap.Name)
-			} else if client.Spec.Server.TLS.TrustedCAFile.Secret != nil && podBuilder.TLSSecret == "" {
-				podBuilder.SetTLSSecret(client.Spec.Server.TLS.TrustedCAFile.Secret.Name)
+		SetPodTemplate(client.Spec.PodTemplate).
+		SetAdminCredentialsHash(adminCredentialsHash).
+		SetActiveServer(activeServer.Endpoint).
+		SetFilesHash(models.FilesHash(config.Files)).
+		SetVirtualNet(config.VirtualNet != nil).
+		SetServerProtocol(config.Common.ServerProtocol)
+
+	// Wire TLS secret of the active server if configured
+	if tls := serverClient.Spec.Server.TLS; tls != nil {
+		if tls.CertFile != nil {
+			podBuilder.SetTLSSecret(tls.CertFile.Secret.Name)
+		} else if tls.KeyFile != nil {
+			podBuilder.SetTLSSecret(tls.KeyFile.Secret.Name)
+		}
+		if tls.TrustedCAFile != nil {
+			if tls.TrustedCAFile.ConfigMap != nil {
+				podBuilder.SetTLSCAConfigMap(tls.TrustedCAFile.ConfigMap.Name)
+			} else if tls.TrustedCAFile.Secret != nil && podBuilder.TLSSecret == "" {
+				podBuilder.SetTLSSecret(tls.TrustedCAFile.Secret.Name)
 			}
 		}
 	}
@@ -252,19 +420,30 @@ func (r *ClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, err
 	}
 
-	log.Info("set reference pod")
-	if err := controllerutil.SetControllerReference(client, pod, r.Scheme); err != nil {
+	log.Info("Build deployment")
+	deployment, err := builder.NewDeploymentBuilder().
+		SetName(client.Name).
+		SetNamespace(client.Namespace).
+		SetReplicas(models.DeploymentReplicas(client)).
+		SetPod(pod).
+		Build()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("set reference deployment")
+	if err := controllerutil.SetControllerReference(client, deployment, r.Scheme); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	log.Info("get pod")
-	createdPod := &corev1.Pod{}
-	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, createdPod)
+	log.Info("get deployment")
+	createdDeployment := &appsv1.Deployment{}
+	err = r.Client.Get(ctx, types.NamespacedName{Name: deployment.Name, Namespace: deployment.Namespace}, createdDeployment)
 	if err != nil && errors.IsNotFound(err) {
-		log.Info("create pod")
-		err = r.Client.Create(context.TODO(), pod)
+		log.Info("create deployment")
+		err = r.Client.Create(ctx, deployment)
 		if err != nil {
-			r.setCondition(client, status.ConditionTypeReady, metav1.ConditionFalse, status.ReasonPodFailed, err.Error())
+			r.setCondition(client, status.ConditionTypeReady, metav1.ConditionFalse, status.ReasonDeploymentFailed, err.Error())
 			if statusErr := r.updateClientStatus(ctx, client, status.ClientPhaseFailed, err.Error(), len(filteredUpstreams), len(filteredVisitors)); statusErr != nil {
 				log.Error(statusErr, "failed to update client status")
 			}
@@ -272,105 +451,258 @@ func (r *ClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		}
 		// Emit event and set status
 		r.Recorder.Event(client, corev1.EventTypeNormal, EventReasonClientConnected,
-			fmt.Sprintf("FRP client pod created for server %s:%d", client.Spec.Server.Host, client.Spec.Server.Port))
-		r.setCondition(client, status.ConditionTypeReady, metav1.ConditionFalse, status.ReasonPodCreated, "Pod created, waiting for it to start")
-		if err := r.updateClientStatus(ctx, client, status.ClientPhasePending, "Pod created, waiting for it to start", len(filteredUpstreams), len(filteredVisitors)); err != nil {
+			fmt.Sprintf("FRP client deployment created for server %s:%d", config.Common.ServerAddress, config.Common.ServerPort))
+		r.setCondition(client, status.ConditionTypeReady, metav1.ConditionFalse, status.ReasonDeploymentCreated, "Deployment created, waiting for pods to start")
+		if err := r.updateClientStatus(ctx, client, status.ClientPhasePending, "Deployment created, waiting for pods to start", len(filteredUpstreams), len(filteredVisitors)); err != nil {
 			log.Error(err, "failed to update client status")
 		}
-		// Requeue to wait for pod to be created and running
+		// Requeue to wait for pods to be created and running
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 	} else if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	log.Info("check pod running")
-	if createdPod.Status.Phase != corev1.PodRunning {
-		r.setCondition(client, status.ConditionTypeReady, metav1.ConditionFalse, status.ReasonPodCreated, "Pod not yet running")
-		if err := r.updateClientStatus(ctx, client, status.ClientPhasePending, "Pod not yet running", len(filteredUpstreams), len(filteredVisitors)); err != nil {
+	log.Info("compare deployment")
+	desiredHash := deployment.Spec.Template.Annotations[builder.SpecHashAnnotation]
+	if createdDeployment.Spec.Template.Annotations[builder.SpecHashAnnotation] != desiredHash ||
+		*createdDeployment.Spec.Replicas != *deployment.Spec.Replicas {
+		log.Info("found deployment diff, update deployment")
+
+		rollout := createdDeployment.Spec.Template.Annotations[builder.SpecHashAnnotation] != desiredHash
+		createdDeployment.Spec = deployment.Spec
+		err := r.Client.Update(ctx, createdDeployment, &ctrlclient.UpdateOptions{})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if rollout {
+			r.Recorder.Event(client, corev1.EventTypeNormal, EventReasonRolloutStarted,
+				fmt.Sprintf("Rolling out frpc pods with spec hash %s", desiredHash))
+		}
+	} else {
+		log.Info("no deployment diff found")
+	}
+
+	log.Info("Build pod disruption budget")
+	pdb, err := builder.NewPodDisruptionBudgetBuilder().
+		SetName(client.Name).
+		SetNamespace(client.Namespace).
+		Build()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("set reference pod disruption budget")
+	if err := controllerutil.SetControllerReference(client, pdb, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("get pod disruption budget")
+	createdPDB := &policyv1.PodDisruptionBudget{}
+	err = r.Client.Get(ctx, types.NamespacedName{Name: pdb.Name, Namespace: pdb.Namespace}, createdPDB)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("create pod disruption budget")
+		err = r.Client.Create(ctx, pdb)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Earlier versions ran frpc as a bare pod with the same name as the deployment
+	log.Info("delete legacy pod")
+	legacyPod := &corev1.Pod{}
+	err = r.Client.Get(ctx, types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, legacyPod)
+	if err == nil && metav1.IsControlledBy(legacyPod, client) {
+		if err := r.Client.Delete(ctx, legacyPod); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+	} else if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	client.Status.ReadyReplicas = createdDeployment.Status.ReadyReplicas
+
+	log.Info("check deployment rollout")
+	if rolloutInProgress(createdDeployment, deployment) {
+		r.setCondition(client, status.ConditionTypeRollout, metav1.ConditionTrue, status.ReasonRolloutInProgress,
+			fmt.Sprintf("%d of %d frpc pods updated", createdDeployment.Status.UpdatedReplicas, *deployment.Spec.Replicas))
+	} else {
+		r.setCondition(client, status.ConditionTypeRollout, metav1.ConditionFalse, status.ReasonRolloutComplete,
+			"All frpc pods run the latest spec")
+	}
+
+	if client.Spec.Suspend {
+		return r.reconcileSuspended(ctx, client, createdConfigSecret, configSecret, len(filteredUpstreams), len(filteredVisitors))
+	}
+
+	log.Info("check deployment available")
+	if createdDeployment.Status.AvailableReplicas == 0 {
+		r.setCondition(client, status.ConditionTypeReady, metav1.ConditionFalse, status.ReasonDeploymentCreated, "No frpc pod available yet")
+		if err := r.updateClientStatus(ctx, client, status.ClientPhasePending, "No frpc pod available yet", len(filteredUpstreams), len(filteredVisitors)); err != nil {
 			log.Error(err, "failed to update client status")
 		}
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 	}
 
-	// Pod is running, update status
-	r.setCondition(client, status.ConditionTypeReady, metav1.ConditionTrue, status.ReasonPodRunning, "FRP client pod is running")
+	// Deployment is available, update status
+	r.setCondition(client, status.ConditionTypeReady, metav1.ConditionTrue, status.ReasonDeploymentReady,
+		fmt.Sprintf("%d/%d FRP client pods are available", createdDeployment.Status.AvailableReplicas, models.Replicas(client)))
 	if err := r.updateClientStatus(ctx, client, status.ClientPhaseRunning,
-		fmt.Sprintf("Connected to %s:%d", client.Spec.Server.Host, client.Spec.Server.Port),
+		fmt.Sprintf("Connected to %s:%d", config.Common.ServerAddress, config.Common.ServerPort),
 		len(filteredUpstreams), len(filteredVisitors)); err != nil {
 		log.Error(err, "failed to update client status")
 	}
 
-	log.Info("compare configmap")
-	reloadPending := createdConfigMap.Annotations != nil && createdConfigMap.Annotations["frp.zufardhiyaulhaq.com/reload-pending"] == "true"
+	log.Info("compare config secret")
+	expectedConfig := string(configSecret.Data[builder.ConfigFileKey])
+	appliedConfig := string(createdConfigSecret.Data[builder.ConfigFileKey])
+	rejected := createdConfigSecret.Annotations[builder.RejectedConfigHashAnnotation] == models.ConfigHash(expectedConfig)
+	reloadPending := createdConfigSecret.Annotations[builder.ReloadPendingAnnotation] == "true"
 
-	if !reflect.DeepEqual(createdConfigMap.Data, configmap.Data) {
-		log.Info("found config diff, update configmap")
+	if rejected && appliedConfig != expectedConfig {
+		log.Info("rendered config was rejected by frpc, keep the last known good config")
+	} else if appliedConfig != expectedConfig {
+		log.Info("found config diff, update config secret")
 
-		createdConfigMap.Data = configmap.Data
-		if createdConfigMap.Annotations == nil {
-			createdConfigMap.Annotations = make(map[string]string)
+		if createdConfigSecret.Data == nil {
+			createdConfigSecret.Data = make(map[string][]byte)
+		}
+		// The config the pods started with is the fallback until a config is verified
+		if _, ok := createdConfigSecret.Data[builder.LastKnownGoodKey]; !ok && appliedConfig != "" {
+			createdConfigSecret.Data[builder.LastKnownGoodKey] = []byte(appliedConfig)
 		}
-		createdConfigMap.Annotations["frp.zufardhiyaulhaq.com/reload-pending"] = "true"
+		createdConfigSecret.Data[builder.ConfigFileKey] = configSecret.Data[builder.ConfigFileKey]
+		if createdConfigSecret.Annotations == nil {
+			createdConfigSecret.Annotations = make(map[string]string)
+		}
+		createdConfigSecret.Annotations[builder.ReloadPendingAnnotation] = "true"
+		delete(createdConfigSecret.Annotations, builder.VerifyUntilAnnotation)
 
-		err := r.Client.Update(ctx, createdConfigMap, &ctrlclient.UpdateOptions{})
+		err := r.Client.Update(ctx, createdConfigSecret, &ctrlclient.UpdateOptions{})
 		if err != nil {
 			return ctrl.Result{}, err
 		}
-
-		// Requeue to allow ConfigMap to sync to the pod
-		log.Info("configmap updated, requeuing to verify sync")
-		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+		client.Status.ConfigRevision = models.ConfigHash(expectedConfig)
+		reloadPending = true
 	}
 
-	// Only verify and reload if there's a pending reload
+	// Only push and reload if there's a pending reload
 	if reloadPending {
-		// Read the config file from the pod to verify it matches expected config
-		log.Info("verifying configmap is synced to pod")
-		podConfigContent, err := r.readPodFile(
-			createdPod.Namespace,
-			createdPod.Name,
-			"frpc",
-			"/frp/config.toml",
-		)
+		log.Info("list frpc pods")
+		pods := &corev1.PodList{}
+		err = r.Client.List(ctx, pods, ctrlclient.InNamespace(client.Namespace), ctrlclient.MatchingLabels(deployment.Spec.Selector.MatchLabels))
 		if err != nil {
-			log.Error(err, "failed to read config from pod, requeuing")
-			return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+			return ctrl.Result{}, err
 		}
 
-		// Compare pod's config with expected config
-		expectedConfig := configmap.Data["config.toml"]
-		if podConfigContent != expectedConfig {
-			log.Info("configmap not yet synced to pod, requeuing")
-			return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+		// The Secret only reaches pods started after the update, running pods get the
+		// configuration through the admin API of frpc
+		log.Info("pushing config to frpc pods")
+		for _, pod := range runningPods(pods.Items) {
+			config.Common.AdminAddress = pod.Status.PodIP
+			err = pushConfig(config, string(createdConfigSecret.Data[builder.ConfigFileKey]))
+			if err != nil {
+				err = fmt.Errorf("pod %s: %w", pod.Name, err)
+				log.Error(err, "failed to reload config")
+
+				// frpc answered, the configuration itself is refused
+				var responseErr *handler.ResponseError
+				if goerrors.As(err, &responseErr) {
+					rolledBack, rollbackErr := r.rollbackConfig(ctx, client, createdConfigSecret, err)
+					if rollbackErr != nil {
+						return ctrl.Result{}, rollbackErr
+					}
+					if rolledBack {
+						if statusErr := r.updateClientStatus(ctx, client, status.ClientPhaseRunning, fmt.Sprintf("Config rolled back: %v", err), len(filteredUpstreams), len(filteredVisitors)); statusErr != nil {
+							log.Error(statusErr, "failed to update client status")
+						}
+						return ctrl.Result{Requeue: true}, nil
+					}
+				}
+
+				r.Recorder.Event(client, corev1.EventTypeWarning, EventReasonConfigReloadFailed,
+					fmt.Sprintf("Failed to reload config: %v", err))
+				r.setCondition(client, status.ConditionTypeConfigSync, metav1.ConditionFalse, status.ReasonConfigReloadFailed, err.Error())
+				if statusErr := r.updateClientStatus(ctx, client, status.ClientPhaseRunning, fmt.Sprintf("Config reload failed: %v", err), len(filteredUpstreams), len(filteredVisitors)); statusErr != nil {
+					log.Error(statusErr, "failed to update client status")
+				}
+				return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+			}
+		}
+
+		// Clear the reload-pending annotation and check the proxies of the config
+		delete(createdConfigSecret.Annotations, builder.ReloadPendingAnnotation)
+		createdConfigSecret.Annotations[builder.VerifyUntilAnnotation] = time.Now().Add(models.CONFIG_VERIFY_PERIOD).UTC().Format(time.RFC3339)
+		err = r.Client.Update(ctx, createdConfigSecret, &ctrlclient.UpdateOptions{})
+		if err != nil {
+			log.Error(err, "failed to clear reload-pending annotation")
+			return ctrl.Result{}, err
 		}
+		log.Info("config reloaded, verifying proxies")
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+	}
 
-		// Config is synced, reload frpc
-		log.Info("configmap synced to pod, reloading frpc config")
-		config.Common.AdminAddress = service.Name + "." + service.Namespace + ".svc"
-		err = handler.Reload(config)
+	if verifyUntil, ok := createdConfigSecret.Annotations[builder.VerifyUntilAnnotation]; ok {
+		log.Info("verify proxies of the reloaded config")
+		pods := &corev1.PodList{}
+		err = r.Client.List(ctx, pods, ctrlclient.InNamespace(client.Namespace), ctrlclient.MatchingLabels(deployment.Spec.Selector.MatchLabels))
 		if err != nil {
-			log.Error(err, "failed to reload config")
+			return ctrl.Result{}, err
+		}
+
+		startErrors, starting := proxyStartErrors(config, runningPods(pods.Items))
+		if len(startErrors) > 0 {
+			err := fmt.Errorf("proxies failed to start: %s", strings.Join(startErrors, ", "))
+			log.Error(err, "reloaded config failed")
+
+			rolledBack, rollbackErr := r.rollbackConfig(ctx, client, createdConfigSecret, err)
+			if rollbackErr != nil {
+				return ctrl.Result{}, rollbackErr
+			}
+			if rolledBack {
+				if statusErr := r.updateClientStatus(ctx, client, status.ClientPhaseRunning, fmt.Sprintf("Config rolled back: %v", err), len(filteredUpstreams), len(filteredVisitors)); statusErr != nil {
+					log.Error(statusErr, "failed to update client status")
+				}
+				return ctrl.Result{Requeue: true}, nil
+			}
+
+			// Nothing to roll back to, report the failure and stop verifying
 			r.Recorder.Event(client, corev1.EventTypeWarning, EventReasonConfigReloadFailed,
 				fmt.Sprintf("Failed to reload config: %v", err))
-			r.setCondition(client, status.ConditionTypeConfigSync, metav1.ConditionFalse, status.ReasonConfigReloadFailed, err.Error())
-			if statusErr := r.updateClientStatus(ctx, client, status.ClientPhaseRunning, fmt.Sprintf("Config reload failed: %v", err), len(filteredUpstreams), len(filteredVisitors)); statusErr != nil {
-				log.Error(statusErr, "failed to update client status")
+			if !rejected {
+				r.setCondition(client, status.ConditionTypeConfigSync, metav1.ConditionFalse, status.ReasonConfigReloadFailed, err.Error())
+			}
+			delete(createdConfigSecret.Annotations, builder.VerifyUntilAnnotation)
+			if err := r.Client.Update(ctx, createdConfigSecret, &ctrlclient.UpdateOptions{}); err != nil {
+				return ctrl.Result{}, err
 			}
+		} else if deadline, err := time.Parse(time.RFC3339, verifyUntil); starting && err == nil && time.Now().Before(deadline) {
+			log.Info("proxies are starting, requeuing")
 			return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
-		}
+		} else {
+			createdConfigSecret.Data[builder.LastKnownGoodKey] = createdConfigSecret.Data[builder.ConfigFileKey]
+			delete(createdConfigSecret.Annotations, builder.VerifyUntilAnnotation)
+			if !rejected {
+				delete(createdConfigSecret.Annotations, builder.RejectedConfigHashAnnotation)
+			}
+			if err := r.Client.Update(ctx, createdConfigSecret, &ctrlclient.UpdateOptions{}); err != nil {
+				return ctrl.Result{}, err
+			}
 
-		// Clear the reload-pending annotation
-		delete(createdConfigMap.Annotations, "frp.zufardhiyaulhaq.com/reload-pending")
-		err = r.Client.Update(ctx, createdConfigMap, &ctrlclient.UpdateOptions{})
-		if err != nil {
-			log.Error(err, "failed to clear reload-pending annotation")
-			return ctrl.Result{}, err
+			log.Info("config reloaded successfully")
+			r.Recorder.Event(client, corev1.EventTypeNormal, EventReasonConfigReloaded, "Configuration reloaded successfully")
+			// A Client running the last known good config after a rollback keeps reporting it
+			if !rejected {
+				r.setCondition(client, status.ConditionTypeConfigSync, metav1.ConditionTrue, status.ReasonConfigReloaded, "Configuration synchronized")
+			}
+			if err := r.Status().Update(ctx, client); err != nil {
+				log.Error(err, "failed to update client status")
+			}
 		}
-		log.Info("config reloaded successfully")
-		r.Recorder.Event(client, corev1.EventTypeNormal, EventReasonConfigReloaded, "Configuration reloaded successfully")
-		r.setCondition(client, status.ConditionTypeConfigSync, metav1.ConditionTrue, status.ReasonConfigReloaded, "Configuration synchronized")
 	} else {
-		log.Info("no configmap diff found")
+		log.Info("no config diff found")
 	}
 
 	log.Info("compare service")
@@ -386,28 +718,464 @@ func (r *ClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		log.Info("no service diff found")
 	}
 
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	// Keep probing the servers of a Client with failover
+	if client.Spec.Failover != nil {
+		return ctrl.Result{RequeueAfter: models.ProbeInterval(client)}, nil
+	}
+
+	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ClientReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	r.Config = mgr.GetConfig()
 	r.Recorder = mgr.GetEventRecorderFor("client-controller")
 
-	clientset, err := kubernetes.NewForConfig(r.Config)
-	if err != nil {
-		return fmt.Errorf("failed to create clientset: %w", err)
+	if err := setupIndexes(context.Background(), mgr); err != nil {
+		return fmt.Errorf("failed to setup field indexes: %w", err)
 	}
-	r.Clientset = clientset
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&frpv1alpha1.Client{}).
-		Owns(&corev1.Pod{}).
-		Owns(&corev1.ConfigMap{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&corev1.Secret{}).
 		Owns(&corev1.Service{}).
+		Watches(&frpv1alpha1.Upstream{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.upstreamToClient),
+			ctrlbuilder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, allocatedPortChangedPredicate(), scheduleChangedPredicate()))).
+		Watches(&frpv1alpha1.Visitor{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.visitorToClient),
+			ctrlbuilder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&frpv1alpha1.VirtualNetwork{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.virtualNetworkToClients)).
+		Watches(&corev1.Secret{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.secretToClients)).
+		Watches(&corev1.Service{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.serviceToClients)).
+		Watches(&corev1.Namespace{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.namespaceToClients),
+			ctrlbuilder.WithPredicates(predicate.LabelChangedPredicate{})).
 		Complete(r)
 }
 
+// reconcileSuspended reports a suspended Client. Without frpc pods to reload, the
+// rendered configuration is stored right away for the pods started on resume.
+func (r *ClientReconciler) reconcileSuspended(ctx context.Context, client *frpv1alpha1.Client,
+	createdConfigSecret *corev1.Secret, configSecret *corev1.Secret, upstreamCount, visitorCount int) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	expectedConfig := configSecret.Data[builder.ConfigFileKey]
+	rejected := createdConfigSecret.Annotations[builder.RejectedConfigHashAnnotation] == models.ConfigHash(string(expectedConfig))
+	if !rejected && !reflect.DeepEqual(createdConfigSecret.Data[builder.ConfigFileKey], expectedConfig) {
+		log.Info("client is suspended, store config secret")
+		createdConfigSecret.Data[builder.ConfigFileKey] = expectedConfig
+		delete(createdConfigSecret.Annotations, builder.ReloadPendingAnnotation)
+		delete(createdConfigSecret.Annotations, builder.VerifyUntilAnnotation)
+		if err := r.Client.Update(ctx, createdConfigSecret, &ctrlclient.UpdateOptions{}); err != nil {
+			return ctrl.Result{}, err
+		}
+		client.Status.ConfigRevision = models.ConfigHash(string(expectedConfig))
+	}
+
+	r.setCondition(client, status.ConditionTypeReady, metav1.ConditionFalse, status.ReasonSuspended, "Client is suspended, frpc is scaled to zero")
+	if err := r.updateClientStatus(ctx, client, status.ClientPhaseSuspended, "Client is suspended", upstreamCount, visitorCount); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// reconcileConfigRevisions stores the rendered configuration as an immutable
+// revision Secret and prunes the revisions beyond the history limit of the Client
+func (r *ClientReconciler) reconcileConfigRevisions(ctx context.Context, client *frpv1alpha1.Client,
+	configuration string, generations map[string]int64, adminCredentialsHash string, activeServer string) error {
+	log := log.FromContext(ctx)
+
+	revisionSecret, err := builder.NewRevisionSecretBuilder().
+		SetName(client.Name).
+		SetNamespace(client.Namespace).
+		SetConfig(configuration).
+		SetGenerations(generations).
+		SetAdminCredentialsHash(adminCredentialsHash).
+		SetActiveServer(activeServer).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	if err := controllerutil.SetControllerReference(client, revisionSecret, r.Scheme); err != nil {
+		return err
+	}
+
+	createdRevision := &corev1.Secret{}
+	err = r.Client.Get(ctx, types.NamespacedName{Name: revisionSecret.Name, Namespace: revisionSecret.Namespace}, createdRevision)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("create config revision", "revision", revisionSecret.Labels[models.CONFIG_REVISION_LABEL])
+		if err := r.Client.Create(ctx, revisionSecret); err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	revisions := &corev1.SecretList{}
+	err = r.Client.List(ctx, revisions, ctrlclient.InNamespace(client.Namespace),
+		ctrlclient.MatchingLabels{models.CONFIG_REVISION_CLIENT_LABEL: client.Name})
+	if err != nil {
+		return err
+	}
+
+	owned := []corev1.Secret{}
+	for _, revision := range revisions.Items {
+		if metav1.IsControlledBy(&revision, client) {
+			owned = append(owned, revision)
+		}
+	}
+
+	// The rendered, pinned and running revisions are never pruned
+	pruned := models.PruneRevisions(owned, models.RevisionHistoryLimit(client),
+		revisionSecret.Labels[models.CONFIG_REVISION_LABEL], client.Spec.ConfigRevision, client.Status.ConfigRevision)
+	for i := range pruned {
+		log.Info("prune config revision", "revision", pruned[i].Labels[models.CONFIG_REVISION_LABEL])
+		if err := r.Client.Delete(ctx, &pruned[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pinnedConfiguration returns the configuration stored in the revision the Client
+// is pinned to. frpc can't be reloaded with other admin credentials or onto another
+// server, so a revision rendered for them is refused.
+func (r *ClientReconciler) pinnedConfiguration(ctx context.Context, client *frpv1alpha1.Client,
+	adminCredentialsHash string, activeServer string) (string, error) {
+
+	revision := &corev1.Secret{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: models.RevisionSecretName(client.Name, client.Spec.ConfigRevision), Namespace: client.Namespace}, revision)
+	if err != nil {
+		return "", err
+	}
+	if !metav1.IsControlledBy(revision, client) {
+		return "", errors.NewBadRequest(fmt.Sprintf("secret %s is no config revision of client %s", revision.Name, client.Name))
+	}
+
+	if revision.Annotations[builder.AdminCredentialsHashAnnotation] != adminCredentialsHash {
+		return "", errors.NewBadRequest("the revision was rendered for other admin credentials")
+	}
+	if revision.Annotations[builder.ActiveServerAnnotation] != activeServer {
+		return "", errors.NewBadRequest(fmt.Sprintf("the revision was rendered for server %s, frpc connects to %s",
+			revision.Annotations[builder.ActiveServerAnnotation], activeServer))
+	}
+
+	return string(revision.Data[builder.ConfigFileKey]), nil
+}
+
+// reconcileAdminSecret makes sure the Client has generated admin credentials, and
+// regenerates them when the rotate-admin-credentials annotation changes. It returns
+// the Secret as written, the cache may not have caught up with it yet.
+func (r *ClientReconciler) reconcileAdminSecret(ctx context.Context, client *frpv1alpha1.Client) (*corev1.Secret, error) {
+	log := log.FromContext(ctx)
+	rotation := client.Annotations[models.RotateAdminCredentialsAnnotation]
+
+	createdSecret := &corev1.Secret{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: models.AdminSecretName(client.Name), Namespace: client.Namespace}, createdSecret)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil && createdSecret.Annotations[builder.AdminRotationAnnotation] == rotation {
+		return createdSecret, nil
+	}
+
+	password, err := models.GenerateAdminPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := builder.NewAdminSecretBuilder().
+		SetName(client.Name).
+		SetNamespace(client.Namespace).
+		SetUsername(models.DEFAULT_ADMIN_USERNAME).
+		SetPassword(password).
+		SetRotation(rotation).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := controllerutil.SetControllerReference(client, secret, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	if createdSecret.Name == "" {
+		log.Info("create admin secret")
+		return secret, r.Client.Create(ctx, secret)
+	}
+
+	log.Info("rotate admin credentials")
+	createdSecret.Data = secret.Data
+	createdSecret.Annotations = secret.Annotations
+	if err := r.Client.Update(ctx, createdSecret); err != nil {
+		return nil, err
+	}
+	r.Recorder.Event(client, corev1.EventTypeNormal, EventReasonAdminRotated,
+		"Admin credentials rotated, restarting frpc pods")
+
+	return createdSecret, nil
+}
+
+// selectServer probes the servers of a Client with failover and returns the one
+// frpc connects to, a switch to another server is recorded in the status
+func (r *ClientReconciler) selectServer(ctx context.Context, client *frpv1alpha1.Client) (models.ServerCandidate, error) {
+	log := log.FromContext(ctx)
+
+	candidates, err := models.ServerCandidates(r.Client, client)
+	if err != nil {
+		return models.ServerCandidate{}, err
+	}
+
+	selected, reason := 0, ""
+	if client.Spec.Failover != nil {
+		// the servers are dialed from the operator pod, concurrently so that the
+		// worker waits for one probe timeout at most
+		probeErrs := handler.ProbeAll(candidates, models.ProbeTimeout(client))
+		selected, reason = models.SelectServer(candidates, client.Status.ActiveServer, probeErrs)
+	}
+
+	active := candidates[selected]
+	if reason != "" {
+		// without an active server yet, frpc leaves the primary server
+		from := client.Status.ActiveServer
+		if from == "" {
+			from = candidates[0].Endpoint
+		}
+
+		log.Info("fail over to another server", "from", from, "to", active.Endpoint, "reason", reason)
+		r.Recorder.Event(client, corev1.EventTypeWarning, EventReasonServerFailover,
+			fmt.Sprintf("Failing over from %s to %s: %s", from, active.Endpoint, reason))
+		client.Status.FailoverHistory = models.RecordFailover(client.Status.FailoverHistory, frpv1alpha1.ClientStatus_Failover{
+			Time:   metav1.Now(),
+			From:   from,
+			To:     active.Endpoint,
+			Reason: reason,
+		})
+	}
+	client.Status.ActiveServer = active.Endpoint
+
+	return active, nil
+}
+
+// upstreamToClient enqueues the Client that owns an Upstream
+func (r *ClientReconciler) upstreamToClient(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	upstream, ok := obj.(*frpv1alpha1.Upstream)
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{NamespacedName: models.UpstreamClientKey(upstream)},
+	}
+}
+
+// allocatedPortChangedPredicate passes the Upstreams whose port a PortPool allocated
+// or released, their Client renders the remote port
+func allocatedPortChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldUpstream, ok := e.ObjectOld.(*frpv1alpha1.Upstream)
+			if !ok {
+				return false
+			}
+			newUpstream, ok := e.ObjectNew.(*frpv1alpha1.Upstream)
+			if !ok {
+				return false
+			}
+			return oldUpstream.Status.AllocatedPort != newUpstream.Status.AllocatedPort
+		},
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+}
+
+// scheduleChangedPredicate passes the Upstreams whose schedule added or removed the
+// proxy, the Upstream controller requeues them at every schedule transition
+func scheduleChangedPredicate() predicate.Predicate {
+	scheduledOut := func(upstream *frpv1alpha1.Upstream) bool {
+		return upstream.Status.Phase == status.UpstreamPhaseInactive || upstream.Status.Phase == status.UpstreamPhaseExpired
+	}
+
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldUpstream, ok := e.ObjectOld.(*frpv1alpha1.Upstream)
+			if !ok {
+				return false
+			}
+			newUpstream, ok := e.ObjectNew.(*frpv1alpha1.Upstream)
+			if !ok {
+				return false
+			}
+			return scheduledOut(oldUpstream) != scheduledOut(newUpstream)
+		},
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+}
+
+// secretToClients enqueues the Clients whose configuration reads a Secret, either
+// directly or through one of their Upstreams or Visitors
+func (r *ClientReconciler) secretToClients(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+	listOptions := []ctrlclient.ListOption{
+		ctrlclient.InNamespace(obj.GetNamespace()),
+		ctrlclient.MatchingFields{secretIndexField: obj.GetName()},
+	}
+
+	clientKeys := map[types.NamespacedName]struct{}{}
+
+	clients := &frpv1alpha1.ClientList{}
+	if err := r.Client.List(ctx, clients, listOptions...); err != nil {
+		log.Error(err, "failed to list clients for secret", "secret", obj.GetName())
+		return nil
+	}
+	for _, client := range clients.Items {
+		clientKeys[types.NamespacedName{Name: client.Name, Namespace: client.Namespace}] = struct{}{}
+	}
+
+	upstreams := &frpv1alpha1.UpstreamList{}
+	if err := r.Client.List(ctx, upstreams, listOptions...); err != nil {
+		log.Error(err, "failed to list upstreams for secret", "secret", obj.GetName())
+		return nil
+	}
+	for _, upstream := range upstreams.Items {
+		clientKeys[models.UpstreamClientKey(&upstream)] = struct{}{}
+	}
+
+	visitors := &frpv1alpha1.VisitorList{}
+	if err := r.Client.List(ctx, visitors, listOptions...); err != nil {
+		log.Error(err, "failed to list visitors for secret", "secret", obj.GetName())
+		return nil
+	}
+	for _, visitor := range visitors.Items {
+		clientKeys[models.VisitorClientKey(&visitor)] = struct{}{}
+	}
+
+	virtualNetworks := &frpv1alpha1.VirtualNetworkList{}
+	if err := r.Client.List(ctx, virtualNetworks, listOptions...); err != nil {
+		log.Error(err, "failed to list virtual networks for secret", "secret", obj.GetName())
+		return nil
+	}
+	for _, virtualNetwork := range virtualNetworks.Items {
+		for _, request := range virtualNetworkClients(&virtualNetwork) {
+			clientKeys[request.NamespacedName] = struct{}{}
+		}
+	}
+
+	requests := make([]reconcile.Request, 0, len(clientKeys))
+	for key := range clientKeys {
+		requests = append(requests, reconcile.Request{NamespacedName: key})
+	}
+
+	return requests
+}
+
+// serviceToClients enqueues the Clients with Upstreams that forward to a Service,
+// so that the configuration follows the Service ports
+func (r *ClientReconciler) serviceToClients(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	upstreams := &frpv1alpha1.UpstreamList{}
+	err := r.Client.List(ctx, upstreams, ctrlclient.MatchingFields{serviceIndexField: ctrlclient.ObjectKeyFromObject(obj).String()})
+	if err != nil {
+		log.Error(err, "failed to list upstreams for service", "service", obj.GetName())
+		return nil
+	}
+
+	clientKeys := map[types.NamespacedName]struct{}{}
+	for _, upstream := range upstreams.Items {
+		clientKeys[models.UpstreamClientKey(&upstream)] = struct{}{}
+	}
+
+	requests := make([]reconcile.Request, 0, len(clientKeys))
+	for key := range clientKeys {
+		requests = append(requests, reconcile.Request{NamespacedName: key})
+	}
+
+	return requests
+}
+
+// namespaceToClients enqueues the Clients that select allowed namespaces by label,
+// so that relabeling a namespace binds or unbinds its Upstreams and Visitors
+func (r *ClientReconciler) namespaceToClients(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	clients := &frpv1alpha1.ClientList{}
+	if err := r.Client.List(ctx, clients); err != nil {
+		log.Error(err, "failed to list clients for namespace", "namespace", obj.GetName())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, client := range clients.Items {
+		if client.Spec.AllowedNamespaces == nil || client.Spec.AllowedNamespaces.Selector == nil {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: client.Name, Namespace: client.Namespace},
+		})
+	}
+
+	return requests
+}
+
+// visitorToClient enqueues the Client that owns a Visitor
+func (r *ClientReconciler) visitorToClient(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	visitor, ok := obj.(*frpv1alpha1.Visitor)
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{NamespacedName: models.VisitorClientKey(visitor)},
+	}
+}
+
+// virtualNetworkToClients enqueues the Clients joining a VirtualNetwork, the old
+// and new members are both enqueued when the members change
+func (r *ClientReconciler) virtualNetworkToClients(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	virtualNetwork, ok := obj.(*frpv1alpha1.VirtualNetwork)
+	if !ok {
+		return nil
+	}
+
+	return virtualNetworkClients(virtualNetwork)
+}
+
+// virtualNetworkClients returns the Clients joining a VirtualNetwork
+func virtualNetworkClients(virtualNetwork *frpv1alpha1.VirtualNetwork) []reconcile.Request {
+	requests := []reconcile.Request{}
+	for _, member := range virtualNetwork.Spec.Members {
+		if member.Client == "" {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: member.Client, Namespace: virtualNetwork.Namespace},
+		})
+	}
+
+	return requests
+}
+
+// rolloutInProgress reports whether the deployment still runs pods of an older spec
+func rolloutInProgress(created *appsv1.Deployment, desired *appsv1.Deployment) bool {
+	if created.Spec.Template.Annotations[builder.SpecHashAnnotation] != desired.Spec.Template.Annotations[builder.SpecHashAnnotation] {
+		return true
+	}
+	if created.Status.ObservedGeneration < created.Generation {
+		return true
+	}
+
+	replicas := *desired.Spec.Replicas
+	return created.Status.UpdatedReplicas < replicas ||
+		created.Status.Replicas > created.Status.UpdatedReplicas ||
+		created.Status.AvailableReplicas < replicas
+}
+
 // updateClientStatus updates the status of a Client resource
 func (r *ClientReconciler) updateClientStatus(ctx context.Context, client *frpv1alpha1.Client,
 	phase, message string, upstreamCount, visitorCount int) error {
@@ -0,0 +1,67 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+	servermodels "github.com/zufardhiyaulhaq/frp-operator/pkg/server/models"
+)
+
+// frpsAddresses returns the addresses traffic reaches the frps of a Client on: the
+// server host of the Client, or the external addresses of the frps Service of an
+// in-cluster Server. It reports pending while that Service has no address yet.
+func frpsAddresses(ctx context.Context, c ctrlclient.Client, frpClient *frpv1alpha1.Client) ([]string, bool, error) {
+	if frpClient.Spec.Server.ServerRef == nil {
+		return []string{frpClient.Spec.Server.Host}, false, nil
+	}
+
+	server, err := models.ServerRef(c, frpClient)
+	if err != nil && errors.IsNotFound(err) {
+		return nil, true, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	service := &corev1.Service{}
+	err = c.Get(ctx, types.NamespacedName{Name: server.Name + "-frps", Namespace: server.Namespace}, service)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, false, err
+	}
+
+	addresses := []string{}
+	for _, address := range service.Status.LoadBalancer.Ingress {
+		if address.IP != "" {
+			addresses = append(addresses, address.IP)
+		} else if address.Hostname != "" {
+			addresses = append(addresses, address.Hostname)
+		}
+	}
+	if len(addresses) > 0 {
+		return addresses, false, nil
+	}
+
+	pending := service.Spec.Type == corev1.ServiceTypeLoadBalancer
+	return []string{servermodels.Address(server)}, pending, nil
+}
@@ -0,0 +1,570 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	ctrlhandler "sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/builder"
+)
+
+// Event reasons
+const (
+	EventReasonRouteFailed = "RouteSyncFailed"
+)
+
+// Gateway API route kinds
+const (
+	KindHTTPRoute gatewayv1.Kind = "HTTPRoute"
+	KindTCPRoute  gatewayv1.Kind = "TCPRoute"
+	KindUDPRoute  gatewayv1.Kind = "UDPRoute"
+)
+
+// kindInstalled reports whether the API server serves the kind of an object. The
+// Gateway API CRDs are optional, their controllers only start when installed.
+func kindInstalled(mgr ctrl.Manager, obj runtime.Object) (bool, error) {
+	gvk, err := apiutil.GVKForObject(obj, mgr.GetScheme())
+	if err != nil {
+		return false, err
+	}
+
+	_, err = mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// listenerRouteKind returns the route kind a listener protocol serves, frp serves
+// HTTP listeners on the frps vhost port and TCP and UDP listeners on remote ports
+func listenerRouteKind(protocol gatewayv1.ProtocolType) (gatewayv1.Kind, bool) {
+	switch protocol {
+	case gatewayv1.HTTPProtocolType:
+		return KindHTTPRoute, true
+	case gatewayv1.TCPProtocolType:
+		return KindTCPRoute, true
+	case gatewayv1.UDPProtocolType:
+		return KindUDPRoute, true
+	}
+
+	return "", false
+}
+
+// frpGateway returns the Gateway a route parentRef references, and nil when the
+// parentRef isn't a Gateway of an frp GatewayClass
+func frpGateway(ctx context.Context, c ctrlclient.Client, parentRef gatewayv1.ParentReference, namespace string) (*gatewayv1.Gateway, *gatewayv1.GatewayClass, error) {
+	if parentRef.Group != nil && *parentRef.Group != gatewayv1.GroupName {
+		return nil, nil, nil
+	}
+	if parentRef.Kind != nil && *parentRef.Kind != "Gateway" {
+		return nil, nil, nil
+	}
+	if parentRef.Namespace != nil {
+		namespace = string(*parentRef.Namespace)
+	}
+
+	gateway := &gatewayv1.Gateway{}
+	err := c.Get(ctx, types.NamespacedName{Name: string(parentRef.Name), Namespace: namespace}, gateway)
+	if err != nil && errors.IsNotFound(err) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	gatewayClass, err := frpGatewayClass(ctx, c, gateway)
+	if err != nil || gatewayClass == nil {
+		return nil, nil, err
+	}
+
+	return gateway, gatewayClass, nil
+}
+
+// frpGatewayClass returns the GatewayClass of a Gateway, and nil when another
+// controller serves it
+func frpGatewayClass(ctx context.Context, c ctrlclient.Client, gateway *gatewayv1.Gateway) (*gatewayv1.GatewayClass, error) {
+	gatewayClass := &gatewayv1.GatewayClass{}
+	err := c.Get(ctx, types.NamespacedName{Name: string(gateway.Spec.GatewayClassName)}, gatewayClass)
+	if err != nil && errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if gatewayClass.Spec.ControllerName != builder.GatewayControllerName {
+		return nil, nil
+	}
+
+	return gatewayClass, nil
+}
+
+// gatewayClient returns the Client serving a Gateway, referenced by the
+// infrastructure parameters of the Gateway, or else by the parameters of its
+// GatewayClass. A GatewayClass Client without namespace is looked up in the
+// namespace of the Gateway.
+func gatewayClient(gateway *gatewayv1.Gateway, gatewayClass *gatewayv1.GatewayClass) (types.NamespacedName, error) {
+	if gateway.Spec.Infrastructure != nil && gateway.Spec.Infrastructure.ParametersRef != nil {
+		params := gateway.Spec.Infrastructure.ParametersRef
+		if string(params.Group) != frpv1alpha1.GroupVersion.Group || params.Kind != "Client" {
+			return types.NamespacedName{}, fmt.Errorf("Gateway %s parametersRef must reference a %s Client",
+				gateway.Name, frpv1alpha1.GroupVersion.Group)
+		}
+
+		return types.NamespacedName{Name: params.Name, Namespace: gateway.Namespace}, nil
+	}
+
+	params := gatewayClass.Spec.ParametersRef
+	if params == nil || string(params.Group) != frpv1alpha1.GroupVersion.Group || params.Kind != "Client" {
+		return types.NamespacedName{}, fmt.Errorf("GatewayClass %s parametersRef must reference a %s Client",
+			gatewayClass.Name, frpv1alpha1.GroupVersion.Group)
+	}
+
+	namespace := gateway.Namespace
+	if params.Namespace != nil && *params.Namespace != "" {
+		namespace = string(*params.Namespace)
+	}
+
+	return types.NamespacedName{Name: params.Name, Namespace: namespace}, nil
+}
+
+// listenerMatchesParent reports whether a listener is selected by the sectionName
+// and port of a parentRef
+func listenerMatchesParent(listener *gatewayv1.Listener, parentRef gatewayv1.ParentReference) bool {
+	if parentRef.SectionName != nil && *parentRef.SectionName != listener.Name {
+		return false
+	}
+	if parentRef.Port != nil && *parentRef.Port != listener.Port {
+		return false
+	}
+
+	return true
+}
+
+// listenerAllowsRoute reports whether a listener accepts routes of a kind from a
+// namespace
+func listenerAllowsRoute(ctx context.Context, c ctrlclient.Client, gateway *gatewayv1.Gateway, listener *gatewayv1.Listener,
+	kind gatewayv1.Kind, namespace string) (bool, error) {
+
+	if listenerKind, ok := listenerRouteKind(listener.Protocol); !ok || listenerKind != kind {
+		return false, nil
+	}
+
+	allowedRoutes := listener.AllowedRoutes
+	if allowedRoutes == nil {
+		return namespace == gateway.Namespace, nil
+	}
+
+	if len(allowedRoutes.Kinds) > 0 {
+		allowed := false
+		for _, allowedKind := range allowedRoutes.Kinds {
+			if allowedKind.Kind == kind && (allowedKind.Group == nil || *allowedKind.Group == gatewayv1.GroupName) {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return false, nil
+		}
+	}
+
+	if allowedRoutes.Namespaces == nil || allowedRoutes.Namespaces.From == nil {
+		return namespace == gateway.Namespace, nil
+	}
+
+	switch *allowedRoutes.Namespaces.From {
+	case gatewayv1.NamespacesFromAll:
+		return true, nil
+	case gatewayv1.NamespacesFromSelector:
+		if allowedRoutes.Namespaces.Selector == nil {
+			return false, nil
+		}
+		selector, err := metav1.LabelSelectorAsSelector(allowedRoutes.Namespaces.Selector)
+		if err != nil {
+			return false, nil
+		}
+
+		routeNamespace := &corev1.Namespace{}
+		if err := c.Get(ctx, types.NamespacedName{Name: namespace}, routeNamespace); err != nil {
+			return false, ctrlclient.IgnoreNotFound(err)
+		}
+		return selector.Matches(labels.Set(routeNamespace.Labels)), nil
+	}
+
+	return namespace == gateway.Namespace, nil
+}
+
+// routeParent is a parentRef of a route to a Gateway of an frp GatewayClass, with
+// the listeners that accept the route
+type routeParent struct {
+	ref          gatewayv1.ParentReference
+	gateway      *gatewayv1.Gateway
+	client       types.NamespacedName
+	listeners    []*gatewayv1.Listener
+	accepted     metav1.Condition
+	resolvedRefs metav1.Condition
+}
+
+// resolveRouteParents returns the parents of a route served by the operator,
+// parentRefs to Gateways of other controllers are left out
+func resolveRouteParents(ctx context.Context, c ctrlclient.Client, route ctrlclient.Object, kind gatewayv1.Kind,
+	parentRefs []gatewayv1.ParentReference) ([]routeParent, error) {
+
+	parents := []routeParent{}
+	for _, parentRef := range parentRefs {
+		gateway, gatewayClass, err := frpGateway(ctx, c, parentRef, route.GetNamespace())
+		if err != nil {
+			return nil, err
+		}
+		if gateway == nil {
+			continue
+		}
+
+		parent := routeParent{
+			ref:          parentRef,
+			gateway:      gateway,
+			accepted:     routeCondition(gatewayv1.RouteConditionAccepted, true, gatewayv1.RouteReasonAccepted, "Route is accepted"),
+			resolvedRefs: routeCondition(gatewayv1.RouteConditionResolvedRefs, true, gatewayv1.RouteReasonResolvedRefs, "References are resolved"),
+		}
+
+		parent.client, err = gatewayClient(gateway, gatewayClass)
+		if err != nil {
+			parent.accepted = routeCondition(gatewayv1.RouteConditionAccepted, false, gatewayv1.RouteReasonNoMatchingParent, err.Error())
+			parents = append(parents, parent)
+			continue
+		}
+
+		matched := false
+		for i := range gateway.Spec.Listeners {
+			listener := &gateway.Spec.Listeners[i]
+			if !listenerMatchesParent(listener, parentRef) {
+				continue
+			}
+			matched = true
+
+			allowed, err := listenerAllowsRoute(ctx, c, gateway, listener, kind, route.GetNamespace())
+			if err != nil {
+				return nil, err
+			}
+			if allowed {
+				parent.listeners = append(parent.listeners, listener)
+			}
+		}
+
+		switch {
+		case !matched:
+			parent.accepted = routeCondition(gatewayv1.RouteConditionAccepted, false, gatewayv1.RouteReasonNoMatchingParent,
+				"No listener matches the parentRef")
+		case len(parent.listeners) == 0:
+			parent.accepted = routeCondition(gatewayv1.RouteConditionAccepted, false, gatewayv1.RouteReasonNotAllowedByListeners,
+				fmt.Sprintf("No listener allows %s from namespace %s", kind, route.GetNamespace()))
+		}
+
+		parents = append(parents, parent)
+	}
+
+	return parents, nil
+}
+
+// setBuildError reports why the Upstreams of a route couldn't be built for a parent
+func (p *routeParent) setBuildError(err error) {
+	switch {
+	case goerrors.Is(err, builder.ErrInvalidBackendKind):
+		p.resolvedRefs = routeCondition(gatewayv1.RouteConditionResolvedRefs, false, gatewayv1.RouteReasonInvalidKind, err.Error())
+	case goerrors.Is(err, builder.ErrBackendRefNotPermitted):
+		p.resolvedRefs = routeCondition(gatewayv1.RouteConditionResolvedRefs, false, gatewayv1.RouteReasonRefNotPermitted, err.Error())
+	case goerrors.Is(err, builder.ErrNoMatchingListenerHostname):
+		p.accepted = routeCondition(gatewayv1.RouteConditionAccepted, false, gatewayv1.RouteReasonNoMatchingListenerHostname, err.Error())
+	default:
+		p.accepted = routeCondition(gatewayv1.RouteConditionAccepted, false, gatewayv1.RouteReasonUnsupportedValue, err.Error())
+	}
+}
+
+func routeCondition(conditionType gatewayv1.RouteConditionType, status bool, reason gatewayv1.RouteConditionReason, message string) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    string(conditionType),
+		Status:  metav1.ConditionTrue,
+		Reason:  string(reason),
+		Message: message,
+	}
+	if !status {
+		condition.Status = metav1.ConditionFalse
+	}
+
+	return condition
+}
+
+// routeParentStatuses returns the parent statuses of a route with the statuses of
+// the operator replaced by the given parents, statuses of other controllers are
+// kept
+func routeParentStatuses(current []gatewayv1.RouteParentStatus, parents []routeParent, generation int64) []gatewayv1.RouteParentStatus {
+	statuses := []gatewayv1.RouteParentStatus{}
+	previous := map[string]gatewayv1.RouteParentStatus{}
+	for _, status := range current {
+		if status.ControllerName != builder.GatewayControllerName {
+			statuses = append(statuses, status)
+			continue
+		}
+		previous[parentRefKey(status.ParentRef)] = status
+	}
+
+	for _, parent := range parents {
+		status := gatewayv1.RouteParentStatus{
+			ParentRef:      parent.ref,
+			ControllerName: builder.GatewayControllerName,
+		}
+		if prev, ok := previous[parentRefKey(parent.ref)]; ok {
+			status.Conditions = prev.Conditions
+		}
+
+		for _, condition := range []metav1.Condition{parent.accepted, parent.resolvedRefs} {
+			condition.ObservedGeneration = generation
+			meta.SetStatusCondition(&status.Conditions, condition)
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+func parentRefKey(ref gatewayv1.ParentReference) string {
+	key := string(ref.Name)
+	if ref.Namespace != nil {
+		key = string(*ref.Namespace) + "/" + key
+	}
+	if ref.SectionName != nil {
+		key += "#" + string(*ref.SectionName)
+	}
+	if ref.Port != nil {
+		key += fmt.Sprintf(":%d", *ref.Port)
+	}
+
+	return key
+}
+
+// parentRefsGateway reports whether parentRefs of a route in a namespace reference
+// a Gateway
+func parentRefsGateway(parentRefs []gatewayv1.ParentReference, namespace string, gateway types.NamespacedName) bool {
+	for _, parentRef := range parentRefs {
+		if parentRef.Group != nil && *parentRef.Group != gatewayv1.GroupName {
+			continue
+		}
+		if parentRef.Kind != nil && *parentRef.Kind != "Gateway" {
+			continue
+		}
+
+		parentNamespace := namespace
+		if parentRef.Namespace != nil {
+			parentNamespace = string(*parentRef.Namespace)
+		}
+		if string(parentRef.Name) == gateway.Name && parentNamespace == gateway.Namespace {
+			return true
+		}
+	}
+
+	return false
+}
+
+// routeRefs are the parentRefs of a route of any kind
+type routeRefs struct {
+	kind       gatewayv1.Kind
+	name       string
+	namespace  string
+	parentRefs []gatewayv1.ParentReference
+}
+
+func newRouteRefs(obj ctrlclient.Object) (routeRefs, bool) {
+	switch route := obj.(type) {
+	case *gatewayv1.HTTPRoute:
+		return routeRefs{kind: KindHTTPRoute, name: route.Name, namespace: route.Namespace, parentRefs: route.Spec.ParentRefs}, true
+	case *gatewayv1alpha2.TCPRoute:
+		return routeRefs{kind: KindTCPRoute, name: route.Name, namespace: route.Namespace, parentRefs: route.Spec.ParentRefs}, true
+	case *gatewayv1alpha2.UDPRoute:
+		return routeRefs{kind: KindUDPRoute, name: route.Name, namespace: route.Namespace, parentRefs: route.Spec.ParentRefs}, true
+	}
+
+	return routeRefs{}, false
+}
+
+// installedRouteKinds returns the route kinds installed in the cluster
+func installedRouteKinds(mgr ctrl.Manager) (map[gatewayv1.Kind]bool, error) {
+	routes := map[gatewayv1.Kind]runtime.Object{
+		KindHTTPRoute: &gatewayv1.HTTPRoute{},
+		KindTCPRoute:  &gatewayv1alpha2.TCPRoute{},
+		KindUDPRoute:  &gatewayv1alpha2.UDPRoute{},
+	}
+
+	kinds := map[gatewayv1.Kind]bool{}
+	for kind, route := range routes {
+		installed, err := kindInstalled(mgr, route)
+		if err != nil {
+			return nil, err
+		}
+		kinds[kind] = installed
+	}
+
+	return kinds, nil
+}
+
+// listRouteRefs returns the parentRefs of the routes of the installed kinds
+func listRouteRefs(ctx context.Context, c ctrlclient.Client, kinds map[gatewayv1.Kind]bool) ([]routeRefs, error) {
+	routes := []routeRefs{}
+
+	if kinds[KindHTTPRoute] {
+		httpRoutes := &gatewayv1.HTTPRouteList{}
+		if err := c.List(ctx, httpRoutes); err != nil {
+			return nil, err
+		}
+		for i := range httpRoutes.Items {
+			route, _ := newRouteRefs(&httpRoutes.Items[i])
+			routes = append(routes, route)
+		}
+	}
+
+	if kinds[KindTCPRoute] {
+		tcpRoutes := &gatewayv1alpha2.TCPRouteList{}
+		if err := c.List(ctx, tcpRoutes); err != nil {
+			return nil, err
+		}
+		for i := range tcpRoutes.Items {
+			route, _ := newRouteRefs(&tcpRoutes.Items[i])
+			routes = append(routes, route)
+		}
+	}
+
+	if kinds[KindUDPRoute] {
+		udpRoutes := &gatewayv1alpha2.UDPRouteList{}
+		if err := c.List(ctx, udpRoutes); err != nil {
+			return nil, err
+		}
+		for i := range udpRoutes.Items {
+			route, _ := newRouteRefs(&udpRoutes.Items[i])
+			routes = append(routes, route)
+		}
+	}
+
+	return routes, nil
+}
+
+// routeAttachesListener reports whether a route is attached to a listener of a
+// Gateway through one of its parentRefs
+func routeAttachesListener(ctx context.Context, c ctrlclient.Client, gateway *gatewayv1.Gateway, listener *gatewayv1.Listener, route routeRefs) (bool, error) {
+	gatewayKey := types.NamespacedName{Name: gateway.Name, Namespace: gateway.Namespace}
+
+	for _, parentRef := range route.parentRefs {
+		if !parentRefsGateway([]gatewayv1.ParentReference{parentRef}, route.namespace, gatewayKey) || !listenerMatchesParent(listener, parentRef) {
+			continue
+		}
+
+		allowed, err := listenerAllowsRoute(ctx, c, gateway, listener, route.kind, route.namespace)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+
+	return false, nil
+}
+
+// syncRoute serves a route on the listeners of its Gateways of frp GatewayClasses
+// and returns the parent statuses of the route. Upstreams are kept as they are
+// while the route can't be served.
+func syncRoute(ctx context.Context, c ctrlclient.Client, scheme *runtime.Scheme, recorder record.EventRecorder,
+	route ctrlclient.Object, kind gatewayv1.Kind, parentRefs []gatewayv1.ParentReference,
+	setRoute func(*builder.RouteUpstreamBuilder) *builder.RouteUpstreamBuilder,
+	current []gatewayv1.RouteParentStatus) ([]gatewayv1.RouteParentStatus, error) {
+
+	parents, err := resolveRouteParents(ctx, c, route, kind, parentRefs)
+	if err != nil {
+		return nil, err
+	}
+
+	upstreams := []*frpv1alpha1.Upstream{}
+	built := true
+	for i := range parents {
+		parent := &parents[i]
+		for _, listener := range parent.listeners {
+			listenerUpstreams, err := setRoute(builder.NewRouteUpstreamBuilder()).
+				SetListener(parent.gateway, listener).
+				SetClient(parent.client.Name, parent.client.Namespace).
+				Build()
+			if err != nil {
+				parent.setBuildError(err)
+				built = false
+				break
+			}
+			upstreams = append(upstreams, listenerUpstreams...)
+		}
+	}
+
+	if built {
+		conflicts, err := syncGeneratedUpstreams(ctx, c, scheme, route,
+			ctrlclient.MatchingLabels{builder.GatewayRouteLabel: route.GetName()}, upstreams)
+		for _, name := range conflicts {
+			recorder.Event(route, corev1.EventTypeWarning, EventReasonRouteFailed,
+				fmt.Sprintf("Upstream %s already exists and is not managed by the %s", name, kind))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return routeParentStatuses(current, parents, route.GetGeneration()), nil
+}
+
+// gatewayToRoutes returns a map function enqueueing the routes of a kind that
+// reference a Gateway
+func gatewayToRoutes(c ctrlclient.Client, kind gatewayv1.Kind) ctrlhandler.MapFunc {
+	return func(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+		log := log.FromContext(ctx)
+
+		routes, err := listRouteRefs(ctx, c, map[gatewayv1.Kind]bool{kind: true})
+		if err != nil {
+			log.Error(err, "failed to list routes for gateway", "gateway", obj.GetName())
+			return nil
+		}
+
+		gateway := types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}
+		requests := []reconcile.Request{}
+		for _, route := range routes {
+			if parentRefsGateway(route.parentRefs, route.namespace, gateway) {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: route.name, Namespace: route.namespace},
+				})
+			}
+		}
+
+		return requests
+	}
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlhandler "sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+// GatewayReconciler reports the status of the Gateways of frp GatewayClasses: the
+// frps addresses of their Client, and the routes attached to their listeners.
+// The routes themselves are served by the route controllers.
+type GatewayReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// routeKinds are the route kinds installed in the cluster
+	routeKinds map[gatewayv1.Kind]bool
+}
+
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get;list;watch
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=clients;servers,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+func (r *GatewayReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	gateway := &gatewayv1.Gateway{}
+	err := r.Client.Get(ctx, req.NamespacedName, gateway)
+	if err != nil && errors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	gatewayClass, err := frpGatewayClass(ctx, r.Client, gateway)
+	if err != nil || gatewayClass == nil {
+		return ctrl.Result{}, err
+	}
+
+	status := gateway.Status.DeepCopy()
+	result := ctrl.Result{}
+
+	accepted := gatewayCondition(gatewayv1.GatewayConditionAccepted, true, gatewayv1.GatewayReasonAccepted, "Gateway is accepted")
+	programmed := gatewayCondition(gatewayv1.GatewayConditionProgrammed, true, gatewayv1.GatewayReasonProgrammed, "Gateway is served by frps")
+
+	clientKey, err := gatewayClient(gateway, gatewayClass)
+	if err != nil {
+		accepted = gatewayCondition(gatewayv1.GatewayConditionAccepted, false, gatewayv1.GatewayReasonInvalidParameters, err.Error())
+		programmed = gatewayCondition(gatewayv1.GatewayConditionProgrammed, false, gatewayv1.GatewayReasonInvalid, err.Error())
+		status.Addresses = nil
+	} else {
+		addresses, pending, err := r.gatewayAddresses(ctx, clientKey)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		status.Addresses = addresses
+		switch {
+		case addresses == nil:
+			programmed = gatewayCondition(gatewayv1.GatewayConditionProgrammed, false, gatewayv1.GatewayReasonPending,
+				fmt.Sprintf("Client %s not found", clientKey))
+		case pending:
+			// the frps Service doesn't have an external address yet
+			programmed = gatewayCondition(gatewayv1.GatewayConditionProgrammed, false, gatewayv1.GatewayReasonAddressNotAssigned,
+				"frps Service has no external address yet")
+			result.RequeueAfter = 30 * time.Second
+		}
+	}
+
+	for _, condition := range []metav1.Condition{accepted, programmed} {
+		condition.ObservedGeneration = gateway.Generation
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+
+	status.Listeners, err = r.listenerStatuses(ctx, gateway, status.Listeners)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if reflect.DeepEqual(&gateway.Status, status) {
+		return result, nil
+	}
+
+	log.Info("update gateway status")
+	gateway.Status = *status
+	return result, r.Status().Update(ctx, gateway)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GatewayReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	installed, err := kindInstalled(mgr, &gatewayv1.Gateway{})
+	if err != nil || !installed {
+		return err
+	}
+
+	r.routeKinds, err = installedRouteKinds(mgr)
+	if err != nil {
+		return err
+	}
+
+	controller := ctrl.NewControllerManagedBy(mgr).
+		For(&gatewayv1.Gateway{}).
+		Watches(&gatewayv1.GatewayClass{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.gatewayClassToGateways)).
+		Watches(&frpv1alpha1.Client{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.clientToGateways))
+
+	routes := map[gatewayv1.Kind]ctrlclient.Object{
+		KindHTTPRoute: &gatewayv1.HTTPRoute{},
+		KindTCPRoute:  &gatewayv1alpha2.TCPRoute{},
+		KindUDPRoute:  &gatewayv1alpha2.UDPRoute{},
+	}
+	for kind, route := range routes {
+		if r.routeKinds[kind] {
+			controller = controller.Watches(route, ctrlhandler.EnqueueRequestsFromMapFunc(r.routeToGateways))
+		}
+	}
+
+	return controller.Complete(r)
+}
+
+// gatewayAddresses returns the frps addresses of a Client as Gateway addresses,
+// and nil when the Client doesn't exist
+func (r *GatewayReconciler) gatewayAddresses(ctx context.Context, clientKey types.NamespacedName) ([]gatewayv1.GatewayStatusAddress, bool, error) {
+	frpClient := &frpv1alpha1.Client{}
+	err := r.Client.Get(ctx, clientKey, frpClient)
+	if err != nil && errors.IsNotFound(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	addresses, pending, err := frpsAddresses(ctx, r.Client, frpClient)
+	if err != nil {
+		return nil, false, err
+	}
+
+	gatewayAddresses := []gatewayv1.GatewayStatusAddress{}
+	for _, address := range addresses {
+		addressType := gatewayv1.HostnameAddressType
+		if net.ParseIP(address) != nil {
+			addressType = gatewayv1.IPAddressType
+		}
+		gatewayAddresses = append(gatewayAddresses, gatewayv1.GatewayStatusAddress{Type: &addressType, Value: address})
+	}
+
+	return gatewayAddresses, pending, nil
+}
+
+// listenerStatuses returns the status of the listeners of a Gateway, listeners of
+// protocols frp can't serve are not accepted
+func (r *GatewayReconciler) listenerStatuses(ctx context.Context, gateway *gatewayv1.Gateway,
+	current []gatewayv1.ListenerStatus) ([]gatewayv1.ListenerStatus, error) {
+
+	routes, err := listRouteRefs(ctx, r.Client, r.routeKinds)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := map[gatewayv1.SectionName][]metav1.Condition{}
+	for _, status := range current {
+		previous[status.Name] = status.Conditions
+	}
+
+	statuses := []gatewayv1.ListenerStatus{}
+	for i := range gateway.Spec.Listeners {
+		listener := &gateway.Spec.Listeners[i]
+		status := gatewayv1.ListenerStatus{
+			Name:           listener.Name,
+			SupportedKinds: []gatewayv1.RouteGroupKind{},
+			Conditions:     previous[listener.Name],
+		}
+
+		accepted := listenerCondition(gatewayv1.ListenerConditionAccepted, true, gatewayv1.ListenerReasonAccepted, "Listener is accepted")
+		programmed := listenerCondition(gatewayv1.ListenerConditionProgrammed, true, gatewayv1.ListenerReasonProgrammed, "Listener is served by frps")
+		resolvedRefs := listenerCondition(gatewayv1.ListenerConditionResolvedRefs, true, gatewayv1.ListenerReasonResolvedRefs, "References are resolved")
+
+		kind, ok := listenerRouteKind(listener.Protocol)
+		if !ok {
+			message := fmt.Sprintf("protocol %s is not supported, frp serves HTTP, TCP and UDP listeners", listener.Protocol)
+			accepted = listenerCondition(gatewayv1.ListenerConditionAccepted, false, gatewayv1.ListenerReasonUnsupportedProtocol, message)
+			programmed = listenerCondition(gatewayv1.ListenerConditionProgrammed, false, gatewayv1.ListenerReasonInvalid, message)
+		} else {
+			group := gatewayv1.Group(gatewayv1.GroupName)
+			status.SupportedKinds = append(status.SupportedKinds, gatewayv1.RouteGroupKind{Group: &group, Kind: kind})
+
+			for _, route := range routes {
+				if route.kind != kind || !parentRefsGateway(route.parentRefs, route.namespace, types.NamespacedName{Name: gateway.Name, Namespace: gateway.Namespace}) {
+					continue
+				}
+
+				attached, err := routeAttachesListener(ctx, r.Client, gateway, listener, route)
+				if err != nil {
+					return nil, err
+				}
+				if attached {
+					status.AttachedRoutes++
+				}
+			}
+		}
+
+		for _, condition := range []metav1.Condition{accepted, programmed, resolvedRefs} {
+			condition.ObservedGeneration = gateway.Generation
+			meta.SetStatusCondition(&status.Conditions, condition)
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// gatewayClassToGateways enqueues the Gateways of a GatewayClass
+func (r *GatewayReconciler) gatewayClassToGateways(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	gateways := &gatewayv1.GatewayList{}
+	if err := r.Client.List(ctx, gateways); err != nil {
+		log.Error(err, "failed to list gateways for gateway class", "gatewayClass", obj.GetName())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, gateway := range gateways.Items {
+		if string(gateway.Spec.GatewayClassName) == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: gateway.Name, Namespace: gateway.Namespace},
+			})
+		}
+	}
+
+	return requests
+}
+
+// clientToGateways enqueues every Gateway, so that their addresses follow the
+// server address of their Client
+func (r *GatewayReconciler) clientToGateways(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	gateways := &gatewayv1.GatewayList{}
+	if err := r.Client.List(ctx, gateways); err != nil {
+		log.Error(err, "failed to list gateways for client", "client", obj.GetName())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, gateway := range gateways.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: gateway.Name, Namespace: gateway.Namespace},
+		})
+	}
+
+	return requests
+}
+
+// routeToGateways enqueues the Gateways a route references, so that their
+// attached routes are counted
+func (r *GatewayReconciler) routeToGateways(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	route, ok := newRouteRefs(obj)
+	if !ok {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, parentRef := range route.parentRefs {
+		if parentRef.Group != nil && *parentRef.Group != gatewayv1.GroupName {
+			continue
+		}
+		if parentRef.Kind != nil && *parentRef.Kind != "Gateway" {
+			continue
+		}
+
+		namespace := route.namespace
+		if parentRef.Namespace != nil {
+			namespace = string(*parentRef.Namespace)
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: string(parentRef.Name), Namespace: namespace},
+		})
+	}
+
+	return requests
+}
+
+func gatewayCondition(conditionType gatewayv1.GatewayConditionType, status bool, reason gatewayv1.GatewayConditionReason, message string) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    string(conditionType),
+		Status:  metav1.ConditionTrue,
+		Reason:  string(reason),
+		Message: message,
+	}
+	if !status {
+		condition.Status = metav1.ConditionFalse
+	}
+
+	return condition
+}
+
+func listenerCondition(conditionType gatewayv1.ListenerConditionType, status bool, reason gatewayv1.ListenerConditionReason, message string) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    string(conditionType),
+		Status:  metav1.ConditionTrue,
+		Reason:  string(reason),
+		Message: message,
+	}
+	if !status {
+		condition.Status = metav1.ConditionFalse
+	}
+
+	return condition
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/builder"
+)
+
+// GatewayClassReconciler accepts the GatewayClasses whose controllerName is
+// frp.zufardhiyaulhaq.com/gateway-controller
+type GatewayClassReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gatewayclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gatewayclasses/status,verbs=get;update;patch
+
+func (r *GatewayClassReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	gatewayClass := &gatewayv1.GatewayClass{}
+	err := r.Client.Get(ctx, req.NamespacedName, gatewayClass)
+	if err != nil && errors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if gatewayClass.Spec.ControllerName != builder.GatewayControllerName {
+		return ctrl.Result{}, nil
+	}
+
+	// a GatewayClass without parameters is valid when its Gateways reference
+	// their Client
+	condition := metav1.Condition{
+		Type:               string(gatewayv1.GatewayClassConditionStatusAccepted),
+		Status:             metav1.ConditionTrue,
+		Reason:             string(gatewayv1.GatewayClassReasonAccepted),
+		Message:            "GatewayClass is accepted",
+		ObservedGeneration: gatewayClass.Generation,
+	}
+	params := gatewayClass.Spec.ParametersRef
+	if params != nil && (string(params.Group) != frpv1alpha1.GroupVersion.Group || params.Kind != "Client") {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = string(gatewayv1.GatewayClassReasonInvalidParameters)
+		condition.Message = "parametersRef must reference a " + frpv1alpha1.GroupVersion.Group + " Client"
+	}
+
+	if !meta.SetStatusCondition(&gatewayClass.Status.Conditions, condition) {
+		return ctrl.Result{}, nil
+	}
+
+	log.Info("update gateway class status")
+	return ctrl.Result{}, r.Status().Update(ctx, gatewayClass)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GatewayClassReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	installed, err := kindInstalled(mgr, &gatewayv1.GatewayClass{})
+	if err != nil || !installed {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&gatewayv1.GatewayClass{}).
+		Complete(r)
+}
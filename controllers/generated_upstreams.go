@@ -0,0 +1,97 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+// syncGeneratedUpstreams makes the Upstreams an owner object controls, found by
+// their labels, match the desired Upstreams. Upstreams of the same name the owner
+// doesn't control are left alone and returned as conflicts.
+func syncGeneratedUpstreams(ctx context.Context, c ctrlclient.Client, scheme *runtime.Scheme, owner ctrlclient.Object,
+	labels ctrlclient.MatchingLabels, upstreams []*frpv1alpha1.Upstream) ([]string, error) {
+
+	log := log.FromContext(ctx)
+
+	createdUpstreams := &frpv1alpha1.UpstreamList{}
+	if err := c.List(ctx, createdUpstreams, ctrlclient.InNamespace(owner.GetNamespace()), labels); err != nil {
+		return nil, err
+	}
+
+	conflicts := []string{}
+	desired := map[string]struct{}{}
+	for _, upstream := range upstreams {
+		desired[upstream.Name] = struct{}{}
+
+		if err := controllerutil.SetControllerReference(owner, upstream, scheme); err != nil {
+			return conflicts, err
+		}
+
+		createdUpstream := &frpv1alpha1.Upstream{}
+		err := c.Get(ctx, ctrlclient.ObjectKeyFromObject(upstream), createdUpstream)
+		if err != nil && errors.IsNotFound(err) {
+			log.Info("create generated upstream", "upstream", upstream.Name)
+			if err := c.Create(ctx, upstream); err != nil {
+				return conflicts, err
+			}
+			continue
+		} else if err != nil {
+			return conflicts, err
+		}
+
+		if !metav1.IsControlledBy(createdUpstream, owner) {
+			conflicts = append(conflicts, upstream.Name)
+			continue
+		}
+
+		if reflect.DeepEqual(createdUpstream.Spec, upstream.Spec) && reflect.DeepEqual(createdUpstream.Labels, upstream.Labels) {
+			continue
+		}
+
+		log.Info("update generated upstream", "upstream", upstream.Name)
+		createdUpstream.Spec = upstream.Spec
+		createdUpstream.Labels = upstream.Labels
+		if err := c.Update(ctx, createdUpstream); err != nil {
+			return conflicts, err
+		}
+	}
+
+	for i := range createdUpstreams.Items {
+		createdUpstream := &createdUpstreams.Items[i]
+		if _, ok := desired[createdUpstream.Name]; ok || !metav1.IsControlledBy(createdUpstream, owner) {
+			continue
+		}
+
+		log.Info("delete generated upstream", "upstream", createdUpstream.Name)
+		if err := c.Delete(ctx, createdUpstream); err != nil && !errors.IsNotFound(err) {
+			return conflicts, err
+		}
+	}
+
+	return conflicts, nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/builder"
+)
+
+func TestSyncGeneratedUpstreams_NoUpdateWhenUnchanged(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = frpv1alpha1.AddToScheme(scheme)
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "team-a",
+			UID:         "web-uid",
+			Annotations: map[string]string{builder.ExposeClientAnnotation: "edge", builder.ExposeRemotePortAnnotation: "8080"},
+		},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80}}},
+	}
+
+	updates := 0
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(service).WithInterceptorFuncs(interceptor.Funcs{
+		// the API server applies the CRD defaults on create
+		Create: func(ctx context.Context, client ctrlclient.WithWatch, obj ctrlclient.Object, opts ...ctrlclient.CreateOption) error {
+			if upstream, ok := obj.(*frpv1alpha1.Upstream); ok && upstream.Spec.Enabled == nil {
+				enabled := true
+				upstream.Spec.Enabled = &enabled
+			}
+			return client.Create(ctx, obj, opts...)
+		},
+		Update: func(ctx context.Context, client ctrlclient.WithWatch, obj ctrlclient.Object, opts ...ctrlclient.UpdateOption) error {
+			updates++
+			return client.Update(ctx, obj, opts...)
+		},
+	}).Build()
+
+	labels := ctrlclient.MatchingLabels{builder.ExposeServiceLabel: service.Name}
+	for i := 0; i < 2; i++ {
+		upstreams, err := builder.NewServiceUpstreamBuilder().SetService(service).Build()
+		if err != nil {
+			t.Fatalf("Build() unexpected error = %v", err)
+		}
+
+		conflicts, err := syncGeneratedUpstreams(context.TODO(), c, scheme, service, labels, upstreams)
+		if err != nil || len(conflicts) != 0 {
+			t.Fatalf("syncGeneratedUpstreams() = %v, %v, want no conflict", conflicts, err)
+		}
+	}
+
+	if updates != 0 {
+		t.Errorf("syncGeneratedUpstreams() updated %d unchanged upstreams, want none", updates)
+	}
+}
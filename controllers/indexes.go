@@ -0,0 +1,92 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+	servermodels "github.com/zufardhiyaulhaq/frp-operator/pkg/server/models"
+)
+
+// Field indexes used to find the objects that belong to a Client or Server
+const (
+	// clientIndexField indexes Upstreams and Visitors by the namespaced name of their Client
+	clientIndexField = "spec.client"
+	// secretIndexField indexes Servers, Clients, Upstreams, Visitors and VirtualNetworks by the Secrets they read
+	secretIndexField = "spec.secrets"
+	// serviceIndexField indexes Upstreams by the namespaced name of the Service they reference
+	serviceIndexField = "spec.serviceRef"
+)
+
+// setupIndexes registers the field indexes with the manager cache
+func setupIndexes(ctx context.Context, mgr ctrl.Manager) error {
+	indexer := mgr.GetFieldIndexer()
+
+	if err := indexer.IndexField(ctx, &frpv1alpha1.Upstream{}, clientIndexField, func(obj ctrlclient.Object) []string {
+		return []string{models.UpstreamClientKey(obj.(*frpv1alpha1.Upstream)).String()}
+	}); err != nil {
+		return err
+	}
+
+	if err := indexer.IndexField(ctx, &frpv1alpha1.Visitor{}, clientIndexField, func(obj ctrlclient.Object) []string {
+		return []string{models.VisitorClientKey(obj.(*frpv1alpha1.Visitor)).String()}
+	}); err != nil {
+		return err
+	}
+
+	if err := indexer.IndexField(ctx, &frpv1alpha1.Server{}, secretIndexField, func(obj ctrlclient.Object) []string {
+		return servermodels.SecretNames(obj.(*frpv1alpha1.Server))
+	}); err != nil {
+		return err
+	}
+
+	if err := indexer.IndexField(ctx, &frpv1alpha1.Client{}, secretIndexField, func(obj ctrlclient.Object) []string {
+		return models.ClientSecretNames(obj.(*frpv1alpha1.Client))
+	}); err != nil {
+		return err
+	}
+
+	if err := indexer.IndexField(ctx, &frpv1alpha1.Upstream{}, secretIndexField, func(obj ctrlclient.Object) []string {
+		return models.UpstreamSecretNames(obj.(*frpv1alpha1.Upstream))
+	}); err != nil {
+		return err
+	}
+
+	if err := indexer.IndexField(ctx, &frpv1alpha1.Upstream{}, serviceIndexField, func(obj ctrlclient.Object) []string {
+		if key, ok := models.UpstreamServiceKey(obj.(*frpv1alpha1.Upstream)); ok {
+			return []string{key.String()}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := indexer.IndexField(ctx, &frpv1alpha1.Visitor{}, secretIndexField, func(obj ctrlclient.Object) []string {
+		return models.VisitorSecretNames(obj.(*frpv1alpha1.Visitor))
+	}); err != nil {
+		return err
+	}
+
+	return indexer.IndexField(ctx, &frpv1alpha1.VirtualNetwork{}, secretIndexField, func(obj ctrlclient.Object) []string {
+		return models.VirtualNetworkSecretNames(obj.(*frpv1alpha1.VirtualNetwork))
+	})
+}
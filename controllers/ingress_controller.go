@@ -0,0 +1,308 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlhandler "sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/builder"
+)
+
+// Event reasons
+const (
+	EventReasonIngressFailed = "IngressSyncFailed"
+)
+
+// IngressReconciler serves the Ingresses of the IngressClasses whose controller is
+// frp.zufardhiyaulhaq.com/ingress-controller, through Upstreams on the Client the
+// IngressClass parameters reference
+type IngressReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingressclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=upstreams,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=clients;servers,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	ingress := &networkingv1.Ingress{}
+	err := r.Client.Get(ctx, req.NamespacedName, ingress)
+	if err != nil && errors.IsNotFound(err) {
+		// owned Upstreams are garbage collected with the Ingress
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	labels := ctrlclient.MatchingLabels{builder.IngressLabel: ingress.Name}
+
+	ingressClass, err := r.ingressClass(ctx, ingress)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if ingressClass == nil {
+		// the Ingress isn't ours, or not anymore
+		_, err := syncGeneratedUpstreams(ctx, r.Client, r.Scheme, ingress, labels, nil)
+		return ctrl.Result{}, err
+	}
+
+	clientKey, err := ingressClassClient(ingressClass, ingress.Namespace)
+	if err != nil {
+		r.Recorder.Event(ingress, corev1.EventTypeWarning, EventReasonIngressFailed, err.Error())
+		return ctrl.Result{}, nil
+	}
+
+	upstreams, err := builder.NewIngressUpstreamBuilder().
+		SetIngress(ingress).
+		SetClient(clientKey.Name, clientKey.Namespace).
+		Build()
+	if err != nil {
+		// keep the existing Upstreams until the Ingress is fixed
+		r.Recorder.Event(ingress, corev1.EventTypeWarning, EventReasonIngressFailed, err.Error())
+		return ctrl.Result{}, nil
+	}
+
+	conflicts, err := syncGeneratedUpstreams(ctx, r.Client, r.Scheme, ingress, labels, upstreams)
+	for _, name := range conflicts {
+		r.Recorder.Event(ingress, corev1.EventTypeWarning, EventReasonIngressFailed,
+			fmt.Sprintf("Upstream %s already exists and is not managed by the Ingress", name))
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("find client configuration")
+	frpClient := &frpv1alpha1.Client{}
+	err = r.Client.Get(ctx, clientKey, frpClient)
+	if err != nil && errors.IsNotFound(err) {
+		r.Recorder.Event(ingress, corev1.EventTypeWarning, EventReasonIngressFailed,
+			fmt.Sprintf("Client %s not found", clientKey))
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	loadBalancer, pending, err := r.loadBalancerStatus(ctx, frpClient)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	result := ctrl.Result{}
+	if pending {
+		// the frps Service doesn't have an external address yet
+		result.RequeueAfter = 30 * time.Second
+	}
+
+	if reflect.DeepEqual(ingress.Status.LoadBalancer.Ingress, loadBalancer) {
+		return result, nil
+	}
+
+	log.Info("update ingress load balancer status")
+	ingress.Status.LoadBalancer.Ingress = loadBalancer
+	return result, r.Status().Update(ctx, ingress)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("ingress-controller")
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&networkingv1.Ingress{}).
+		Owns(&frpv1alpha1.Upstream{}).
+		Watches(&networkingv1.IngressClass{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.ingressClassToIngresses)).
+		Watches(&frpv1alpha1.Client{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.clientToIngresses)).
+		Complete(r)
+}
+
+// ingressClass returns the frp IngressClass of an Ingress, either named by
+// spec.ingressClassName or the default IngressClass, and nil when the Ingress
+// belongs to another controller
+func (r *IngressReconciler) ingressClass(ctx context.Context, ingress *networkingv1.Ingress) (*networkingv1.IngressClass, error) {
+	if ingress.Spec.IngressClassName != nil {
+		ingressClass := &networkingv1.IngressClass{}
+		err := r.Client.Get(ctx, types.NamespacedName{Name: *ingress.Spec.IngressClassName}, ingressClass)
+		if err != nil && errors.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if ingressClass.Spec.Controller != builder.IngressControllerName {
+			return nil, nil
+		}
+		return ingressClass, nil
+	}
+
+	ingressClasses := &networkingv1.IngressClassList{}
+	if err := r.Client.List(ctx, ingressClasses); err != nil {
+		return nil, err
+	}
+	for i, ingressClass := range ingressClasses.Items {
+		if ingressClass.Spec.Controller == builder.IngressControllerName &&
+			ingressClass.Annotations[networkingv1.AnnotationIsDefaultIngressClass] == "true" {
+			return &ingressClasses.Items[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ingressClassClient returns the Client referenced by the parameters of an frp
+// IngressClass, a Client without namespace is looked up in the Ingress namespace
+func ingressClassClient(ingressClass *networkingv1.IngressClass, namespace string) (types.NamespacedName, error) {
+	params := ingressClass.Spec.Parameters
+	if params == nil || params.APIGroup == nil || *params.APIGroup != frpv1alpha1.GroupVersion.Group || params.Kind != "Client" {
+		return types.NamespacedName{}, fmt.Errorf("IngressClass %s parameters must reference a %s Client",
+			ingressClass.Name, frpv1alpha1.GroupVersion.Group)
+	}
+
+	if params.Namespace != nil && *params.Namespace != "" {
+		namespace = *params.Namespace
+	}
+
+	return types.NamespacedName{Name: params.Name, Namespace: namespace}, nil
+}
+
+// loadBalancerStatus returns the frps addresses of the Client as Ingress load
+// balancer addresses
+func (r *IngressReconciler) loadBalancerStatus(ctx context.Context, frpClient *frpv1alpha1.Client) ([]networkingv1.IngressLoadBalancerIngress, bool, error) {
+	addresses, pending, err := frpsAddresses(ctx, r.Client, frpClient)
+	if err != nil {
+		return nil, false, err
+	}
+
+	loadBalancer := []networkingv1.IngressLoadBalancerIngress{}
+	for _, address := range addresses {
+		loadBalancer = append(loadBalancer, ingressAddress(address))
+	}
+
+	return loadBalancer, pending, nil
+}
+
+// ingressAddress reports an address as an IP or a hostname
+func ingressAddress(address string) networkingv1.IngressLoadBalancerIngress {
+	if net.ParseIP(address) != nil {
+		return networkingv1.IngressLoadBalancerIngress{IP: address}
+	}
+
+	return networkingv1.IngressLoadBalancerIngress{Hostname: address}
+}
+
+// ingressClassToIngresses enqueues the Ingresses of an IngressClass, including the
+// Ingresses without class when it is the default IngressClass
+func (r *IngressReconciler) ingressClassToIngresses(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	ingressClass, ok := obj.(*networkingv1.IngressClass)
+	if !ok {
+		return nil
+	}
+
+	return r.ingressesOfClasses(ctx, map[string]struct{}{ingressClass.Name: {}},
+		ingressClass.Annotations[networkingv1.AnnotationIsDefaultIngressClass] == "true")
+}
+
+// clientToIngresses enqueues the Ingresses served by a Client, so that their load
+// balancer status follows the server address
+func (r *IngressReconciler) clientToIngresses(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	ingressClasses := &networkingv1.IngressClassList{}
+	if err := r.Client.List(ctx, ingressClasses); err != nil {
+		log.Error(err, "failed to list ingress classes for client", "client", obj.GetName())
+		return nil
+	}
+
+	classNames := map[string]struct{}{}
+	includeDefault := false
+	for _, ingressClass := range ingressClasses.Items {
+		if ingressClass.Spec.Controller != builder.IngressControllerName {
+			continue
+		}
+
+		// parameters without namespace resolve in the namespace of each Ingress,
+		// so they match a Client of that name in any namespace
+		clientKey, err := ingressClassClient(&ingressClass, obj.GetNamespace())
+		if err != nil || clientKey.Name != obj.GetName() || clientKey.Namespace != obj.GetNamespace() {
+			continue
+		}
+
+		classNames[ingressClass.Name] = struct{}{}
+		if ingressClass.Annotations[networkingv1.AnnotationIsDefaultIngressClass] == "true" {
+			includeDefault = true
+		}
+	}
+
+	if len(classNames) == 0 {
+		return nil
+	}
+
+	return r.ingressesOfClasses(ctx, classNames, includeDefault)
+}
+
+// ingressesOfClasses enqueues the Ingresses of the given IngressClasses
+func (r *IngressReconciler) ingressesOfClasses(ctx context.Context, classNames map[string]struct{}, includeDefault bool) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	ingresses := &networkingv1.IngressList{}
+	if err := r.Client.List(ctx, ingresses); err != nil {
+		log.Error(err, "failed to list ingresses")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, ingress := range ingresses.Items {
+		if ingress.Spec.IngressClassName == nil && !includeDefault {
+			continue
+		}
+		if ingress.Spec.IngressClassName != nil {
+			if _, ok := classNames[*ingress.Spec.IngressClassName]; !ok {
+				continue
+			}
+		}
+
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: ingress.Name, Namespace: ingress.Namespace},
+		})
+	}
+
+	return requests
+}
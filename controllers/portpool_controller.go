@@ -0,0 +1,253 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	ctrlhandler "sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/status"
+)
+
+// PortPoolReconciler allocates the remote ports of the Upstreams that omit
+// server.port. The allocations are recorded in the PortPool status, the only
+// writer of a pool, and copied to the allocatedPort of every Upstream.
+type PortPoolReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=portpools,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=portpools/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=portpools/finalizers,verbs=update
+
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=clients,verbs=get;list;watch
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=servers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=upstreams,verbs=get;list;watch
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=upstreams/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+func (r *PortPoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	portPool := &frpv1alpha1.PortPool{}
+	err := r.Client.Get(ctx, req.NamespacedName, portPool)
+	if err != nil && errors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Clients of every namespace share the remote ports of a server, so the pools
+	// and Clients are listed cluster-wide
+	portPools := &frpv1alpha1.PortPoolList{}
+	if err := r.Client.List(ctx, portPools); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	clients := &frpv1alpha1.ClientList{}
+	if err := r.Client.List(ctx, clients); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Ports of every Upstream of the Clients on the server are taken into account,
+	// fixed ports are reserved and the others request a port
+	requests := []models.PortRequest{}
+	reserved := map[int]bool{}
+	upstreams := map[string]*frpv1alpha1.Upstream{}
+	for i := range clients.Items {
+		frpClient := &clients.Items[i]
+		selected, err := models.PortPoolFor(r.Client, frpClient, portPools.Items)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if selected == nil || selected.Name != portPool.Name || selected.Namespace != portPool.Namespace {
+			continue
+		}
+
+		clientUpstreams := &frpv1alpha1.UpstreamList{}
+		err = r.Client.List(ctx, clientUpstreams, ctrlclient.MatchingFields{clientIndexField: ctrlclient.ObjectKeyFromObject(frpClient).String()})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		for j := range clientUpstreams.Items {
+			upstream := &clientUpstreams.Items[j]
+			allowed, err := namespaceAllowed(ctx, r.Client, frpClient, upstream.Namespace)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if !allowed {
+				continue
+			}
+
+			if !upstream.AllocatesPort() {
+				if port := upstream.RemotePort(); port != 0 {
+					reserved[port] = true
+				}
+				continue
+			}
+
+			key := ctrlclient.ObjectKeyFromObject(upstream).String()
+			upstreams[key] = upstream
+			requests = append(requests, models.PortRequest{Upstream: key, Port: upstream.Status.AllocatedPort})
+		}
+	}
+
+	allocations, unallocated := models.AllocatePorts(portPool, requests, reserved)
+
+	newStatus := frpv1alpha1.PortPoolStatus{
+		Phase:       status.PortPoolPhaseReady,
+		Message:     fmt.Sprintf("%d ports allocated", len(allocations)),
+		Allocations: allocations,
+		Available:   models.AvailablePorts(portPool.Spec.Ranges, allocations, reserved),
+	}
+	if len(unallocated) > 0 {
+		newStatus.Phase = status.PortPoolPhaseExhausted
+		newStatus.Message = fmt.Sprintf("no free port left for upstreams %s", strings.Join(unallocated, ", "))
+	}
+
+	released := portPool.Status.Allocations
+	if !reflect.DeepEqual(portPool.Status, newStatus) {
+		log.Info("update port pool status", "phase", newStatus.Phase, "allocations", len(allocations))
+		portPool.Status = newStatus
+		if err := r.Status().Update(ctx, portPool); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	allocated := map[string]int{}
+	for _, allocation := range allocations {
+		allocated[allocation.Upstream] = allocation.Port
+	}
+
+	for key, upstream := range upstreams {
+		if upstream.Status.AllocatedPort == allocated[key] {
+			continue
+		}
+
+		log.Info("update allocated port", "upstream", key, "port", allocated[key])
+		upstream.Status.AllocatedPort = allocated[key]
+		if err := r.Status().Update(ctx, upstream); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Upstreams that set server.port since give their allocated port back
+	for _, allocation := range released {
+		if _, ok := allocated[allocation.Upstream]; ok {
+			continue
+		}
+		if err := r.releasePort(ctx, allocation); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// releasePort clears the allocated port of an Upstream that no longer requests one
+func (r *PortPoolReconciler) releasePort(ctx context.Context, allocation frpv1alpha1.PortPoolStatus_Allocation) error {
+	namespace, name, ok := strings.Cut(allocation.Upstream, "/")
+	if !ok {
+		return nil
+	}
+
+	upstream := &frpv1alpha1.Upstream{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, upstream)
+	if err != nil {
+		return ctrlclient.IgnoreNotFound(err)
+	}
+	if upstream.AllocatesPort() || upstream.Status.AllocatedPort != allocation.Port {
+		return nil
+	}
+
+	upstream.Status.AllocatedPort = 0
+	return r.Status().Update(ctx, upstream)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PortPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&frpv1alpha1.PortPool{}).
+		Watches(&frpv1alpha1.Upstream{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.allPortPools),
+			ctrlbuilder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&frpv1alpha1.Client{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.allPortPools),
+			ctrlbuilder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, activeServerChangedPredicate()))).
+		Watches(&frpv1alpha1.Server{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.allPortPools),
+			ctrlbuilder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
+
+// allPortPools enqueues every PortPool, a pool covers Clients and Upstreams of any
+// namespace and the bind port of a Server decides which pool covers it
+func (r *PortPoolReconciler) allPortPools(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	portPools := &frpv1alpha1.PortPoolList{}
+	if err := r.Client.List(ctx, portPools); err != nil {
+		log.Error(err, "failed to list port pools")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(portPools.Items))
+	for _, portPool := range portPools.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: portPool.Name, Namespace: portPool.Namespace},
+		})
+	}
+
+	return requests
+}
+
+// activeServerChangedPredicate passes the Clients that failed over to another
+// server, the PortPool covering the active server allocates their ports
+func activeServerChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldClient, ok := e.ObjectOld.(*frpv1alpha1.Client)
+			if !ok {
+				return false
+			}
+			newClient, ok := e.ObjectNew.(*frpv1alpha1.Client)
+			if !ok {
+				return false
+			}
+			return oldClient.Status.ActiveServer != newClient.Status.ActiveServer
+		},
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+}
@@ -0,0 +1,448 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrlhandler "sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	clientmodels "github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/server/builder"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/server/models"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/server/status"
+)
+
+// Event reasons
+const (
+	EventReasonServerConfigUpdated = "ServerConfigUpdated"
+)
+
+// ServerReconciler reconciles a Server object
+type ServerReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=servers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=servers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=servers/finalizers,verbs=update
+
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+
+func (r *ServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Start Server Reconciler")
+
+	log.Info("find server configuration")
+	server := &frpv1alpha1.Server{}
+	err := r.Client.Get(ctx, req.NamespacedName, server)
+	if err != nil {
+		return ctrl.Result{}, nil
+	}
+
+	log.Info("list upstream configuration")
+	filteredUpstreams, err := serverUpstreams(ctx, r.Client, server, time.Now())
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	log.Info(fmt.Sprintf("find %d upstream for %s", len(filteredUpstreams), server.Name))
+
+	config, err := models.NewConfig(r.Client, server, filteredUpstreams)
+	if err != nil {
+		r.setCondition(server, status.ConditionTypeReady, metav1.ConditionFalse, status.ReasonConfigInvalid, err.Error())
+		if statusErr := r.updateServerStatus(ctx, server, status.ServerPhaseFailed, err.Error()); statusErr != nil {
+			log.Error(statusErr, "failed to update server status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Build configuration")
+	configuration, err := builder.NewConfigurationBuilder().
+		SetConfig(config).
+		Build()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Build config secret")
+	configSecret, err := builder.NewSecretBuilder().
+		SetConfig(configuration).
+		SetName(server.Name).
+		SetNamespace(server.Namespace).
+		Build()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("set reference config secret")
+	if err := controllerutil.SetControllerReference(server, configSecret, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("get config secret")
+	createdConfigSecret := &corev1.Secret{}
+	err = r.Client.Get(ctx, types.NamespacedName{Name: configSecret.Name, Namespace: configSecret.Namespace}, createdConfigSecret)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("create config secret")
+		err = r.Client.Create(ctx, configSecret)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		return ctrl.Result{}, err
+	} else if !reflect.DeepEqual(createdConfigSecret.Data, configSecret.Data) {
+		log.Info("found config diff, update config secret")
+		createdConfigSecret.Data = configSecret.Data
+		err = r.Client.Update(ctx, createdConfigSecret)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Event(server, corev1.EventTypeNormal, EventReasonServerConfigUpdated,
+			"Configuration changed, restarting FRP server")
+	}
+
+	// Older releases rendered the configuration, including the token, into a ConfigMap
+	log.Info("delete legacy config map")
+	legacyConfigMap := &corev1.ConfigMap{}
+	err = r.Client.Get(ctx, types.NamespacedName{Name: configSecret.Name, Namespace: configSecret.Namespace}, legacyConfigMap)
+	if err == nil && metav1.IsControlledBy(legacyConfigMap, server) {
+		if err := r.Client.Delete(ctx, legacyConfigMap); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+	} else if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Build service")
+	service, err := builder.NewServiceBuilder().
+		SetName(server.Name).
+		SetNamespace(server.Namespace).
+		SetConfig(config).
+		SetService(server.Spec.Service).
+		Build()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("set reference service")
+	if err := controllerutil.SetControllerReference(server, service, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("get service")
+	createdService := &corev1.Service{}
+	err = r.Client.Get(ctx, types.NamespacedName{Name: service.Name, Namespace: service.Namespace}, createdService)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("create service")
+		err = r.Client.Create(ctx, service)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		return ctrl.Result{}, err
+	} else if !equality.Semantic.DeepDerivative(service.Spec.Ports, createdService.Spec.Ports) ||
+		createdService.Spec.Type != service.Spec.Type ||
+		!reflect.DeepEqual(createdService.Annotations, service.Annotations) {
+		log.Info("found service diff, update service")
+		createdService.Spec.Ports = service.Spec.Ports
+		createdService.Spec.Type = service.Spec.Type
+		createdService.Spec.LoadBalancerIP = service.Spec.LoadBalancerIP
+		createdService.Annotations = service.Annotations
+		err = r.Client.Update(ctx, createdService)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	log.Info("Build deployment")
+	configHash := sha256.Sum256([]byte(configuration))
+	deploymentBuilder := builder.NewDeploymentBuilder().
+		SetName(server.Name).
+		SetNamespace(server.Namespace).
+		SetImage("fatedier/frps:v0.65.0").
+		SetConfig(config).
+		SetConfigHash(hex.EncodeToString(configHash[:])[:16]).
+		SetPodTemplate(server.Spec.PodTemplate)
+
+	// Wire TLS secret if configured
+	if server.Spec.TLS != nil {
+		if server.Spec.TLS.CertFile != nil {
+			deploymentBuilder.SetTLSSecret(server.Spec.TLS.CertFile.Secret.Name)
+		} else if server.Spec.TLS.KeyFile != nil {
+			deploymentBuilder.SetTLSSecret(server.Spec.TLS.KeyFile.Secret.Name)
+		}
+		if server.Spec.TLS.TrustedCAFile != nil {
+			if server.Spec.TLS.TrustedCAFile.ConfigMap != nil {
+				deploymentBuilder.SetTLSCAConfigMap(server.Spec.TLS.TrustedCAFile.ConfigMap.Name)
+			} else if server.Spec.TLS.TrustedCAFile.Secret != nil && deploymentBuilder.TLSSecret == "" {
+				deploymentBuilder.SetTLSSecret(server.Spec.TLS.TrustedCAFile.Secret.Name)
+			}
+		}
+	}
+
+	deployment, err := deploymentBuilder.Build()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("set reference deployment")
+	if err := controllerutil.SetControllerReference(server, deployment, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("get deployment")
+	createdDeployment := &appsv1.Deployment{}
+	err = r.Client.Get(ctx, types.NamespacedName{Name: deployment.Name, Namespace: deployment.Namespace}, createdDeployment)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("create deployment")
+		err = r.Client.Create(ctx, deployment)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		r.setCondition(server, status.ConditionTypeReady, metav1.ConditionFalse, status.ReasonDeploymentCreated, "Deployment created, waiting for it to become available")
+		if err := r.updateServerStatus(ctx, server, status.ServerPhasePending, "Deployment created, waiting for it to become available"); err != nil {
+			log.Error(err, "failed to update server status")
+		}
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !equality.Semantic.DeepDerivative(deployment.Spec.Template, createdDeployment.Spec.Template) {
+		log.Info("found deployment diff, update deployment")
+		createdDeployment.Spec.Template = deployment.Spec.Template
+		err = r.Client.Update(ctx, createdDeployment)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	log.Info("check deployment available")
+	if createdDeployment.Status.AvailableReplicas == 0 {
+		r.setCondition(server, status.ConditionTypeReady, metav1.ConditionFalse, status.ReasonDeploymentUnavailable, "FRP server pod is not yet available")
+		if err := r.updateServerStatus(ctx, server, status.ServerPhasePending, "FRP server pod is not yet available"); err != nil {
+			log.Error(err, "failed to update server status")
+		}
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	r.setCondition(server, status.ConditionTypeReady, metav1.ConditionTrue, status.ReasonDeploymentAvailable, "FRP server pod is available")
+	if err := r.updateServerStatus(ctx, server, status.ServerPhaseRunning,
+		fmt.Sprintf("Listening on %s:%d", models.Address(server), config.BindPort)); err != nil {
+		log.Error(err, "failed to update server status")
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// serverUpstreams returns the Upstreams whose proxies the Clients connected to a
+// Server render at now, their remote ports are published on the Server Service
+func serverUpstreams(ctx context.Context, reader client.Reader, server *frpv1alpha1.Server, now time.Time) ([]frpv1alpha1.Upstream, error) {
+	clients := &frpv1alpha1.ClientList{}
+	if err := reader.List(ctx, clients, client.InNamespace(server.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var filteredUpstreams []frpv1alpha1.Upstream
+	for i := range clients.Items {
+		frpClient := &clients.Items[i]
+		if frpClient.Spec.Server.ServerRef == nil || frpClient.Spec.Server.ServerRef.Name != server.Name {
+			continue
+		}
+
+		upstreams := &frpv1alpha1.UpstreamList{}
+		err := reader.List(ctx, upstreams, client.MatchingFields{clientIndexField: client.ObjectKeyFromObject(frpClient).String()})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, upstream := range upstreams.Items {
+			allowed, err := namespaceAllowed(ctx, reader, frpClient, upstream.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				continue
+			}
+			if rendered, _ := clientmodels.UpstreamRendered(frpClient, &upstream, now); rendered {
+				filteredUpstreams = append(filteredUpstreams, upstream)
+			}
+		}
+	}
+
+	return filteredUpstreams, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("server-controller")
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&frpv1alpha1.Server{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Secret{}).
+		Owns(&corev1.Service{}).
+		Watches(&frpv1alpha1.Client{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.clientToServer),
+			ctrlbuilder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&frpv1alpha1.Upstream{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.upstreamToServer),
+			ctrlbuilder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, allocatedPortChangedPredicate(), scheduleChangedPredicate()))).
+		Watches(&corev1.Secret{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.secretToServers)).
+		Watches(&corev1.Namespace{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.namespaceToServers),
+			ctrlbuilder.WithPredicates(predicate.LabelChangedPredicate{})).
+		Complete(r)
+}
+
+// serverRequest returns the request of the Server a Client connects to through
+// spec.server.serverRef
+func serverRequest(frpClient *frpv1alpha1.Client) []reconcile.Request {
+	if frpClient.Spec.Server.ServerRef == nil {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: frpClient.Spec.Server.ServerRef.Name, Namespace: frpClient.Namespace}},
+	}
+}
+
+// clientToServer enqueues the Server a Client connects to
+func (r *ServerReconciler) clientToServer(ctx context.Context, obj client.Object) []reconcile.Request {
+	frpClient, ok := obj.(*frpv1alpha1.Client)
+	if !ok {
+		return nil
+	}
+
+	return serverRequest(frpClient)
+}
+
+// upstreamToServer enqueues the Server the Client of an Upstream connects to, the
+// Server publishes the remote port of the Upstream
+func (r *ServerReconciler) upstreamToServer(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	upstream, ok := obj.(*frpv1alpha1.Upstream)
+	if !ok {
+		return nil
+	}
+
+	frpClient := &frpv1alpha1.Client{}
+	if err := r.Client.Get(ctx, clientmodels.UpstreamClientKey(upstream), frpClient); err != nil {
+		if !errors.IsNotFound(err) {
+			log.Error(err, "failed to get client for upstream", "upstream", upstream.Name)
+		}
+		return nil
+	}
+
+	return serverRequest(frpClient)
+}
+
+// secretToServers enqueues the Servers whose configuration reads a Secret
+func (r *ServerReconciler) secretToServers(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	servers := &frpv1alpha1.ServerList{}
+	err := r.Client.List(ctx, servers, client.InNamespace(obj.GetNamespace()), client.MatchingFields{secretIndexField: obj.GetName()})
+	if err != nil {
+		log.Error(err, "failed to list servers for secret", "secret", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(servers.Items))
+	for _, server := range servers.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: server.Name, Namespace: server.Namespace},
+		})
+	}
+
+	return requests
+}
+
+// namespaceToServers enqueues the Servers of the Clients that allow namespaces by
+// label, the Upstreams of a relabeled namespace may bind to them or stop to
+func (r *ServerReconciler) namespaceToServers(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	clients := &frpv1alpha1.ClientList{}
+	if err := r.Client.List(ctx, clients); err != nil {
+		log.Error(err, "failed to list clients for namespace", "namespace", obj.GetName())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for i := range clients.Items {
+		frpClient := &clients.Items[i]
+		if frpClient.Spec.AllowedNamespaces == nil || frpClient.Spec.AllowedNamespaces.Selector == nil {
+			continue
+		}
+		requests = append(requests, serverRequest(frpClient)...)
+	}
+
+	return requests
+}
+
+// updateServerStatus updates the status of a Server resource
+func (r *ServerReconciler) updateServerStatus(ctx context.Context, server *frpv1alpha1.Server, phase, message string) error {
+	server.Status.Phase = phase
+	server.Status.Message = message
+	server.Status.Address = models.Address(server)
+
+	return r.Status().Update(ctx, server)
+}
+
+// setCondition sets or updates a condition on the Server status
+func (r *ServerReconciler) setCondition(server *frpv1alpha1.Server,
+	conditionType string, conditionStatus metav1.ConditionStatus, reason, message string) {
+
+	condition := metav1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+
+	// Find and update or append
+	for i, c := range server.Status.Conditions {
+		if c.Type == conditionType {
+			server.Status.Conditions[i] = condition
+			return
+		}
+	}
+	server.Status.Conditions = append(server.Status.Conditions, condition)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	clientmodels "github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/server/models"
+)
+
+func createServerClient(name string, replicas int32) *frpv1alpha1.Client {
+	return &frpv1alpha1.Client{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "frp-system"},
+		Spec: frpv1alpha1.ClientSpec{
+			Replicas: &replicas,
+			Server: frpv1alpha1.ClientSpec_Server{
+				ServerRef: &frpv1alpha1.ClientSpec_Server_ServerRef{Name: "edge"},
+			},
+		},
+	}
+}
+
+func createServerUpstream(name, client string, port int, mutate func(*frpv1alpha1.UpstreamSpec)) *frpv1alpha1.Upstream {
+	upstream := &frpv1alpha1.Upstream{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "frp-system"},
+		Spec: frpv1alpha1.UpstreamSpec{
+			Client: client,
+			TCP:    &frpv1alpha1.UpstreamSpec_TCP{Host: "127.0.0.1", Port: 80, Server: frpv1alpha1.UpstreamSpec_TCP_Server{Port: port}},
+		},
+	}
+	if mutate != nil {
+		mutate(&upstream.Spec)
+	}
+	return upstream
+}
+
+func TestServerUpstreams(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = frpv1alpha1.AddToScheme(scheme)
+
+	// a Saturday, outside of the business hours window
+	now := time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC)
+	disabled := false
+
+	server := &frpv1alpha1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge", Namespace: "frp-system"},
+		Spec: frpv1alpha1.ServerSpec{
+			Authentication: frpv1alpha1.ServerSpec_Authentication{
+				Token: &frpv1alpha1.ServerSpec_Authentication_Token{
+					Secret: frpv1alpha1.Secret{Name: "frps-token", Key: "token"},
+				},
+			},
+		},
+	}
+	token := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "frps-token", Namespace: "frp-system"},
+		Data:       map[string][]byte{"token": []byte("secret")},
+	}
+	other := createServerClient("other", 1)
+	other.Spec.Server.ServerRef.Name = "other"
+
+	objects := []ctrlclient.Object{
+		server, token,
+		createServerClient("single", 1),
+		createServerClient("replicated", 2),
+		other,
+		createServerUpstream("published", "single", 8001, nil),
+		createServerUpstream("disabled", "single", 8002, func(spec *frpv1alpha1.UpstreamSpec) {
+			spec.Enabled = &disabled
+		}),
+		createServerUpstream("scheduled", "single", 8003, func(spec *frpv1alpha1.UpstreamSpec) {
+			spec.Schedule = &frpv1alpha1.UpstreamSpec_Schedule{
+				Windows: []frpv1alpha1.UpstreamSpec_Schedule_Window{
+					{Start: "0 9 * * 1-5", Duration: metav1.Duration{Duration: 8 * time.Hour}},
+				},
+			}
+		}),
+		createServerUpstream("load-balanced", "replicated", 8004, nil),
+		createServerUpstream("unreplicable", "replicated", 0, func(spec *frpv1alpha1.UpstreamSpec) {
+			spec.TCP = nil
+			spec.UDP = &frpv1alpha1.UpstreamSpec_UDP{Host: "127.0.0.1", Port: 53, Server: frpv1alpha1.UpstreamSpec_UDP_Server{Port: 8005}}
+		}),
+		createServerUpstream("other-server", "other", 8006, nil),
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).
+		WithIndex(&frpv1alpha1.Upstream{}, clientIndexField, func(obj ctrlclient.Object) []string {
+			return []string{clientmodels.UpstreamClientKey(obj.(*frpv1alpha1.Upstream)).String()}
+		}).Build()
+
+	upstreams, err := serverUpstreams(context.TODO(), c, server, now)
+	if err != nil {
+		t.Fatalf("serverUpstreams() unexpected error = %v", err)
+	}
+
+	config, err := models.NewConfig(c, server, upstreams)
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	published := map[int]bool{}
+	for _, proxyPort := range config.ProxyPorts {
+		published[proxyPort.Port] = true
+	}
+
+	tests := []struct {
+		name string
+		port int
+		want bool
+	}{
+		{name: "published", port: 8001, want: true},
+		{name: "disabled", port: 8002},
+		{name: "outside of its schedule", port: 8003},
+		{name: "tcp upstream shared by replicas", port: 8004, want: true},
+		{name: "udp upstream shared by replicas", port: 8005},
+		{name: "client of another server", port: 8006},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if published[tt.port] != tt.want {
+				t.Errorf("port %d published = %v, want %v", tt.port, published[tt.port], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/builder"
+)
+
+// Event reasons
+const (
+	EventReasonExposeFailed = "ExposeFailed"
+)
+
+// ServiceReconciler creates the Upstreams of Services exposed through the
+// frp.zufardhiyaulhaq.com annotations
+type ServiceReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=upstreams,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	service := &corev1.Service{}
+	err := r.Client.Get(ctx, req.NamespacedName, service)
+	if err != nil && errors.IsNotFound(err) {
+		// owned Upstreams are garbage collected with the Service
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	upstreams, err := builder.NewServiceUpstreamBuilder().SetService(service).Build()
+	if err != nil {
+		// keep the existing Upstreams until the annotations are fixed
+		r.Recorder.Event(service, corev1.EventTypeWarning, EventReasonExposeFailed, err.Error())
+		return ctrl.Result{}, nil
+	}
+
+	conflicts, err := syncGeneratedUpstreams(ctx, r.Client, r.Scheme, service,
+		ctrlclient.MatchingLabels{builder.ExposeServiceLabel: service.Name}, upstreams)
+	for _, name := range conflicts {
+		r.Recorder.Event(service, corev1.EventTypeWarning, EventReasonExposeFailed,
+			fmt.Sprintf("Upstream %s already exists and is not managed by the Service", name))
+	}
+
+	return ctrl.Result{}, err
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("service-controller")
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Service{}).
+		Owns(&frpv1alpha1.Upstream{}).
+		Complete(r)
+}
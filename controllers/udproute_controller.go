@@ -0,0 +1,92 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlhandler "sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/builder"
+)
+
+// UDPRouteReconciler serves the UDPRoutes attached to Gateways of frp
+// GatewayClasses through Upstreams on the Client of the Gateway
+type UDPRouteReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=udproutes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=udproutes/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=upstreams,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *UDPRouteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	route := &gatewayv1alpha2.UDPRoute{}
+	err := r.Client.Get(ctx, req.NamespacedName, route)
+	if err != nil && errors.IsNotFound(err) {
+		// owned Upstreams are garbage collected with the route
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	parents, err := syncRoute(ctx, r.Client, r.Scheme, r.Recorder, route, KindUDPRoute, route.Spec.ParentRefs,
+		func(b *builder.RouteUpstreamBuilder) *builder.RouteUpstreamBuilder { return b.SetUDPRoute(route) },
+		route.Status.Parents)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if reflect.DeepEqual(route.Status.Parents, parents) {
+		return ctrl.Result{}, nil
+	}
+
+	log.Info("update udproute status")
+	route.Status.Parents = parents
+	return ctrl.Result{}, r.Status().Update(ctx, route)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *UDPRouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	installed, err := kindInstalled(mgr, &gatewayv1alpha2.UDPRoute{})
+	if err != nil || !installed {
+		return err
+	}
+
+	r.Recorder = mgr.GetEventRecorderFor("udproute-controller")
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&gatewayv1alpha2.UDPRoute{}).
+		Owns(&frpv1alpha1.Upstream{}).
+		Watches(&gatewayv1.Gateway{}, ctrlhandler.EnqueueRequestsFromMapFunc(gatewayToRoutes(mgr.GetClient(), KindUDPRoute))).
+		Complete(r)
+}
@@ -18,34 +18,339 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlhandler "sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/builder"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/handler"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/status"
+)
+
+// Event reasons
+const (
+	EventReasonScheduleWindowOpened = "ScheduleWindowOpened"
+	EventReasonScheduleWindowClosed = "ScheduleWindowClosed"
+	EventReasonScheduleExpired      = "ScheduleExpired"
 )
 
 // UpstreamReconciler reconciles a Upstream object
 type UpstreamReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=upstreams,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=upstreams/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=upstreams/finalizers,verbs=update
 
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=clients,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
 func (r *UpstreamReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	log := log.FromContext(ctx)
+	log.Info("Start Upstream Reconciler")
+
+	log.Info("find upstream configuration")
+	upstream := &frpv1alpha1.Upstream{}
+	err := r.Client.Get(ctx, req.NamespacedName, upstream)
+	if err != nil {
+		return ctrl.Result{}, nil
+	}
+
+	if !upstream.Enabled() {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhaseDisabled,
+			"Upstream is disabled, the proxy is dropped from the frpc configuration", "")
+	}
+
+	log.Info("evaluate schedule")
+	schedule, err := models.UpstreamSchedule(upstream, time.Now())
+	if err != nil {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhaseFailed, fmt.Sprintf("Invalid schedule: %v", err), "")
+	}
+	if err := r.reconcileSchedule(ctx, upstream, schedule); err != nil {
+		return ctrl.Result{}, err
+	}
+	if schedule.Expired {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhaseExpired,
+			fmt.Sprintf("Upstream expired at %s, the proxy is dropped from the frpc configuration",
+				upstream.Spec.Schedule.ExpiresAt.UTC().Format(time.RFC3339)), "")
+	}
+	if !schedule.Active {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhaseInactive,
+			fmt.Sprintf("Outside of the schedule windows until %s", schedule.NextTransition.UTC().Format(time.RFC3339)), "")
+	}
+
+	log.Info("find client configuration")
+	client := &frpv1alpha1.Client{}
+	clientKey := models.UpstreamClientKey(upstream)
+	err = r.Client.Get(ctx, clientKey, client)
+	if err != nil && errors.IsNotFound(err) {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhasePending,
+			fmt.Sprintf("Client %s not found", clientKey), "")
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	allowed, err := namespaceAllowed(ctx, r.Client, client, upstream.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !allowed {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhaseFailed,
+			fmt.Sprintf("Client %s does not allow namespace %s", clientKey, upstream.Namespace), "")
+	}
+
+	if replicas := models.Replicas(client); replicas > 1 && !upstream.Replicable() {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhaseFailed,
+			fmt.Sprintf("Client %s runs %d replicas, only TCP, HTTP and TCPMUX upstreams can be shared by frpc replicas",
+				clientKey, replicas), "")
+	}
+
+	if upstream.RemotePort() == 0 && upstream.AllocatesPort() {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhasePending,
+			"Waiting for a port from the PortPool of the server", "")
+	}
+
+	if client.Spec.Suspend {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhasePending,
+			fmt.Sprintf("Client %s is suspended", clientKey), "")
+	}
 
-	return ctrl.Result{}, nil
+	log.Info("resolve service reference")
+	if err := r.reconcileServiceCondition(ctx, upstream); err != nil {
+		return ctrl.Result{}, err
+	}
+	condition := meta.FindStatusCondition(upstream.Status.Conditions, status.ConditionTypeServiceResolved)
+	if condition != nil && condition.Status == metav1.ConditionFalse {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhaseFailed, condition.Message, "")
+	}
+
+	log.Info("list frpc pods")
+	pods := &corev1.PodList{}
+	labels := builder.NewDeploymentBuilder().SetName(client.Name).BuildLabels()
+	err = r.Client.List(ctx, pods, ctrlclient.InNamespace(client.Namespace), ctrlclient.MatchingLabels(labels))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	replicas := models.Replicas(client)
+	adminConfig, err := models.NewAdminConfig(r.Client, client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var pendingMessage, failedMessage, remoteAddress string
+	runningPods, running := 0, 0
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		runningPods++
+
+		log.Info("query frpc proxy status", "pod", pod.Name)
+		adminConfig.Common.AdminAddress = pod.Status.PodIP
+		proxies, err := handler.Status(adminConfig)
+		if err != nil {
+			pendingMessage = fmt.Sprintf("Failed to query frpc in pod %s: %v", pod.Name, err)
+			continue
+		}
+
+		proxy, ok := proxies.Find(models.ProxyName(models.BindingName(upstream.Name, upstream.Namespace, client.Namespace), pod.Name, replicas))
+		if !ok {
+			pendingMessage = fmt.Sprintf("Proxy not yet loaded by frpc in pod %s", pod.Name)
+			continue
+		}
+
+		switch proxy.Status {
+		case handler.ProxyStatusRunning:
+			running++
+			remoteAddress = proxy.RemoteAddr
+		case handler.ProxyStatusStartError, handler.ProxyStatusCheckFailed:
+			failedMessage = fmt.Sprintf("Proxy %s in pod %s: %s", proxy.Status, pod.Name, proxy.Err)
+		default:
+			pendingMessage = fmt.Sprintf("Proxy %s in pod %s", proxy.Status, pod.Name)
+		}
+	}
+
+	if failedMessage != "" {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhaseFailed, failedMessage, "")
+	}
+	if runningPods == 0 {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhasePending, "No running frpc pod", "")
+	}
+	if pendingMessage != "" {
+		return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhasePending, pendingMessage, "")
+	}
+
+	return r.updateUpstreamStatus(ctx, upstream, status.UpstreamPhaseActive,
+		fmt.Sprintf("Proxy running on %d/%d frpc pods", running, runningPods), remoteAddress)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *UpstreamReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("upstream-controller")
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&frpv1alpha1.Upstream{}).
+		Watches(&corev1.Service{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.serviceToUpstreams)).
 		Complete(r)
 }
+
+// reconcileSchedule records an Event when the schedule adds or removes the proxy and
+// keeps the next schedule transition in the status
+func (r *UpstreamReconciler) reconcileSchedule(ctx context.Context, upstream *frpv1alpha1.Upstream, schedule models.Schedule) error {
+	clientKey := models.UpstreamClientKey(upstream)
+	scheduledOut := upstream.Status.Phase == status.UpstreamPhaseInactive || upstream.Status.Phase == status.UpstreamPhaseExpired
+
+	switch {
+	case upstream.Status.Phase == "":
+		// a new Upstream has no previous state to transition from
+	case schedule.Expired && upstream.Status.Phase != status.UpstreamPhaseExpired:
+		r.Recorder.Event(upstream, corev1.EventTypeNormal, EventReasonScheduleExpired,
+			fmt.Sprintf("Upstream expired, the proxy is removed from Client %s", clientKey))
+	case !schedule.Expired && !schedule.Active && upstream.Status.Phase != status.UpstreamPhaseInactive:
+		r.Recorder.Event(upstream, corev1.EventTypeNormal, EventReasonScheduleWindowClosed,
+			fmt.Sprintf("Schedule window closed, the proxy is removed from Client %s until %s",
+				clientKey, schedule.NextTransition.UTC().Format(time.RFC3339)))
+	case schedule.Active && scheduledOut:
+		r.Recorder.Event(upstream, corev1.EventTypeNormal, EventReasonScheduleWindowOpened,
+			fmt.Sprintf("Schedule window opened, the proxy is added to Client %s", clientKey))
+	}
+
+	var transition *metav1.Time
+	if !schedule.NextTransition.IsZero() {
+		transition = &metav1.Time{Time: schedule.NextTransition}
+	}
+	if transition.Equal(upstream.Status.NextScheduleTransition) {
+		return nil
+	}
+
+	upstream.Status.NextScheduleTransition = transition
+	return r.Status().Update(ctx, upstream)
+}
+
+// reconcileServiceCondition resolves the serviceRef of the Upstream and records
+// the outcome in the ServiceResolved condition
+func (r *UpstreamReconciler) reconcileServiceCondition(ctx context.Context, upstream *frpv1alpha1.Upstream) error {
+	serviceKey, ok := models.UpstreamServiceKey(upstream)
+	if !ok {
+		if !meta.RemoveStatusCondition(&upstream.Status.Conditions, status.ConditionTypeServiceResolved) {
+			return nil
+		}
+		return r.Status().Update(ctx, upstream)
+	}
+
+	condition := metav1.Condition{
+		Type:               status.ConditionTypeServiceResolved,
+		ObservedGeneration: upstream.Generation,
+	}
+
+	host, port, err := models.ResolveServiceRef(r.Client, upstream)
+	switch {
+	case errors.IsNotFound(err):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = status.ReasonServiceNotFound
+		condition.Message = fmt.Sprintf("Service %s not found", serviceKey)
+	case models.IsServiceUnresolved(err):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = status.ReasonServicePortMissing
+		condition.Message = err.Error()
+	case err != nil:
+		return err
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = status.ReasonServiceResolved
+		condition.Message = fmt.Sprintf("Service %s resolved to %s:%d", serviceKey, host, port)
+	}
+
+	if !meta.SetStatusCondition(&upstream.Status.Conditions, condition) {
+		return nil
+	}
+
+	return r.Status().Update(ctx, upstream)
+}
+
+// serviceToUpstreams enqueues the Upstreams that reference a Service
+func (r *UpstreamReconciler) serviceToUpstreams(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	upstreams := &frpv1alpha1.UpstreamList{}
+	err := r.Client.List(ctx, upstreams, ctrlclient.MatchingFields{serviceIndexField: ctrlclient.ObjectKeyFromObject(obj).String()})
+	if err != nil {
+		log.Error(err, "failed to list upstreams for service", "service", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(upstreams.Items))
+	for _, upstream := range upstreams.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: upstream.Name, Namespace: upstream.Namespace},
+		})
+	}
+
+	return requests
+}
+
+// updateUpstreamStatus updates the status of an Upstream resource when it changed and
+// requeues to follow the proxy state, which frpc only exposes through its admin API
+func (r *UpstreamReconciler) updateUpstreamStatus(ctx context.Context, upstream *frpv1alpha1.Upstream,
+	phase, message, remoteAddress string) (ctrl.Result, error) {
+
+	requeue := ctrl.Result{RequeueAfter: 10 * time.Second}
+	if phase == status.UpstreamPhaseActive {
+		requeue = ctrl.Result{RequeueAfter: 60 * time.Second}
+	}
+	// a disabled or scheduled out Upstream changes only with its spec or schedule
+	if phase == status.UpstreamPhaseDisabled || phase == status.UpstreamPhaseInactive || phase == status.UpstreamPhaseExpired {
+		requeue = ctrl.Result{}
+	}
+	// requeue precisely when the schedule adds or removes the proxy
+	if transition := upstream.Status.NextScheduleTransition; transition != nil && phase != status.UpstreamPhaseDisabled {
+		until := max(time.Until(transition.Time), time.Second)
+		if requeue.RequeueAfter == 0 || until < requeue.RequeueAfter {
+			requeue = ctrl.Result{RequeueAfter: until}
+		}
+	}
+
+	if upstream.Status.Phase == phase && upstream.Status.Message == message && upstream.Status.RemoteAddress == remoteAddress {
+		return requeue, nil
+	}
+
+	if phase == status.UpstreamPhaseActive {
+		if upstream.Status.Phase != status.UpstreamPhaseActive || upstream.Status.RegisteredAt == nil {
+			now := metav1.Now()
+			upstream.Status.RegisteredAt = &now
+		}
+	} else {
+		upstream.Status.RegisteredAt = nil
+	}
+
+	upstream.Status.Phase = phase
+	upstream.Status.Message = message
+	upstream.Status.RemoteAddress = remoteAddress
+
+	if err := r.Status().Update(ctx, upstream); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return requeue, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/status"
+)
+
+// VirtualNetworkReconciler assigns the addresses of the VirtualNetwork members, the
+// Client reconciler renders the virtual network of its Client from them
+type VirtualNetworkReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=virtualnetworks,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=virtualnetworks/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=virtualnetworks/finalizers,verbs=update
+
+func (r *VirtualNetworkReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	virtualNetwork := &frpv1alpha1.VirtualNetwork{}
+	err := r.Client.Get(ctx, req.NamespacedName, virtualNetwork)
+	if err != nil && errors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	newStatus := frpv1alpha1.VirtualNetworkStatus{}
+	members, err := models.AssignVirtualNetworkAddresses(virtualNetwork)
+	if err != nil {
+		// keep the assigned addresses, the Clients keep their network until the spec is fixed
+		newStatus.Phase = status.VirtualNetworkPhaseFailed
+		newStatus.Message = err.Error()
+		newStatus.Members = virtualNetwork.Status.Members
+	} else {
+		newStatus.Phase = status.VirtualNetworkPhaseReady
+		newStatus.Message = fmt.Sprintf("%d members in %s", len(members), virtualNetwork.Spec.CIDR)
+		newStatus.Members = members
+	}
+
+	if reflect.DeepEqual(virtualNetwork.Status, newStatus) {
+		return ctrl.Result{}, nil
+	}
+
+	log.Info("update virtual network status", "phase", newStatus.Phase)
+	virtualNetwork.Status = newStatus
+	return ctrl.Result{}, r.Status().Update(ctx, virtualNetwork)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *VirtualNetworkReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&frpv1alpha1.VirtualNetwork{}).
+		Complete(r)
+}
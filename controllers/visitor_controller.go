@@ -18,13 +18,28 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlhandler "sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/builder"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/handler"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/status"
 )
 
 // VisitorReconciler reconciles a Visitor object
@@ -33,19 +48,172 @@ type VisitorReconciler struct {
 	Scheme *runtime.Scheme
 }
 
-//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=Visitors,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=Visitors/status,verbs=get;update;patch
-//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=Visitors/finalizers,verbs=update
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=visitors,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=visitors/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=visitors/finalizers,verbs=update
+
+//+kubebuilder:rbac:groups=frp.zufardhiyaulhaq.com,resources=clients,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 
 func (r *VisitorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	log := log.FromContext(ctx)
+	log.Info("Start Visitor Reconciler")
+
+	log.Info("find visitor configuration")
+	visitor := &frpv1alpha1.Visitor{}
+	err := r.Client.Get(ctx, req.NamespacedName, visitor)
+	if err != nil && errors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !visitor.Enabled() {
+		return r.updateVisitorStatus(ctx, visitor, status.VisitorPhaseDisabled,
+			"Visitor is disabled, it is dropped from the frpc configuration")
+	}
+
+	log.Info("find client configuration")
+	client := &frpv1alpha1.Client{}
+	clientKey := models.VisitorClientKey(visitor)
+	err = r.Client.Get(ctx, clientKey, client)
+	if err != nil && errors.IsNotFound(err) {
+		return r.updateVisitorStatus(ctx, visitor, status.VisitorPhasePending,
+			fmt.Sprintf("Client %s not found", clientKey))
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	allowed, err := namespaceAllowed(ctx, r.Client, client, visitor.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !allowed {
+		return r.updateVisitorStatus(ctx, visitor, status.VisitorPhaseFailed,
+			fmt.Sprintf("Client %s does not allow namespace %s", clientKey, visitor.Namespace))
+	}
+
+	if client.Spec.Suspend {
+		return r.updateVisitorStatus(ctx, visitor, status.VisitorPhasePending,
+			fmt.Sprintf("Client %s is suspended", clientKey))
+	}
+
+	log.Info("list frpc pods")
+	pods := &corev1.PodList{}
+	labels := builder.NewDeploymentBuilder().SetName(client.Name).BuildLabels()
+	err = r.Client.List(ctx, pods, ctrlclient.InNamespace(client.Namespace), ctrlclient.MatchingLabels(labels))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
-	return ctrl.Result{}, nil
+	adminConfig, err := models.NewAdminConfig(r.Client, client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// frpc doesn't report visitor state, a visitor is active once every
+	// frpc pod runs a configuration that declares it
+	var pendingMessage string
+	runningPods := 0
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		runningPods++
+
+		log.Info("query frpc configuration", "pod", pod.Name)
+		adminConfig.Common.AdminAddress = pod.Status.PodIP
+		config, err := handler.Config(adminConfig)
+		if err != nil {
+			pendingMessage = fmt.Sprintf("Failed to query frpc in pod %s: %v", pod.Name, err)
+			continue
+		}
+
+		loaded, err := handler.HasVisitor(config, models.BindingName(visitor.Name, visitor.Namespace, client.Namespace))
+		if err != nil {
+			pendingMessage = fmt.Sprintf("Failed to parse the frpc configuration of pod %s: %v", pod.Name, err)
+			continue
+		}
+		if !loaded {
+			pendingMessage = fmt.Sprintf("Visitor not yet loaded by frpc in pod %s", pod.Name)
+		}
+	}
+
+	if runningPods == 0 {
+		return r.updateVisitorStatus(ctx, visitor, status.VisitorPhasePending, "No running frpc pod")
+	}
+	if pendingMessage != "" {
+		return r.updateVisitorStatus(ctx, visitor, status.VisitorPhasePending, pendingMessage)
+	}
+
+	return r.updateVisitorStatus(ctx, visitor, status.VisitorPhaseActive,
+		fmt.Sprintf("Visitor loaded by %d frpc pods", runningPods))
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *VisitorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&frpv1alpha1.Visitor{}).
+		Watches(&frpv1alpha1.Client{}, ctrlhandler.EnqueueRequestsFromMapFunc(r.clientToVisitors),
+			ctrlbuilder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
+
+// clientToVisitors enqueues the Visitors of a Client, a suspended Client or one
+// that stops allowing their namespace changes their phase
+func (r *VisitorReconciler) clientToVisitors(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	visitors := &frpv1alpha1.VisitorList{}
+	err := r.Client.List(ctx, visitors, ctrlclient.MatchingFields{clientIndexField: ctrlclient.ObjectKeyFromObject(obj).String()})
+	if err != nil {
+		log.Error(err, "failed to list visitors for client", "client", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(visitors.Items))
+	for _, visitor := range visitors.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: visitor.Name, Namespace: visitor.Namespace},
+		})
+	}
+
+	return requests
+}
+
+// updateVisitorStatus updates the status of a Visitor resource when it changed and
+// requeues to follow the frpc configuration
+func (r *VisitorReconciler) updateVisitorStatus(ctx context.Context, visitor *frpv1alpha1.Visitor,
+	phase, message string) (ctrl.Result, error) {
+
+	requeue := ctrl.Result{RequeueAfter: 10 * time.Second}
+	if phase == status.VisitorPhaseActive {
+		requeue = ctrl.Result{RequeueAfter: 60 * time.Second}
+	}
+	// a disabled Visitor changes only with its spec
+	if phase == status.VisitorPhaseDisabled {
+		requeue = ctrl.Result{}
+	}
+
+	if visitor.Status.Phase == phase && visitor.Status.Message == message {
+		return requeue, nil
+	}
+
+	if phase == status.VisitorPhaseActive {
+		if visitor.Status.Phase != status.VisitorPhaseActive || visitor.Status.ConnectedAt == nil {
+			now := metav1.Now()
+			visitor.Status.ConnectedAt = &now
+		}
+	} else {
+		visitor.Status.ConnectedAt = nil
+	}
+
+	visitor.Status.Phase = phase
+	visitor.Status.Message = message
+
+	if err := r.Status().Update(ctx, visitor); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return requeue, nil
+}
@@ -0,0 +1,80 @@
+package builder
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+)
+
+// AdminRotationAnnotation records the rotate-admin-credentials value of the Client
+// the credentials in the admin Secret were generated for
+const AdminRotationAnnotation = "frp.zufardhiyaulhaq.com/admin-rotation"
+
+// AdminSecretBuilder builds the Secret holding the generated frpc admin credentials
+type AdminSecretBuilder struct {
+	Name      string
+	Namespace string
+	Username  string
+	Password  string
+	Rotation  string
+}
+
+func NewAdminSecretBuilder() *AdminSecretBuilder {
+	return &AdminSecretBuilder{}
+}
+
+func (n *AdminSecretBuilder) SetName(name string) *AdminSecretBuilder {
+	n.Name = name
+	return n
+}
+
+func (n *AdminSecretBuilder) SetNamespace(namespace string) *AdminSecretBuilder {
+	n.Namespace = namespace
+	return n
+}
+
+func (n *AdminSecretBuilder) SetUsername(username string) *AdminSecretBuilder {
+	n.Username = username
+	return n
+}
+
+func (n *AdminSecretBuilder) SetPassword(password string) *AdminSecretBuilder {
+	n.Password = password
+	return n
+}
+
+func (n *AdminSecretBuilder) SetRotation(rotation string) *AdminSecretBuilder {
+	n.Rotation = rotation
+	return n
+}
+
+func (n *AdminSecretBuilder) Build() (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      models.AdminSecretName(n.Name),
+			Namespace: n.Namespace,
+			Labels:    n.BuildLabels(),
+			Annotations: map[string]string{
+				AdminRotationAnnotation: n.Rotation,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			models.ADMIN_SECRET_USERNAME_KEY: []byte(n.Username),
+			models.ADMIN_SECRET_PASSWORD_KEY: []byte(n.Password),
+		},
+	}
+
+	return secret, nil
+}
+
+func (n *AdminSecretBuilder) BuildLabels() map[string]string {
+	var labels = map[string]string{
+		"app.kubernetes.io/name":       models.AdminSecretName(n.Name),
+		"app.kubernetes.io/managed-by": "frp-operator",
+		"app.kubernetes.io/created-by": n.Name,
+	}
+
+	return labels
+}
@@ -2,13 +2,26 @@ package builder
 
 import (
 	"bytes"
+	"fmt"
+	"net"
+	"strconv"
 	"strings"
-	"text/template"
+
+	"github.com/BurntSushi/toml"
 
 	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
 	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/utils"
 )
 
+// templateEscaper keeps frpc from executing user input. frpc renders its
+// configuration as a Go template before parsing it, so every delimiter is written
+// as a template string except the pod name the operator adds to replicated proxies
+var templateEscaper = strings.NewReplacer(
+	models.POD_NAME_TEMPLATE, models.POD_NAME_TEMPLATE,
+	"{{", "{{ `{{` }}",
+	"}}", "{{ `}}` }}",
+)
+
 type ConfigurationBuilder struct {
 	Config models.Config
 }
@@ -24,19 +37,20 @@ func (n *ConfigurationBuilder) SetConfig(config models.Config) *ConfigurationBui
 
 func (n *ConfigurationBuilder) Build() (string, error) {
 	var configurationBuffer bytes.Buffer
+	configurationBuffer.WriteString("# frpc.toml\n")
 
-	templateEngine, err := template.New("frpc").Parse(utils.CLIENT_TEMPLATE)
-	if err != nil {
-		return "", err
-	}
+	encoder := toml.NewEncoder(&configurationBuffer)
+	encoder.Indent = ""
 
-	err = templateEngine.Execute(&configurationBuffer, n.Config)
+	err := encoder.Encode(newClientConfig(n.Config))
 	if err != nil {
 		return "", err
 	}
 
+	// the encoder escapes newlines inside values, so every blank line is a
+	// separator between tables
 	var configuration []string
-	for _, data := range strings.Split(configurationBuffer.String(), "\n") {
+	for _, data := range strings.Split(templateEscaper.Replace(configurationBuffer.String()), "\n") {
 		if len(strings.TrimSpace(data)) != 0 {
 			configuration = append(configuration, data)
 		}
@@ -44,3 +58,360 @@ func (n *ConfigurationBuilder) Build() (string, error) {
 
 	return strings.Join(configuration, "\n"), nil
 }
+
+func newClientConfig(config models.Config) utils.ClientConfig {
+	common := config.Common
+
+	clientConfig := utils.ClientConfig{
+		ServerAddr: common.ServerAddress,
+		ServerPort: common.ServerPort,
+		WebServer: utils.WebServerConfig{
+			Addr:        common.AdminAddress,
+			Port:        common.AdminPort,
+			User:        common.AdminUsername,
+			Password:    common.AdminPassword,
+			PprofEnable: common.PprofEnable,
+		},
+	}
+
+	if common.STUNServer != nil {
+		clientConfig.NatHoleStunServer = *common.STUNServer
+	}
+
+	authentication := common.ServerAuthentication
+	if authentication.Type == 1 {
+		clientConfig.Auth = &utils.AuthClientConfig{
+			Method: "token",
+			Token:  authentication.Token,
+		}
+	}
+
+	if authentication.Type == 2 {
+		clientConfig.Auth = &utils.AuthClientConfig{
+			Method: "oidc",
+			OIDC: &utils.AuthOIDCClientConfig{
+				ClientID:         authentication.OIDCClientID,
+				ClientSecret:     authentication.OIDCClientSecret,
+				Audience:         authentication.OIDCAudience,
+				Scope:            authentication.OIDCScope,
+				TokenEndpointURL: authentication.OIDCTokenURL,
+			},
+		}
+	}
+
+	protocol := strings.ToLower(common.ServerProtocol)
+	if protocol == "tcp" {
+		protocol = ""
+	}
+
+	if common.TLS != nil || common.Transport != nil || protocol != "" {
+		clientConfig.Transport = &utils.ClientTransportConfig{Protocol: protocol}
+	}
+
+	if common.TLS != nil {
+		clientConfig.Transport.TLS = &utils.TLSClientConfig{
+			Enable:        boolPtr(common.TLS.Enable),
+			CertFile:      common.TLS.CertFile,
+			KeyFile:       common.TLS.KeyFile,
+			TrustedCaFile: common.TLS.TrustedCAFile,
+		}
+	}
+
+	if common.Transport != nil {
+		clientConfig.Transport.PoolCount = common.Transport.PoolCount
+		clientConfig.Transport.TCPMux = boolPtr(common.Transport.TCPMux)
+		clientConfig.Transport.DialServerTimeout = common.Transport.DialServerTimeout
+		clientConfig.Transport.DialServerKeepalive = common.Transport.DialServerKeepalive
+		clientConfig.Transport.ConnectServerLocalIP = common.Transport.ConnectServerLocalIP
+
+		if quic := common.Transport.QUIC; quic != nil {
+			clientConfig.Transport.QUIC = &utils.QUICClientConfig{
+				KeepalivePeriod:    quic.KeepalivePeriod,
+				MaxIdleTimeout:     quic.MaxIdleTimeout,
+				MaxIncomingStreams: quic.MaxIncomingStreams,
+			}
+		}
+	}
+
+	for _, upstream := range config.Upstreams {
+		clientConfig.Proxies = append(clientConfig.Proxies, newProxyConfig(upstream))
+	}
+
+	for _, visitor := range config.Visitors {
+		clientConfig.Visitors = append(clientConfig.Visitors, newVisitorConfigs(visitor)...)
+	}
+
+	if config.VirtualNet != nil {
+		setVirtualNet(&clientConfig, config.VirtualNet)
+	}
+
+	return clientConfig
+}
+
+// setVirtualNet joins frpc to a virtual network, frpc serves its address through an
+// stcp proxy with the virtual_net plugin and reaches every peer through an stcp
+// visitor routing the peer address
+func setVirtualNet(clientConfig *utils.ClientConfig, virtualNet *models.VirtualNet) {
+	clientConfig.FeatureGates = map[string]bool{"VirtualNet": true}
+	clientConfig.VirtualNet = &utils.VirtualNetConfig{Address: virtualNet.Address}
+
+	clientConfig.Proxies = append(clientConfig.Proxies, utils.ProxyConfig{
+		Name:      virtualNet.ProxyName,
+		Type:      "stcp",
+		SecretKey: virtualNet.SecretKey,
+		Plugin:    &utils.ProxyPlugin{Type: "virtual_net"},
+	})
+
+	// the visitors don't listen, the plugin hands them the packets routed to the peer
+	for _, peer := range virtualNet.Peers {
+		clientConfig.Visitors = append(clientConfig.Visitors, utils.VisitorConfig{
+			Name:       peer.ProxyName + "-visitor",
+			Type:       "stcp",
+			ServerName: peer.ProxyName,
+			SecretKey:  virtualNet.SecretKey,
+			BindPort:   -1,
+			Plugin: &utils.VisitorPlugin{
+				Type:          "virtual_net",
+				DestinationIP: peer.Address,
+			},
+		})
+	}
+}
+
+func newProxyConfig(upstream models.Upstream) utils.ProxyConfig {
+	proxy := utils.ProxyConfig{
+		Name: upstream.Name,
+	}
+
+	switch upstream.Type {
+	case models.TCP:
+		proxy.Type = "tcp"
+		proxy.LocalIP = upstream.TCP.Host
+		proxy.LocalPort = upstream.TCP.Port
+		proxy.RemotePort = upstream.TCP.ServerPort
+		proxy.Plugin = newProxyPlugin(upstream.TCP.Plugin)
+		proxy.Transport = newProxyTransport(upstream.TCP.Transport, upstream.TCP.ProxyProtocol)
+		proxy.HealthCheck = newTCPHealthCheck(upstream.TCP.HealthCheck)
+		proxy.LoadBalancer = newLoadBalancer(upstream.TCP.LoadBalancer)
+	case models.UDP:
+		proxy.Type = "udp"
+		proxy.LocalIP = upstream.UDP.Host
+		proxy.LocalPort = upstream.UDP.Port
+		proxy.RemotePort = upstream.UDP.ServerPort
+	case models.STCP, models.XTCP:
+		secure := upstream.STCP
+		proxy.Type = "stcp"
+		if upstream.Type == models.XTCP {
+			secure = upstream.XTCP
+			proxy.Type = "xtcp"
+		}
+		proxy.LocalIP = secure.Host
+		proxy.LocalPort = secure.Port
+		proxy.SecretKey = secure.SecretKey
+		proxy.AllowUsers = secure.AllowUsers
+		proxy.Transport = newProxyTransport(secure.Transport, secure.ProxyProtocol)
+		proxy.HealthCheck = newTCPHealthCheck(secure.HealthCheck)
+	case models.HTTP:
+		proxy.Type = "http"
+		proxy.LocalIP = upstream.HTTP.Host
+		proxy.LocalPort = upstream.HTTP.Port
+		proxy.Subdomain = upstream.HTTP.Subdomain
+		proxy.CustomDomains = upstream.HTTP.CustomDomains
+		proxy.Locations = upstream.HTTP.Locations
+		proxy.HostHeaderRewrite = upstream.HTTP.HostHeaderRewrite
+		proxy.HTTPUser = upstream.HTTP.HTTPUser
+		proxy.HTTPPassword = upstream.HTTP.HTTPPassword
+		proxy.RequestHeaders = newHeaderOperations(upstream.HTTP.RequestHeaders)
+		proxy.ResponseHeaders = newHeaderOperations(upstream.HTTP.ResponseHeaders)
+		proxy.Transport = newProxyTransport(upstream.HTTP.Transport, nil)
+		proxy.LoadBalancer = newLoadBalancer(upstream.HTTP.LoadBalancer)
+		if upstream.HTTP.HealthCheck != nil {
+			proxy.HealthCheck = &utils.HealthCheckConfig{
+				Type:            upstream.HTTP.HealthCheck.Type,
+				Path:            upstream.HTTP.HealthCheck.Path,
+				TimeoutSeconds:  upstream.HTTP.HealthCheck.TimeoutSeconds,
+				MaxFailed:       upstream.HTTP.HealthCheck.MaxFailed,
+				IntervalSeconds: upstream.HTTP.HealthCheck.IntervalSeconds,
+			}
+		}
+	case models.HTTPS:
+		proxy.Type = "https"
+		proxy.LocalIP = upstream.HTTPS.Host
+		proxy.LocalPort = upstream.HTTPS.Port
+		proxy.CustomDomains = upstream.HTTPS.CustomDomains
+		proxy.Transport = newProxyTransport(upstream.HTTPS.Transport, upstream.HTTPS.ProxyProtocol)
+		// frpc terminates TLS and forwards plain HTTP to the local address
+		if upstream.HTTPS.CertFile != "" {
+			proxy.Plugin = &utils.ProxyPlugin{
+				Type:      "https2http",
+				LocalAddr: net.JoinHostPort(upstream.HTTPS.Host, strconv.Itoa(upstream.HTTPS.Port)),
+				CrtPath:   upstream.HTTPS.CertFile,
+				KeyPath:   upstream.HTTPS.KeyFile,
+			}
+			proxy.LocalIP = ""
+			proxy.LocalPort = 0
+		}
+	case models.TCPMUX:
+		proxy.Type = "tcpmux"
+		proxy.Multiplexer = upstream.TCPMUX.Multiplexer
+		proxy.LocalIP = upstream.TCPMUX.Host
+		proxy.LocalPort = upstream.TCPMUX.Port
+		proxy.CustomDomains = upstream.TCPMUX.CustomDomains
+		proxy.Transport = newProxyTransport(upstream.TCPMUX.Transport, nil)
+		proxy.LoadBalancer = newLoadBalancer(upstream.TCPMUX.LoadBalancer)
+	case models.SUDP:
+		proxy.Type = "sudp"
+		proxy.LocalIP = upstream.SUDP.Host
+		proxy.LocalPort = upstream.SUDP.Port
+		proxy.SecretKey = upstream.SUDP.SecretKey
+		proxy.AllowUsers = upstream.SUDP.AllowUsers
+		proxy.Transport = newProxyTransport(upstream.SUDP.Transport, nil)
+	}
+
+	return proxy
+}
+
+func newVisitorConfigs(visitor models.Visitor) []utils.VisitorConfig {
+	switch visitor.Type {
+	case models.STCPVisitor:
+		return []utils.VisitorConfig{{
+			Name:       visitor.Name,
+			Type:       "stcp",
+			ServerName: visitor.STCP.ServerName,
+			SecretKey:  visitor.STCP.SecretKey,
+			BindAddr:   visitor.STCP.Host,
+			BindPort:   visitor.STCP.Port,
+		}}
+	case models.XTCPVisitor:
+		xtcp := utils.VisitorConfig{
+			Name:           visitor.Name,
+			Type:           "xtcp",
+			ServerName:     visitor.XTCP.ServerName,
+			SecretKey:      visitor.XTCP.SecretKey,
+			BindAddr:       visitor.XTCP.Host,
+			BindPort:       visitor.XTCP.Port,
+			KeepTunnelOpen: boolPtr(visitor.XTCP.PersistantConnection),
+		}
+		if !visitor.XTCP.EnableAssistedAddrs {
+			xtcp.NatHoleStun = &utils.NatHoleStun{DisableAssistedAddrs: true}
+		}
+		if visitor.XTCP.Fallback == nil {
+			return []utils.VisitorConfig{xtcp}
+		}
+
+		// the fallback stcp visitor is only dialed by the xtcp visitor, a
+		// bindPort of -1 keeps it from listening on its own
+		fallbackName := visitor.Name + "-fallback"
+		xtcp.FallbackTo = fallbackName
+		xtcp.FallbackTimeoutMs = visitor.XTCP.Fallback.Timeout
+
+		return []utils.VisitorConfig{xtcp, {
+			Name:       fallbackName,
+			Type:       "stcp",
+			ServerName: visitor.XTCP.Fallback.ServerName,
+			SecretKey:  visitor.XTCP.SecretKey,
+			BindPort:   -1,
+		}}
+	case models.SUDPVisitor:
+		return []utils.VisitorConfig{{
+			Name:       visitor.Name,
+			Type:       "sudp",
+			ServerName: visitor.SUDP.ServerName,
+			SecretKey:  visitor.SUDP.SecretKey,
+			BindAddr:   visitor.SUDP.Host,
+			BindPort:   visitor.SUDP.Port,
+		}}
+	}
+
+	return nil
+}
+
+func newProxyTransport(transport *models.Upstream_TCP_Transport, proxyProtocol *string) *utils.ProxyTransport {
+	if transport == nil && proxyProtocol == nil {
+		return nil
+	}
+
+	proxyTransport := &utils.ProxyTransport{}
+	if proxyProtocol != nil {
+		proxyTransport.ProxyProtocolVersion = *proxyProtocol
+	}
+
+	if transport == nil {
+		return proxyTransport
+	}
+
+	proxyTransport.UseEncryption = boolPtr(transport.UseEncryption)
+	proxyTransport.UseCompression = boolPtr(transport.UseCompression)
+	if transport.BandwdithLimit != nil && transport.BandwdithLimit.Enabled {
+		proxyTransport.BandwidthLimit = fmt.Sprintf("%d%s", transport.BandwdithLimit.Limit, transport.BandwdithLimit.Type)
+		proxyTransport.BandwidthLimitMode = "client"
+	}
+	if transport.ProxyURL != nil {
+		proxyTransport.ProxyURL = *transport.ProxyURL
+	}
+
+	return proxyTransport
+}
+
+func newTCPHealthCheck(healthCheck *models.Upstream_TCP_HealthCheck) *utils.HealthCheckConfig {
+	if healthCheck == nil {
+		return nil
+	}
+
+	return &utils.HealthCheckConfig{
+		Type:            "tcp",
+		TimeoutSeconds:  healthCheck.TimeoutSeconds,
+		MaxFailed:       healthCheck.MaxFailed,
+		IntervalSeconds: healthCheck.IntervalSeconds,
+	}
+}
+
+func newLoadBalancer(loadBalancer *models.LoadBalancerConfig) *utils.LoadBalancer {
+	if loadBalancer == nil {
+		return nil
+	}
+
+	return &utils.LoadBalancer{
+		Group:    loadBalancer.Group,
+		GroupKey: loadBalancer.GroupKey,
+	}
+}
+
+func newHeaderOperations(headers map[string]string) *utils.HeaderOperations {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	return &utils.HeaderOperations{Set: headers}
+}
+
+func newProxyPlugin(plugin *models.PluginConfig) *utils.ProxyPlugin {
+	if plugin == nil {
+		return nil
+	}
+
+	proxyPlugin := &utils.ProxyPlugin{Type: plugin.Type}
+	switch plugin.Type {
+	case "socks5":
+		proxyPlugin.Username = plugin.Username
+		proxyPlugin.Password = plugin.Password
+	case "http_proxy":
+		proxyPlugin.HTTPUser = plugin.Username
+		proxyPlugin.HTTPPassword = plugin.Password
+	case "static_file":
+		proxyPlugin.LocalPath = plugin.LocalPath
+		proxyPlugin.StripPrefix = plugin.StripPrefix
+		proxyPlugin.HTTPUser = plugin.HTTPUser
+		proxyPlugin.HTTPPassword = plugin.HTTPPassword
+	case "unix_domain_socket":
+		proxyPlugin.UnixPath = plugin.UnixPath
+	case "https2http", "https2https", "http2https", "http2http":
+		proxyPlugin.LocalAddr = plugin.LocalAddr
+	}
+
+	return proxyPlugin
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
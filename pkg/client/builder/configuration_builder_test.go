@@ -1,10 +1,15 @@
 package builder
 
 import (
+	"bytes"
 	"strings"
 	"testing"
+	"text/template"
+
+	"github.com/BurntSushi/toml"
 
 	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/utils"
 )
 
 func TestConfigurationBuilder_Build(t *testing.T) {
@@ -801,6 +806,37 @@ func TestConfigurationBuilder_Build(t *testing.T) {
 				`allowUsers = ["*"]`,
 			},
 		},
+		{
+			name: "SUDP upstream",
+			config: models.Config{
+				Common: basicCommon(),
+				Upstreams: []models.Upstream{
+					{
+						Name: "my-sudp-service",
+						Type: 8,
+						SUDP: models.Upstream_SUDP{
+							Host:       "127.0.0.1",
+							Port:       53,
+							SecretKey:  "sudp-secret-key",
+							AllowUsers: []string{"*"},
+							Transport: &models.Upstream_TCP_Transport{
+								UseEncryption: true,
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+			wantContains: []string{
+				`name = "my-sudp-service"`,
+				`type = "sudp"`,
+				`localIP = "127.0.0.1"`,
+				`localPort = 53`,
+				`secretKey = "sudp-secret-key"`,
+				`allowUsers = ["*"]`,
+				`useEncryption = true`,
+			},
+		},
 		{
 			name: "STCP visitor",
 			config: models.Config{
@@ -829,6 +865,34 @@ func TestConfigurationBuilder_Build(t *testing.T) {
 				`bindPort = 2222`,
 			},
 		},
+		{
+			name: "SUDP visitor",
+			config: models.Config{
+				Common: basicCommon(),
+				Visitors: []models.Visitor{
+					{
+						Name: "my-sudp-visitor",
+						Type: 3, // SUDPVisitor
+						SUDP: models.Visitor_SUDP{
+							Host:       "0.0.0.0",
+							Port:       5353,
+							ServerName: "remote-dns-service",
+							SecretKey:  "sudp-visitor-secret",
+						},
+					},
+				},
+			},
+			wantErr: false,
+			wantContains: []string{
+				`[[visitors]]`,
+				`name = "my-sudp-visitor"`,
+				`type = "sudp"`,
+				`serverName = "remote-dns-service"`,
+				`secretKey = "sudp-visitor-secret"`,
+				`bindAddr = "0.0.0.0"`,
+				`bindPort = 5353`,
+			},
+		},
 		{
 			name: "XTCP visitor without fallback",
 			config: models.Config{
@@ -1232,6 +1296,34 @@ func TestConfigurationBuilder_Build(t *testing.T) {
 				`customDomains = ["secure.example.com"]`,
 			},
 		},
+		{
+			name: "HTTPS upstream - TLS terminated with https2http",
+			config: models.Config{
+				Common: basicCommon(),
+				Upstreams: []models.Upstream{
+					{
+						Name: "https-terminated",
+						Type: 6,
+						HTTPS: models.Upstream_HTTPS{
+							Host:          "web.default.svc",
+							Port:          80,
+							CustomDomains: []string{"web.example.com"},
+							CertFile:      "/frp-config/tls.default.web-tls.crt",
+							KeyFile:       "/frp-config/tls.default.web-tls.key",
+						},
+					},
+				},
+			},
+			wantErr: false,
+			wantContains: []string{
+				`type = "https"`,
+				`customDomains = ["web.example.com"]`,
+				`plugin.type = "https2http"`,
+				`plugin.localAddr = "web.default.svc:80"`,
+				`plugin.crtPath = "/frp-config/tls.default.web-tls.crt"`,
+				`plugin.keyPath = "/frp-config/tls.default.web-tls.key"`,
+			},
+		},
 		{
 			name: "HTTPS upstream - with proxy protocol",
 			config: models.Config{
@@ -1308,7 +1400,7 @@ func TestConfigurationBuilder_Build(t *testing.T) {
 				`name = "socks5-proxy"`,
 				`type = "tcp"`,
 				`remotePort = 1080`,
-				`plugin = "socks5"`,
+				`plugin.type = "socks5"`,
 				`plugin.username = "proxyuser"`,
 				`plugin.password = "proxypass"`,
 			},
@@ -1339,7 +1431,7 @@ func TestConfigurationBuilder_Build(t *testing.T) {
 			wantErr: false,
 			wantContains: []string{
 				`name = "http-proxy"`,
-				`plugin = "http_proxy"`,
+				`plugin.type = "http_proxy"`,
 				`plugin.httpUser = "proxyuser"`,
 				`plugin.httpPassword = "proxypass"`,
 			},
@@ -1367,7 +1459,7 @@ func TestConfigurationBuilder_Build(t *testing.T) {
 			},
 			wantErr: false,
 			wantContains: []string{
-				`plugin = "static_file"`,
+				`plugin.type = "static_file"`,
 				`plugin.localPath = "/data/public"`,
 				`plugin.stripPrefix = "/download"`,
 				`plugin.httpUser = "admin"`,
@@ -1394,7 +1486,7 @@ func TestConfigurationBuilder_Build(t *testing.T) {
 			},
 			wantErr: false,
 			wantContains: []string{
-				`plugin = "unix_domain_socket"`,
+				`plugin.type = "unix_domain_socket"`,
 				`plugin.unixPath = "/var/run/docker.sock"`,
 			},
 		},
@@ -1483,6 +1575,111 @@ func TestConfigurationBuilder_Build(t *testing.T) {
 				`transport.connectServerLocalIP = "10.0.0.5"`,
 			},
 		},
+		{
+			name: "common config with quic transport",
+			config: models.Config{
+				Common: models.Common{
+					ServerAddress:  "frp.example.com",
+					ServerPort:     7002,
+					ServerProtocol: "quic",
+					AdminAddress:   "0.0.0.0",
+					AdminPort:      7400,
+					AdminUsername:  "admin",
+					AdminPassword:  "secret",
+					Transport: &models.TransportConfig{
+						TCPMux: true,
+						QUIC: &models.QUICConfig{
+							KeepalivePeriod:    10,
+							MaxIdleTimeout:     30,
+							MaxIncomingStreams: 100000,
+						},
+					},
+				},
+			},
+			wantErr: false,
+			wantContains: []string{
+				`transport.protocol = "quic"`,
+				`transport.quic.keepalivePeriod = 10`,
+				`transport.quic.maxIdleTimeout = 30`,
+				`transport.quic.maxIncomingStreams = 100000`,
+			},
+		},
+		{
+			name: "common config with websocket protocol",
+			config: models.Config{
+				Common: models.Common{
+					ServerAddress:  "frp.example.com",
+					ServerPort:     443,
+					ServerProtocol: "wss",
+					AdminAddress:   "0.0.0.0",
+					AdminPort:      7400,
+					AdminUsername:  "admin",
+					AdminPassword:  "secret",
+				},
+			},
+			wantErr: false,
+			wantContains: []string{
+				`transport.protocol = "wss"`,
+			},
+		},
+		{
+			name: "HTTP upstream shared by replicas",
+			config: models.Config{
+				Common: basicCommon(),
+				Upstreams: models.Upstreams{
+					{
+						Name: "web-{{ .Envs.FRPC_POD_NAME }}",
+						Type: 5,
+						HTTP: models.Upstream_HTTP{
+							Host:          "web.default.svc",
+							Port:          80,
+							CustomDomains: []string{"web.example.com"},
+							LoadBalancer: &models.LoadBalancerConfig{
+								Group: "web",
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+			wantContains: []string{
+				`name = "web-{{ .Envs.FRPC_POD_NAME }}"`,
+				`type = "http"`,
+				`loadBalancer.group = "web"`,
+			},
+			wantNotContain: []string{
+				`loadBalancer.groupKey`,
+			},
+		},
+		{
+			name: "TCPMUX upstream shared by replicas",
+			config: models.Config{
+				Common: basicCommon(),
+				Upstreams: models.Upstreams{
+					{
+						Name: "mux-{{ .Envs.FRPC_POD_NAME }}",
+						Type: 7,
+						TCPMUX: models.Upstream_TCPMUX{
+							Host:          "mux.default.svc",
+							Port:          8080,
+							Multiplexer:   "httpconnect",
+							CustomDomains: []string{"mux.example.com"},
+							LoadBalancer: &models.LoadBalancerConfig{
+								Group:    "mux",
+								GroupKey: "group-secret",
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+			wantContains: []string{
+				`name = "mux-{{ .Envs.FRPC_POD_NAME }}"`,
+				`type = "tcpmux"`,
+				`loadBalancer.group = "mux"`,
+				`loadBalancer.groupKey = "group-secret"`,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1502,6 +1699,11 @@ func TestConfigurationBuilder_Build(t *testing.T) {
 				return
 			}
 
+			if _, err := toml.Decode(result, &utils.ClientConfig{}); err != nil {
+				t.Errorf("ConfigurationBuilder.Build() rendered invalid TOML: %v\nGot:\n%s", err, result)
+			}
+			result = dottedKeys(result)
+
 			for _, want := range tt.wantContains {
 				if !strings.Contains(result, want) {
 					t.Errorf("ConfigurationBuilder.Build() result missing expected content: %q\nGot:\n%s", want, result)
@@ -1535,6 +1737,146 @@ func TestConfigurationBuilder_Build_RemovesEmptyLines(t *testing.T) {
 	}
 }
 
+func TestConfigurationBuilder_Build_EscapesUserInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		injection string
+	}{
+		{
+			name:      "toml",
+			injection: "x\"\n[[proxies]]\nname = \"injected\"\ntype = \"tcp\"\nremotePort = 22\n#",
+		},
+		{
+			name:      "template",
+			injection: "x{{ printf `%c` 34 }}{{ printf `%c` 10 }}[[proxies]]{{ printf `%c` 10 }}name = {{ printf `%c` 34 }}injected{{ printf `%c` 34 }}}}{{",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injection := tt.injection
+
+			common := basicCommon()
+			common.ServerAuthentication = models.ServerAuthentication{Type: 1, Token: injection}
+			config := models.Config{
+				Common: common,
+				Upstreams: []models.Upstream{
+					{
+						Name: "web-" + models.POD_NAME_TEMPLATE,
+						Type: 5,
+						HTTP: models.Upstream_HTTP{
+							Host:              "web.default.svc",
+							Port:              80,
+							Subdomain:         injection,
+							Locations:         []string{"/", injection},
+							HostHeaderRewrite: injection,
+							RequestHeaders:    map[string]string{"X-Injected\" = \"1": injection},
+						},
+					},
+				},
+			}
+
+			result, err := NewConfigurationBuilder().SetConfig(config).Build()
+			if err != nil {
+				t.Fatalf("ConfigurationBuilder.Build() unexpected error = %v", err)
+			}
+			result = renderFrpcTemplate(t, result)
+
+			rendered := utils.ClientConfig{}
+			if _, err := toml.Decode(result, &rendered); err != nil {
+				t.Fatalf("ConfigurationBuilder.Build() rendered invalid TOML: %v\nGot:\n%s", err, result)
+			}
+
+			if len(rendered.Proxies) != 1 {
+				t.Fatalf("ConfigurationBuilder.Build() rendered %d proxies, want 1\nGot:\n%s", len(rendered.Proxies), result)
+			}
+
+			proxy := rendered.Proxies[0]
+			if proxy.Name != "web-frpc-0" {
+				t.Errorf("name = %q, want the pod name expanded to %q", proxy.Name, "web-frpc-0")
+			}
+			if rendered.Auth.Token != injection {
+				t.Errorf("auth.token = %q, want %q", rendered.Auth.Token, injection)
+			}
+			if proxy.Subdomain != injection {
+				t.Errorf("subdomain = %q, want %q", proxy.Subdomain, injection)
+			}
+			if proxy.HostHeaderRewrite != injection {
+				t.Errorf("hostHeaderRewrite = %q, want %q", proxy.HostHeaderRewrite, injection)
+			}
+			if len(proxy.Locations) != 2 || proxy.Locations[1] != injection {
+				t.Errorf("locations = %q, want [\"/\" %q]", proxy.Locations, injection)
+			}
+			if proxy.RequestHeaders.Set["X-Injected\" = \"1"] != injection {
+				t.Errorf("requestHeaders.set = %q, want the injected key and value kept intact", proxy.RequestHeaders.Set)
+			}
+		})
+	}
+}
+
+func TestConfigurationBuilder_Build_VirtualNet(t *testing.T) {
+	config := models.Config{
+		Common: basicCommon(),
+		VirtualNet: &models.VirtualNet{
+			Address:   "100.86.0.1/24",
+			ProxyName: "vnet-mesh-cluster-a",
+			SecretKey: "vnet-secret",
+			Peers: []models.VirtualNetPeer{
+				{ProxyName: "vnet-mesh-cluster-b", Address: "100.86.0.2"},
+			},
+		},
+	}
+
+	result, err := NewConfigurationBuilder().SetConfig(config).Build()
+	if err != nil {
+		t.Fatalf("ConfigurationBuilder.Build() unexpected error = %v", err)
+	}
+
+	rendered := utils.ClientConfig{}
+	if _, err := toml.Decode(result, &rendered); err != nil {
+		t.Fatalf("ConfigurationBuilder.Build() rendered invalid TOML: %v\nGot:\n%s", err, result)
+	}
+
+	if !rendered.FeatureGates["VirtualNet"] {
+		t.Errorf("featureGates.VirtualNet = false, want true\nGot:\n%s", result)
+	}
+	if rendered.VirtualNet == nil || rendered.VirtualNet.Address != "100.86.0.1/24" {
+		t.Errorf("virtualNet.address = %v, want 100.86.0.1/24", rendered.VirtualNet)
+	}
+
+	if len(rendered.Proxies) != 1 {
+		t.Fatalf("ConfigurationBuilder.Build() rendered %d proxies, want 1\nGot:\n%s", len(rendered.Proxies), result)
+	}
+	proxy := rendered.Proxies[0]
+	if proxy.Name != "vnet-mesh-cluster-a" || proxy.Type != "stcp" || proxy.SecretKey != "vnet-secret" ||
+		proxy.Plugin == nil || proxy.Plugin.Type != "virtual_net" {
+		t.Errorf("proxy = %+v, want an stcp proxy with the virtual_net plugin", proxy)
+	}
+
+	if len(rendered.Visitors) != 1 {
+		t.Fatalf("ConfigurationBuilder.Build() rendered %d visitors, want 1\nGot:\n%s", len(rendered.Visitors), result)
+	}
+	visitor := rendered.Visitors[0]
+	if visitor.ServerName != "vnet-mesh-cluster-b" || visitor.Type != "stcp" || visitor.BindPort != -1 ||
+		visitor.Plugin == nil || visitor.Plugin.Type != "virtual_net" || visitor.Plugin.DestinationIP != "100.86.0.2" {
+		t.Errorf("visitor = %+v, want an stcp visitor routing 100.86.0.2", visitor)
+	}
+}
+
+func TestConfigurationBuilder_Build_DefaultProtocol(t *testing.T) {
+	common := basicCommon()
+	common.ServerProtocol = "TCP"
+
+	result, err := NewConfigurationBuilder().SetConfig(models.Config{Common: common}).Build()
+	if err != nil {
+		t.Fatalf("ConfigurationBuilder.Build() unexpected error = %v", err)
+	}
+
+	if strings.Contains(result, "transport.protocol") {
+		t.Errorf("ConfigurationBuilder.Build() rendered the default tcp protocol\nGot:\n%s", result)
+	}
+}
+
 func TestNewConfigurationBuilder(t *testing.T) {
 	builder := NewConfigurationBuilder()
 	if builder == nil {
@@ -1572,19 +1914,1566 @@ func TestConfigurationBuilder_SetConfig_Chainable(t *testing.T) {
 	}
 }
 
-// Helper functions
+func TestConfigurationBuilder_CommonSection(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+	}
 
-func basicCommon() models.Common {
-	return models.Common{
-		ServerAddress: "frp.example.com",
-		ServerPort:    7000,
-		AdminAddress:  "0.0.0.0",
-		AdminPort:     7400,
-		AdminUsername: "admin",
-		AdminPassword: "password",
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `serverAddr = "frp.example.com"`)
+	assertContains(t, output, `serverPort = 7000`)
+	assertContains(t, output, `webServer.addr = "0.0.0.0"`)
+	assertContains(t, output, `webServer.port = 7400`)
+	assertContains(t, output, `webServer.user = "admin"`)
+	assertContains(t, output, `webServer.password = "secret"`)
+}
+
+func TestConfigurationBuilder_CommonWithTokenAuth(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+			ServerAuthentication: models.ServerAuthentication{
+				Type:  1,
+				Token: "my-secret-token",
+			},
+		},
 	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `auth.method = "token"`)
+	assertContains(t, output, `auth.token = "my-secret-token"`)
 }
 
-func stringPtr(s string) *string {
-	return &s
+func TestConfigurationBuilder_CommonWithSTUNServer(t *testing.T) {
+	stunServer := "stun.example.com:3478"
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+			STUNServer:    &stunServer,
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `natHoleStunServer = "stun.example.com:3478"`)
+}
+
+func TestConfigurationBuilder_CommonWithoutSTUNServer(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+			STUNServer:    nil,
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertNotContains(t, output, `natHoleStunServer`)
+}
+
+func TestConfigurationBuilder_TCPUpstreamBasic(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "tcp-service",
+				Type: 1,
+				TCP: models.Upstream_TCP{
+					Host:       "localhost",
+					Port:       8080,
+					ServerPort: 9080,
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `[[proxies]]`)
+	assertContains(t, output, `name = "tcp-service"`)
+	assertContains(t, output, `type = "tcp"`)
+	assertContains(t, output, `localIP = "localhost"`)
+	assertContains(t, output, `localPort = 8080`)
+	assertContains(t, output, `remotePort = 9080`)
+}
+
+func TestConfigurationBuilder_TCPUpstreamWithProxyProtocol(t *testing.T) {
+	proxyProtocol := "v2"
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "tcp-service",
+				Type: 1,
+				TCP: models.Upstream_TCP{
+					Host:          "localhost",
+					Port:          8080,
+					ServerPort:    9080,
+					ProxyProtocol: &proxyProtocol,
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `transport.proxyProtocolVersion = "v2"`)
+}
+
+func TestConfigurationBuilder_TCPUpstreamWithHealthCheck(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "tcp-service",
+				Type: 1,
+				TCP: models.Upstream_TCP{
+					Host:       "localhost",
+					Port:       8080,
+					ServerPort: 9080,
+					HealthCheck: &models.Upstream_TCP_HealthCheck{
+						TimeoutSeconds:  3,
+						MaxFailed:       5,
+						IntervalSeconds: 10,
+					},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `healthCheck.type = "tcp"`)
+	assertContains(t, output, `healthCheck.timeoutSeconds = 3`)
+	assertContains(t, output, `healthCheck.maxFailed = 5`)
+	assertContains(t, output, `healthCheck.intervalSeconds = 10`)
+}
+
+func TestConfigurationBuilder_TCPUpstreamWithTransport(t *testing.T) {
+	proxyURL := "http://proxy.example.com:8080"
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "tcp-service",
+				Type: 1,
+				TCP: models.Upstream_TCP{
+					Host:       "localhost",
+					Port:       8080,
+					ServerPort: 9080,
+					Transport: &models.Upstream_TCP_Transport{
+						UseEncryption:  true,
+						UseCompression: true,
+						BandwdithLimit: &models.Upstream_TCP_Transport_BandwidthLimit{
+							Enabled: true,
+							Limit:   10,
+							Type:    "MB",
+						},
+						ProxyURL: &proxyURL,
+					},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `transport.useEncryption = true`)
+	assertContains(t, output, `transport.useCompression = true`)
+	assertContains(t, output, `transport.bandwidthLimit = "10MB"`)
+	assertContains(t, output, `transport.bandwidthLimitMode = "client"`)
+	assertContains(t, output, `transport.proxyURL = "http://proxy.example.com:8080"`)
+}
+
+func TestConfigurationBuilder_UDPUpstream(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "udp-service",
+				Type: 2,
+				UDP: models.Upstream_UDP{
+					Host:       "localhost",
+					Port:       53,
+					ServerPort: 5353,
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `[[proxies]]`)
+	assertContains(t, output, `name = "udp-service"`)
+	assertContains(t, output, `type = "udp"`)
+	assertContains(t, output, `localIP = "localhost"`)
+	assertContains(t, output, `localPort = 53`)
+	assertContains(t, output, `remotePort = 5353`)
+}
+
+func TestConfigurationBuilder_STCPUpstream(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "stcp-service",
+				Type: 3,
+				STCP: models.Upstream_STCP{
+					Host:      "localhost",
+					Port:      22,
+					SecretKey: "my-stcp-secret",
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `[[proxies]]`)
+	assertContains(t, output, `name = "stcp-service"`)
+	assertContains(t, output, `type = "stcp"`)
+	assertContains(t, output, `localIP = "localhost"`)
+	assertContains(t, output, `localPort = 22`)
+	assertContains(t, output, `secretKey = "my-stcp-secret"`)
+}
+
+func TestConfigurationBuilder_STCPUpstreamWithAllOptions(t *testing.T) {
+	proxyProtocol := "v1"
+	proxyURL := "socks5://proxy:1080"
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "stcp-service",
+				Type: 3,
+				STCP: models.Upstream_STCP{
+					Host:          "localhost",
+					Port:          22,
+					SecretKey:     "my-stcp-secret",
+					ProxyProtocol: &proxyProtocol,
+					HealthCheck: &models.Upstream_TCP_HealthCheck{
+						TimeoutSeconds:  5,
+						MaxFailed:       3,
+						IntervalSeconds: 30,
+					},
+					Transport: &models.Upstream_TCP_Transport{
+						UseEncryption:  false,
+						UseCompression: true,
+						BandwdithLimit: &models.Upstream_TCP_Transport_BandwidthLimit{
+							Enabled: true,
+							Limit:   5,
+							Type:    "KB",
+						},
+						ProxyURL: &proxyURL,
+					},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `type = "stcp"`)
+	assertContains(t, output, `secretKey = "my-stcp-secret"`)
+	assertContains(t, output, `transport.proxyProtocolVersion = "v1"`)
+	assertContains(t, output, `healthCheck.type = "tcp"`)
+	assertContains(t, output, `healthCheck.timeoutSeconds = 5`)
+	assertContains(t, output, `transport.useEncryption = false`)
+	assertContains(t, output, `transport.useCompression = true`)
+	assertContains(t, output, `transport.bandwidthLimit = "5KB"`)
+	assertContains(t, output, `transport.proxyURL = "socks5://proxy:1080"`)
+}
+
+func TestConfigurationBuilder_XTCPUpstream(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "xtcp-service",
+				Type: 4,
+				XTCP: models.Upstream_STCP{
+					Host:      "localhost",
+					Port:      3389,
+					SecretKey: "my-xtcp-secret",
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `[[proxies]]`)
+	assertContains(t, output, `name = "xtcp-service"`)
+	assertContains(t, output, `type = "xtcp"`)
+	assertContains(t, output, `localIP = "localhost"`)
+	assertContains(t, output, `localPort = 3389`)
+	assertContains(t, output, `secretKey = "my-xtcp-secret"`)
+}
+
+func TestConfigurationBuilder_HTTPUpstreamBasic(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "http-service",
+				Type: 5,
+				HTTP: models.Upstream_HTTP{
+					Host: "localhost",
+					Port: 80,
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `[[proxies]]`)
+	assertContains(t, output, `name = "http-service"`)
+	assertContains(t, output, `type = "http"`)
+	assertContains(t, output, `localIP = "localhost"`)
+	assertContains(t, output, `localPort = 80`)
+}
+
+func TestConfigurationBuilder_HTTPUpstreamWithSubdomain(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "http-service",
+				Type: 5,
+				HTTP: models.Upstream_HTTP{
+					Host:      "localhost",
+					Port:      80,
+					Subdomain: "myapp",
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `subdomain = "myapp"`)
+}
+
+func TestConfigurationBuilder_HTTPUpstreamWithCustomDomains(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "http-service",
+				Type: 5,
+				HTTP: models.Upstream_HTTP{
+					Host:          "localhost",
+					Port:          80,
+					CustomDomains: []string{"example.com", "www.example.com"},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `customDomains = ["example.com", "www.example.com"]`)
+}
+
+func TestConfigurationBuilder_HTTPUpstreamWithLocations(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "http-service",
+				Type: 5,
+				HTTP: models.Upstream_HTTP{
+					Host:      "localhost",
+					Port:      80,
+					Locations: []string{"/api", "/web"},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `locations = ["/api", "/web"]`)
+}
+
+func TestConfigurationBuilder_HTTPUpstreamWithHostHeaderRewrite(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "http-service",
+				Type: 5,
+				HTTP: models.Upstream_HTTP{
+					Host:              "localhost",
+					Port:              80,
+					HostHeaderRewrite: "internal.example.com",
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `hostHeaderRewrite = "internal.example.com"`)
+}
+
+func TestConfigurationBuilder_HTTPUpstreamWithRequestHeaders(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "http-service",
+				Type: 5,
+				HTTP: models.Upstream_HTTP{
+					Host: "localhost",
+					Port: 80,
+					RequestHeaders: map[string]string{
+						"X-Custom-Header": "custom-value",
+					},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `requestHeaders.set.X-Custom-Header = "custom-value"`)
+}
+
+func TestConfigurationBuilder_HTTPUpstreamWithResponseHeaders(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "http-service",
+				Type: 5,
+				HTTP: models.Upstream_HTTP{
+					Host: "localhost",
+					Port: 80,
+					ResponseHeaders: map[string]string{
+						"X-Server": "frp-operator",
+					},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `responseHeaders.set.X-Server = "frp-operator"`)
+}
+
+func TestConfigurationBuilder_HTTPUpstreamWithAuth(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "http-service",
+				Type: 5,
+				HTTP: models.Upstream_HTTP{
+					Host:         "localhost",
+					Port:         80,
+					HTTPUser:     "user123",
+					HTTPPassword: "pass456",
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `httpUser = "user123"`)
+	assertContains(t, output, `httpPassword = "pass456"`)
+}
+
+func TestConfigurationBuilder_HTTPUpstreamWithHealthCheck(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "http-service",
+				Type: 5,
+				HTTP: models.Upstream_HTTP{
+					Host: "localhost",
+					Port: 80,
+					HealthCheck: &models.Upstream_HTTP_HealthCheck{
+						Type:            "http",
+						Path:            "/health",
+						TimeoutSeconds:  5,
+						MaxFailed:       3,
+						IntervalSeconds: 10,
+					},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `healthCheck.type = "http"`)
+	assertContains(t, output, `healthCheck.path = "/health"`)
+	assertContains(t, output, `healthCheck.timeoutSeconds = 5`)
+	assertContains(t, output, `healthCheck.maxFailed = 3`)
+	assertContains(t, output, `healthCheck.intervalSeconds = 10`)
+}
+
+func TestConfigurationBuilder_HTTPUpstreamWithTransport(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "http-service",
+				Type: 5,
+				HTTP: models.Upstream_HTTP{
+					Host: "localhost",
+					Port: 80,
+					Transport: &models.Upstream_TCP_Transport{
+						UseEncryption:  true,
+						UseCompression: true,
+					},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `transport.useEncryption = true`)
+	assertContains(t, output, `transport.useCompression = true`)
+}
+
+func TestConfigurationBuilder_HTTPSUpstreamBasic(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "https-service",
+				Type: 6,
+				HTTPS: models.Upstream_HTTPS{
+					Host:          "localhost",
+					Port:          443,
+					CustomDomains: []string{"secure.example.com"},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `[[proxies]]`)
+	assertContains(t, output, `name = "https-service"`)
+	assertContains(t, output, `type = "https"`)
+	assertContains(t, output, `localIP = "localhost"`)
+	assertContains(t, output, `localPort = 443`)
+	assertContains(t, output, `customDomains = ["secure.example.com"]`)
+}
+
+func TestConfigurationBuilder_HTTPSUpstreamWithProxyProtocol(t *testing.T) {
+	proxyProtocol := "v2"
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "https-service",
+				Type: 6,
+				HTTPS: models.Upstream_HTTPS{
+					Host:          "localhost",
+					Port:          443,
+					CustomDomains: []string{"secure.example.com"},
+					ProxyProtocol: &proxyProtocol,
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `transport.proxyProtocolVersion = "v2"`)
+}
+
+func TestConfigurationBuilder_HTTPSUpstreamWithTransport(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "https-service",
+				Type: 6,
+				HTTPS: models.Upstream_HTTPS{
+					Host:          "localhost",
+					Port:          443,
+					CustomDomains: []string{"secure.example.com"},
+					Transport: &models.Upstream_TCP_Transport{
+						UseEncryption:  true,
+						UseCompression: false,
+						BandwdithLimit: &models.Upstream_TCP_Transport_BandwidthLimit{
+							Enabled: true,
+							Limit:   100,
+							Type:    "MB",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `transport.useEncryption = true`)
+	assertContains(t, output, `transport.useCompression = false`)
+	assertContains(t, output, `transport.bandwidthLimit = "100MB"`)
+}
+
+func TestConfigurationBuilder_HTTPSUpstreamWithProxyURL(t *testing.T) {
+	proxyURL := "http://proxy.example.com:8080"
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "https-service",
+				Type: 6,
+				HTTPS: models.Upstream_HTTPS{
+					Host:          "localhost",
+					Port:          443,
+					CustomDomains: []string{"secure.example.com"},
+					Transport: &models.Upstream_TCP_Transport{
+						UseEncryption:  true,
+						UseCompression: true,
+						ProxyURL:       &proxyURL,
+					},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `transport.proxyURL = "http://proxy.example.com:8080"`)
+}
+
+func TestConfigurationBuilder_STCPVisitor(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Visitors: []models.Visitor{
+			{
+				Name: "stcp-visitor",
+				Type: 1,
+				STCP: models.Visitor_STCP{
+					Host:       "127.0.0.1",
+					Port:       6000,
+					ServerName: "remote-stcp",
+					SecretKey:  "shared-secret",
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `[[visitors]]`)
+	assertContains(t, output, `name = "stcp-visitor"`)
+	assertContains(t, output, `type = "stcp"`)
+	assertContains(t, output, `serverName = "remote-stcp"`)
+	assertContains(t, output, `secretKey = "shared-secret"`)
+	assertContains(t, output, `bindAddr = "127.0.0.1"`)
+	assertContains(t, output, `bindPort = 6000`)
+}
+
+func TestConfigurationBuilder_XTCPVisitor(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Visitors: []models.Visitor{
+			{
+				Name: "xtcp-visitor",
+				Type: 2,
+				XTCP: models.Visitor_XTCP{
+					Host:                 "127.0.0.1",
+					Port:                 7000,
+					ServerName:           "remote-xtcp",
+					SecretKey:            "xtcp-secret",
+					PersistantConnection: true,
+					EnableAssistedAddrs:  false,
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `[[visitors]]`)
+	assertContains(t, output, `name = "xtcp-visitor"`)
+	assertContains(t, output, `type = "xtcp"`)
+	assertContains(t, output, `serverName = "remote-xtcp"`)
+	assertContains(t, output, `secretKey = "xtcp-secret"`)
+	assertContains(t, output, `bindAddr = "127.0.0.1"`)
+	assertContains(t, output, `bindPort = 7000`)
+	assertContains(t, output, `keepTunnelOpen = true`)
+	assertContains(t, output, `natHoleStun.disableAssistedAddrs = true`)
+}
+
+func TestConfigurationBuilder_XTCPVisitorWithAssistedAddrs(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Visitors: []models.Visitor{
+			{
+				Name: "xtcp-visitor",
+				Type: 2,
+				XTCP: models.Visitor_XTCP{
+					Host:                 "127.0.0.1",
+					Port:                 7000,
+					ServerName:           "remote-xtcp",
+					SecretKey:            "xtcp-secret",
+					PersistantConnection: false,
+					EnableAssistedAddrs:  true,
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `keepTunnelOpen = false`)
+	assertNotContains(t, output, `natHoleStun.disableAssistedAddrs`)
+}
+
+func TestConfigurationBuilder_XTCPVisitorWithFallback(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Visitors: []models.Visitor{
+			{
+				Name: "xtcp-visitor",
+				Type: 2,
+				XTCP: models.Visitor_XTCP{
+					Host:                 "127.0.0.1",
+					Port:                 7000,
+					ServerName:           "remote-xtcp",
+					SecretKey:            "xtcp-secret",
+					PersistantConnection: true,
+					Fallback: &models.Visitor_XTCP_Fallback{
+						ServerName: "fallback-stcp",
+						Timeout:    5000,
+					},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `fallbackTo = "xtcp-visitor-fallback"`)
+	assertContains(t, output, `fallbackTimeoutMs = 5000`)
+	// Check the fallback visitor is created
+	assertContains(t, output, `name = "xtcp-visitor-fallback"`)
+	assertContains(t, output, `serverName = "fallback-stcp"`)
+	assertContains(t, output, `bindPort = -1`)
+}
+
+func TestConfigurationBuilder_MultipleUpstreams(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "tcp-service",
+				Type: 1,
+				TCP: models.Upstream_TCP{
+					Host:       "localhost",
+					Port:       8080,
+					ServerPort: 9080,
+				},
+			},
+			{
+				Name: "http-service",
+				Type: 5,
+				HTTP: models.Upstream_HTTP{
+					Host:      "localhost",
+					Port:      80,
+					Subdomain: "web",
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	// Count [[proxies]] occurrences
+	count := strings.Count(output, "[[proxies]]")
+	if count != 2 {
+		t.Errorf("Expected 2 [[proxies]] sections, got %d", count)
+	}
+
+	assertContains(t, output, `name = "tcp-service"`)
+	assertContains(t, output, `name = "http-service"`)
+}
+
+func TestConfigurationBuilder_MultipleVisitors(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Visitors: []models.Visitor{
+			{
+				Name: "stcp-visitor",
+				Type: 1,
+				STCP: models.Visitor_STCP{
+					Host:       "127.0.0.1",
+					Port:       6000,
+					ServerName: "remote-stcp",
+					SecretKey:  "secret1",
+				},
+			},
+			{
+				Name: "xtcp-visitor",
+				Type: 2,
+				XTCP: models.Visitor_XTCP{
+					Host:                 "127.0.0.1",
+					Port:                 7000,
+					ServerName:           "remote-xtcp",
+					SecretKey:            "secret2",
+					PersistantConnection: true,
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	// Count [[visitors]] occurrences
+	count := strings.Count(output, "[[visitors]]")
+	if count != 2 {
+		t.Errorf("Expected 2 [[visitors]] sections, got %d", count)
+	}
+
+	assertContains(t, output, `name = "stcp-visitor"`)
+	assertContains(t, output, `name = "xtcp-visitor"`)
+}
+
+func TestConfigurationBuilder_EmptyUpstreamsAndVisitors(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{},
+		Visitors:  []models.Visitor{},
+	}
+
+	output := renderConfig(t, config)
+
+	// Should still have common section
+	assertContains(t, output, `serverAddr = "frp.example.com"`)
+	// Should not have any proxies or visitors
+	assertNotContains(t, output, `[[proxies]]`)
+	assertNotContains(t, output, `[[visitors]]`)
+}
+
+func TestConfigurationBuilder_HTTPUpstreamFull(t *testing.T) {
+	proxyURL := "http://proxy:8080"
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "full-http",
+				Type: 5,
+				HTTP: models.Upstream_HTTP{
+					Host:              "backend.local",
+					Port:              8080,
+					Subdomain:         "api",
+					CustomDomains:     []string{"api.example.com", "api2.example.com"},
+					Locations:         []string{"/v1", "/v2"},
+					HostHeaderRewrite: "backend.internal",
+					RequestHeaders: map[string]string{
+						"X-Forwarded-Proto": "https",
+					},
+					ResponseHeaders: map[string]string{
+						"X-Powered-By": "frp",
+					},
+					HTTPUser:     "apiuser",
+					HTTPPassword: "apipass",
+					HealthCheck: &models.Upstream_HTTP_HealthCheck{
+						Type:            "http",
+						Path:            "/healthz",
+						TimeoutSeconds:  10,
+						MaxFailed:       5,
+						IntervalSeconds: 30,
+					},
+					Transport: &models.Upstream_TCP_Transport{
+						UseEncryption:  true,
+						UseCompression: true,
+						BandwdithLimit: &models.Upstream_TCP_Transport_BandwidthLimit{
+							Enabled: true,
+							Limit:   50,
+							Type:    "MB",
+						},
+						ProxyURL: &proxyURL,
+					},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	// Verify all HTTP features
+	assertContains(t, output, `type = "http"`)
+	assertContains(t, output, `localIP = "backend.local"`)
+	assertContains(t, output, `localPort = 8080`)
+	assertContains(t, output, `subdomain = "api"`)
+	assertContains(t, output, `customDomains = ["api.example.com", "api2.example.com"]`)
+	assertContains(t, output, `locations = ["/v1", "/v2"]`)
+	assertContains(t, output, `hostHeaderRewrite = "backend.internal"`)
+	assertContains(t, output, `requestHeaders.set.X-Forwarded-Proto = "https"`)
+	assertContains(t, output, `responseHeaders.set.X-Powered-By = "frp"`)
+	assertContains(t, output, `httpUser = "apiuser"`)
+	assertContains(t, output, `httpPassword = "apipass"`)
+	assertContains(t, output, `healthCheck.type = "http"`)
+	assertContains(t, output, `healthCheck.path = "/healthz"`)
+	assertContains(t, output, `transport.useEncryption = true`)
+	assertContains(t, output, `transport.bandwidthLimit = "50MB"`)
+	assertContains(t, output, `transport.proxyURL = "http://proxy:8080"`)
+}
+
+func TestConfigurationBuilder_BandwidthLimitDisabled(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "tcp-service",
+				Type: 1,
+				TCP: models.Upstream_TCP{
+					Host:       "localhost",
+					Port:       8080,
+					ServerPort: 9080,
+					Transport: &models.Upstream_TCP_Transport{
+						UseEncryption:  true,
+						UseCompression: false,
+						BandwdithLimit: &models.Upstream_TCP_Transport_BandwidthLimit{
+							Enabled: false,
+							Limit:   10,
+							Type:    "MB",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	// Should have transport settings but NOT bandwidth limit
+	assertContains(t, output, `transport.useEncryption = true`)
+	assertNotContains(t, output, `transport.bandwidthLimit`)
+	assertNotContains(t, output, `transport.bandwidthLimitMode`)
+}
+
+func TestConfigurationBuilder_PprofEnabled(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			ServerAuthentication: models.ServerAuthentication{
+				Type:  1,
+				Token: "test-token",
+			},
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+			PprofEnable:   true,
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `webServer.pprofEnable = true`)
+}
+
+func TestConfigurationBuilder_PprofDisabled(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			ServerAuthentication: models.ServerAuthentication{
+				Type:  1,
+				Token: "test-token",
+			},
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+			PprofEnable:   false,
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	// Should NOT contain pprofEnable when disabled
+	assertNotContains(t, output, `pprofEnable`)
+}
+
+func TestConfigurationBuilder_STCPUpstreamWithAllowUsers(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "stcp-service",
+				Type: 3,
+				STCP: models.Upstream_STCP{
+					Host:       "localhost",
+					Port:       22,
+					SecretKey:  "my-stcp-secret",
+					AllowUsers: []string{"user1", "user2"},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `type = "stcp"`)
+	assertContains(t, output, `allowUsers = ["user1", "user2"]`)
+}
+
+func TestConfigurationBuilder_STCPUpstreamWithAllowUsersWildcard(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "stcp-service",
+				Type: 3,
+				STCP: models.Upstream_STCP{
+					Host:       "localhost",
+					Port:       22,
+					SecretKey:  "my-stcp-secret",
+					AllowUsers: []string{"*"},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `allowUsers = ["*"]`)
+}
+
+func TestConfigurationBuilder_XTCPUpstreamWithAllowUsers(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "xtcp-service",
+				Type: 4,
+				XTCP: models.Upstream_STCP{
+					Host:       "localhost",
+					Port:       3389,
+					SecretKey:  "my-xtcp-secret",
+					AllowUsers: []string{"admin", "operator"},
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `type = "xtcp"`)
+	assertContains(t, output, `allowUsers = ["admin", "operator"]`)
+}
+
+func TestConfigurationBuilder_STCPUpstreamWithoutAllowUsers(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+		Upstreams: []models.Upstream{
+			{
+				Name: "stcp-service",
+				Type: 3,
+				STCP: models.Upstream_STCP{
+					Host:      "localhost",
+					Port:      22,
+					SecretKey: "my-stcp-secret",
+				},
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `type = "stcp"`)
+	assertNotContains(t, output, `allowUsers`)
+}
+
+func TestConfigurationBuilder_TLSEnabled(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+			TLS: &models.TLSConfig{
+				Enable: true,
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `transport.tls.enable = true`)
+	assertNotContains(t, output, `transport.tls.certFile`)
+	assertNotContains(t, output, `transport.tls.keyFile`)
+	assertNotContains(t, output, `transport.tls.trustedCaFile`)
+}
+
+func TestConfigurationBuilder_TLSWithCertificates(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+			TLS: &models.TLSConfig{
+				Enable:        true,
+				CertFile:      "/etc/frp/tls/tls.crt",
+				KeyFile:       "/etc/frp/tls/tls.key",
+				TrustedCAFile: "/etc/frp/tls/ca.crt",
+			},
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `transport.tls.enable = true`)
+	assertContains(t, output, `transport.tls.certFile = "/etc/frp/tls/tls.crt"`)
+	assertContains(t, output, `transport.tls.keyFile = "/etc/frp/tls/tls.key"`)
+	assertContains(t, output, `transport.tls.trustedCaFile = "/etc/frp/tls/ca.crt"`)
+}
+
+func TestConfigurationBuilder_WithoutTLS(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertNotContains(t, output, `transport.tls`)
+}
+
+func TestConfigurationBuilder_OIDCAuth(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			ServerAuthentication: models.ServerAuthentication{
+				Type:             2, // OIDC
+				OIDCClientID:     "my-client-id",
+				OIDCClientSecret: "my-client-secret",
+				OIDCTokenURL:     "https://auth.example.com/oauth/token",
+				OIDCAudience:     "frp-server",
+				OIDCScope:        "openid profile",
+			},
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `auth.method = "oidc"`)
+	assertContains(t, output, `auth.oidc.clientID = "my-client-id"`)
+	assertContains(t, output, `auth.oidc.clientSecret = "my-client-secret"`)
+	assertContains(t, output, `auth.oidc.tokenEndpointURL = "https://auth.example.com/oauth/token"`)
+	assertContains(t, output, `auth.oidc.audience = "frp-server"`)
+	assertContains(t, output, `auth.oidc.scope = "openid profile"`)
+	assertNotContains(t, output, `auth.token`)
+}
+
+func TestConfigurationBuilder_OIDCAuthWithoutOptionalFields(t *testing.T) {
+	config := models.Config{
+		Common: models.Common{
+			ServerAddress: "frp.example.com",
+			ServerPort:    7000,
+			ServerAuthentication: models.ServerAuthentication{
+				Type:             2, // OIDC
+				OIDCClientID:     "my-client-id",
+				OIDCClientSecret: "my-client-secret",
+				OIDCTokenURL:     "https://auth.example.com/oauth/token",
+			},
+			AdminAddress:  "0.0.0.0",
+			AdminPort:     7400,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+	}
+
+	output := renderConfig(t, config)
+
+	assertContains(t, output, `auth.method = "oidc"`)
+	assertContains(t, output, `auth.oidc.clientID = "my-client-id"`)
+	assertNotContains(t, output, `auth.oidc.audience`)
+	assertNotContains(t, output, `auth.oidc.scope`)
+}
+
+// Helper functions
+
+func basicCommon() models.Common {
+	return models.Common{
+		ServerAddress: "frp.example.com",
+		ServerPort:    7000,
+		AdminAddress:  "0.0.0.0",
+		AdminPort:     7400,
+		AdminUsername: "admin",
+		AdminPassword: "password",
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// dottedKeys rewrites the tables of a rendered configuration into dotted keys
+// relative to their [[proxies]] or [[visitors]] entry, the form frp documents
+// its options in
+func dottedKeys(configuration string) string {
+	var lines []string
+	prefix := ""
+
+	for _, line := range strings.Split(configuration, "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, "[["):
+			lines = append(lines, line)
+			prefix = ""
+		case strings.HasPrefix(line, "["):
+			table := strings.Trim(line, "[]")
+			table = strings.TrimPrefix(table, "proxies.")
+			table = strings.TrimPrefix(table, "visitors.")
+			prefix = table + "."
+		default:
+			lines = append(lines, prefix+line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// renderConfig builds a configuration, checks it is valid TOML and returns it
+// with dotted keys
+func renderConfig(t *testing.T, config models.Config) string {
+	t.Helper()
+	result, err := NewConfigurationBuilder().SetConfig(config).Build()
+	if err != nil {
+		t.Fatalf("ConfigurationBuilder.Build() unexpected error = %v", err)
+	}
+	if _, err := toml.Decode(result, &utils.ClientConfig{}); err != nil {
+		t.Fatalf("ConfigurationBuilder.Build() rendered invalid TOML: %v\nGot:\n%s", err, result)
+	}
+	return dottedKeys(result)
+}
+
+// Helper to check if output contains expected strings
+func assertContains(t *testing.T, output, expected string) {
+	t.Helper()
+	if !strings.Contains(output, expected) {
+		t.Errorf("Expected output to contain %q, but it didn't.\nOutput:\n%s", expected, output)
+	}
+}
+
+// Helper to check if output does NOT contain a string
+func assertNotContains(t *testing.T, output, unexpected string) {
+	t.Helper()
+	if strings.Contains(output, unexpected) {
+		t.Errorf("Expected output NOT to contain %q, but it did.\nOutput:\n%s", unexpected, output)
+	}
+}
+
+// renderFrpcTemplate renders a configuration as a Go template the way frpc does
+// before parsing it
+func renderFrpcTemplate(t *testing.T, configuration string) string {
+	t.Helper()
+	tmpl, err := template.New("frpc").Parse(configuration)
+	if err != nil {
+		t.Fatalf("frpc template parse error = %v\nGot:\n%s", err, configuration)
+	}
+
+	var rendered bytes.Buffer
+	values := map[string]any{"Envs": map[string]string{models.POD_NAME_ENV: "frpc-0"}}
+	if err := tmpl.Execute(&rendered, values); err != nil {
+		t.Fatalf("frpc template execute error = %v\nGot:\n%s", err, configuration)
+	}
+	return rendered.String()
 }
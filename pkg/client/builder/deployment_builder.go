@@ -0,0 +1,128 @@
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SpecHashAnnotation records the hash of the generated pod template, a change of
+// the hash replaces the frpc pods
+const SpecHashAnnotation = "frp.zufardhiyaulhaq.com/spec-hash"
+
+type DeploymentBuilder struct {
+	Name      string
+	Namespace string
+	Replicas  int32
+	Pod       *corev1.Pod
+}
+
+func NewDeploymentBuilder() *DeploymentBuilder {
+	return &DeploymentBuilder{
+		Replicas: 1,
+	}
+}
+
+func (n *DeploymentBuilder) SetName(name string) *DeploymentBuilder {
+	n.Name = name
+	return n
+}
+
+func (n *DeploymentBuilder) SetNamespace(namespace string) *DeploymentBuilder {
+	n.Namespace = namespace
+	return n
+}
+
+func (n *DeploymentBuilder) SetReplicas(replicas int32) *DeploymentBuilder {
+	n.Replicas = replicas
+	return n
+}
+
+// SetPod sets the pod built by PodBuilder that is used as the pod template
+func (n *DeploymentBuilder) SetPod(pod *corev1.Pod) *DeploymentBuilder {
+	n.Pod = pod
+	return n
+}
+
+func (n *DeploymentBuilder) Build() (*appsv1.Deployment, error) {
+	selector := n.BuildLabels()
+
+	// The selector labels always win over PodTemplate labels, otherwise the
+	// deployment would not select its own pods
+	podLabels := map[string]string{}
+	for k, v := range n.Pod.Labels {
+		podLabels[k] = v
+	}
+	for k, v := range selector {
+		podLabels[k] = v
+	}
+
+	// A single replica is recreated, two pods registering the same proxy names
+	// would conflict on the server. Replicas use unique proxy names and roll
+	strategy := appsv1.DeploymentStrategy{
+		Type: appsv1.RecreateDeploymentStrategyType,
+	}
+	if n.Replicas > 1 {
+		strategy.Type = appsv1.RollingUpdateDeploymentStrategyType
+	}
+
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      podLabels,
+			Annotations: map[string]string{},
+		},
+		Spec: n.Pod.Spec,
+	}
+	for k, v := range n.Pod.Annotations {
+		template.Annotations[k] = v
+	}
+
+	specHash, err := n.hashTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+	template.Annotations[SpecHashAnnotation] = specHash
+
+	replicas := n.Replicas
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      n.Name + "-frpc",
+			Namespace: n.Namespace,
+			Labels:    n.BuildLabels(),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selector,
+			},
+			Strategy: strategy,
+			Template: template,
+		},
+	}
+
+	return deployment, nil
+}
+
+func (n *DeploymentBuilder) hashTemplate(template corev1.PodTemplateSpec) (string, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])[:16], nil
+}
+
+func (n *DeploymentBuilder) BuildLabels() map[string]string {
+	var labels = map[string]string{
+		"app.kubernetes.io/name":       n.Name,
+		"app.kubernetes.io/managed-by": "frp-operator",
+		"app.kubernetes.io/created-by": n.Name,
+	}
+
+	return labels
+}
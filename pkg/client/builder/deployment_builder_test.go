@@ -0,0 +1,174 @@
+package builder
+
+import (
+	"testing"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+func TestDeploymentBuilder_Basic(t *testing.T) {
+	pod, err := NewPodBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetImage("fatedier/frpc:v0.65.0").
+		Build()
+	if err != nil {
+		t.Fatalf("PodBuilder.Build() error = %v", err)
+	}
+
+	deployment, err := NewDeploymentBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetPod(pod).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if deployment.Name != "test-frpc" {
+		t.Errorf("Expected deployment name test-frpc, got %s", deployment.Name)
+	}
+	if *deployment.Spec.Replicas != 1 {
+		t.Errorf("Expected 1 replica, got %d", *deployment.Spec.Replicas)
+	}
+	if deployment.Spec.Strategy.Type != appsv1.RecreateDeploymentStrategyType {
+		t.Errorf("Expected Recreate strategy for a single replica, got %s", deployment.Spec.Strategy.Type)
+	}
+	if deployment.Spec.Template.Spec.Containers[0].Image != "fatedier/frpc:v0.65.0" {
+		t.Errorf("Expected pod template from PodBuilder")
+	}
+	if deployment.Spec.Template.Annotations["sidecar.istio.io/inject"] != "false" {
+		t.Errorf("Expected pod annotations on the pod template")
+	}
+
+	for k, v := range deployment.Spec.Selector.MatchLabels {
+		if deployment.Spec.Template.Labels[k] != v {
+			t.Errorf("Expected pod template label %s=%s to match selector", k, v)
+		}
+	}
+}
+
+func TestDeploymentBuilder_Replicas(t *testing.T) {
+	pod, err := NewPodBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetImage("fatedier/frpc:v0.65.0").
+		Build()
+	if err != nil {
+		t.Fatalf("PodBuilder.Build() error = %v", err)
+	}
+
+	deployment, err := NewDeploymentBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetReplicas(3).
+		SetPod(pod).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if *deployment.Spec.Replicas != 3 {
+		t.Errorf("Expected 3 replicas, got %d", *deployment.Spec.Replicas)
+	}
+	if deployment.Spec.Strategy.Type != appsv1.RollingUpdateDeploymentStrategyType {
+		t.Errorf("Expected RollingUpdate strategy for replicas, got %s", deployment.Spec.Strategy.Type)
+	}
+}
+
+func TestDeploymentBuilder_SelectorLabelsWin(t *testing.T) {
+	pod, err := NewPodBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetImage("fatedier/frpc:v0.65.0").
+		SetPodTemplate(&frpv1alpha1.ClientSpec_PodTemplate{
+			Labels: map[string]string{
+				"app.kubernetes.io/name": "custom-name",
+				"team":                   "platform",
+			},
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("PodBuilder.Build() error = %v", err)
+	}
+
+	deployment, err := NewDeploymentBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetPod(pod).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if deployment.Spec.Template.Labels["app.kubernetes.io/name"] != "test" {
+		t.Errorf("Expected selector label to override PodTemplate label, got %s", deployment.Spec.Template.Labels["app.kubernetes.io/name"])
+	}
+	if deployment.Spec.Template.Labels["team"] != "platform" {
+		t.Errorf("Expected custom PodTemplate label to be kept")
+	}
+}
+
+func TestPodDisruptionBudgetBuilder_Build(t *testing.T) {
+	pdb, err := NewPodDisruptionBudgetBuilder().
+		SetName("test").
+		SetNamespace("default").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if pdb.Name != "test-frpc" {
+		t.Errorf("Expected pod disruption budget name test-frpc, got %s", pdb.Name)
+	}
+	if pdb.Spec.MaxUnavailable == nil || pdb.Spec.MaxUnavailable.IntValue() != 1 {
+		t.Errorf("Expected maxUnavailable 1, got %v", pdb.Spec.MaxUnavailable)
+	}
+	if pdb.Spec.Selector.MatchLabels["app.kubernetes.io/name"] != "test" {
+		t.Errorf("Expected selector app.kubernetes.io/name=test")
+	}
+}
+
+func TestDeploymentBuilder_SpecHash(t *testing.T) {
+	build := func(podTemplate *frpv1alpha1.ClientSpec_PodTemplate, image string) string {
+		pod, err := NewPodBuilder().
+			SetName("test").
+			SetNamespace("default").
+			SetImage(image).
+			SetPodTemplate(podTemplate).
+			Build()
+		if err != nil {
+			t.Fatalf("PodBuilder.Build() error = %v", err)
+		}
+
+		deployment, err := NewDeploymentBuilder().
+			SetName("test").
+			SetNamespace("default").
+			SetPod(pod).
+			Build()
+		if err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+
+		return deployment.Spec.Template.Annotations[SpecHashAnnotation]
+	}
+
+	base := build(nil, "fatedier/frpc:v0.65.0")
+	if base == "" {
+		t.Fatalf("Expected %s annotation on the pod template", SpecHashAnnotation)
+	}
+	if again := build(nil, "fatedier/frpc:v0.65.0"); again != base {
+		t.Errorf("Expected stable spec hash, got %s and %s", base, again)
+	}
+	if image := build(nil, "fatedier/frpc:v0.66.0"); image == base {
+		t.Errorf("Expected spec hash to change with the image")
+	}
+
+	nodeSelector := build(&frpv1alpha1.ClientSpec_PodTemplate{
+		NodeSelector: map[string]string{"kubernetes.io/os": "linux"},
+	}, "fatedier/frpc:v0.65.0")
+	if nodeSelector == base {
+		t.Errorf("Expected spec hash to change with the pod template")
+	}
+}
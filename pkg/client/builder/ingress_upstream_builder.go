@@ -0,0 +1,215 @@
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+const (
+	// IngressControllerName is the spec.controller of the IngressClasses served by
+	// the operator. The parameters of the IngressClass reference the Client.
+	IngressControllerName = "frp.zufardhiyaulhaq.com/ingress-controller"
+
+	// IngressLabel marks the Upstreams generated for an Ingress
+	IngressLabel = "frp.zufardhiyaulhaq.com/ingress"
+)
+
+// IngressUpstreamBuilder builds the Upstreams that serve the rules of an Ingress.
+// Every host and backend pair becomes an http Upstream routing the paths of the
+// backend as locations. Every TLS host becomes an https Upstream where frpc
+// terminates TLS with the certificate of the TLS Secret and forwards plain HTTP to
+// the backend of the host. frps routes https by SNI only, so a TLS host can't route
+// its paths to several backends.
+type IngressUpstreamBuilder struct {
+	Ingress *networkingv1.Ingress
+	Client  types.NamespacedName
+}
+
+func NewIngressUpstreamBuilder() *IngressUpstreamBuilder {
+	return &IngressUpstreamBuilder{}
+}
+
+func (n *IngressUpstreamBuilder) SetIngress(ingress *networkingv1.Ingress) *IngressUpstreamBuilder {
+	n.Ingress = ingress
+	return n
+}
+
+func (n *IngressUpstreamBuilder) SetClient(name string, namespace string) *IngressUpstreamBuilder {
+	n.Client = types.NamespacedName{Name: name, Namespace: namespace}
+	return n
+}
+
+// ingressBackend is an http Upstream, the paths of a host served by one Service port
+type ingressBackend struct {
+	host      string
+	service   *networkingv1.IngressServiceBackend
+	locations []string
+}
+
+func (n *IngressUpstreamBuilder) Build() ([]*frpv1alpha1.Upstream, error) {
+	backends := []*ingressBackend{}
+	backendIndex := map[string]*ingressBackend{}
+	hostBackends := map[string][]*ingressBackend{}
+
+	for _, rule := range n.Ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		if rule.Host == "" {
+			return nil, fmt.Errorf("rules without host are not supported, frp routes by domain")
+		}
+
+		for _, path := range rule.HTTP.Paths {
+			service := path.Backend.Service
+			if service == nil {
+				return nil, fmt.Errorf("backend of host %s path %s must be a Service", rule.Host, path.Path)
+			}
+
+			location, err := ingressLocation(path)
+			if err != nil {
+				return nil, fmt.Errorf("host %s: %v", rule.Host, err)
+			}
+
+			key := strings.Join([]string{rule.Host, service.Name, service.Port.Name, fmt.Sprint(service.Port.Number)}, "/")
+			backend, ok := backendIndex[key]
+			if !ok {
+				backend = &ingressBackend{host: rule.Host, service: service}
+				backendIndex[key] = backend
+				backends = append(backends, backend)
+				hostBackends[rule.Host] = append(hostBackends[rule.Host], backend)
+			}
+			backend.locations = append(backend.locations, location)
+		}
+	}
+
+	upstreams := []*frpv1alpha1.Upstream{}
+	for _, backend := range backends {
+		upstream := n.newUpstream(upstreamNameHash(backend.host, backend.service.Name, backend.service.Port.Name, fmt.Sprint(backend.service.Port.Number)))
+		upstream.Spec.HTTP = &frpv1alpha1.UpstreamSpec_HTTP{
+			ServiceRef:    ingressServiceRef(backend.service),
+			CustomDomains: []string{backend.host},
+			Locations:     backend.locations,
+		}
+		upstreams = append(upstreams, upstream)
+	}
+
+	tlsHosts := map[string]struct{}{}
+	for _, tls := range n.Ingress.Spec.TLS {
+		for _, host := range tls.Hosts {
+			if _, ok := tlsHosts[host]; ok {
+				continue
+			}
+			tlsHosts[host] = struct{}{}
+
+			switch len(hostBackends[host]) {
+			case 0:
+				return nil, fmt.Errorf("TLS host %s has no rule", host)
+			case 1:
+			default:
+				return nil, fmt.Errorf("TLS host %s routes paths to %d backends, frp terminates TLS for a single backend per host",
+					host, len(hostBackends[host]))
+			}
+			if tls.SecretName == "" {
+				return nil, fmt.Errorf("TLS host %s has no secretName, frpc needs the certificate to terminate TLS", host)
+			}
+
+			upstream := n.newUpstream("tls-" + upstreamNameHash(host))
+			upstream.Spec.HTTPS = &frpv1alpha1.UpstreamSpec_HTTPS{
+				ServiceRef:    ingressServiceRef(hostBackends[host][0].service),
+				CustomDomains: []string{host},
+				TLSSecret:     tls.SecretName,
+			}
+			upstreams = append(upstreams, upstream)
+		}
+	}
+
+	return upstreams, nil
+}
+
+func (n *IngressUpstreamBuilder) BuildLabels() map[string]string {
+	var labels = map[string]string{
+		"app.kubernetes.io/managed-by": "frp-operator",
+		"app.kubernetes.io/created-by": n.Ingress.Name,
+		IngressLabel:                   n.Ingress.Name,
+	}
+
+	return labels
+}
+
+func (n *IngressUpstreamBuilder) newUpstream(suffix string) *frpv1alpha1.Upstream {
+	upstream := &frpv1alpha1.Upstream{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      n.Ingress.Name + "-" + suffix,
+			Namespace: n.Ingress.Namespace,
+			Labels:    n.BuildLabels(),
+		},
+		Spec: frpv1alpha1.UpstreamSpec{
+			Enabled: ptr.To(true),
+		},
+	}
+
+	bindClient(upstream, n.Client)
+
+	return upstream
+}
+
+// bindClient binds an Upstream to a Client by name in its namespace, or by
+// reference in another namespace
+func bindClient(upstream *frpv1alpha1.Upstream, client types.NamespacedName) {
+	if client.Namespace == "" || client.Namespace == upstream.Namespace {
+		upstream.Spec.Client = client.Name
+	} else {
+		upstream.Spec.ClientRef = &frpv1alpha1.ClientRef{Name: client.Name, Namespace: client.Namespace}
+	}
+}
+
+func ingressServiceRef(service *networkingv1.IngressServiceBackend) *frpv1alpha1.ServiceRef {
+	ref := &frpv1alpha1.ServiceRef{
+		Name: service.Name,
+		Port: intstr.FromInt32(service.Port.Number),
+	}
+	if service.Port.Name != "" {
+		ref.Port = intstr.FromString(service.Port.Name)
+	}
+
+	return ref
+}
+
+// ingressLocation returns the frp location of an Ingress path. frp matches locations
+// as path prefixes, so Exact paths can't be served. A Prefix path matches its path
+// elements with or without a trailing slash, which frp matches without it.
+func ingressLocation(path networkingv1.HTTPIngressPath) (string, error) {
+	location := path.Path
+	if location == "" {
+		location = "/"
+	}
+
+	if path.PathType != nil {
+		switch *path.PathType {
+		case networkingv1.PathTypeExact:
+			return "", fmt.Errorf("path %s uses pathType Exact, frp only routes by path prefix", location)
+		case networkingv1.PathTypePrefix:
+			if trimmed := strings.TrimRight(location, "/"); trimmed != "" {
+				location = trimmed
+			}
+		}
+	}
+
+	return location, nil
+}
+
+// upstreamNameHash returns a short stable name suffix for the given values
+func upstreamNameHash(values ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	return hex.EncodeToString(sum[:])[:10]
+}
@@ -0,0 +1,202 @@
+package builder
+
+import (
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func newIngressPath(path string, service string, port networkingv1.ServiceBackendPort) networkingv1.HTTPIngressPath {
+	return networkingv1.HTTPIngressPath{
+		Path: path,
+		Backend: networkingv1.IngressBackend{
+			Service: &networkingv1.IngressServiceBackend{Name: service, Port: port},
+		},
+	}
+}
+
+func newIngress(tls []networkingv1.IngressTLS, rules ...networkingv1.IngressRule) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "team-a",
+		},
+		Spec: networkingv1.IngressSpec{TLS: tls, Rules: rules},
+	}
+}
+
+func newIngressRule(host string, paths ...networkingv1.HTTPIngressPath) networkingv1.IngressRule {
+	return networkingv1.IngressRule{
+		Host: host,
+		IngressRuleValue: networkingv1.IngressRuleValue{
+			HTTP: &networkingv1.HTTPIngressRuleValue{Paths: paths},
+		},
+	}
+}
+
+func TestIngressUpstreamBuilder_HTTP(t *testing.T) {
+	ingress := newIngress(nil,
+		newIngressRule("web.example.com",
+			newIngressPath("/", "web", networkingv1.ServiceBackendPort{Name: "http"}),
+			newIngressPath("/static", "web", networkingv1.ServiceBackendPort{Name: "http"}),
+			newIngressPath("/api", "api", networkingv1.ServiceBackendPort{Number: 8080}),
+		),
+	)
+
+	upstreams, err := NewIngressUpstreamBuilder().SetIngress(ingress).SetClient("edge", "team-a").Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error = %v", err)
+	}
+	if len(upstreams) != 2 {
+		t.Fatalf("Build() = %d upstreams, want one per host and backend", len(upstreams))
+	}
+
+	web, api := upstreams[0], upstreams[1]
+	if web.Spec.Client != "edge" || web.Spec.ClientRef != nil || web.Namespace != "team-a" {
+		t.Errorf("Build() bound to %s/%s %v, want client edge in team-a", web.Namespace, web.Spec.Client, web.Spec.ClientRef)
+	}
+	if web.Labels[IngressLabel] != "web" {
+		t.Errorf("Build() label %s = %q, want web", IngressLabel, web.Labels[IngressLabel])
+	}
+	if len(web.Spec.HTTP.Locations) != 2 || web.Spec.HTTP.Locations[0] != "/" || web.Spec.HTTP.Locations[1] != "/static" {
+		t.Errorf("Build() locations = %v, want [/ /static]", web.Spec.HTTP.Locations)
+	}
+	if domains := web.Spec.HTTP.CustomDomains; len(domains) != 1 || domains[0] != "web.example.com" {
+		t.Errorf("Build() customDomains = %v, want [web.example.com]", domains)
+	}
+	if web.Spec.HTTP.ServiceRef.Name != "web" || web.Spec.HTTP.ServiceRef.Port != intstr.FromString("http") {
+		t.Errorf("Build() serviceRef = %+v, want web port http", web.Spec.HTTP.ServiceRef)
+	}
+	if api.Spec.HTTP.ServiceRef.Name != "api" || api.Spec.HTTP.ServiceRef.Port != intstr.FromInt32(8080) {
+		t.Errorf("Build() serviceRef = %+v, want api port 8080", api.Spec.HTTP.ServiceRef)
+	}
+	if web.Name == api.Name {
+		t.Errorf("Build() names = %s, %s, want distinct names", web.Name, api.Name)
+	}
+
+	again, _ := NewIngressUpstreamBuilder().SetIngress(ingress).SetClient("edge", "team-a").Build()
+	if again[0].Name != web.Name {
+		t.Errorf("Build() name = %s, then %s, want stable names", web.Name, again[0].Name)
+	}
+}
+
+func TestIngressUpstreamBuilder_TLS(t *testing.T) {
+	ingress := newIngress(
+		[]networkingv1.IngressTLS{{Hosts: []string{"web.example.com"}, SecretName: "web-tls"}},
+		newIngressRule("web.example.com",
+			newIngressPath("/", "web", networkingv1.ServiceBackendPort{Number: 80}),
+			newIngressPath("/static", "web", networkingv1.ServiceBackendPort{Number: 80}),
+		),
+	)
+
+	upstreams, err := NewIngressUpstreamBuilder().SetIngress(ingress).SetClient("edge", "frp-system").Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error = %v", err)
+	}
+	if len(upstreams) != 2 {
+		t.Fatalf("Build() = %d upstreams, want 1 http and 1 https", len(upstreams))
+	}
+
+	http, https := upstreams[0], upstreams[1]
+	if http.Spec.HTTP == nil || len(http.Spec.HTTP.Locations) != 2 {
+		t.Errorf("Build() first upstream = %+v, want http routing / and /static", http.Spec)
+	}
+	if https.Spec.HTTPS == nil {
+		t.Fatalf("Build() last upstream = %+v, want https", https.Spec)
+	}
+	if ref := https.Spec.ClientRef; ref == nil || ref.Name != "edge" || ref.Namespace != "frp-system" {
+		t.Errorf("Build() clientRef = %+v, want frp-system/edge", ref)
+	}
+	if https.Spec.HTTPS.TLSSecret != "web-tls" {
+		t.Errorf("Build() https tlsSecret = %q, want TLS terminated with web-tls", https.Spec.HTTPS.TLSSecret)
+	}
+	if ref := https.Spec.HTTPS.ServiceRef; ref.Name != "web" || ref.Port != intstr.FromInt32(80) {
+		t.Errorf("Build() https serviceRef = %+v, want the plain HTTP backend web port 80", ref)
+	}
+	if domains := https.Spec.HTTPS.CustomDomains; len(domains) != 1 || domains[0] != "web.example.com" {
+		t.Errorf("Build() customDomains = %v, want [web.example.com]", domains)
+	}
+}
+
+func TestIngressUpstreamBuilder_PathType(t *testing.T) {
+	prefix := networkingv1.PathTypePrefix
+	specific := networkingv1.PathTypeImplementationSpecific
+
+	static := newIngressPath("/static/", "web", networkingv1.ServiceBackendPort{Number: 80})
+	static.PathType = &prefix
+	root := newIngressPath("/", "web", networkingv1.ServiceBackendPort{Number: 80})
+	root.PathType = &prefix
+	docs := newIngressPath("/docs/", "web", networkingv1.ServiceBackendPort{Number: 80})
+	docs.PathType = &specific
+
+	upstreams, err := NewIngressUpstreamBuilder().
+		SetIngress(newIngress(nil, newIngressRule("web.example.com", root, static, docs))).
+		SetClient("edge", "team-a").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error = %v", err)
+	}
+
+	locations := upstreams[0].Spec.HTTP.Locations
+	if len(locations) != 3 || locations[0] != "/" || locations[1] != "/static" || locations[2] != "/docs/" {
+		t.Errorf("Build() locations = %v, want [/ /static /docs/]", locations)
+	}
+}
+
+func TestIngressUpstreamBuilder_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		ingress *networkingv1.Ingress
+	}{
+		{
+			name: "rule without host",
+			ingress: newIngress(nil,
+				newIngressRule("", newIngressPath("/", "web", networkingv1.ServiceBackendPort{Number: 80}))),
+		},
+		{
+			name: "resource backend",
+			ingress: newIngress(nil,
+				newIngressRule("web.example.com", networkingv1.HTTPIngressPath{Path: "/"})),
+		},
+		{
+			name: "exact path",
+			ingress: func() *networkingv1.Ingress {
+				exact := networkingv1.PathTypeExact
+				path := newIngressPath("/health", "web", networkingv1.ServiceBackendPort{Number: 80})
+				path.PathType = &exact
+				return newIngress(nil, newIngressRule("web.example.com", path))
+			}(),
+		},
+		{
+			name: "TLS host without secretName",
+			ingress: newIngress(
+				[]networkingv1.IngressTLS{{Hosts: []string{"web.example.com"}}},
+				newIngressRule("web.example.com", newIngressPath("/", "web", networkingv1.ServiceBackendPort{Number: 80}))),
+		},
+		{
+			name: "TLS host with several backends",
+			ingress: newIngress(
+				[]networkingv1.IngressTLS{{Hosts: []string{"web.example.com"}, SecretName: "web-tls"}},
+				newIngressRule("web.example.com",
+					newIngressPath("/", "web", networkingv1.ServiceBackendPort{Number: 80}),
+					newIngressPath("/api", "api", networkingv1.ServiceBackendPort{Number: 8080}))),
+		},
+		{
+			name: "TLS host without rule",
+			ingress: newIngress(
+				[]networkingv1.IngressTLS{{Hosts: []string{"other.example.com"}, SecretName: "web-tls"}},
+				newIngressRule("web.example.com", newIngressPath("/", "web", networkingv1.ServiceBackendPort{Number: 80}))),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewIngressUpstreamBuilder().SetIngress(tt.ingress).SetClient("edge", "team-a").Build()
+			if err == nil {
+				t.Errorf("Build() expected an error")
+			}
+		})
+	}
+}
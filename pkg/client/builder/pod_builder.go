@@ -5,6 +5,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
 )
 
 type PodBuilder struct {
@@ -14,6 +15,12 @@ type PodBuilder struct {
 	PodTemplate    *frpv1alpha1.ClientSpec_PodTemplate
 	TLSSecret      string
 	TLSCAConfigMap string
+	VirtualNet     bool
+	ServerProtocol string
+
+	AdminCredentialsHash string
+	ActiveServer         string
+	FilesHash            string
 }
 
 func NewPodBuilder() *PodBuilder {
@@ -50,6 +57,40 @@ func (n *PodBuilder) SetTLSCAConfigMap(tlsCAConfigMap string) *PodBuilder {
 	return n
 }
 
+// SetVirtualNet gives frpc the NET_ADMIN capability and the /dev/net/tun device
+// the virtual_net plugin creates its interface with
+func (n *PodBuilder) SetVirtualNet(virtualNet bool) *PodBuilder {
+	n.VirtualNet = virtualNet
+	return n
+}
+
+// SetServerProtocol sets the transport frpc dials the server with
+func (n *PodBuilder) SetServerProtocol(protocol string) *PodBuilder {
+	n.ServerProtocol = protocol
+	return n
+}
+
+// SetAdminCredentialsHash annotates the pod with the hash of its admin credentials,
+// so that rotating the credentials rolls the pods
+func (n *PodBuilder) SetAdminCredentialsHash(hash string) *PodBuilder {
+	n.AdminCredentialsHash = hash
+	return n
+}
+
+// SetActiveServer annotates the pod with the server it connects to, so that a
+// failover to another server rolls the pods
+func (n *PodBuilder) SetActiveServer(activeServer string) *PodBuilder {
+	n.ActiveServer = activeServer
+	return n
+}
+
+// SetFilesHash annotates the pod with the hash of the files stored next to its
+// configuration, so that a renewed certificate rolls the pods
+func (n *PodBuilder) SetFilesHash(hash string) *PodBuilder {
+	n.FilesHash = hash
+	return n
+}
+
 func (n *PodBuilder) Build() (*corev1.Pod, error) {
 	// Build base labels and annotations
 	labels := n.BuildLabels()
@@ -71,22 +112,78 @@ func (n *PodBuilder) Build() (*corev1.Pod, error) {
 		}
 	}
 
+	if n.AdminCredentialsHash != "" {
+		annotations[AdminCredentialsHashAnnotation] = n.AdminCredentialsHash
+	}
+
+	if n.ActiveServer != "" {
+		annotations[ActiveServerAnnotation] = n.ActiveServer
+	}
+
+	if n.FilesHash != "" {
+		annotations[FilesHashAnnotation] = n.FilesHash
+	}
+
 	// Build container
 	container := corev1.Container{
 		Name:    "frpc",
 		Image:   n.Image,
-		Command: []string{"frpc", "-c", "/frp/config.toml"},
+		Command: []string{"frpc", "-c", "/frp/" + ConfigFileKey},
 		Ports: []corev1.ContainerPort{
 			{ContainerPort: int32(4040)},
 		},
+		Env: []corev1.EnvVar{
+			{
+				Name: models.POD_NAME_ENV,
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "metadata.name",
+					},
+				},
+			},
+		},
 		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "runtime-config",
+				MountPath: "/frp",
+			},
+			// the files the configuration references, such as TLS certificates
 			{
 				Name:      n.Name + "-frpc-config",
+				MountPath: models.CONFIG_FILES_PATH,
+				ReadOnly:  true,
+			},
+		},
+	}
+
+	// frpc writes the configuration uploaded to its admin API back to the file it
+	// started with, the Secret is copied to a writable volume before frpc starts
+	initContainer := corev1.Container{
+		Name:    "copy-config",
+		Image:   n.Image,
+		Command: []string{"cp", models.CONFIG_FILES_PATH + "/" + ConfigFileKey, "/frp/" + ConfigFileKey},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      n.Name + "-frpc-config",
+				MountPath: models.CONFIG_FILES_PATH,
+				ReadOnly:  true,
+			},
+			{
+				Name:      "runtime-config",
 				MountPath: "/frp",
 			},
 		},
 	}
 
+	// quic-go warns on every connection when it can't raise the UDP receive buffer,
+	// which an unprivileged pod can't do beyond net.core.rmem_max
+	if models.IsProtocol(n.ServerProtocol, "quic") {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "QUIC_GO_DISABLE_RECEIVE_BUFFER_WARNING",
+			Value: "true",
+		})
+	}
+
 	// Apply container resources from PodTemplate
 	if n.PodTemplate != nil && n.PodTemplate.Resources != nil {
 		container.Resources = *n.PodTemplate.Resources
@@ -100,18 +197,23 @@ func (n *PodBuilder) Build() (*corev1.Pod, error) {
 			Annotations: annotations,
 		},
 		Spec: corev1.PodSpec{
-			Containers: []corev1.Container{container},
+			InitContainers: []corev1.Container{initContainer},
+			Containers:     []corev1.Container{container},
 			Volumes: []corev1.Volume{
 				{
 					Name: n.Name + "-frpc-config",
 					VolumeSource: corev1.VolumeSource{
-						ConfigMap: &corev1.ConfigMapVolumeSource{
-							LocalObjectReference: corev1.LocalObjectReference{
-								Name: n.Name + "-frpc-config",
-							},
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: n.Name + "-frpc-config",
 						},
 					},
 				},
+				{
+					Name: "runtime-config",
+					VolumeSource: corev1.VolumeSource{
+						EmptyDir: &corev1.EmptyDirVolumeSource{},
+					},
+				},
 			},
 		},
 	}
@@ -158,6 +260,31 @@ func (n *PodBuilder) Build() (*corev1.Pod, error) {
 		)
 	}
 
+	if n.VirtualNet {
+		tunDevice := corev1.HostPathCharDev
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: "dev-net-tun",
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: "/dev/net/tun",
+					Type: &tunDevice,
+				},
+			},
+		})
+		pod.Spec.Containers[0].VolumeMounts = append(
+			pod.Spec.Containers[0].VolumeMounts,
+			corev1.VolumeMount{
+				Name:      "dev-net-tun",
+				MountPath: "/dev/net/tun",
+			},
+		)
+		pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+			Capabilities: &corev1.Capabilities{
+				Add: []corev1.Capability{"NET_ADMIN"},
+			},
+		}
+	}
+
 	// Apply PodTemplate fields to pod spec
 	if n.PodTemplate != nil {
 		if n.PodTemplate.NodeSelector != nil {
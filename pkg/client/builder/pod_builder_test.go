@@ -41,6 +41,12 @@ func TestPodBuilder_Basic(t *testing.T) {
 		t.Errorf("Expected label app.kubernetes.io/managed-by=frp-operator")
 	}
 
+	// Check pod name is exposed to frpc for replica proxy names
+	env := pod.Spec.Containers[0].Env
+	if len(env) != 1 || env[0].Name != "FRPC_POD_NAME" || env[0].ValueFrom.FieldRef.FieldPath != "metadata.name" {
+		t.Errorf("Expected FRPC_POD_NAME env from metadata.name, got %v", env)
+	}
+
 	// Check default annotations (service mesh disabled)
 	if pod.Annotations["sidecar.istio.io/inject"] != "false" {
 		t.Errorf("Expected Istio sidecar injection disabled")
@@ -307,3 +313,65 @@ func TestPodBuilder_LabelAnnotationOverride(t *testing.T) {
 		t.Errorf("Expected custom annotation to override default, got %s", pod.Annotations["sidecar.istio.io/inject"])
 	}
 }
+
+func TestPodBuilder_WithVirtualNet(t *testing.T) {
+	pod, err := NewPodBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetImage("fatedier/frpc:v0.65.0").
+		SetVirtualNet(true).
+		Build()
+
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	container := pod.Spec.Containers[0]
+	if container.SecurityContext == nil || container.SecurityContext.Capabilities == nil ||
+		len(container.SecurityContext.Capabilities.Add) != 1 || container.SecurityContext.Capabilities.Add[0] != "NET_ADMIN" {
+		t.Errorf("Expected NET_ADMIN capability, got %v", container.SecurityContext)
+	}
+
+	var tun *corev1.Volume
+	for i := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[i].Name == "dev-net-tun" {
+			tun = &pod.Spec.Volumes[i]
+		}
+	}
+	if tun == nil || tun.HostPath == nil || tun.HostPath.Path != "/dev/net/tun" {
+		t.Fatalf("Expected /dev/net/tun host path volume, got %v", pod.Spec.Volumes)
+	}
+
+	mounted := false
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == "dev-net-tun" && mount.MountPath == "/dev/net/tun" {
+			mounted = true
+		}
+	}
+	if !mounted {
+		t.Errorf("Expected /dev/net/tun to be mounted, got %v", container.VolumeMounts)
+	}
+}
+
+func TestPodBuilder_WithQUICProtocol(t *testing.T) {
+	pod, err := NewPodBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetImage("fatedier/frpc:v0.65.0").
+		SetServerProtocol("quic").
+		Build()
+
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	found := false
+	for _, env := range pod.Spec.Containers[0].Env {
+		if env.Name == "QUIC_GO_DISABLE_RECEIVE_BUFFER_WARNING" && env.Value == "true" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected QUIC_GO_DISABLE_RECEIVE_BUFFER_WARNING env, got %v", pod.Spec.Containers[0].Env)
+	}
+}
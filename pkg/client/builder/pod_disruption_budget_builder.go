@@ -0,0 +1,57 @@
+package builder
+
+import (
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+type PodDisruptionBudgetBuilder struct {
+	Name      string
+	Namespace string
+}
+
+func NewPodDisruptionBudgetBuilder() *PodDisruptionBudgetBuilder {
+	return &PodDisruptionBudgetBuilder{}
+}
+
+func (n *PodDisruptionBudgetBuilder) SetName(name string) *PodDisruptionBudgetBuilder {
+	n.Name = name
+	return n
+}
+
+func (n *PodDisruptionBudgetBuilder) SetNamespace(namespace string) *PodDisruptionBudgetBuilder {
+	n.Namespace = namespace
+	return n
+}
+
+func (n *PodDisruptionBudgetBuilder) Build() (*policyv1.PodDisruptionBudget, error) {
+	// Evict at most one frpc pod at a time so replicas keep serving the tunnels
+	maxUnavailable := intstr.FromInt32(1)
+
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      n.Name + "-frpc",
+			Namespace: n.Namespace,
+			Labels:    n.BuildLabels(),
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: n.BuildLabels(),
+			},
+		},
+	}
+
+	return pdb, nil
+}
+
+func (n *PodDisruptionBudgetBuilder) BuildLabels() map[string]string {
+	var labels = map[string]string{
+		"app.kubernetes.io/name":       n.Name,
+		"app.kubernetes.io/managed-by": "frp-operator",
+		"app.kubernetes.io/created-by": n.Name,
+	}
+
+	return labels
+}
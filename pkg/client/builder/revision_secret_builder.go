@@ -0,0 +1,100 @@
+package builder
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+)
+
+// GenerationsAnnotation records the generations of the Client, Upstreams and
+// Visitors a revision was rendered from
+const GenerationsAnnotation = "frp.zufardhiyaulhaq.com/generations"
+
+// RevisionSecretBuilder builds an immutable Secret storing a revision of the
+// rendered frpc configuration, named after the hash of the configuration
+type RevisionSecretBuilder struct {
+	Name                 string
+	Namespace            string
+	Config               string
+	Generations          map[string]int64
+	AdminCredentialsHash string
+	ActiveServer         string
+}
+
+func NewRevisionSecretBuilder() *RevisionSecretBuilder {
+	return &RevisionSecretBuilder{}
+}
+
+func (n *RevisionSecretBuilder) SetName(name string) *RevisionSecretBuilder {
+	n.Name = name
+	return n
+}
+
+func (n *RevisionSecretBuilder) SetNamespace(namespace string) *RevisionSecretBuilder {
+	n.Namespace = namespace
+	return n
+}
+
+func (n *RevisionSecretBuilder) SetConfig(config string) *RevisionSecretBuilder {
+	n.Config = config
+	return n
+}
+
+func (n *RevisionSecretBuilder) SetGenerations(generations map[string]int64) *RevisionSecretBuilder {
+	n.Generations = generations
+	return n
+}
+
+func (n *RevisionSecretBuilder) SetAdminCredentialsHash(hash string) *RevisionSecretBuilder {
+	n.AdminCredentialsHash = hash
+	return n
+}
+
+func (n *RevisionSecretBuilder) SetActiveServer(activeServer string) *RevisionSecretBuilder {
+	n.ActiveServer = activeServer
+	return n
+}
+
+func (n *RevisionSecretBuilder) Build() (*corev1.Secret, error) {
+	generations, err := json.Marshal(n.Generations)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := models.ConfigHash(n.Config)
+	immutable := true
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      models.RevisionSecretName(n.Name, revision),
+			Namespace: n.Namespace,
+			Labels:    n.BuildLabels(revision),
+			Annotations: map[string]string{
+				GenerationsAnnotation:          string(generations),
+				AdminCredentialsHashAnnotation: n.AdminCredentialsHash,
+				ActiveServerAnnotation:         n.ActiveServer,
+			},
+		},
+		Type:      corev1.SecretTypeOpaque,
+		Immutable: &immutable,
+		Data: map[string][]byte{
+			ConfigFileKey: []byte(n.Config),
+		},
+	}
+
+	return secret, nil
+}
+
+func (n *RevisionSecretBuilder) BuildLabels(revision string) map[string]string {
+	var labels = map[string]string{
+		"app.kubernetes.io/name":            n.Name + "-frpc-config",
+		"app.kubernetes.io/managed-by":      "frp-operator",
+		"app.kubernetes.io/created-by":      n.Name,
+		models.CONFIG_REVISION_CLIENT_LABEL: n.Name,
+		models.CONFIG_REVISION_LABEL:        revision,
+	}
+
+	return labels
+}
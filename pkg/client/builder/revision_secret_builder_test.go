@@ -0,0 +1,42 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+)
+
+func TestRevisionSecretBuilder_Build(t *testing.T) {
+	config := "serverAddr = \"frp.example.com\"\n"
+	secret, err := NewRevisionSecretBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetConfig(config).
+		SetGenerations(map[string]int64{"Client/default/test": 2, "Upstream/default/web": 1}).
+		SetAdminCredentialsHash("abcdef0123456789").
+		SetActiveServer("frp.example.com:7000").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	revision := models.ConfigHash(config)
+	if secret.Name != "test-frpc-config-"+revision {
+		t.Errorf("Expected secret name test-frpc-config-%s, got %s", revision, secret.Name)
+	}
+	if secret.Immutable == nil || !*secret.Immutable {
+		t.Errorf("Expected an immutable revision secret")
+	}
+	if secret.Labels[models.CONFIG_REVISION_LABEL] != revision || secret.Labels[models.CONFIG_REVISION_CLIENT_LABEL] != "test" {
+		t.Errorf("Expected revision labels, got %v", secret.Labels)
+	}
+	if secret.Annotations[GenerationsAnnotation] != `{"Client/default/test":2,"Upstream/default/web":1}` {
+		t.Errorf("Expected generations annotation, got %s", secret.Annotations[GenerationsAnnotation])
+	}
+	if secret.Annotations[AdminCredentialsHashAnnotation] != "abcdef0123456789" || secret.Annotations[ActiveServerAnnotation] != "frp.example.com:7000" {
+		t.Errorf("Expected admin credentials and active server annotations, got %v", secret.Annotations)
+	}
+	if string(secret.Data[ConfigFileKey]) != config {
+		t.Errorf("Expected rendered config in %s, got %q", ConfigFileKey, secret.Data[ConfigFileKey])
+	}
+}
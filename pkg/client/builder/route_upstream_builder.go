@@ -0,0 +1,382 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+const (
+	// GatewayControllerName is the spec.controllerName of the GatewayClasses served
+	// by the operator
+	GatewayControllerName = "frp.zufardhiyaulhaq.com/gateway-controller"
+
+	// GatewayRouteLabel marks the Upstreams generated for a Gateway API route
+	GatewayRouteLabel = "frp.zufardhiyaulhaq.com/route"
+)
+
+var (
+	// ErrInvalidBackendKind is returned for backendRefs that aren't Services
+	ErrInvalidBackendKind = errors.New("backendRef must be a Service")
+	// ErrBackendRefNotPermitted is returned for backendRefs in another namespace
+	ErrBackendRefNotPermitted = errors.New("backendRef must be in the namespace of the route")
+	// ErrNoMatchingListenerHostname is returned when no route hostname matches the
+	// listener hostname
+	ErrNoMatchingListenerHostname = errors.New("no hostname matches the listener hostname")
+)
+
+// RouteUpstreamBuilder builds the Upstreams that serve a Gateway API route on one
+// listener of a Gateway. HTTPRoute rules become http Upstreams on the route
+// hostnames, with the path matches as locations. TCPRoute and UDPRoute backends
+// become tcp and udp Upstreams on the listener port, several TCPRoute backends
+// share a load balancer group.
+type RouteUpstreamBuilder struct {
+	Gateway   types.NamespacedName
+	Listener  *gatewayv1.Listener
+	Client    types.NamespacedName
+	HTTPRoute *gatewayv1.HTTPRoute
+	TCPRoute  *gatewayv1alpha2.TCPRoute
+	UDPRoute  *gatewayv1alpha2.UDPRoute
+}
+
+func NewRouteUpstreamBuilder() *RouteUpstreamBuilder {
+	return &RouteUpstreamBuilder{}
+}
+
+func (n *RouteUpstreamBuilder) SetListener(gateway *gatewayv1.Gateway, listener *gatewayv1.Listener) *RouteUpstreamBuilder {
+	n.Gateway = types.NamespacedName{Name: gateway.Name, Namespace: gateway.Namespace}
+	n.Listener = listener
+	return n
+}
+
+func (n *RouteUpstreamBuilder) SetClient(name string, namespace string) *RouteUpstreamBuilder {
+	n.Client = types.NamespacedName{Name: name, Namespace: namespace}
+	return n
+}
+
+func (n *RouteUpstreamBuilder) SetHTTPRoute(route *gatewayv1.HTTPRoute) *RouteUpstreamBuilder {
+	n.HTTPRoute = route
+	return n
+}
+
+func (n *RouteUpstreamBuilder) SetTCPRoute(route *gatewayv1alpha2.TCPRoute) *RouteUpstreamBuilder {
+	n.TCPRoute = route
+	return n
+}
+
+func (n *RouteUpstreamBuilder) SetUDPRoute(route *gatewayv1alpha2.UDPRoute) *RouteUpstreamBuilder {
+	n.UDPRoute = route
+	return n
+}
+
+func (n *RouteUpstreamBuilder) Build() ([]*frpv1alpha1.Upstream, error) {
+	switch {
+	case n.HTTPRoute != nil:
+		return n.buildHTTP()
+	case n.TCPRoute != nil:
+		backendRefs := []gatewayv1.BackendRef{}
+		for _, rule := range n.TCPRoute.Spec.Rules {
+			backendRefs = append(backendRefs, rule.BackendRefs...)
+		}
+		return n.buildTCP(backendRefs)
+	case n.UDPRoute != nil:
+		backendRefs := []gatewayv1.BackendRef{}
+		for _, rule := range n.UDPRoute.Spec.Rules {
+			backendRefs = append(backendRefs, rule.BackendRefs...)
+		}
+		return n.buildUDP(backendRefs)
+	}
+
+	return nil, fmt.Errorf("route is not set")
+}
+
+func (n *RouteUpstreamBuilder) BuildLabels() map[string]string {
+	var labels = map[string]string{
+		"app.kubernetes.io/managed-by": "frp-operator",
+		"app.kubernetes.io/created-by": n.route().GetName(),
+		GatewayRouteLabel:              n.route().GetName(),
+	}
+
+	return labels
+}
+
+func (n *RouteUpstreamBuilder) buildHTTP() ([]*frpv1alpha1.Upstream, error) {
+	hostnames, err := n.httpHostnames()
+	if err != nil {
+		return nil, err
+	}
+
+	upstreams := []*frpv1alpha1.Upstream{}
+	for i, rule := range n.HTTPRoute.Spec.Rules {
+		if len(rule.BackendRefs) == 0 {
+			// nothing to forward to, frps answers 404
+			continue
+		}
+		if len(rule.BackendRefs) > 1 {
+			return nil, fmt.Errorf("rule %d must have a single backendRef, frp doesn't split http traffic", i)
+		}
+
+		backendRef := rule.BackendRefs[0]
+		if len(backendRef.Filters) > 0 {
+			return nil, fmt.Errorf("rule %d backendRef filters are not supported", i)
+		}
+
+		serviceRef, err := RouteServiceRef(backendRef.BackendRef, n.HTTPRoute.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		locations, err := httpRouteLocations(rule.Matches)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+
+		upstream := n.newUpstream(fmt.Sprint(i))
+		upstream.Spec.HTTP = &frpv1alpha1.UpstreamSpec_HTTP{
+			ServiceRef:    serviceRef,
+			CustomDomains: hostnames,
+			Locations:     locations,
+		}
+		if err := setHTTPRouteFilters(upstream.Spec.HTTP, rule.Filters); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+
+		upstreams = append(upstreams, upstream)
+	}
+
+	return upstreams, nil
+}
+
+func (n *RouteUpstreamBuilder) buildTCP(backendRefs []gatewayv1.BackendRef) ([]*frpv1alpha1.Upstream, error) {
+	upstreams := []*frpv1alpha1.Upstream{}
+	for i, backendRef := range backendRefs {
+		serviceRef, err := RouteServiceRef(backendRef, n.TCPRoute.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		upstream := n.newUpstream(fmt.Sprint(i))
+		upstream.Spec.TCP = &frpv1alpha1.UpstreamSpec_TCP{
+			ServiceRef: serviceRef,
+			Server:     frpv1alpha1.UpstreamSpec_TCP_Server{Port: int(n.Listener.Port)},
+		}
+		if len(backendRefs) > 1 {
+			// frps balances the connections of a group round robin, weights are ignored
+			upstream.Spec.TCP.LoadBalancer = &frpv1alpha1.LoadBalancer{
+				Group: n.TCPRoute.Namespace + "-" + n.TCPRoute.Name + "-" + upstreamNameHash(n.listenerKey()...),
+			}
+		}
+
+		upstreams = append(upstreams, upstream)
+	}
+
+	return upstreams, nil
+}
+
+func (n *RouteUpstreamBuilder) buildUDP(backendRefs []gatewayv1.BackendRef) ([]*frpv1alpha1.Upstream, error) {
+	if len(backendRefs) == 0 {
+		return []*frpv1alpha1.Upstream{}, nil
+	}
+	if len(backendRefs) > 1 {
+		return nil, fmt.Errorf("UDPRoute must have a single backendRef, frp doesn't balance udp")
+	}
+
+	serviceRef, err := RouteServiceRef(backendRefs[0], n.UDPRoute.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	upstream := n.newUpstream("0")
+	upstream.Spec.UDP = &frpv1alpha1.UpstreamSpec_UDP{
+		ServiceRef: serviceRef,
+		Server:     frpv1alpha1.UpstreamSpec_UDP_Server{Port: int(n.Listener.Port)},
+	}
+
+	return []*frpv1alpha1.Upstream{upstream}, nil
+}
+
+// httpHostnames returns the custom domains of an HTTPRoute on the listener, the
+// route hostnames the listener hostname matches, or the listener hostname
+func (n *RouteUpstreamBuilder) httpHostnames() ([]string, error) {
+	hostnames := []string{}
+	for _, hostname := range n.HTTPRoute.Spec.Hostnames {
+		if n.Listener.Hostname == nil {
+			hostnames = append(hostnames, string(hostname))
+			continue
+		}
+
+		listenerHostname := string(*n.Listener.Hostname)
+		switch {
+		case hostnameMatches(listenerHostname, string(hostname)):
+			hostnames = append(hostnames, string(hostname))
+		case hostnameMatches(string(hostname), listenerHostname):
+			hostnames = append(hostnames, listenerHostname)
+		}
+	}
+
+	if len(n.HTTPRoute.Spec.Hostnames) > 0 && len(hostnames) == 0 {
+		return nil, ErrNoMatchingListenerHostname
+	}
+	if len(hostnames) == 0 && n.Listener.Hostname != nil {
+		hostnames = append(hostnames, string(*n.Listener.Hostname))
+	}
+	if len(hostnames) == 0 {
+		return nil, fmt.Errorf("HTTPRoute or listener must have a hostname, frp routes by domain")
+	}
+
+	return hostnames, nil
+}
+
+func (n *RouteUpstreamBuilder) route() metav1.Object {
+	switch {
+	case n.HTTPRoute != nil:
+		return n.HTTPRoute
+	case n.TCPRoute != nil:
+		return n.TCPRoute
+	}
+
+	return n.UDPRoute
+}
+
+func (n *RouteUpstreamBuilder) listenerKey() []string {
+	return []string{n.Gateway.Namespace, n.Gateway.Name, string(n.Listener.Name)}
+}
+
+func (n *RouteUpstreamBuilder) newUpstream(index string) *frpv1alpha1.Upstream {
+	route := n.route()
+	upstream := &frpv1alpha1.Upstream{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      route.GetName() + "-" + upstreamNameHash(append(n.listenerKey(), index)...),
+			Namespace: route.GetNamespace(),
+			Labels:    n.BuildLabels(),
+		},
+		Spec: frpv1alpha1.UpstreamSpec{
+			Enabled: ptr.To(true),
+		},
+	}
+	bindClient(upstream, n.Client)
+
+	return upstream
+}
+
+// RouteServiceRef returns the Service reference of a route backendRef. Backends
+// must be Services in the namespace of the route, and name their port.
+func RouteServiceRef(backendRef gatewayv1.BackendRef, namespace string) (*frpv1alpha1.ServiceRef, error) {
+	if (backendRef.Group != nil && *backendRef.Group != "") || (backendRef.Kind != nil && *backendRef.Kind != "Service") {
+		return nil, fmt.Errorf("%w, got %s", ErrInvalidBackendKind, backendRef.Name)
+	}
+	if backendRef.Namespace != nil && string(*backendRef.Namespace) != namespace {
+		return nil, fmt.Errorf("%w, got %s/%s", ErrBackendRefNotPermitted, *backendRef.Namespace, backendRef.Name)
+	}
+	if backendRef.Port == nil {
+		return nil, fmt.Errorf("backendRef %s must have a port", backendRef.Name)
+	}
+
+	return &frpv1alpha1.ServiceRef{
+		Name: string(backendRef.Name),
+		Port: intstr.FromInt32(int32(*backendRef.Port)),
+	}, nil
+}
+
+// httpRouteLocations returns the path prefixes of HTTPRoute matches, frp matches
+// locations by prefix so exact paths are served as prefixes
+func httpRouteLocations(matches []gatewayv1.HTTPRouteMatch) ([]string, error) {
+	locations := []string{}
+	seen := map[string]struct{}{}
+	for _, match := range matches {
+		if len(match.Headers) > 0 || len(match.QueryParams) > 0 || match.Method != nil {
+			return nil, fmt.Errorf("header, query and method matches are not supported")
+		}
+
+		location := "/"
+		if match.Path != nil {
+			if match.Path.Type != nil && *match.Path.Type == gatewayv1.PathMatchRegularExpression {
+				return nil, fmt.Errorf("regular expression path matches are not supported")
+			}
+			if match.Path.Value != nil {
+				location = *match.Path.Value
+			}
+		}
+
+		if _, ok := seen[location]; ok {
+			continue
+		}
+		seen[location] = struct{}{}
+		locations = append(locations, location)
+	}
+
+	if len(locations) == 0 {
+		locations = append(locations, "/")
+	}
+
+	return locations, nil
+}
+
+// setHTTPRouteFilters maps HTTPRoute filters to http Upstream options: header
+// modifiers that set headers, and URL rewrites of the hostname
+func setHTTPRouteFilters(http *frpv1alpha1.UpstreamSpec_HTTP, filters []gatewayv1.HTTPRouteFilter) error {
+	for _, filter := range filters {
+		switch filter.Type {
+		case gatewayv1.HTTPRouteFilterRequestHeaderModifier:
+			headers, err := httpRouteHeaders(filter.RequestHeaderModifier)
+			if err != nil {
+				return err
+			}
+			http.RequestHeaders = headers
+		case gatewayv1.HTTPRouteFilterResponseHeaderModifier:
+			headers, err := httpRouteHeaders(filter.ResponseHeaderModifier)
+			if err != nil {
+				return err
+			}
+			http.ResponseHeaders = headers
+		case gatewayv1.HTTPRouteFilterURLRewrite:
+			if filter.URLRewrite == nil || filter.URLRewrite.Path != nil {
+				return fmt.Errorf("only hostname URL rewrites are supported")
+			}
+			if filter.URLRewrite.Hostname != nil {
+				http.HostHeaderRewrite = string(*filter.URLRewrite.Hostname)
+			}
+		default:
+			return fmt.Errorf("filter %s is not supported", filter.Type)
+		}
+	}
+
+	return nil
+}
+
+func httpRouteHeaders(filter *gatewayv1.HTTPHeaderFilter) (*frpv1alpha1.HTTPHeaders, error) {
+	if filter == nil {
+		return nil, nil
+	}
+	if len(filter.Add) > 0 || len(filter.Remove) > 0 {
+		return nil, fmt.Errorf("only header modifiers that set headers are supported")
+	}
+
+	headers := &frpv1alpha1.HTTPHeaders{Set: map[string]string{}}
+	for _, header := range filter.Set {
+		headers.Set[string(header.Name)] = header.Value
+	}
+
+	return headers, nil
+}
+
+// hostnameMatches reports whether a hostname, possibly a wildcard, matches another
+func hostnameMatches(pattern string, hostname string) bool {
+	if pattern == hostname {
+		return true
+	}
+	if !strings.HasPrefix(pattern, "*.") {
+		return false
+	}
+
+	return strings.HasSuffix(hostname, pattern[1:]) && !strings.HasPrefix(hostname, "*.")
+}
@@ -0,0 +1,247 @@
+package builder
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+func newGateway(listeners ...gatewayv1.Listener) *gatewayv1.Gateway {
+	return &gatewayv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge", Namespace: "frp-system"},
+		Spec:       gatewayv1.GatewaySpec{GatewayClassName: "frp", Listeners: listeners},
+	}
+}
+
+func newBackendRef(name string, port gatewayv1.PortNumber) gatewayv1.BackendRef {
+	return gatewayv1.BackendRef{
+		BackendObjectReference: gatewayv1.BackendObjectReference{Name: gatewayv1.ObjectName(name), Port: &port},
+	}
+}
+
+func newHTTPRoute(hostnames []gatewayv1.Hostname, rules ...gatewayv1.HTTPRouteRule) *gatewayv1.HTTPRoute {
+	return &gatewayv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a"},
+		Spec:       gatewayv1.HTTPRouteSpec{Hostnames: hostnames, Rules: rules},
+	}
+}
+
+func pathPrefix(value string) gatewayv1.HTTPRouteMatch {
+	matchType := gatewayv1.PathMatchPathPrefix
+	return gatewayv1.HTTPRouteMatch{Path: &gatewayv1.HTTPPathMatch{Type: &matchType, Value: &value}}
+}
+
+func TestRouteUpstreamBuilder_HTTPRoute(t *testing.T) {
+	hostname := gatewayv1.Hostname("*.example.com")
+	gateway := newGateway(gatewayv1.Listener{Name: "http", Port: 80, Protocol: gatewayv1.HTTPProtocolType, Hostname: &hostname})
+
+	route := newHTTPRoute([]gatewayv1.Hostname{"web.example.com", "web.other.com"},
+		gatewayv1.HTTPRouteRule{
+			Matches: []gatewayv1.HTTPRouteMatch{pathPrefix("/"), pathPrefix("/static")},
+			Filters: []gatewayv1.HTTPRouteFilter{{
+				Type: gatewayv1.HTTPRouteFilterRequestHeaderModifier,
+				RequestHeaderModifier: &gatewayv1.HTTPHeaderFilter{
+					Set: []gatewayv1.HTTPHeader{{Name: "X-From", Value: "frp"}},
+				},
+			}},
+			BackendRefs: []gatewayv1.HTTPBackendRef{{BackendRef: newBackendRef("web", 8080)}},
+		},
+		gatewayv1.HTTPRouteRule{
+			Matches:     []gatewayv1.HTTPRouteMatch{pathPrefix("/api")},
+			BackendRefs: []gatewayv1.HTTPBackendRef{{BackendRef: newBackendRef("api", 9090)}},
+		},
+	)
+
+	upstreams, err := NewRouteUpstreamBuilder().
+		SetListener(gateway, &gateway.Spec.Listeners[0]).
+		SetClient("edge", "frp-system").
+		SetHTTPRoute(route).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error = %v", err)
+	}
+	if len(upstreams) != 2 {
+		t.Fatalf("Build() = %d upstreams, want one per rule", len(upstreams))
+	}
+
+	web, api := upstreams[0], upstreams[1]
+	if web.Namespace != "team-a" || web.Spec.ClientRef == nil || web.Spec.ClientRef.Name != "edge" {
+		t.Errorf("Build() bound to %s %v, want clientRef frp-system/edge in team-a", web.Namespace, web.Spec.ClientRef)
+	}
+	if web.Labels[GatewayRouteLabel] != "web" {
+		t.Errorf("Build() label %s = %q, want web", GatewayRouteLabel, web.Labels[GatewayRouteLabel])
+	}
+	if domains := web.Spec.HTTP.CustomDomains; len(domains) != 1 || domains[0] != "web.example.com" {
+		t.Errorf("Build() customDomains = %v, want the hostnames matching the listener", domains)
+	}
+	if locations := web.Spec.HTTP.Locations; len(locations) != 2 || locations[0] != "/" || locations[1] != "/static" {
+		t.Errorf("Build() locations = %v, want [/ /static]", locations)
+	}
+	if web.Spec.HTTP.RequestHeaders == nil || web.Spec.HTTP.RequestHeaders.Set["X-From"] != "frp" {
+		t.Errorf("Build() requestHeaders = %+v, want X-From: frp", web.Spec.HTTP.RequestHeaders)
+	}
+	if web.Spec.HTTP.ServiceRef.Name != "web" || web.Spec.HTTP.ServiceRef.Port != intstr.FromInt32(8080) {
+		t.Errorf("Build() serviceRef = %+v, want web port 8080", web.Spec.HTTP.ServiceRef)
+	}
+	if api.Spec.HTTP.ServiceRef.Name != "api" || web.Name == api.Name {
+		t.Errorf("Build() second upstream = %s %+v, want a distinct api upstream", api.Name, api.Spec.HTTP.ServiceRef)
+	}
+}
+
+func TestRouteUpstreamBuilder_HTTPRouteListenerHostname(t *testing.T) {
+	hostname := gatewayv1.Hostname("web.example.com")
+	gateway := newGateway(gatewayv1.Listener{Name: "http", Port: 80, Protocol: gatewayv1.HTTPProtocolType, Hostname: &hostname})
+
+	route := newHTTPRoute(nil, gatewayv1.HTTPRouteRule{
+		BackendRefs: []gatewayv1.HTTPBackendRef{{BackendRef: newBackendRef("web", 8080)}},
+	})
+
+	upstreams, err := NewRouteUpstreamBuilder().SetListener(gateway, &gateway.Spec.Listeners[0]).SetHTTPRoute(route).Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error = %v", err)
+	}
+	if domains := upstreams[0].Spec.HTTP.CustomDomains; len(domains) != 1 || domains[0] != "web.example.com" {
+		t.Errorf("Build() customDomains = %v, want the listener hostname", domains)
+	}
+	if locations := upstreams[0].Spec.HTTP.Locations; len(locations) != 1 || locations[0] != "/" {
+		t.Errorf("Build() locations = %v, want [/] without matches", locations)
+	}
+
+	route.Spec.Hostnames = []gatewayv1.Hostname{"other.example.com"}
+	_, err = NewRouteUpstreamBuilder().SetListener(gateway, &gateway.Spec.Listeners[0]).SetHTTPRoute(route).Build()
+	if !errors.Is(err, ErrNoMatchingListenerHostname) {
+		t.Errorf("Build() error = %v, want %v", err, ErrNoMatchingListenerHostname)
+	}
+}
+
+func TestRouteUpstreamBuilder_TCPRoute(t *testing.T) {
+	gateway := newGateway(gatewayv1.Listener{Name: "postgres", Port: 15432, Protocol: gatewayv1.TCPProtocolType})
+	route := &gatewayv1alpha2.TCPRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "frp-system"},
+		Spec: gatewayv1alpha2.TCPRouteSpec{
+			Rules: []gatewayv1alpha2.TCPRouteRule{{
+				BackendRefs: []gatewayv1.BackendRef{newBackendRef("postgres-a", 5432), newBackendRef("postgres-b", 5432)},
+			}},
+		},
+	}
+
+	upstreams, err := NewRouteUpstreamBuilder().
+		SetListener(gateway, &gateway.Spec.Listeners[0]).
+		SetClient("edge", "frp-system").
+		SetTCPRoute(route).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error = %v", err)
+	}
+	if len(upstreams) != 2 {
+		t.Fatalf("Build() = %d upstreams, want one per backend", len(upstreams))
+	}
+
+	first, second := upstreams[0], upstreams[1]
+	if first.Spec.Client != "edge" {
+		t.Errorf("Build() client = %q, want edge in the same namespace", first.Spec.Client)
+	}
+	if first.Spec.TCP.Server.Port != 15432 || second.Spec.TCP.Server.Port != 15432 {
+		t.Errorf("Build() remote ports = %d, %d, want the listener port", first.Spec.TCP.Server.Port, second.Spec.TCP.Server.Port)
+	}
+	if first.Spec.TCP.LoadBalancer == nil || second.Spec.TCP.LoadBalancer == nil ||
+		first.Spec.TCP.LoadBalancer.Group != second.Spec.TCP.LoadBalancer.Group {
+		t.Errorf("Build() load balancers = %+v, %+v, want a shared group", first.Spec.TCP.LoadBalancer, second.Spec.TCP.LoadBalancer)
+	}
+}
+
+func TestRouteUpstreamBuilder_UDPRoute(t *testing.T) {
+	gateway := newGateway(gatewayv1.Listener{Name: "dns", Port: 5353, Protocol: gatewayv1.UDPProtocolType})
+	route := &gatewayv1alpha2.UDPRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "dns", Namespace: "frp-system"},
+		Spec: gatewayv1alpha2.UDPRouteSpec{
+			Rules: []gatewayv1alpha2.UDPRouteRule{{BackendRefs: []gatewayv1.BackendRef{newBackendRef("dns", 53)}}},
+		},
+	}
+
+	upstreams, err := NewRouteUpstreamBuilder().SetListener(gateway, &gateway.Spec.Listeners[0]).SetUDPRoute(route).Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error = %v", err)
+	}
+	if len(upstreams) != 1 || upstreams[0].Spec.UDP.Server.Port != 5353 || upstreams[0].Spec.UDP.ServiceRef.Name != "dns" {
+		t.Errorf("Build() = %+v, want one udp upstream on port 5353 to dns", upstreams)
+	}
+
+	route.Spec.Rules[0].BackendRefs = append(route.Spec.Rules[0].BackendRefs, newBackendRef("dns-b", 53))
+	if _, err := NewRouteUpstreamBuilder().SetListener(gateway, &gateway.Spec.Listeners[0]).SetUDPRoute(route).Build(); err == nil {
+		t.Errorf("Build() expected an error for several udp backends")
+	}
+}
+
+func TestRouteUpstreamBuilder_InvalidHTTPRoute(t *testing.T) {
+	regex := gatewayv1.PathMatchRegularExpression
+	otherNamespace := gatewayv1.Namespace("team-b")
+	otherKind := gatewayv1.Kind("Bucket")
+	method := gatewayv1.HTTPMethodGet
+
+	tests := []struct {
+		name    string
+		rule    gatewayv1.HTTPRouteRule
+		wantErr error
+	}{
+		{
+			name: "regular expression path",
+			rule: gatewayv1.HTTPRouteRule{
+				Matches: []gatewayv1.HTTPRouteMatch{{Path: &gatewayv1.HTTPPathMatch{Type: &regex}}},
+			},
+		},
+		{
+			name: "method match",
+			rule: gatewayv1.HTTPRouteRule{Matches: []gatewayv1.HTTPRouteMatch{{Method: &method}}},
+		},
+		{
+			name: "redirect filter",
+			rule: gatewayv1.HTTPRouteRule{
+				Filters: []gatewayv1.HTTPRouteFilter{{Type: gatewayv1.HTTPRouteFilterRequestRedirect}},
+			},
+		},
+		{
+			name: "backend in another namespace",
+			rule: gatewayv1.HTTPRouteRule{
+				BackendRefs: []gatewayv1.HTTPBackendRef{{BackendRef: gatewayv1.BackendRef{
+					BackendObjectReference: gatewayv1.BackendObjectReference{Name: "web", Namespace: &otherNamespace},
+				}}},
+			},
+			wantErr: ErrBackendRefNotPermitted,
+		},
+		{
+			name: "backend not a Service",
+			rule: gatewayv1.HTTPRouteRule{
+				BackendRefs: []gatewayv1.HTTPBackendRef{{BackendRef: gatewayv1.BackendRef{
+					BackendObjectReference: gatewayv1.BackendObjectReference{Name: "web", Kind: &otherKind},
+				}}},
+			},
+			wantErr: ErrInvalidBackendKind,
+		},
+	}
+
+	gateway := newGateway(gatewayv1.Listener{Name: "http", Port: 80, Protocol: gatewayv1.HTTPProtocolType})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := tt.rule
+			if rule.BackendRefs == nil {
+				rule.BackendRefs = []gatewayv1.HTTPBackendRef{{BackendRef: newBackendRef("web", 8080)}}
+			}
+
+			_, err := NewRouteUpstreamBuilder().
+				SetListener(gateway, &gateway.Spec.Listeners[0]).
+				SetHTTPRoute(newHTTPRoute([]gatewayv1.Hostname{"web.example.com"}, rule)).
+				Build()
+			if err == nil {
+				t.Fatalf("Build() expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Build() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
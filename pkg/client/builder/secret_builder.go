@@ -0,0 +1,126 @@
+package builder
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+)
+
+// ConfigFileKey is the key of the rendered frpc configuration in the config Secret
+const ConfigFileKey = "config.toml"
+
+// LastKnownGoodKey is the key of the last configuration frpc reloaded and started
+// every proxy with, it is restored when frpc rejects a newer configuration
+const LastKnownGoodKey = "last-known-good.toml"
+
+// AdminCredentialsHashAnnotation records the hash of the admin credentials in the
+// rendered configuration. frpc can't be reloaded once its credentials changed, so
+// the pods are rolled instead, see PodBuilder.SetAdminCredentialsHash.
+const AdminCredentialsHashAnnotation = "frp.zufardhiyaulhaq.com/admin-credentials-hash"
+
+// ActiveServerAnnotation records the server the rendered configuration connects to.
+// frpc can't be reloaded onto another server, so the pods are rolled instead, see
+// PodBuilder.SetActiveServer.
+const ActiveServerAnnotation = "frp.zufardhiyaulhaq.com/active-server"
+
+// FilesHashAnnotation records the hash of the files stored next to the configuration,
+// such as the certificates of TLS terminating proxies. frpc reads them when a proxy
+// starts, so the pods are rolled when they change, see PodBuilder.SetFilesHash.
+const FilesHashAnnotation = "frp.zufardhiyaulhaq.com/files-hash"
+
+// ReloadPendingAnnotation marks a config Secret whose configuration isn't pushed to
+// the running frpc pods through their admin API yet
+const ReloadPendingAnnotation = "frp.zufardhiyaulhaq.com/reload-pending"
+
+// VerifyUntilAnnotation records until when the proxies of a reloaded configuration
+// are checked before it becomes the last known good configuration
+const VerifyUntilAnnotation = "frp.zufardhiyaulhaq.com/verify-until"
+
+// RejectedConfigHashAnnotation records the hash of the configuration frpc rejected,
+// the configuration isn't pushed again until the rendered configuration changes
+const RejectedConfigHashAnnotation = "frp.zufardhiyaulhaq.com/rejected-config-hash"
+
+// SecretBuilder builds the Secret holding the rendered frpc configuration.
+// The configuration embeds tokens and secret keys, so it is never stored in a ConfigMap.
+type SecretBuilder struct {
+	Name                 string
+	Namespace            string
+	Config               string
+	AdminCredentialsHash string
+	ActiveServer         string
+	Files                map[string][]byte
+}
+
+func NewSecretBuilder() *SecretBuilder {
+	return &SecretBuilder{}
+}
+
+func (n *SecretBuilder) SetConfig(config string) *SecretBuilder {
+	n.Config = config
+	return n
+}
+
+func (n *SecretBuilder) SetName(name string) *SecretBuilder {
+	n.Name = name
+	return n
+}
+
+func (n *SecretBuilder) SetNamespace(namespace string) *SecretBuilder {
+	n.Namespace = namespace
+	return n
+}
+
+func (n *SecretBuilder) SetAdminCredentialsHash(hash string) *SecretBuilder {
+	n.AdminCredentialsHash = hash
+	return n
+}
+
+func (n *SecretBuilder) SetActiveServer(activeServer string) *SecretBuilder {
+	n.ActiveServer = activeServer
+	return n
+}
+
+// SetFiles stores files the configuration references next to it
+func (n *SecretBuilder) SetFiles(files map[string][]byte) *SecretBuilder {
+	n.Files = files
+	return n
+}
+
+func (n *SecretBuilder) Build() (*corev1.Secret, error) {
+	data := make(map[string][]byte)
+	for key, file := range n.Files {
+		data[key] = file
+	}
+	data[ConfigFileKey] = []byte(n.Config)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      n.Name + "-frpc-config",
+			Namespace: n.Namespace,
+			Labels: map[string]string{
+				"app":       n.Name,
+				"generated": "frp-operator",
+			},
+			Annotations: map[string]string{
+				AdminCredentialsHashAnnotation: n.AdminCredentialsHash,
+				ActiveServerAnnotation:         n.ActiveServer,
+				FilesHashAnnotation:            models.FilesHash(n.Files),
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+
+	return secret, nil
+}
+
+func (n *SecretBuilder) BuildLabels() map[string]string {
+	var labels = map[string]string{
+		"app.kubernetes.io/name":       n.Name + "-frpc-config",
+		"app.kubernetes.io/managed-by": "frp-operator",
+		"app.kubernetes.io/created-by": n.Name,
+	}
+
+	return labels
+}
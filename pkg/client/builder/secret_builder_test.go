@@ -0,0 +1,202 @@
+package builder
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSecretBuilder_Build(t *testing.T) {
+	secret, err := NewSecretBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetConfig("auth.token = \"my-token\"\n").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if secret.Name != "test-frpc-config" {
+		t.Errorf("Expected secret name test-frpc-config, got %s", secret.Name)
+	}
+	if secret.Type != corev1.SecretTypeOpaque {
+		t.Errorf("Expected secret type Opaque, got %s", secret.Type)
+	}
+	if string(secret.Data[ConfigFileKey]) != "auth.token = \"my-token\"\n" {
+		t.Errorf("Expected rendered config in %s, got %q", ConfigFileKey, secret.Data[ConfigFileKey])
+	}
+}
+
+func TestPodBuilder_ConfigFromSecret(t *testing.T) {
+	pod, err := NewPodBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetImage("fatedier/frpc:v0.65.0").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	volume := pod.Spec.Volumes[0]
+	if volume.ConfigMap != nil {
+		t.Errorf("Expected config volume not to use a ConfigMap")
+	}
+	if volume.Secret == nil || volume.Secret.SecretName != "test-frpc-config" {
+		t.Errorf("Expected config volume from secret test-frpc-config, got %+v", volume.VolumeSource)
+	}
+}
+
+func TestPodBuilder_WritableConfig(t *testing.T) {
+	pod, err := NewPodBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetImage("fatedier/frpc:v0.65.0").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	var runtimeConfig *corev1.Volume
+	for i := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[i].Name == "runtime-config" {
+			runtimeConfig = &pod.Spec.Volumes[i]
+		}
+	}
+	if runtimeConfig == nil || runtimeConfig.EmptyDir == nil {
+		t.Fatalf("Expected an emptyDir volume for the frpc config, got %v", pod.Spec.Volumes)
+	}
+
+	container := pod.Spec.Containers[0]
+	if len(container.VolumeMounts) == 0 || container.VolumeMounts[0].Name != "runtime-config" || container.VolumeMounts[0].ReadOnly {
+		t.Errorf("Expected frpc to run with the writable config, got %v", container.VolumeMounts)
+	}
+
+	if len(pod.Spec.InitContainers) != 1 {
+		t.Fatalf("Expected an init container copying the config, got %v", pod.Spec.InitContainers)
+	}
+	initContainer := pod.Spec.InitContainers[0]
+	if !reflect.DeepEqual(initContainer.Command, []string{"cp", "/frp-config/config.toml", "/frp/config.toml"}) {
+		t.Errorf("Expected the init container to copy the config, got %v", initContainer.Command)
+	}
+	if initContainer.Image != "fatedier/frpc:v0.65.0" {
+		t.Errorf("Expected the init container to use the frpc image, got %v", initContainer.Image)
+	}
+}
+
+func TestAdminSecretBuilder_Build(t *testing.T) {
+	secret, err := NewAdminSecretBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetUsername("frpc-user").
+		SetPassword("generated").
+		SetRotation("2024-01-01").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if secret.Name != "test-frpc-admin" {
+		t.Errorf("Expected secret name test-frpc-admin, got %s", secret.Name)
+	}
+	if string(secret.Data["username"]) != "frpc-user" || string(secret.Data["password"]) != "generated" {
+		t.Errorf("Expected generated credentials in secret, got %v", secret.Data)
+	}
+	if secret.Annotations[AdminRotationAnnotation] != "2024-01-01" {
+		t.Errorf("Expected rotation annotation 2024-01-01, got %s", secret.Annotations[AdminRotationAnnotation])
+	}
+}
+
+func TestPodBuilder_AdminCredentialsHash(t *testing.T) {
+	pod, err := NewPodBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetImage("fatedier/frpc:v0.65.0").
+		SetPodTemplate(nil).
+		SetAdminCredentialsHash("abcdef0123456789").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if pod.Annotations[AdminCredentialsHashAnnotation] != "abcdef0123456789" {
+		t.Errorf("Expected admin credentials hash annotation, got %v", pod.Annotations)
+	}
+}
+
+func TestSecretBuilder_ActiveServer(t *testing.T) {
+	secret, err := NewSecretBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetConfig("# frpc.toml").
+		SetActiveServer("frp.example.com:7000").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if secret.Annotations[ActiveServerAnnotation] != "frp.example.com:7000" {
+		t.Errorf("Expected active server annotation, got %v", secret.Annotations)
+	}
+}
+
+func TestPodBuilder_ActiveServer(t *testing.T) {
+	pod, err := NewPodBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetImage("fatedier/frpc:v0.65.0").
+		SetActiveServer("frp.example.com:7000").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if pod.Annotations[ActiveServerAnnotation] != "frp.example.com:7000" {
+		t.Errorf("Expected active server annotation, got %v", pod.Annotations)
+	}
+}
+
+func TestSecretBuilder_Files(t *testing.T) {
+	secret, err := NewSecretBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetConfig("# frpc.toml").
+		SetFiles(map[string][]byte{"tls.default.web.crt": []byte("certificate")}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if string(secret.Data["tls.default.web.crt"]) != "certificate" || string(secret.Data[ConfigFileKey]) != "# frpc.toml" {
+		t.Errorf("Expected the files next to the config, got %v", secret.Data)
+	}
+	if secret.Annotations[FilesHashAnnotation] == "" {
+		t.Errorf("Expected files hash annotation, got %v", secret.Annotations)
+	}
+}
+
+func TestPodBuilder_ConfigFiles(t *testing.T) {
+	pod, err := NewPodBuilder().
+		SetName("test").
+		SetNamespace("default").
+		SetImage("fatedier/frpc:v0.65.0").
+		SetFilesHash("abcdef0123456789").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if pod.Annotations[FilesHashAnnotation] != "abcdef0123456789" {
+		t.Errorf("Expected files hash annotation, got %v", pod.Annotations)
+	}
+
+	mounted := false
+	for _, mount := range pod.Spec.Containers[0].VolumeMounts {
+		if mount.Name == "test-frpc-config" && mount.MountPath == "/frp-config" && mount.ReadOnly {
+			mounted = true
+		}
+	}
+	if !mounted {
+		t.Errorf("Expected frpc to mount the config Secret read-only, got %v", pod.Spec.Containers[0].VolumeMounts)
+	}
+}
@@ -2,6 +2,7 @@ package builder
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
 	corev1 "k8s.io/api/core/v1"
@@ -13,7 +14,13 @@ type ServiceBuilder struct {
 	Name        string
 	Namespace   string
 	AdminPort   int
-	VisitorPort []int
+	VisitorPort []VisitorPort
+}
+
+// VisitorPort is a port a visitor binds in the frpc pod
+type VisitorPort struct {
+	Port     int
+	Protocol corev1.Protocol
 }
 
 func NewServiceBuilder() *ServiceBuilder {
@@ -37,8 +44,8 @@ func (n *ServiceBuilder) SetAdminPort(adminPort int) *ServiceBuilder {
 	return n
 }
 
-func (n *ServiceBuilder) AddVisitorPort(visitorPort int) *ServiceBuilder {
-	n.VisitorPort = append(n.VisitorPort, visitorPort)
+func (n *ServiceBuilder) AddVisitorPort(visitorPort int, protocol corev1.Protocol) *ServiceBuilder {
+	n.VisitorPort = append(n.VisitorPort, VisitorPort{Port: visitorPort, Protocol: protocol})
 	return n
 }
 
@@ -66,14 +73,14 @@ func (n *ServiceBuilder) Build() (*corev1.Service, error) {
 		},
 	}
 
-	for _, port := range n.VisitorPort {
+	for _, visitorPort := range n.VisitorPort {
 		servicePort := corev1.ServicePort{
-			Name:     "tcp-visitor-" + fmt.Sprint(port),
-			Protocol: corev1.ProtocolTCP,
-			Port:     int32(port),
+			Name:     strings.ToLower(string(visitorPort.Protocol)) + "-visitor-" + fmt.Sprint(visitorPort.Port),
+			Protocol: visitorPort.Protocol,
+			Port:     int32(visitorPort.Port),
 			TargetPort: intstr.IntOrString{
 				Type:   0,
-				IntVal: int32(port),
+				IntVal: int32(visitorPort.Port),
 			},
 		}
 		Service.Spec.Ports = append(Service.Spec.Ports, servicePort)
@@ -0,0 +1,200 @@
+package builder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+// Annotations that expose a Service through frp. A Service is exposed when it
+// carries the client annotation, every TCP port of the Service is mapped to an
+// Upstream named <service>-<port name or number>.
+const (
+	// ExposeClientAnnotation names the Client, either "name" in the Service
+	// namespace or "namespace/name"
+	ExposeClientAnnotation = "frp.zufardhiyaulhaq.com/client"
+	// ExposeTypeAnnotation is the proxy type, tcp (default), http or https
+	ExposeTypeAnnotation = "frp.zufardhiyaulhaq.com/type"
+	// ExposeRemotePortAnnotation is the remote port of the first Service port of
+	// a tcp proxy, the following ports get the consecutive remote ports
+	ExposeRemotePortAnnotation = "frp.zufardhiyaulhaq.com/remote-port"
+	// ExposeSubdomainAnnotation is the subdomain of an http proxy, Services with
+	// several ports get <subdomain>-<port name or number> per port
+	ExposeSubdomainAnnotation = "frp.zufardhiyaulhaq.com/subdomain"
+	// ExposeCustomDomainsAnnotation is a comma separated list of domains of an
+	// http or https proxy of a single port Service
+	ExposeCustomDomainsAnnotation = "frp.zufardhiyaulhaq.com/custom-domains"
+
+	// ExposeServiceLabel marks the Upstreams generated for a Service
+	ExposeServiceLabel = "frp.zufardhiyaulhaq.com/service"
+)
+
+// ServiceUpstreamBuilder builds the Upstreams a Service asks for through its
+// expose annotations
+type ServiceUpstreamBuilder struct {
+	Service *corev1.Service
+}
+
+func NewServiceUpstreamBuilder() *ServiceUpstreamBuilder {
+	return &ServiceUpstreamBuilder{}
+}
+
+func (n *ServiceUpstreamBuilder) SetService(service *corev1.Service) *ServiceUpstreamBuilder {
+	n.Service = service
+	return n
+}
+
+// Build returns the Upstreams of the Service, none when the Service isn't
+// exposed, and an error when its expose annotations are invalid
+func (n *ServiceUpstreamBuilder) Build() ([]*frpv1alpha1.Upstream, error) {
+	annotations := n.Service.Annotations
+	clientAnnotation, ok := annotations[ExposeClientAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	client, clientRef, err := parseClientAnnotation(clientAnnotation)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := []corev1.ServicePort{}
+	for _, port := range n.Service.Spec.Ports {
+		if port.Protocol == "" || port.Protocol == corev1.ProtocolTCP {
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("service %s has no TCP port to expose", n.Service.Name)
+	}
+
+	proxyType := annotations[ExposeTypeAnnotation]
+	if proxyType == "" {
+		proxyType = "tcp"
+	}
+
+	var customDomains []string
+	for _, domain := range strings.Split(annotations[ExposeCustomDomainsAnnotation], ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			customDomains = append(customDomains, domain)
+		}
+	}
+	if len(customDomains) > 0 && len(ports) > 1 {
+		return nil, fmt.Errorf("annotation %s can only expose a Service with a single port", ExposeCustomDomainsAnnotation)
+	}
+
+	remotePort := 0
+	subdomain := annotations[ExposeSubdomainAnnotation]
+	switch proxyType {
+	case "tcp":
+		remotePort, err = strconv.Atoi(annotations[ExposeRemotePortAnnotation])
+		if err != nil || remotePort < 1 || remotePort+len(ports)-1 > 65535 {
+			return nil, fmt.Errorf("annotation %s must be a port leaving room for %d Service ports", ExposeRemotePortAnnotation, len(ports))
+		}
+	case "http":
+		if subdomain == "" && len(customDomains) == 0 {
+			return nil, fmt.Errorf("annotation %s or %s is required for http", ExposeSubdomainAnnotation, ExposeCustomDomainsAnnotation)
+		}
+	case "https":
+		if len(customDomains) == 0 {
+			return nil, fmt.Errorf("annotation %s is required for https", ExposeCustomDomainsAnnotation)
+		}
+	default:
+		return nil, fmt.Errorf("annotation %s must be tcp, http or https, got %q", ExposeTypeAnnotation, proxyType)
+	}
+
+	upstreams := []*frpv1alpha1.Upstream{}
+	for i, port := range ports {
+		portKey := servicePortKey(port)
+		serviceRef := &frpv1alpha1.ServiceRef{
+			Name: n.Service.Name,
+			Port: intstr.FromInt32(port.Port),
+		}
+		if port.Name != "" {
+			serviceRef.Port = intstr.FromString(port.Name)
+		}
+
+		upstream := &frpv1alpha1.Upstream{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      n.Service.Name + "-" + portKey,
+				Namespace: n.Service.Namespace,
+				Labels:    n.BuildLabels(),
+			},
+			Spec: frpv1alpha1.UpstreamSpec{
+				Client:    client,
+				ClientRef: clientRef,
+				Enabled:   ptr.To(true),
+			},
+		}
+
+		switch proxyType {
+		case "tcp":
+			upstream.Spec.TCP = &frpv1alpha1.UpstreamSpec_TCP{
+				ServiceRef: serviceRef,
+				Server:     frpv1alpha1.UpstreamSpec_TCP_Server{Port: remotePort + i},
+			}
+		case "http":
+			portSubdomain := subdomain
+			if subdomain != "" && len(ports) > 1 {
+				portSubdomain = subdomain + "-" + portKey
+			}
+			upstream.Spec.HTTP = &frpv1alpha1.UpstreamSpec_HTTP{
+				ServiceRef:    serviceRef,
+				Subdomain:     portSubdomain,
+				CustomDomains: customDomains,
+			}
+		case "https":
+			upstream.Spec.HTTPS = &frpv1alpha1.UpstreamSpec_HTTPS{
+				ServiceRef:    serviceRef,
+				CustomDomains: customDomains,
+			}
+		}
+
+		upstreams = append(upstreams, upstream)
+	}
+
+	return upstreams, nil
+}
+
+func (n *ServiceUpstreamBuilder) BuildLabels() map[string]string {
+	var labels = map[string]string{
+		"app.kubernetes.io/managed-by": "frp-operator",
+		"app.kubernetes.io/created-by": n.Service.Name,
+		ExposeServiceLabel:             n.Service.Name,
+	}
+
+	return labels
+}
+
+// parseClientAnnotation returns the Client of a "name" or "namespace/name" value
+func parseClientAnnotation(value string) (string, *frpv1alpha1.ClientRef, error) {
+	namespace, name, found := strings.Cut(value, "/")
+	if !found {
+		namespace, name = "", value
+	}
+	if name == "" || (found && namespace == "") {
+		return "", nil, fmt.Errorf("annotation %s must be a Client name or namespace/name, got %q", ExposeClientAnnotation, value)
+	}
+
+	if namespace == "" {
+		return name, nil, nil
+	}
+
+	return "", &frpv1alpha1.ClientRef{Name: name, Namespace: namespace}, nil
+}
+
+// servicePortKey names a Service port by its name, or by its number when unnamed
+func servicePortKey(port corev1.ServicePort) string {
+	if port.Name != "" {
+		return port.Name
+	}
+
+	return strconv.Itoa(int(port.Port))
+}
@@ -0,0 +1,178 @@
+package builder
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func newExposedService(annotations map[string]string, ports ...corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "team-a",
+			Annotations: annotations,
+		},
+		Spec: corev1.ServiceSpec{Ports: ports},
+	}
+}
+
+func TestServiceUpstreamBuilder_NotExposed(t *testing.T) {
+	service := newExposedService(nil, corev1.ServicePort{Name: "http", Port: 80})
+
+	upstreams, err := NewServiceUpstreamBuilder().SetService(service).Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error = %v", err)
+	}
+	if len(upstreams) != 0 {
+		t.Errorf("Build() = %d upstreams, want none without the client annotation", len(upstreams))
+	}
+}
+
+func TestServiceUpstreamBuilder_TCP(t *testing.T) {
+	service := newExposedService(map[string]string{
+		ExposeClientAnnotation:     "edge",
+		ExposeRemotePortAnnotation: "15432",
+	},
+		corev1.ServicePort{Name: "postgres", Port: 5432},
+		corev1.ServicePort{Port: 9187},
+		corev1.ServicePort{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP},
+	)
+
+	upstreams, err := NewServiceUpstreamBuilder().SetService(service).Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error = %v", err)
+	}
+	if len(upstreams) != 2 {
+		t.Fatalf("Build() = %d upstreams, want 2, UDP ports are skipped", len(upstreams))
+	}
+
+	first, second := upstreams[0], upstreams[1]
+	if first.Name != "web-postgres" || second.Name != "web-9187" {
+		t.Errorf("Build() names = %s, %s, want web-postgres, web-9187", first.Name, second.Name)
+	}
+	if first.Namespace != "team-a" || first.Spec.Client != "edge" || first.Spec.ClientRef != nil {
+		t.Errorf("Build() bound to %s/%s %v, want client edge in team-a", first.Namespace, first.Spec.Client, first.Spec.ClientRef)
+	}
+	if first.Labels[ExposeServiceLabel] != "web" {
+		t.Errorf("Build() label %s = %q, want web", ExposeServiceLabel, first.Labels[ExposeServiceLabel])
+	}
+	if first.Spec.TCP.Server.Port != 15432 || second.Spec.TCP.Server.Port != 15433 {
+		t.Errorf("Build() remote ports = %d, %d, want 15432, 15433", first.Spec.TCP.Server.Port, second.Spec.TCP.Server.Port)
+	}
+	if first.Spec.TCP.ServiceRef.Name != "web" || first.Spec.TCP.ServiceRef.Port != intstr.FromString("postgres") {
+		t.Errorf("Build() serviceRef = %+v, want web port postgres", first.Spec.TCP.ServiceRef)
+	}
+	if second.Spec.TCP.ServiceRef.Port != intstr.FromInt32(9187) {
+		t.Errorf("Build() serviceRef port = %v, want 9187 for an unnamed port", second.Spec.TCP.ServiceRef.Port)
+	}
+}
+
+func TestServiceUpstreamBuilder_HTTP(t *testing.T) {
+	service := newExposedService(map[string]string{
+		ExposeClientAnnotation:    "frp-system/edge",
+		ExposeTypeAnnotation:      "http",
+		ExposeSubdomainAnnotation: "web",
+	},
+		corev1.ServicePort{Name: "http", Port: 80},
+		corev1.ServicePort{Name: "admin", Port: 8081},
+	)
+
+	upstreams, err := NewServiceUpstreamBuilder().SetService(service).Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error = %v", err)
+	}
+	if len(upstreams) != 2 {
+		t.Fatalf("Build() = %d upstreams, want 2", len(upstreams))
+	}
+
+	if ref := upstreams[0].Spec.ClientRef; ref == nil || ref.Name != "edge" || ref.Namespace != "frp-system" {
+		t.Errorf("Build() clientRef = %+v, want frp-system/edge", ref)
+	}
+	if upstreams[0].Spec.HTTP.Subdomain != "web-http" || upstreams[1].Spec.HTTP.Subdomain != "web-admin" {
+		t.Errorf("Build() subdomains = %s, %s, want web-http, web-admin",
+			upstreams[0].Spec.HTTP.Subdomain, upstreams[1].Spec.HTTP.Subdomain)
+	}
+}
+
+func TestServiceUpstreamBuilder_HTTPS(t *testing.T) {
+	service := newExposedService(map[string]string{
+		ExposeClientAnnotation:        "edge",
+		ExposeTypeAnnotation:          "https",
+		ExposeCustomDomainsAnnotation: "web.example.com, www.example.com",
+	}, corev1.ServicePort{Name: "https", Port: 443})
+
+	upstreams, err := NewServiceUpstreamBuilder().SetService(service).Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error = %v", err)
+	}
+
+	domains := upstreams[0].Spec.HTTPS.CustomDomains
+	if len(domains) != 2 || domains[0] != "web.example.com" || domains[1] != "www.example.com" {
+		t.Errorf("Build() customDomains = %v, want [web.example.com www.example.com]", domains)
+	}
+}
+
+func TestServiceUpstreamBuilder_InvalidAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		ports       []corev1.ServicePort
+	}{
+		{
+			name:        "empty client namespace",
+			annotations: map[string]string{ExposeClientAnnotation: "/edge", ExposeRemotePortAnnotation: "8080"},
+		},
+		{
+			name:        "unknown type",
+			annotations: map[string]string{ExposeClientAnnotation: "edge", ExposeTypeAnnotation: "udp"},
+		},
+		{
+			name:        "tcp without remote port",
+			annotations: map[string]string{ExposeClientAnnotation: "edge"},
+		},
+		{
+			name:        "tcp remote ports out of range",
+			annotations: map[string]string{ExposeClientAnnotation: "edge", ExposeRemotePortAnnotation: "65535"},
+			ports:       []corev1.ServicePort{{Name: "a", Port: 80}, {Name: "b", Port: 81}},
+		},
+		{
+			name:        "http without subdomain or domains",
+			annotations: map[string]string{ExposeClientAnnotation: "edge", ExposeTypeAnnotation: "http"},
+		},
+		{
+			name:        "https without domains",
+			annotations: map[string]string{ExposeClientAnnotation: "edge", ExposeTypeAnnotation: "https"},
+		},
+		{
+			name: "custom domains with several ports",
+			annotations: map[string]string{
+				ExposeClientAnnotation:        "edge",
+				ExposeTypeAnnotation:          "http",
+				ExposeCustomDomainsAnnotation: "web.example.com",
+			},
+			ports: []corev1.ServicePort{{Name: "a", Port: 80}, {Name: "b", Port: 81}},
+		},
+		{
+			name:        "no TCP port",
+			annotations: map[string]string{ExposeClientAnnotation: "edge", ExposeRemotePortAnnotation: "8080"},
+			ports:       []corev1.ServicePort{{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ports := tt.ports
+			if ports == nil {
+				ports = []corev1.ServicePort{{Name: "http", Port: 80}}
+			}
+
+			_, err := NewServiceUpstreamBuilder().SetService(newExposedService(tt.annotations, ports...)).Build()
+			if err == nil {
+				t.Errorf("Build() expected an error")
+			}
+		})
+	}
+}
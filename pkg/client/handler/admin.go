@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+)
+
+// ADMIN_REQUEST_TIMEOUT bounds a call to the frpc admin API
+const ADMIN_REQUEST_TIMEOUT = 5 * time.Second
+
+// adminRequest calls an endpoint of the frpc admin API with the admin credentials
+// of the configuration. It returns the body of a 200 response, other responses
+// are returned as a ResponseError.
+func adminRequest(clientCfg models.Config, method string, path string, body io.Reader) ([]byte, error) {
+	if clientCfg.Common.AdminPort == 0 {
+		return nil, fmt.Errorf("admin port should be set to call %s on the frpc admin API", path)
+	}
+
+	address := net.JoinHostPort(clientCfg.Common.AdminAddress, strconv.Itoa(clientCfg.Common.AdminPort))
+	request, err := http.NewRequest(method, "http://"+address+path, body)
+	if err != nil {
+		return nil, err
+	}
+	request.SetBasicAuth(clientCfg.Common.AdminUsername, clientCfg.Common.AdminPassword)
+
+	client := http.Client{
+		Timeout: ADMIN_REQUEST_TIMEOUT,
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, &ResponseError{Code: response.StatusCode, Body: strings.TrimSpace(string(responseBody))}
+	}
+
+	return responseBody, nil
+}
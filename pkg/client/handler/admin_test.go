@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+)
+
+func TestAdminRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("unauthorized\n"))
+			return
+		}
+
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	clientCfg := newTestConfig(t, server)
+	body, err := adminRequest(clientCfg, http.MethodPut, "/api/config", strings.NewReader("serverPort = 7000"))
+	if err != nil {
+		t.Fatalf("adminRequest() unexpected error = %v", err)
+	}
+	if string(body) != "PUT /api/config" {
+		t.Errorf("adminRequest() = %q, want PUT /api/config", body)
+	}
+
+	clientCfg.Common.AdminPassword = "wrong"
+	_, err = adminRequest(clientCfg, http.MethodGet, "/api/status", nil)
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) || responseErr.Code != http.StatusUnauthorized || responseErr.Body != "unauthorized" {
+		t.Errorf("adminRequest() error = %v, want a 401 ResponseError", err)
+	}
+
+	if _, err := adminRequest(models.Config{}, http.MethodGet, "/api/status", nil); err == nil {
+		t.Error("adminRequest() expected error without an admin port")
+	}
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/utils"
+)
+
+// Config returns the configuration file frpc runs with from the /api/config endpoint
+func Config(clientCfg models.Config) (string, error) {
+	body, err := adminRequest(clientCfg, http.MethodGet, "/api/config", nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+// PutConfig uploads a configuration to the /api/config endpoint, frpc writes it to
+// the file it runs with and applies it on the next reload
+func PutConfig(clientCfg models.Config, config string) error {
+	_, err := adminRequest(clientCfg, http.MethodPut, "/api/config", strings.NewReader(config))
+	return err
+}
+
+// HasVisitor reports whether a frpc configuration declares a visitor with the given name
+func HasVisitor(config string, name string) (bool, error) {
+	clientConfig := utils.ClientConfig{}
+	if _, err := toml.Decode(config, &clientConfig); err != nil {
+		return false, err
+	}
+
+	for _, visitor := range clientConfig.Visitors {
+		if visitor.Name == name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testClientConfig = `
+serverAddr = "frp.example.com"
+serverPort = 7000
+
+[[proxies]]
+name = "ssh"
+type = "tcp"
+localPort = 22
+remotePort = 6000
+
+[[visitors]]
+name = "db"
+type = "stcp"
+serverName = "db"
+secretKey = "secret"
+bindAddr = "0.0.0.0"
+bindPort = 5432
+`
+
+func TestConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/config" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = w.Write([]byte(testClientConfig))
+	}))
+	defer server.Close()
+
+	config, err := Config(newTestConfig(t, server))
+	if err != nil {
+		t.Fatalf("Config() unexpected error = %v", err)
+	}
+	if config != testClientConfig {
+		t.Errorf("Config() = %q, want %q", config, testClientConfig)
+	}
+}
+
+func TestConfig_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("read config file error"))
+	}))
+	defer server.Close()
+
+	_, err := Config(newTestConfig(t, server))
+	if err == nil {
+		t.Error("Config() expected error for failed response")
+	}
+}
+
+func TestPutConfig(t *testing.T) {
+	var uploaded string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/api/config" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		uploaded = string(body)
+	}))
+	defer server.Close()
+
+	if err := PutConfig(newTestConfig(t, server), testClientConfig); err != nil {
+		t.Fatalf("PutConfig() unexpected error = %v", err)
+	}
+	if uploaded != testClientConfig {
+		t.Errorf("PutConfig() uploaded %q, want %q", uploaded, testClientConfig)
+	}
+}
+
+func TestPutConfig_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("body can't be empty"))
+	}))
+	defer server.Close()
+
+	err := PutConfig(newTestConfig(t, server), "")
+	if err == nil || !strings.Contains(err.Error(), "body can't be empty") {
+		t.Errorf("PutConfig() error = %v, want the response of frpc", err)
+	}
+}
+
+func TestReload_ResponseError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/reload" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("reload frpc proxy config error: proxy [ssh] remotePort is invalid\n"))
+	}))
+	defer server.Close()
+
+	err := Reload(newTestConfig(t, server))
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) {
+		t.Fatalf("Reload() error = %v, want a ResponseError", err)
+	}
+	if responseErr.Code != http.StatusBadRequest || responseErr.Body != "reload frpc proxy config error: proxy [ssh] remotePort is invalid" {
+		t.Errorf("Reload() error = %+v", responseErr)
+	}
+
+	server.Close()
+	err = Reload(newTestConfig(t, server))
+	if err == nil || errors.As(err, &responseErr) {
+		t.Errorf("Reload() error = %v, want a connection error", err)
+	}
+}
+
+func TestHasVisitor(t *testing.T) {
+	tests := []struct {
+		name    string
+		visitor string
+		want    bool
+	}{
+		{name: "declared visitor", visitor: "db", want: true},
+		{name: "proxy is not a visitor", visitor: "ssh", want: false},
+		{name: "unknown visitor", visitor: "cache", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HasVisitor(testClientConfig, tt.visitor)
+			if err != nil {
+				t.Fatalf("HasVisitor() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("HasVisitor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasVisitor_DecodesTOML(t *testing.T) {
+	// a visitor name written as a literal string after other keys, and a proxy
+	// named like the visitors table
+	config := `
+[[visitors]]
+type = "stcp"
+serverName = "db"
+name = 'db-literal'
+
+[[proxies]]
+name = "visitors"
+type = "tcp"
+[proxies.plugin]
+type = "http_proxy"
+`
+	got, err := HasVisitor(config, "db-literal")
+	if err != nil || !got {
+		t.Errorf("HasVisitor() = %v, %v, want the visitor declared with a literal string", got, err)
+	}
+
+	got, err = HasVisitor(config, "visitors")
+	if err != nil || got {
+		t.Errorf("HasVisitor() = %v, %v, want a proxy name not to match", got, err)
+	}
+
+	if _, err := HasVisitor("[[visitors]\nname = ", "db"); err == nil {
+		t.Error("HasVisitor() expected an error for an invalid configuration")
+	}
+}
@@ -0,0 +1,14 @@
+package handler
+
+import "fmt"
+
+// ResponseError is an error response of the frpc admin API, frpc received the
+// request and refused it, unlike a connection error
+type ResponseError struct {
+	Code int
+	Body string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("code [%d], %s", e.Code, e.Body)
+}
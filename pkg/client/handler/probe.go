@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+)
+
+// Probe checks that a server accepts TCP connections from the operator pod, which
+// may reach servers frpc pods can't, or the other way around. kcp and quic servers
+// listen on UDP and can't be probed, the Client webhook rejects failover with them
+// and they are reported reachable when the webhook is bypassed.
+func Probe(candidate models.ServerCandidate, timeout time.Duration) error {
+	if protocol := candidate.Server.Protocol; protocol != nil &&
+		(models.IsProtocol(*protocol, "kcp") || models.IsProtocol(*protocol, "quic")) {
+		return nil
+	}
+
+	connection, err := net.DialTimeout("tcp", candidate.Endpoint, timeout)
+	if err != nil {
+		return err
+	}
+
+	return connection.Close()
+}
+
+// ProbeAll probes the candidates concurrently and returns the result of every
+// candidate, it takes at most one timeout whatever the number of candidates
+func ProbeAll(candidates []models.ServerCandidate, timeout time.Duration) []error {
+	probeErrs := make([]error, len(candidates))
+
+	var wg sync.WaitGroup
+	for i, candidate := range candidates {
+		wg.Add(1)
+		go func(i int, candidate models.ServerCandidate) {
+			defer wg.Done()
+			probeErrs[i] = Probe(candidate, timeout)
+		}(i, candidate)
+	}
+	wg.Wait()
+
+	return probeErrs
+}
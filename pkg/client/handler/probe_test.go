@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+)
+
+func TestProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	endpoint := listener.Addr().String()
+
+	if err := Probe(models.ServerCandidate{Endpoint: endpoint}, time.Second); err != nil {
+		t.Errorf("Probe() unexpected error = %v", err)
+	}
+
+	listener.Close()
+	if err := Probe(models.ServerCandidate{Endpoint: endpoint}, time.Second); err == nil {
+		t.Error("Probe() expected error for a closed port")
+	}
+
+	quic := "quic"
+	candidate := models.ServerCandidate{Endpoint: endpoint, Server: frpv1alpha1.ClientSpec_Server{Protocol: &quic}}
+	if err := Probe(candidate, time.Second); err != nil {
+		t.Errorf("Probe() unexpected error for a quic server = %v", err)
+	}
+}
+
+func TestProbeAll(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	closed.Close()
+
+	candidates := []models.ServerCandidate{
+		{Endpoint: closed.Addr().String()},
+		{Endpoint: listener.Addr().String()},
+	}
+
+	probeErrs := ProbeAll(candidates, time.Second)
+	if len(probeErrs) != 2 || probeErrs[0] == nil || probeErrs[1] != nil {
+		t.Errorf("ProbeAll() = %v, want the closed port unreachable and the listener reachable", probeErrs)
+	}
+}
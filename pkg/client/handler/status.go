@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+)
+
+// Proxy states reported by frpc
+const (
+	ProxyStatusNew         = "new"
+	ProxyStatusWaitStart   = "wait start"
+	ProxyStatusStartError  = "start error"
+	ProxyStatusRunning     = "running"
+	ProxyStatusCheckFailed = "check failed"
+	ProxyStatusClosed      = "closed"
+)
+
+// ProxyStatus is a proxy as reported by the frpc /api/status endpoint
+type ProxyStatus struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Status     string `json:"status"`
+	Err        string `json:"err"`
+	LocalAddr  string `json:"local_addr"`
+	Plugin     string `json:"plugin"`
+	RemoteAddr string `json:"remote_addr"`
+}
+
+// StatusResponse groups proxies by proxy type
+type StatusResponse map[string][]ProxyStatus
+
+// Find returns the proxy with the given name
+func (s StatusResponse) Find(name string) (ProxyStatus, bool) {
+	for _, proxies := range s {
+		for _, proxy := range proxies {
+			if proxy.Name == name {
+				return proxy, true
+			}
+		}
+	}
+
+	return ProxyStatus{}, false
+}
+
+// StartErrors returns the proxies frpc failed to start
+func (s StatusResponse) StartErrors() []ProxyStatus {
+	proxies := []ProxyStatus{}
+	for _, typeProxies := range s {
+		for _, proxy := range typeProxies {
+			if proxy.Status == ProxyStatusStartError {
+				proxies = append(proxies, proxy)
+			}
+		}
+	}
+
+	sort.Slice(proxies, func(i, j int) bool {
+		return proxies[i].Name < proxies[j].Name
+	})
+
+	return proxies
+}
+
+// Starting reports whether frpc is still starting a proxy
+func (s StatusResponse) Starting() bool {
+	for _, proxies := range s {
+		for _, proxy := range proxies {
+			if proxy.Status == ProxyStatusNew || proxy.Status == ProxyStatusWaitStart {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func Status(clientCfg models.Config) (StatusResponse, error) {
+	body, err := adminRequest(clientCfg, http.MethodGet, "/api/status", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status := StatusResponse{}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/client/models"
+)
+
+func newTestConfig(t *testing.T, server *httptest.Server) models.Config {
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse server url: %v", err)
+	}
+	port, err := strconv.Atoi(serverURL.Port())
+	if err != nil {
+		t.Fatalf("failed to parse server port: %v", err)
+	}
+
+	return models.Config{
+		Common: models.Common{
+			AdminAddress:  serverURL.Hostname(),
+			AdminPort:     port,
+			AdminUsername: "admin",
+			AdminPassword: "secret",
+		},
+	}
+}
+
+func TestStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/status" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{
+			"tcp": [
+				{"name": "ssh", "type": "tcp", "status": "running", "err": "", "local_addr": "127.0.0.1:22", "plugin": "", "remote_addr": ":6000"},
+				{"name": "db", "type": "tcp", "status": "start error", "err": "port already used", "local_addr": "127.0.0.1:5432", "plugin": "", "remote_addr": ""}
+			],
+			"http": [
+				{"name": "web", "type": "http", "status": "wait start", "err": "", "local_addr": "127.0.0.1:80", "plugin": "", "remote_addr": ""}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	status, err := Status(newTestConfig(t, server))
+	if err != nil {
+		t.Fatalf("Status() unexpected error = %v", err)
+	}
+
+	ssh, ok := status.Find("ssh")
+	if !ok {
+		t.Fatal("Status() expected proxy ssh")
+	}
+	if ssh.Status != ProxyStatusRunning || ssh.RemoteAddr != ":6000" {
+		t.Errorf("Status() ssh = %+v", ssh)
+	}
+
+	db, ok := status.Find("db")
+	if !ok {
+		t.Fatal("Status() expected proxy db")
+	}
+	if db.Status != ProxyStatusStartError || db.Err != "port already used" {
+		t.Errorf("Status() db = %+v", db)
+	}
+
+	if _, ok := status.Find("missing"); ok {
+		t.Error("Status() unexpected proxy missing")
+	}
+
+	startErrors := status.StartErrors()
+	if len(startErrors) != 1 || startErrors[0].Name != "db" {
+		t.Errorf("StartErrors() = %+v, want db", startErrors)
+	}
+	if !status.Starting() {
+		t.Error("Starting() = false, want true for the web proxy waiting to start")
+	}
+}
+
+func TestStatusResponse_Started(t *testing.T) {
+	status := StatusResponse{
+		"tcp": {
+			{Name: "ssh", Status: ProxyStatusRunning},
+			{Name: "db", Status: ProxyStatusCheckFailed},
+		},
+	}
+
+	if status.Starting() {
+		t.Error("Starting() = true, want false when every proxy started")
+	}
+	if startErrors := status.StartErrors(); len(startErrors) != 0 {
+		t.Errorf("StartErrors() = %+v, want none", startErrors)
+	}
+}
+
+func TestStatus_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("unauthorized"))
+	}))
+	defer server.Close()
+
+	_, err := Status(newTestConfig(t, server))
+	if err == nil {
+		t.Error("Status() expected error for unauthorized response")
+	}
+}
+
+func TestStatus_AdminPortRequired(t *testing.T) {
+	_, err := Status(models.Config{})
+	if err == nil {
+		t.Error("Status() expected error when admin port is not set")
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+// Keys of the generated admin credentials Secret
+const (
+	ADMIN_SECRET_USERNAME_KEY = "username"
+	ADMIN_SECRET_PASSWORD_KEY = "password"
+)
+
+// ADMIN_PASSWORD_BYTES is the amount of random bytes in a generated admin password
+const ADMIN_PASSWORD_BYTES = 24
+
+// RotateAdminCredentialsAnnotation on a Client requests new admin credentials,
+// they are regenerated every time the value of the annotation changes
+const RotateAdminCredentialsAnnotation = "frp.zufardhiyaulhaq.com/rotate-admin-credentials"
+
+// AdminSecretName returns the name of the Secret holding the generated admin credentials of a client
+func AdminSecretName(clientName string) string {
+	return clientName + "-frpc-admin"
+}
+
+// GenerateAdminPassword returns a random password for the frpc admin server
+func GenerateAdminPassword() (string, error) {
+	password := make([]byte, ADMIN_PASSWORD_BYTES)
+	if _, err := rand.Read(password); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(password), nil
+}
+
+// AdminCredentialsHash returns a short hash of the admin credentials, it changes
+// whenever the credentials frpc has to be started with change
+func AdminCredentialsHash(username string, password string) string {
+	hash := sha256.Sum256([]byte(username + ":" + password))
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// setGeneratedAdminCredentials applies the credentials from the generated admin
+// Secret of a client to common, the Secret is read when not given
+func setGeneratedAdminCredentials(k8sclient client.Client, clientObject *frpv1alpha1.Client, secret *corev1.Secret, common *Common) error {
+	if secret == nil {
+		secret = &corev1.Secret{}
+		err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: AdminSecretName(clientObject.Name), Namespace: clientObject.Namespace}, secret)
+		if err != nil {
+			return err
+		}
+	}
+
+	if username, ok := secret.Data[ADMIN_SECRET_USERNAME_KEY]; ok {
+		common.AdminUsername = string(username)
+	}
+	if password, ok := secret.Data[ADMIN_SECRET_PASSWORD_KEY]; ok {
+		common.AdminPassword = string(password)
+	}
+
+	return nil
+}
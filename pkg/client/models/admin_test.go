@@ -0,0 +1,96 @@
+package models
+
+import (
+	"testing"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGenerateAdminPassword(t *testing.T) {
+	first, err := GenerateAdminPassword()
+	if err != nil {
+		t.Fatalf("GenerateAdminPassword() error = %v", err)
+	}
+	second, err := GenerateAdminPassword()
+	if err != nil {
+		t.Fatalf("GenerateAdminPassword() error = %v", err)
+	}
+
+	if len(first) != ADMIN_PASSWORD_BYTES*2 {
+		t.Errorf("GenerateAdminPassword() length = %v, want %v", len(first), ADMIN_PASSWORD_BYTES*2)
+	}
+	if first == second {
+		t.Errorf("GenerateAdminPassword() returned the same password twice")
+	}
+}
+
+func TestAdminCredentialsHash(t *testing.T) {
+	hash := AdminCredentialsHash("frpc-user", "password")
+	if len(hash) != 16 {
+		t.Errorf("AdminCredentialsHash() length = %v, want 16", len(hash))
+	}
+	if hash != AdminCredentialsHash("frpc-user", "password") {
+		t.Errorf("AdminCredentialsHash() is not stable")
+	}
+	if hash == AdminCredentialsHash("frpc-user", "rotated") {
+		t.Errorf("AdminCredentialsHash() didn't change with the password")
+	}
+}
+
+func TestNewConfig_AdminServerOverridesGeneratedCredentials(t *testing.T) {
+	adminSecret := createSecret("default", "admin-secret", map[string][]byte{
+		"password": []byte("custom-password"),
+	})
+	fakeClient := createFakeClient(createDefaultTokenSecret("default"), adminSecret).Build()
+
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+	clientObj.Spec.Server.AdminServer = &frpv1alpha1.ClientSpec_Server_AdminServer{
+		Port: 7400,
+		Password: &frpv1alpha1.ClientSpec_Server_AdminServer_Password{
+			Secret: frpv1alpha1.Secret{Name: "admin-secret", Key: "password"},
+		},
+	}
+
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	if config.Common.AdminUsername != DEFAULT_ADMIN_USERNAME {
+		t.Errorf("NewConfig() AdminUsername = %v, want %v", config.Common.AdminUsername, DEFAULT_ADMIN_USERNAME)
+	}
+	if config.Common.AdminPassword != "custom-password" {
+		t.Errorf("NewConfig() AdminPassword = %v, want %v", config.Common.AdminPassword, "custom-password")
+	}
+}
+
+func TestNewConfig_WrittenAdminSecret(t *testing.T) {
+	// the cache holds the admin Secret from before the rotation, or none yet
+	fakeClient := createFakeClient(createDefaultTokenSecret("default")).Build()
+
+	written := createAdminSecret("default", "test-client")
+	written.Data[ADMIN_SECRET_PASSWORD_KEY] = []byte("rotated-password")
+
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+	config, err := NewConfig(fakeClient, clientObj, written, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+	if config.Common.AdminPassword != "rotated-password" {
+		t.Errorf("NewConfig() AdminPassword = %v, want the written rotated-password", config.Common.AdminPassword)
+	}
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(createDefaultTokenSecret("default")).Build()
+	config, err = NewConfig(fakeClient, clientObj, written, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error for an admin Secret missing from the cache = %v", err)
+	}
+	if config.Common.AdminPassword != "rotated-password" {
+		t.Errorf("NewConfig() AdminPassword = %v, want the written rotated-password", config.Common.AdminPassword)
+	}
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+// UpstreamClientKey returns the namespaced name of the Client an Upstream is bound to
+func UpstreamClientKey(upstream *frpv1alpha1.Upstream) types.NamespacedName {
+	return upstream.ClientKey()
+}
+
+// VisitorClientKey returns the namespaced name of the Client a Visitor is bound to
+func VisitorClientKey(visitor *frpv1alpha1.Visitor) types.NamespacedName {
+	return visitor.ClientKey()
+}
+
+// NamespaceAllowed reports whether objects in the namespace may bind to the Client.
+// The Client namespace is always allowed, other namespaces must be listed by name
+// or match the namespace selector in spec.allowedNamespaces.
+func NamespaceAllowed(clientObject *frpv1alpha1.Client, namespace string, namespaceLabels map[string]string) (bool, error) {
+	if namespace == "" || namespace == clientObject.Namespace {
+		return true, nil
+	}
+
+	allowed := clientObject.Spec.AllowedNamespaces
+	if allowed == nil {
+		return false, nil
+	}
+
+	for _, name := range allowed.Names {
+		if name == namespace {
+			return true, nil
+		}
+	}
+
+	if allowed.Selector == nil {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(allowed.Selector)
+	if err != nil {
+		return false, err
+	}
+
+	return selector.Matches(labels.Set(namespaceLabels)), nil
+}
+
+// BindingName returns the name an Upstream or Visitor is rendered under in the
+// Client configuration. Objects from another namespace are prefixed with their
+// namespace so that equal names in different namespaces do not collide.
+func BindingName(name string, namespace string, clientNamespace string) string {
+	if namespace == "" || namespace == clientNamespace {
+		return name
+	}
+
+	return namespace + "." + name
+}
+
+// UpstreamRendered reports whether a Client renders the proxy of an Upstream at now,
+// with the reason when it doesn't. The namespace of the Upstream must already be
+// allowed by the Client.
+func UpstreamRendered(clientObject *frpv1alpha1.Client, upstream *frpv1alpha1.Upstream, now time.Time) (bool, string) {
+	if !upstream.Enabled() {
+		return false, "upstream is disabled"
+	}
+
+	schedule, err := UpstreamSchedule(upstream, now)
+	if err != nil {
+		return false, err.Error()
+	}
+	if !schedule.Active {
+		return false, "upstream is outside of its schedule"
+	}
+
+	if upstream.RemotePort() == 0 && upstream.AllocatesPort() {
+		return false, "waiting for a port from a PortPool"
+	}
+
+	if Replicas(clientObject) > 1 && !upstream.Replicable() {
+		return false, "upstream can't be shared by frpc replicas"
+	}
+
+	return true, ""
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestUpstreamClientKey(t *testing.T) {
+	tests := []struct {
+		name string
+		spec frpv1alpha1.UpstreamSpec
+		want types.NamespacedName
+	}{
+		{
+			name: "client in the same namespace",
+			spec: frpv1alpha1.UpstreamSpec{Client: "edge"},
+			want: types.NamespacedName{Name: "edge", Namespace: "team-a"},
+		},
+		{
+			name: "clientRef without namespace",
+			spec: frpv1alpha1.UpstreamSpec{ClientRef: &frpv1alpha1.ClientRef{Name: "edge"}},
+			want: types.NamespacedName{Name: "edge", Namespace: "team-a"},
+		},
+		{
+			name: "clientRef in another namespace",
+			spec: frpv1alpha1.UpstreamSpec{ClientRef: &frpv1alpha1.ClientRef{Name: "edge", Namespace: "frp-system"}},
+			want: types.NamespacedName{Name: "edge", Namespace: "frp-system"},
+		},
+		{
+			name: "clientRef takes precedence over client",
+			spec: frpv1alpha1.UpstreamSpec{Client: "local", ClientRef: &frpv1alpha1.ClientRef{Name: "edge", Namespace: "frp-system"}},
+			want: types.NamespacedName{Name: "edge", Namespace: "frp-system"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := &frpv1alpha1.Upstream{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a"},
+				Spec:       tt.spec,
+			}
+			if got := UpstreamClientKey(upstream); got != tt.want {
+				t.Errorf("UpstreamClientKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisitorClientKey(t *testing.T) {
+	visitor := &frpv1alpha1.Visitor{
+		ObjectMeta: metav1.ObjectMeta{Name: "ssh", Namespace: "team-a"},
+		Spec: frpv1alpha1.VisitorSpec{
+			ClientRef: &frpv1alpha1.ClientRef{Name: "edge", Namespace: "frp-system"},
+		},
+	}
+
+	want := types.NamespacedName{Name: "edge", Namespace: "frp-system"}
+	if got := VisitorClientKey(visitor); got != want {
+		t.Errorf("VisitorClientKey() = %v, want %v", got, want)
+	}
+}
+
+func TestNamespaceAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowed   *frpv1alpha1.ClientSpec_AllowedNamespaces
+		namespace string
+		labels    map[string]string
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name:      "same namespace is always allowed",
+			namespace: "frp-system",
+			want:      true,
+		},
+		{
+			name:      "other namespace is denied by default",
+			namespace: "team-a",
+			want:      false,
+		},
+		{
+			name:      "other namespace allowed by name",
+			allowed:   &frpv1alpha1.ClientSpec_AllowedNamespaces{Names: []string{"team-b", "team-a"}},
+			namespace: "team-a",
+			want:      true,
+		},
+		{
+			name:      "other namespace not listed",
+			allowed:   &frpv1alpha1.ClientSpec_AllowedNamespaces{Names: []string{"team-b"}},
+			namespace: "team-a",
+			want:      false,
+		},
+		{
+			name: "other namespace allowed by selector",
+			allowed: &frpv1alpha1.ClientSpec_AllowedNamespaces{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"frp.zufardhiyaulhaq.com/expose": "true"}},
+			},
+			namespace: "team-a",
+			labels:    map[string]string{"frp.zufardhiyaulhaq.com/expose": "true"},
+			want:      true,
+		},
+		{
+			name: "other namespace not matching selector",
+			allowed: &frpv1alpha1.ClientSpec_AllowedNamespaces{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"frp.zufardhiyaulhaq.com/expose": "true"}},
+			},
+			namespace: "team-a",
+			labels:    map[string]string{"team": "a"},
+			want:      false,
+		},
+		{
+			name: "invalid selector",
+			allowed: &frpv1alpha1.ClientSpec_AllowedNamespaces{
+				Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: "Unknown"},
+				}},
+			},
+			namespace: "team-a",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientObj := createBasicClient("frp-system", "edge", "frp.example.com", 7000)
+			clientObj.Spec.AllowedNamespaces = tt.allowed
+
+			got, err := NamespaceAllowed(clientObj, tt.namespace, tt.labels)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NamespaceAllowed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NamespaceAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindingName(t *testing.T) {
+	if got := BindingName("web", "frp-system", "frp-system"); got != "web" {
+		t.Errorf("BindingName() = %v, want web", got)
+	}
+	if got := BindingName("web", "", "frp-system"); got != "web" {
+		t.Errorf("BindingName() = %v, want web", got)
+	}
+	if got := BindingName("web", "team-a", "frp-system"); got != "team-a.web" {
+		t.Errorf("BindingName() = %v, want team-a.web", got)
+	}
+}
+
+func TestUpstreamRendered(t *testing.T) {
+	now := time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC)
+	disabled := false
+
+	tests := []struct {
+		name     string
+		replicas int32
+		spec     frpv1alpha1.UpstreamSpec
+		want     bool
+	}{
+		{
+			name: "tcp upstream",
+			spec: frpv1alpha1.UpstreamSpec{TCP: &frpv1alpha1.UpstreamSpec_TCP{Server: frpv1alpha1.UpstreamSpec_TCP_Server{Port: 8080}}},
+			want: true,
+		},
+		{
+			name: "disabled",
+			spec: frpv1alpha1.UpstreamSpec{
+				Enabled: &disabled,
+				TCP:     &frpv1alpha1.UpstreamSpec_TCP{Server: frpv1alpha1.UpstreamSpec_TCP_Server{Port: 8080}},
+			},
+		},
+		{
+			name: "outside of its schedule",
+			spec: frpv1alpha1.UpstreamSpec{
+				TCP: &frpv1alpha1.UpstreamSpec_TCP{Server: frpv1alpha1.UpstreamSpec_TCP_Server{Port: 8080}},
+				Schedule: &frpv1alpha1.UpstreamSpec_Schedule{
+					Windows: []frpv1alpha1.UpstreamSpec_Schedule_Window{createWindow("0 9 * * 1-5", 8*time.Hour)},
+				},
+			},
+		},
+		{
+			name: "waiting for a port from a PortPool",
+			spec: frpv1alpha1.UpstreamSpec{TCP: &frpv1alpha1.UpstreamSpec_TCP{}},
+		},
+		{
+			name:     "udp upstream shared by replicas",
+			replicas: 2,
+			spec:     frpv1alpha1.UpstreamSpec{UDP: &frpv1alpha1.UpstreamSpec_UDP{Server: frpv1alpha1.UpstreamSpec_UDP_Server{Port: 5353}}},
+		},
+		{
+			name:     "tcp upstream shared by replicas",
+			replicas: 2,
+			spec:     frpv1alpha1.UpstreamSpec{TCP: &frpv1alpha1.UpstreamSpec_TCP{Server: frpv1alpha1.UpstreamSpec_TCP_Server{Port: 8080}}},
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientObj := createBasicClient("default", "edge", "frp.example.com", 7000)
+			if tt.replicas != 0 {
+				clientObj.Spec.Replicas = int32Ptr(tt.replicas)
+			}
+			upstream := &frpv1alpha1.Upstream{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       tt.spec,
+			}
+
+			got, reason := UpstreamRendered(clientObj, upstream, now)
+			if got != tt.want {
+				t.Errorf("UpstreamRendered() = %v, %q, want %v", got, reason, tt.want)
+			}
+			if !got && reason == "" {
+				t.Errorf("UpstreamRendered() returned no reason for a skipped upstream")
+			}
+		})
+	}
+}
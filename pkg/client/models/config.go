@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 
 	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	servermodels "github.com/zufardhiyaulhaq/frp-operator/pkg/server/models"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -18,7 +20,15 @@ type ServerAuthenticationType int64
 const DEFAULT_ADMIN_ADDRESS = "0.0.0.0"
 const DEFAULT_ADMIN_PORT = 7400
 const DEFAULT_ADMIN_USERNAME = "frpc-user"
-const DEFAULT_ADMIN_PASSWORD = "frpc-password"
+const DEFAULT_REPLICAS = 1
+
+// POD_NAME_ENV is the environment variable holding the frpc pod name, it is
+// used to give every replica unique proxy names on the server
+const POD_NAME_ENV = "FRPC_POD_NAME"
+
+// POD_NAME_TEMPLATE expands to the frpc pod name, frpc renders its configuration
+// as a Go template before parsing it
+const POD_NAME_TEMPLATE = "{{ .Envs." + POD_NAME_ENV + " }}"
 
 const (
 	NoAuth    ServerAuthenticationType = iota // 0 - no authentication
@@ -27,9 +37,13 @@ const (
 )
 
 type Config struct {
-	Common    Common
-	Upstreams Upstreams
-	Visitors  Visitors
+	Common     Common
+	Upstreams  Upstreams
+	Visitors   Visitors
+	VirtualNet *VirtualNet
+	// Files are stored in the config Secret next to the configuration, keyed by
+	// their name in CONFIG_FILES_PATH
+	Files map[string][]byte
 }
 
 type TransportConfig struct {
@@ -38,6 +52,13 @@ type TransportConfig struct {
 	DialServerTimeout    string
 	DialServerKeepalive  string
 	ConnectServerLocalIP string
+	QUIC                 *QUICConfig
+}
+
+type QUICConfig struct {
+	KeepalivePeriod    int
+	MaxIdleTimeout     int
+	MaxIncomingStreams int
 }
 
 type Common struct {
@@ -75,8 +96,9 @@ type ServerAuthentication struct {
 type VisitorType int64
 
 const (
-	STCPVisitor VisitorType = iota
-	XTCPVisitor VisitorType = iota
+	STCPVisitor VisitorType = iota + 1
+	XTCPVisitor VisitorType = iota + 1
+	SUDPVisitor VisitorType = iota + 1
 )
 
 type Visitor struct {
@@ -84,6 +106,7 @@ type Visitor struct {
 	Type VisitorType
 	STCP Visitor_STCP
 	XTCP Visitor_XTCP
+	SUDP Visitor_SUDP
 }
 
 type Visitors []Visitor
@@ -120,16 +143,24 @@ type Visitor_XTCP_Fallback struct {
 	Timeout    int
 }
 
+type Visitor_SUDP struct {
+	Host       string
+	Port       int
+	ServerName string
+	SecretKey  string
+}
+
 type UpstreamType int64
 
 const (
-	TCP    UpstreamType = iota
-	UDP    UpstreamType = iota
-	STCP   UpstreamType = iota
-	XTCP   UpstreamType = iota
-	HTTP   UpstreamType = iota
-	HTTPS  UpstreamType = iota
-	TCPMUX UpstreamType = iota
+	TCP    UpstreamType = iota + 1
+	UDP    UpstreamType = iota + 1
+	STCP   UpstreamType = iota + 1
+	XTCP   UpstreamType = iota + 1
+	HTTP   UpstreamType = iota + 1
+	HTTPS  UpstreamType = iota + 1
+	TCPMUX UpstreamType = iota + 1
+	SUDP   UpstreamType = iota + 1
 )
 
 type Upstream_TCPMUX struct {
@@ -138,6 +169,7 @@ type Upstream_TCPMUX struct {
 	Multiplexer   string
 	CustomDomains []string
 	Transport     *Upstream_TCP_Transport
+	LoadBalancer  *LoadBalancerConfig
 }
 
 type Upstream struct {
@@ -150,6 +182,7 @@ type Upstream struct {
 	HTTP   Upstream_HTTP
 	HTTPS  Upstream_HTTPS
 	TCPMUX Upstream_TCPMUX
+	SUDP   Upstream_SUDP
 }
 
 type Upstreams []Upstream
@@ -184,6 +217,14 @@ type Upstream_XTCP struct {
 	AllowUsers    []string
 }
 
+type Upstream_SUDP struct {
+	Host       string
+	Port       int
+	SecretKey  string
+	Transport  *Upstream_TCP_Transport
+	AllowUsers []string
+}
+
 type LoadBalancerConfig struct {
 	Group    string
 	GroupKey string
@@ -250,6 +291,7 @@ type Upstream_HTTP struct {
 	HTTPPassword      string
 	HealthCheck       *Upstream_HTTP_HealthCheck
 	Transport         *Upstream_TCP_Transport
+	LoadBalancer      *LoadBalancerConfig
 }
 
 type Upstream_HTTP_HealthCheck struct {
@@ -266,6 +308,9 @@ type Upstream_HTTPS struct {
 	CustomDomains []string
 	ProxyProtocol *string
 	Transport     *Upstream_TCP_Transport
+	// CertFile and KeyFile terminate TLS with the https2http plugin when set
+	CertFile string
+	KeyFile  string
 }
 
 // validateUpstreamServerPorts checks that no two TCP/UDP upstreams use the same server port
@@ -284,16 +329,16 @@ func validateUpstreamServerPorts(upstreamObjects []frpv1alpha1.Upstream) error {
 		var lbGroup string
 
 		if upstream.Spec.TCP != nil {
-			port = upstream.Spec.TCP.Server.Port
+			port = upstream.RemotePort()
 			protocol = "TCP"
 			if upstream.Spec.TCP.LoadBalancer != nil {
 				lbGroup = upstream.Spec.TCP.LoadBalancer.Group
 			}
 		} else if upstream.Spec.UDP != nil {
-			port = upstream.Spec.UDP.Server.Port
+			port = upstream.RemotePort()
 			protocol = "UDP"
 		} else {
-			continue // STCP/XTCP/HTTP/HTTPS/TCPMUX don't have server ports
+			continue // STCP/XTCP/SUDP/HTTP/HTTPS/TCPMUX don't have server ports
 		}
 
 		if existing, exists := serverPorts[port]; exists {
@@ -311,7 +356,7 @@ func validateUpstreamServerPorts(upstreamObjects []frpv1alpha1.Upstream) error {
 	return nil
 }
 
-// validateVisitorPorts checks that no two STCP/XTCP visitors use the same port
+// validateVisitorPorts checks that no two STCP/XTCP/SUDP visitors use the same port
 func validateVisitorPorts(visitorObjects []frpv1alpha1.Visitor) error {
 	visitorPorts := make(map[int]string) // port -> visitor name
 
@@ -325,6 +370,9 @@ func validateVisitorPorts(visitorObjects []frpv1alpha1.Visitor) error {
 		} else if visitor.Spec.XTCP != nil {
 			port = visitor.Spec.XTCP.Port
 			protocol = "XTCP"
+		} else if visitor.Spec.SUDP != nil {
+			port = visitor.Spec.SUDP.Port
+			protocol = "SUDP"
 		} else {
 			continue
 		}
@@ -340,41 +388,61 @@ func validateVisitorPorts(visitorObjects []frpv1alpha1.Visitor) error {
 	return nil
 }
 
-func NewConfig(k8sclient client.Client,
-	clientObject *frpv1alpha1.Client,
-	upstreamObjects []frpv1alpha1.Upstream,
-	visitorObjects []frpv1alpha1.Visitor,
-) (Config, error) {
-	// Validate that no duplicate server ports exist for TCP/UDP upstreams
-	if err := validateUpstreamServerPorts(upstreamObjects); err != nil {
-		return Config{}, err
+// Replicas returns the number of frpc pods of a client
+func Replicas(clientObject *frpv1alpha1.Client) int32 {
+	if clientObject.Spec.Replicas == nil {
+		return DEFAULT_REPLICAS
 	}
 
-	// Validate that no duplicate ports exist for STCP/XTCP visitors
-	if err := validateVisitorPorts(visitorObjects); err != nil {
-		return Config{}, err
+	return *clientObject.Spec.Replicas
+}
+
+// DeploymentReplicas returns the number of frpc pods to run, none while the client
+// is suspended. The configuration is still rendered for Replicas pods.
+func DeploymentReplicas(clientObject *frpv1alpha1.Client) int32 {
+	if clientObject.Spec.Suspend {
+		return 0
 	}
 
-	config := Config{
-		Common: Common{
-			ServerAddress:  clientObject.Spec.Server.Host,
-			ServerPort:     clientObject.Spec.Server.Port,
-			ServerProtocol: "TCP",
-			AdminAddress:   DEFAULT_ADMIN_ADDRESS,
-			AdminPort:      DEFAULT_ADMIN_PORT,
-			AdminUsername:  DEFAULT_ADMIN_USERNAME,
-			AdminPassword:  DEFAULT_ADMIN_PASSWORD,
-			STUNServer:     clientObject.Spec.Server.STUNServer,
-		},
+	return Replicas(clientObject)
+}
+
+// replicateUpstreams shares upstreams across frpc replicas. Every replica registers
+// the proxy under its own name and joins a load balancer group named after the
+// upstream, callers leave out upstreams frp can't load balance
+func replicateUpstreams(upstreams []Upstream) {
+	for i := range upstreams {
+		upstream := &upstreams[i]
+
+		switch upstream.Type {
+		case TCP:
+			if upstream.TCP.LoadBalancer == nil {
+				upstream.TCP.LoadBalancer = &LoadBalancerConfig{Group: upstream.Name}
+			}
+		case HTTP:
+			upstream.HTTP.LoadBalancer = &LoadBalancerConfig{Group: upstream.Name}
+		case TCPMUX:
+			upstream.TCPMUX.LoadBalancer = &LoadBalancerConfig{Group: upstream.Name}
+		}
+
+		upstream.Name = upstream.Name + "-" + POD_NAME_TEMPLATE
 	}
+}
 
-	if clientObject.Spec.Server.Protocol != nil {
-		config.Common.ServerProtocol = *clientObject.Spec.Server.Protocol
+// setAdminServer applies the admin server settings of a client to common. Credentials
+// not set on the client are taken from the generated admin Secret of the client,
+// read through k8sclient when adminSecret is nil.
+func setAdminServer(k8sclient client.Client, clientObject *frpv1alpha1.Client, adminSecret *corev1.Secret, common *Common) error {
+	adminServer := clientObject.Spec.Server.AdminServer
+	if adminServer == nil || adminServer.Username == nil || adminServer.Password == nil {
+		if err := setGeneratedAdminCredentials(k8sclient, clientObject, adminSecret, common); err != nil {
+			return err
+		}
 	}
 
 	if clientObject.Spec.Server.AdminServer != nil {
-		config.Common.AdminPort = clientObject.Spec.Server.AdminServer.Port
-		config.Common.PprofEnable = clientObject.Spec.Server.AdminServer.PprofEnable
+		common.AdminPort = clientObject.Spec.Server.AdminServer.Port
+		common.PprofEnable = clientObject.Spec.Server.AdminServer.PprofEnable
 
 		// fetch admin username from secret
 		if clientObject.Spec.Server.AdminServer.Username != nil {
@@ -384,7 +452,7 @@ func NewConfig(k8sclient client.Client,
 			if err == nil {
 				usernameByte, ok := secret.Data[clientObject.Spec.Server.AdminServer.Username.Secret.Key]
 				if ok {
-					config.Common.AdminUsername = string(usernameByte)
+					common.AdminUsername = string(usernameByte)
 				}
 			}
 		}
@@ -397,12 +465,124 @@ func NewConfig(k8sclient client.Client,
 			if err == nil {
 				usernameByte, ok := secret.Data[clientObject.Spec.Server.AdminServer.Password.Secret.Key]
 				if ok {
-					config.Common.AdminPassword = string(usernameByte)
+					common.AdminPassword = string(usernameByte)
 				}
 			}
 		}
 	}
 
+	return nil
+}
+
+// NewAdminConfig builds a configuration holding only the admin server settings of a
+// client, it is used to call the frpc admin API without rendering the whole configuration
+func NewAdminConfig(k8sclient client.Client, clientObject *frpv1alpha1.Client) (Config, error) {
+	config := Config{
+		Common: Common{
+			AdminAddress:  DEFAULT_ADMIN_ADDRESS,
+			AdminPort:     DEFAULT_ADMIN_PORT,
+			AdminUsername: DEFAULT_ADMIN_USERNAME,
+		},
+	}
+	if err := setAdminServer(k8sclient, clientObject, nil, &config.Common); err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// serverRefPort returns the port of an in-cluster Server for the transport protocol,
+// kcp and quic are served on their own UDP port that the Server has to set
+func serverRefPort(server *frpv1alpha1.Server, protocol string) (int, error) {
+	if IsProtocol(protocol, "kcp") {
+		if server.Spec.KCPBindPort == 0 {
+			return 0, errors.NewBadRequest(fmt.Sprintf("server %s doesn't set kcpBindPort required by the kcp protocol", server.Name))
+		}
+		return server.Spec.KCPBindPort, nil
+	}
+	if IsProtocol(protocol, "quic") {
+		if server.Spec.QUICBindPort == 0 {
+			return 0, errors.NewBadRequest(fmt.Sprintf("server %s doesn't set quicBindPort required by the quic protocol", server.Name))
+		}
+		return server.Spec.QUICBindPort, nil
+	}
+
+	return servermodels.BindPort(server), nil
+}
+
+// IsProtocol reports whether the transport protocol of a Client is the given one
+func IsProtocol(protocol string, want string) bool {
+	return strings.EqualFold(protocol, want)
+}
+
+// ServerRef returns the in-cluster Server a Client references with spec.server.serverRef
+func ServerRef(k8sclient client.Reader, clientObject *frpv1alpha1.Client) (*frpv1alpha1.Server, error) {
+	server := &frpv1alpha1.Server{}
+	err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: clientObject.Spec.Server.ServerRef.Name, Namespace: clientObject.Namespace}, server)
+	if err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+// ProxyName returns the name frpc registers an upstream under in the given pod
+func ProxyName(upstreamName string, podName string, replicas int32) string {
+	if replicas > 1 {
+		return upstreamName + "-" + podName
+	}
+
+	return upstreamName
+}
+
+// NewConfig builds the configuration of a client. adminSecret is the generated admin
+// Secret the caller just wrote, nil reads it through k8sclient.
+func NewConfig(k8sclient client.Client,
+	clientObject *frpv1alpha1.Client,
+	adminSecret *corev1.Secret,
+	upstreamObjects []frpv1alpha1.Upstream,
+	visitorObjects []frpv1alpha1.Visitor,
+) (Config, error) {
+	// Validate that no duplicate server ports exist for TCP/UDP upstreams
+	if err := validateUpstreamServerPorts(upstreamObjects); err != nil {
+		return Config{}, err
+	}
+
+	// Validate that no duplicate ports exist for STCP/XTCP visitors
+	if err := validateVisitorPorts(visitorObjects); err != nil {
+		return Config{}, err
+	}
+
+	config := Config{
+		Common: Common{
+			ServerProtocol: "TCP",
+			AdminAddress:   DEFAULT_ADMIN_ADDRESS,
+			AdminPort:      DEFAULT_ADMIN_PORT,
+			AdminUsername:  DEFAULT_ADMIN_USERNAME,
+			STUNServer:     clientObject.Spec.Server.STUNServer,
+		},
+	}
+
+	if clientObject.Spec.Server.Protocol != nil {
+		config.Common.ServerProtocol = *clientObject.Spec.Server.Protocol
+	}
+
+	// Resolve the address of an in-cluster Server
+	address, port, err := ServerEndpoint(k8sclient, clientObject)
+	if err != nil {
+		return config, err
+	}
+	config.Common.ServerAddress = address
+	config.Common.ServerPort = port
+
+	if config.Common.ServerAddress == "" {
+		return config, errors.NewBadRequest("either server host or serverRef is required")
+	}
+
+	if err := setAdminServer(k8sclient, clientObject, adminSecret, &config.Common); err != nil {
+		return config, err
+	}
+
 	// Validate authentication - exactly one method must be specified
 	if clientObject.Spec.Server.Authentication.Token == nil && clientObject.Spec.Server.Authentication.OIDC == nil {
 		return config, errors.NewBadRequest("either token or oidc authentication is required")
@@ -508,16 +688,33 @@ func NewConfig(k8sclient client.Client,
 		} else {
 			config.Common.Transport.TCPMux = true // default
 		}
+
+		if quic := clientObject.Spec.Server.Transport.QUIC; quic != nil {
+			if !IsProtocol(config.Common.ServerProtocol, "quic") {
+				return config, errors.NewBadRequest("transport quic settings require the quic protocol")
+			}
+
+			config.Common.Transport.QUIC = &QUICConfig{
+				KeepalivePeriod:    quic.KeepalivePeriod,
+				MaxIdleTimeout:     quic.MaxIdleTimeout,
+				MaxIncomingStreams: quic.MaxIncomingStreams,
+			}
+		}
 	}
 
 	upstreams := []Upstream{}
 	for _, upstreamObject := range upstreamObjects {
+		namespace := upstreamObject.Namespace
+		if namespace == "" {
+			namespace = clientObject.Namespace
+		}
+
 		upstream := Upstream{
-			Name: upstreamObject.Name,
+			Name: BindingName(upstreamObject.Name, namespace, clientObject.Namespace),
 		}
 
-		if upstreamObject.Spec.TCP == nil && upstreamObject.Spec.UDP == nil && upstreamObject.Spec.STCP == nil && upstreamObject.Spec.XTCP == nil && upstreamObject.Spec.HTTP == nil && upstreamObject.Spec.HTTPS == nil && upstreamObject.Spec.TCPMUX == nil {
-			return config, errors.NewBadRequest("TCP, UDP, STCP, XTCP, HTTP, HTTPS, or TCPMUX upstream is required")
+		if upstreamObject.Spec.TCP == nil && upstreamObject.Spec.UDP == nil && upstreamObject.Spec.STCP == nil && upstreamObject.Spec.XTCP == nil && upstreamObject.Spec.HTTP == nil && upstreamObject.Spec.HTTPS == nil && upstreamObject.Spec.TCPMUX == nil && upstreamObject.Spec.SUDP == nil {
+			return config, errors.NewBadRequest("TCP, UDP, STCP, XTCP, HTTP, HTTPS, TCPMUX, or SUDP upstream is required")
 		}
 
 		protocolCount := 0
@@ -542,15 +739,18 @@ func NewConfig(k8sclient client.Client,
 		if upstreamObject.Spec.TCPMUX != nil {
 			protocolCount++
 		}
+		if upstreamObject.Spec.SUDP != nil {
+			protocolCount++
+		}
 		if protocolCount > 1 {
 			return config, errors.NewBadRequest("Multiple protocol on the same Upstream object")
 		}
 
 		if upstreamObject.Spec.TCP != nil {
-			upstream.Type = 1
+			upstream.Type = TCP
 			upstream.TCP.Host = upstreamObject.Spec.TCP.Host
 			upstream.TCP.Port = upstreamObject.Spec.TCP.Port
-			upstream.TCP.ServerPort = upstreamObject.Spec.TCP.Server.Port
+			upstream.TCP.ServerPort = upstreamObject.RemotePort()
 
 			if upstreamObject.Spec.TCP.ProxyProtocol != nil {
 				upstream.TCP.ProxyProtocol = upstreamObject.Spec.TCP.ProxyProtocol
@@ -593,7 +793,7 @@ func NewConfig(k8sclient client.Client,
 					secret := &corev1.Secret{}
 					err := k8sclient.Get(context.TODO(), types.NamespacedName{
 						Name:      upstreamObject.Spec.TCP.LoadBalancer.GroupKey.Secret.Name,
-						Namespace: clientObject.Namespace,
+						Namespace: namespace,
 					}, secret)
 					if err == nil {
 						if val, ok := secret.Data[upstreamObject.Spec.TCP.LoadBalancer.GroupKey.Secret.Key]; ok {
@@ -618,7 +818,7 @@ func NewConfig(k8sclient client.Client,
 					secret := &corev1.Secret{}
 					err := k8sclient.Get(context.TODO(), types.NamespacedName{
 						Name:      upstreamObject.Spec.TCP.Plugin.Username.Secret.Name,
-						Namespace: clientObject.Namespace,
+						Namespace: namespace,
 					}, secret)
 					if err == nil {
 						if val, ok := secret.Data[upstreamObject.Spec.TCP.Plugin.Username.Secret.Key]; ok {
@@ -632,7 +832,7 @@ func NewConfig(k8sclient client.Client,
 					secret := &corev1.Secret{}
 					err := k8sclient.Get(context.TODO(), types.NamespacedName{
 						Name:      upstreamObject.Spec.TCP.Plugin.Password.Secret.Name,
-						Namespace: clientObject.Namespace,
+						Namespace: namespace,
 					}, secret)
 					if err == nil {
 						if val, ok := secret.Data[upstreamObject.Spec.TCP.Plugin.Password.Secret.Key]; ok {
@@ -646,7 +846,7 @@ func NewConfig(k8sclient client.Client,
 					secret := &corev1.Secret{}
 					err := k8sclient.Get(context.TODO(), types.NamespacedName{
 						Name:      upstreamObject.Spec.TCP.Plugin.HTTPUser.Secret.Name,
-						Namespace: clientObject.Namespace,
+						Namespace: namespace,
 					}, secret)
 					if err == nil {
 						if val, ok := secret.Data[upstreamObject.Spec.TCP.Plugin.HTTPUser.Secret.Key]; ok {
@@ -660,7 +860,7 @@ func NewConfig(k8sclient client.Client,
 					secret := &corev1.Secret{}
 					err := k8sclient.Get(context.TODO(), types.NamespacedName{
 						Name:      upstreamObject.Spec.TCP.Plugin.HTTPPassword.Secret.Name,
-						Namespace: clientObject.Namespace,
+						Namespace: namespace,
 					}, secret)
 					if err == nil {
 						if val, ok := secret.Data[upstreamObject.Spec.TCP.Plugin.HTTPPassword.Secret.Key]; ok {
@@ -672,20 +872,20 @@ func NewConfig(k8sclient client.Client,
 		}
 
 		if upstreamObject.Spec.UDP != nil {
-			upstream.Type = 2
+			upstream.Type = UDP
 			upstream.UDP.Host = upstreamObject.Spec.UDP.Host
 			upstream.UDP.Port = upstreamObject.Spec.UDP.Port
-			upstream.UDP.ServerPort = upstreamObject.Spec.UDP.Server.Port
+			upstream.UDP.ServerPort = upstreamObject.RemotePort()
 		}
 
 		if upstreamObject.Spec.STCP != nil {
-			upstream.Type = 3
+			upstream.Type = STCP
 			upstream.STCP.Host = upstreamObject.Spec.STCP.Host
 			upstream.STCP.Port = upstreamObject.Spec.STCP.Port
 
 			// fetch secret key from secret
 			secret := &corev1.Secret{}
-			err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: upstreamObject.Spec.STCP.SecretKey.Secret.Name, Namespace: clientObject.Namespace}, secret)
+			err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: upstreamObject.Spec.STCP.SecretKey.Secret.Name, Namespace: namespace}, secret)
 			if err != nil && errors.IsNotFound(err) {
 				return config, err
 			} else if err != nil {
@@ -734,13 +934,13 @@ func NewConfig(k8sclient client.Client,
 		}
 
 		if upstreamObject.Spec.XTCP != nil {
-			upstream.Type = 4
+			upstream.Type = XTCP
 			upstream.XTCP.Host = upstreamObject.Spec.XTCP.Host
 			upstream.XTCP.Port = upstreamObject.Spec.XTCP.Port
 
 			// fetch secret key from secret
 			secret := &corev1.Secret{}
-			err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: upstreamObject.Spec.XTCP.SecretKey.Secret.Name, Namespace: clientObject.Namespace}, secret)
+			err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: upstreamObject.Spec.XTCP.SecretKey.Secret.Name, Namespace: namespace}, secret)
 			if err != nil && errors.IsNotFound(err) {
 				return config, err
 			} else if err != nil {
@@ -789,7 +989,7 @@ func NewConfig(k8sclient client.Client,
 		}
 
 		if upstreamObject.Spec.HTTP != nil {
-			upstream.Type = 5
+			upstream.Type = HTTP
 			upstream.HTTP.Host = upstreamObject.Spec.HTTP.Host
 			upstream.HTTP.Port = upstreamObject.Spec.HTTP.Port
 
@@ -822,7 +1022,7 @@ func NewConfig(k8sclient client.Client,
 				secret := &corev1.Secret{}
 				err := k8sclient.Get(context.TODO(), types.NamespacedName{
 					Name:      upstreamObject.Spec.HTTP.HTTPUser.Secret.Name,
-					Namespace: clientObject.Namespace,
+					Namespace: namespace,
 				}, secret)
 				if err != nil {
 					return config, err
@@ -841,7 +1041,7 @@ func NewConfig(k8sclient client.Client,
 				secret := &corev1.Secret{}
 				err := k8sclient.Get(context.TODO(), types.NamespacedName{
 					Name:      upstreamObject.Spec.HTTP.HTTPPassword.Secret.Name,
-					Namespace: clientObject.Namespace,
+					Namespace: namespace,
 				}, secret)
 				if err != nil {
 					return config, err
@@ -886,7 +1086,7 @@ func NewConfig(k8sclient client.Client,
 		}
 
 		if upstreamObject.Spec.HTTPS != nil {
-			upstream.Type = 6
+			upstream.Type = HTTPS
 			upstream.HTTPS.Host = upstreamObject.Spec.HTTPS.Host
 			upstream.HTTPS.Port = upstreamObject.Spec.HTTPS.Port
 			upstream.HTTPS.CustomDomains = upstreamObject.Spec.HTTPS.CustomDomains
@@ -895,6 +1095,28 @@ func NewConfig(k8sclient client.Client,
 				upstream.HTTPS.ProxyProtocol = upstreamObject.Spec.HTTPS.ProxyProtocol
 			}
 
+			if tlsSecret := upstreamObject.Spec.HTTPS.TLSSecret; tlsSecret != "" {
+				secret := &corev1.Secret{}
+				err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: tlsSecret, Namespace: namespace}, secret)
+				if err != nil {
+					return config, err
+				}
+
+				certFile, keyFile := TLSFileKeys(namespace, tlsSecret)
+				for secretKey, file := range map[string]string{corev1.TLSCertKey: certFile, corev1.TLSPrivateKeyKey: keyFile} {
+					data, ok := secret.Data[secretKey]
+					if !ok {
+						return config, errors.NewBadRequest(fmt.Sprintf("key %s not found in secret %s", secretKey, tlsSecret))
+					}
+					if config.Files == nil {
+						config.Files = map[string][]byte{}
+					}
+					config.Files[file] = data
+				}
+				upstream.HTTPS.CertFile = CONFIG_FILES_PATH + "/" + certFile
+				upstream.HTTPS.KeyFile = CONFIG_FILES_PATH + "/" + keyFile
+			}
+
 			if upstreamObject.Spec.HTTPS.Transport != nil {
 				upstream.HTTPS.Transport = &Upstream_TCP_Transport{
 					UseCompression: upstreamObject.Spec.HTTPS.Transport.UseCompression,
@@ -912,7 +1134,7 @@ func NewConfig(k8sclient client.Client,
 		}
 
 		if upstreamObject.Spec.TCPMUX != nil {
-			upstream.Type = 7
+			upstream.Type = TCPMUX
 			upstream.TCPMUX.Host = upstreamObject.Spec.TCPMUX.Host
 			upstream.TCPMUX.Port = upstreamObject.Spec.TCPMUX.Port
 			upstream.TCPMUX.Multiplexer = upstreamObject.Spec.TCPMUX.Multiplexer
@@ -926,32 +1148,103 @@ func NewConfig(k8sclient client.Client,
 			}
 		}
 
+		if upstreamObject.Spec.SUDP != nil {
+			upstream.Type = SUDP
+			upstream.SUDP.Host = upstreamObject.Spec.SUDP.Host
+			upstream.SUDP.Port = upstreamObject.Spec.SUDP.Port
+
+			// fetch secret key from secret
+			secret := &corev1.Secret{}
+			err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: upstreamObject.Spec.SUDP.SecretKey.Secret.Name, Namespace: namespace}, secret)
+			if err != nil {
+				return config, err
+			}
+			secretKeyByte, ok := secret.Data[upstreamObject.Spec.SUDP.SecretKey.Secret.Key]
+			if !ok {
+				return config, errors.NewBadRequest(fmt.Sprintf("key %s not found in secret %s",
+					upstreamObject.Spec.SUDP.SecretKey.Secret.Key,
+					upstreamObject.Spec.SUDP.SecretKey.Secret.Name))
+			}
+			upstream.SUDP.SecretKey = string(secretKeyByte)
+
+			if upstreamObject.Spec.SUDP.Transport != nil {
+				upstream.SUDP.Transport = &Upstream_TCP_Transport{
+					UseCompression: upstreamObject.Spec.SUDP.Transport.UseCompression,
+					UseEncryption:  upstreamObject.Spec.SUDP.Transport.UseEncryption,
+				}
+
+				if upstreamObject.Spec.SUDP.Transport.BandwdithLimit != nil {
+					upstream.SUDP.Transport.BandwdithLimit = &Upstream_TCP_Transport_BandwidthLimit{
+						Enabled: upstreamObject.Spec.SUDP.Transport.BandwdithLimit.Enabled,
+						Limit:   upstreamObject.Spec.SUDP.Transport.BandwdithLimit.Limit,
+						Type:    upstreamObject.Spec.SUDP.Transport.BandwdithLimit.Type,
+					}
+				}
+			}
+
+			if len(upstreamObject.Spec.SUDP.AllowUsers) > 0 {
+				upstream.SUDP.AllowUsers = upstreamObject.Spec.SUDP.AllowUsers
+			}
+		}
+
+		if _, ok := UpstreamServiceKey(&upstreamObject); ok {
+			host, port, err := ResolveServiceRef(k8sclient, &upstreamObject)
+			if IsServiceUnresolved(err) {
+				// the Upstream reports the missing Service in its status, the other
+				// proxies of the Client keep running
+				continue
+			} else if err != nil {
+				return config, err
+			}
+
+			upstream.setLocalAddress(host, port)
+		}
+
 		upstreams = append(upstreams, upstream)
 	}
 
+	if Replicas(clientObject) > 1 {
+		replicateUpstreams(upstreams)
+	}
+
 	visitors := []Visitor{}
 	for _, visitorObject := range visitorObjects {
+		namespace := visitorObject.Namespace
+		if namespace == "" {
+			namespace = clientObject.Namespace
+		}
+
 		visitor := Visitor{
-			Name: visitorObject.Name,
+			Name: BindingName(visitorObject.Name, namespace, clientObject.Namespace),
 		}
 
-		if visitorObject.Spec.STCP == nil && visitorObject.Spec.XTCP == nil {
-			return config, errors.NewBadRequest("STCP, XTCP visitor is required")
+		if visitorObject.Spec.STCP == nil && visitorObject.Spec.XTCP == nil && visitorObject.Spec.SUDP == nil {
+			return config, errors.NewBadRequest("STCP, XTCP, or SUDP visitor is required")
 		}
 
-		if visitorObject.Spec.STCP != nil && visitorObject.Spec.XTCP != nil {
+		visitorProtocolCount := 0
+		if visitorObject.Spec.STCP != nil {
+			visitorProtocolCount++
+		}
+		if visitorObject.Spec.XTCP != nil {
+			visitorProtocolCount++
+		}
+		if visitorObject.Spec.SUDP != nil {
+			visitorProtocolCount++
+		}
+		if visitorProtocolCount > 1 {
 			return config, errors.NewBadRequest("Multiple protocol on the same Visitor object")
 		}
 
 		if visitorObject.Spec.STCP != nil {
-			visitor.Type = 1
+			visitor.Type = STCPVisitor
 			visitor.STCP.Host = visitorObject.Spec.STCP.Host
 			visitor.STCP.Port = visitorObject.Spec.STCP.Port
 			visitor.STCP.ServerName = visitorObject.Spec.STCP.ServerName
 
 			// fetch secret key from secret
 			secret := &corev1.Secret{}
-			err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: visitorObject.Spec.STCP.ServerSecretKey.Secret.Name, Namespace: clientObject.Namespace}, secret)
+			err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: visitorObject.Spec.STCP.ServerSecretKey.Secret.Name, Namespace: namespace}, secret)
 			if err != nil && errors.IsNotFound(err) {
 				return config, err
 			} else if err != nil {
@@ -965,7 +1258,7 @@ func NewConfig(k8sclient client.Client,
 		}
 
 		if visitorObject.Spec.XTCP != nil {
-			visitor.Type = 2
+			visitor.Type = XTCPVisitor
 			visitor.XTCP.Host = visitorObject.Spec.XTCP.Host
 			visitor.XTCP.Port = visitorObject.Spec.XTCP.Port
 			visitor.XTCP.ServerName = visitorObject.Spec.XTCP.ServerName
@@ -974,7 +1267,7 @@ func NewConfig(k8sclient client.Client,
 
 			// fetch secret key from secret
 			secret := &corev1.Secret{}
-			err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: visitorObject.Spec.XTCP.ServerSecretKey.Secret.Name, Namespace: clientObject.Namespace}, secret)
+			err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: visitorObject.Spec.XTCP.ServerSecretKey.Secret.Name, Namespace: namespace}, secret)
 			if err != nil && errors.IsNotFound(err) {
 				return config, err
 			} else if err != nil {
@@ -994,6 +1287,27 @@ func NewConfig(k8sclient client.Client,
 			}
 		}
 
+		if visitorObject.Spec.SUDP != nil {
+			visitor.Type = SUDPVisitor
+			visitor.SUDP.Host = visitorObject.Spec.SUDP.Host
+			visitor.SUDP.Port = visitorObject.Spec.SUDP.Port
+			visitor.SUDP.ServerName = visitorObject.Spec.SUDP.ServerName
+
+			// fetch secret key from secret
+			secret := &corev1.Secret{}
+			err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: visitorObject.Spec.SUDP.ServerSecretKey.Secret.Name, Namespace: namespace}, secret)
+			if err != nil {
+				return config, err
+			}
+			secretKeyByte, ok := secret.Data[visitorObject.Spec.SUDP.ServerSecretKey.Secret.Key]
+			if !ok {
+				return config, errors.NewBadRequest(fmt.Sprintf("key %s not found in secret %s",
+					visitorObject.Spec.SUDP.ServerSecretKey.Secret.Key,
+					visitorObject.Spec.SUDP.ServerSecretKey.Secret.Name))
+			}
+			visitor.SUDP.SecretKey = string(secretKeyByte)
+		}
+
 		visitors = append(visitors, visitor)
 	}
 
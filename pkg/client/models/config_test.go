@@ -5,6 +5,7 @@ import (
 
 	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -519,14 +520,19 @@ func searchSubstring(s, substr string) bool {
 	return false
 }
 
-// Helper to create a fake client with secrets
+// Helper to create a fake client with secrets. The generated admin secrets of the
+// test clients are always present, as the Client controller creates them before
+// rendering the configuration.
 func createFakeClient(secrets ...*corev1.Secret) *fake.ClientBuilder {
 	scheme := runtime.NewScheme()
 	_ = corev1.AddToScheme(scheme)
 
-	objects := make([]runtime.Object, len(secrets))
-	for i, s := range secrets {
-		objects[i] = s
+	objects := []runtime.Object{
+		createAdminSecret("default", "test-client"),
+		createAdminSecret("frp-system", "edge"),
+	}
+	for _, s := range secrets {
+		objects = append(objects, s)
 	}
 
 	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...)
@@ -543,6 +549,14 @@ func createSecret(namespace, name string, data map[string][]byte) *corev1.Secret
 	}
 }
 
+// Helper to create the generated admin secret of a client
+func createAdminSecret(namespace, clientName string) *corev1.Secret {
+	return createSecret(namespace, AdminSecretName(clientName), map[string][]byte{
+		ADMIN_SECRET_USERNAME_KEY: []byte(DEFAULT_ADMIN_USERNAME),
+		ADMIN_SECRET_PASSWORD_KEY: []byte("generated-password"),
+	})
+}
+
 // Helper to create a basic client object
 func createBasicClient(namespace, name, host string, port int) *frpv1alpha1.Client {
 	return &frpv1alpha1.Client{
@@ -583,7 +597,7 @@ func TestNewConfig_BasicClient(t *testing.T) {
 
 	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
 
-	config, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -606,8 +620,8 @@ func TestNewConfig_BasicClient(t *testing.T) {
 	if config.Common.AdminUsername != DEFAULT_ADMIN_USERNAME {
 		t.Errorf("NewConfig() AdminUsername = %v, want %v", config.Common.AdminUsername, DEFAULT_ADMIN_USERNAME)
 	}
-	if config.Common.AdminPassword != DEFAULT_ADMIN_PASSWORD {
-		t.Errorf("NewConfig() AdminPassword = %v, want %v", config.Common.AdminPassword, DEFAULT_ADMIN_PASSWORD)
+	if config.Common.AdminPassword != "generated-password" {
+		t.Errorf("NewConfig() AdminPassword = %v, want %v", config.Common.AdminPassword, "generated-password")
 	}
 }
 
@@ -617,7 +631,7 @@ func TestNewConfig_WithProtocol(t *testing.T) {
 	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
 	clientObj.Spec.Server.Protocol = stringPtr("kcp")
 
-	config, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -627,13 +641,38 @@ func TestNewConfig_WithProtocol(t *testing.T) {
 	}
 }
 
+func TestNewConfig_WithQUICTransport(t *testing.T) {
+	fakeClient := createFakeClient(createDefaultTokenSecret("default")).Build()
+
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7002)
+	clientObj.Spec.Server.Protocol = stringPtr("quic")
+	clientObj.Spec.Server.Transport = &frpv1alpha1.ClientSpec_Server_Transport{
+		QUIC: &frpv1alpha1.ClientSpec_Server_Transport_QUIC{KeepalivePeriod: 10, MaxIdleTimeout: 30, MaxIncomingStreams: 1000},
+	}
+
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	quic := config.Common.Transport.QUIC
+	if quic == nil || quic.KeepalivePeriod != 10 || quic.MaxIdleTimeout != 30 || quic.MaxIncomingStreams != 1000 {
+		t.Errorf("NewConfig() Transport.QUIC = %+v, want the quic settings", quic)
+	}
+
+	clientObj.Spec.Server.Protocol = stringPtr("kcp")
+	if _, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{}); err == nil {
+		t.Error("NewConfig() expected error for quic settings with the kcp protocol")
+	}
+}
+
 func TestNewConfig_WithSTUNServer(t *testing.T) {
 	fakeClient := createFakeClient(createDefaultTokenSecret("default")).Build()
 
 	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
 	clientObj.Spec.Server.STUNServer = stringPtr("stun.example.com:3478")
 
-	config, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -660,7 +699,7 @@ func TestNewConfig_WithTokenAuthentication(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -684,7 +723,7 @@ func TestNewConfig_TokenSecretNotFound(t *testing.T) {
 		},
 	}
 
-	_, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	_, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
 	if err == nil {
 		t.Error("NewConfig() expected error for missing secret, got nil")
 	}
@@ -714,7 +753,7 @@ func TestNewConfig_WithAdminServer(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -747,7 +786,7 @@ func TestNewConfig_TCPUpstream(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, upstreams, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -807,7 +846,7 @@ func TestNewConfig_TCPUpstreamWithAllOptions(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, upstreams, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -862,7 +901,7 @@ func TestNewConfig_UDPUpstream(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, upstreams, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -911,7 +950,7 @@ func TestNewConfig_STCPUpstream(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, upstreams, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -970,7 +1009,7 @@ func TestNewConfig_STCPUpstreamWithOptions(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, upstreams, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -1018,7 +1057,7 @@ func TestNewConfig_XTCPUpstream(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, upstreams, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -1082,7 +1121,7 @@ func TestNewConfig_XTCPUpstreamWithOptions(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, upstreams, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -1131,7 +1170,7 @@ func TestNewConfig_STCPVisitor(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, visitors)
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, visitors)
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -1161,6 +1200,106 @@ func TestNewConfig_STCPVisitor(t *testing.T) {
 	}
 }
 
+func TestNewConfig_SUDPUpstream(t *testing.T) {
+	secretKeySecret := createSecret("default", "sudp-secret", map[string][]byte{
+		"key": []byte("sudp-secret-key"),
+	})
+	fakeClient := createFakeClient(createDefaultTokenSecret("default"), secretKeySecret).Build()
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+
+	upstreams := []frpv1alpha1.Upstream{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "sudp-upstream"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				SUDP: &frpv1alpha1.UpstreamSpec_SUDP{
+					Host: "127.0.0.1",
+					Port: 53,
+					SecretKey: frpv1alpha1.UpstreamSpec_SUDP_SecretKey{
+						Secret: frpv1alpha1.Secret{
+							Name: "sudp-secret",
+							Key:  "key",
+						},
+					},
+					AllowUsers: []string{"*"},
+				},
+			},
+		},
+	}
+
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	if len(config.Upstreams) != 1 {
+		t.Fatalf("NewConfig() Upstreams length = %v, want 1", len(config.Upstreams))
+	}
+
+	upstream := config.Upstreams[0]
+	if upstream.Type != 8 {
+		t.Errorf("NewConfig() upstream.Type = %v, want 8 (SUDP)", upstream.Type)
+	}
+	if upstream.SUDP.Host != "127.0.0.1" || upstream.SUDP.Port != 53 {
+		t.Errorf("NewConfig() upstream.SUDP address = %v:%v, want 127.0.0.1:53", upstream.SUDP.Host, upstream.SUDP.Port)
+	}
+	if upstream.SUDP.SecretKey != "sudp-secret-key" {
+		t.Errorf("NewConfig() upstream.SUDP.SecretKey = %v, want %v", upstream.SUDP.SecretKey, "sudp-secret-key")
+	}
+	if len(upstream.SUDP.AllowUsers) != 1 || upstream.SUDP.AllowUsers[0] != "*" {
+		t.Errorf("NewConfig() upstream.SUDP.AllowUsers = %v, want [*]", upstream.SUDP.AllowUsers)
+	}
+}
+
+func TestNewConfig_SUDPVisitor(t *testing.T) {
+	secretKeySecret := createSecret("default", "visitor-secret", map[string][]byte{
+		"key": []byte("sudp-visitor-secret-key"),
+	})
+	fakeClient := createFakeClient(createDefaultTokenSecret("default"), secretKeySecret).Build()
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+
+	visitors := []frpv1alpha1.Visitor{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "sudp-visitor"},
+			Spec: frpv1alpha1.VisitorSpec{
+				SUDP: &frpv1alpha1.VisitorSpec_SUDP{
+					Host:       "0.0.0.0",
+					Port:       5353,
+					ServerName: "dns-server",
+					ServerSecretKey: frpv1alpha1.VisitorSpec_SUDP_ServerSecretKey{
+						Secret: frpv1alpha1.Secret{
+							Name: "visitor-secret",
+							Key:  "key",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, visitors)
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	if len(config.Visitors) != 1 {
+		t.Fatalf("NewConfig() Visitors length = %v, want 1", len(config.Visitors))
+	}
+
+	visitor := config.Visitors[0]
+	if visitor.Type != 3 {
+		t.Errorf("NewConfig() visitor.Type = %v, want 3 (SUDP)", visitor.Type)
+	}
+	if visitor.SUDP.Port != 5353 {
+		t.Errorf("NewConfig() visitor.SUDP.Port = %v, want %v", visitor.SUDP.Port, 5353)
+	}
+	if visitor.SUDP.ServerName != "dns-server" {
+		t.Errorf("NewConfig() visitor.SUDP.ServerName = %v, want %v", visitor.SUDP.ServerName, "dns-server")
+	}
+	if visitor.SUDP.SecretKey != "sudp-visitor-secret-key" {
+		t.Errorf("NewConfig() visitor.SUDP.SecretKey = %v, want %v", visitor.SUDP.SecretKey, "sudp-visitor-secret-key")
+	}
+}
+
 func TestNewConfig_XTCPVisitor(t *testing.T) {
 	secretKeySecret := createSecret("default", "visitor-secret", map[string][]byte{
 		"key": []byte("xtcp-visitor-secret-key"),
@@ -1188,7 +1327,7 @@ func TestNewConfig_XTCPVisitor(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, visitors)
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, visitors)
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -1249,7 +1388,7 @@ func TestNewConfig_XTCPVisitorWithEnableAssistedAddrs(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, visitors)
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, visitors)
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -1291,7 +1430,7 @@ func TestNewConfig_XTCPVisitorWithFallback(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, visitors)
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, visitors)
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -1319,12 +1458,12 @@ func TestNewConfig_UpstreamNoProtocol(t *testing.T) {
 		},
 	}
 
-	_, err := NewConfig(fakeClient, clientObj, upstreams, []frpv1alpha1.Visitor{})
+	_, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
 	if err == nil {
 		t.Error("NewConfig() expected error for upstream without protocol")
 	}
-	if !contains(err.Error(), "TCP, UDP, STCP, XTCP, HTTP, HTTPS, or TCPMUX upstream is required") {
-		t.Errorf("NewConfig() error = %v, want error containing 'TCP, UDP, STCP, XTCP, HTTP, HTTPS, or TCPMUX upstream is required'", err)
+	if !contains(err.Error(), "TCP, UDP, STCP, XTCP, HTTP, HTTPS, TCPMUX, or SUDP upstream is required") {
+		t.Errorf("NewConfig() error = %v, want error containing 'TCP, UDP, STCP, XTCP, HTTP, HTTPS, TCPMUX, or SUDP upstream is required'", err)
 	}
 }
 
@@ -1339,11 +1478,11 @@ func TestNewConfig_VisitorNoProtocol(t *testing.T) {
 		},
 	}
 
-	_, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, visitors)
+	_, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, visitors)
 	if err == nil {
 		t.Error("NewConfig() expected error for visitor without protocol")
 	}
-	if !contains(err.Error(), "STCP, XTCP visitor is required") {
+	if !contains(err.Error(), "STCP, XTCP, or SUDP visitor is required") {
 		t.Errorf("NewConfig() error = %v, want error containing 'STCP, XTCP visitor is required'", err)
 	}
 }
@@ -1370,7 +1509,7 @@ func TestNewConfig_STCPUpstreamSecretNotFound(t *testing.T) {
 		},
 	}
 
-	_, err := NewConfig(fakeClient, clientObj, upstreams, []frpv1alpha1.Visitor{})
+	_, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
 	if err == nil {
 		t.Error("NewConfig() expected error for missing secret")
 	}
@@ -1398,7 +1537,7 @@ func TestNewConfig_XTCPUpstreamSecretNotFound(t *testing.T) {
 		},
 	}
 
-	_, err := NewConfig(fakeClient, clientObj, upstreams, []frpv1alpha1.Visitor{})
+	_, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
 	if err == nil {
 		t.Error("NewConfig() expected error for missing secret")
 	}
@@ -1427,7 +1566,7 @@ func TestNewConfig_STCPVisitorSecretNotFound(t *testing.T) {
 		},
 	}
 
-	_, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, visitors)
+	_, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, visitors)
 	if err == nil {
 		t.Error("NewConfig() expected error for missing secret")
 	}
@@ -1456,7 +1595,7 @@ func TestNewConfig_XTCPVisitorSecretNotFound(t *testing.T) {
 		},
 	}
 
-	_, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, visitors)
+	_, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, visitors)
 	if err == nil {
 		t.Error("NewConfig() expected error for missing secret")
 	}
@@ -1499,7 +1638,7 @@ func TestNewConfig_MultipleUpstreams_Sorted(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, upstreams, []frpv1alpha1.Visitor{})
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -1552,7 +1691,7 @@ func TestNewConfig_MultipleVisitors_Sorted(t *testing.T) {
 		},
 	}
 
-	config, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, visitors)
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, visitors)
 	if err != nil {
 		t.Fatalf("NewConfig() unexpected error = %v", err)
 	}
@@ -1596,7 +1735,7 @@ func TestNewConfig_DuplicateUpstreamServerPorts(t *testing.T) {
 		},
 	}
 
-	_, err := NewConfig(fakeClient, clientObj, upstreams, []frpv1alpha1.Visitor{})
+	_, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
 	if err == nil {
 		t.Error("NewConfig() expected error for duplicate server ports")
 	}
@@ -1641,7 +1780,7 @@ func TestNewConfig_DuplicateVisitorPorts(t *testing.T) {
 		},
 	}
 
-	_, err := NewConfig(fakeClient, clientObj, []frpv1alpha1.Upstream{}, visitors)
+	_, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, visitors)
 	if err == nil {
 		t.Error("NewConfig() expected error for duplicate visitor ports")
 	}
@@ -1649,3 +1788,389 @@ func TestNewConfig_DuplicateVisitorPorts(t *testing.T) {
 		t.Errorf("NewConfig() error = %v, want error containing 'duplicate visitor port'", err)
 	}
 }
+
+func TestNewConfig_ServerRef(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = frpv1alpha1.AddToScheme(scheme)
+
+	server := &frpv1alpha1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge", Namespace: "default"},
+		Spec:       frpv1alpha1.ServerSpec{BindPort: 7100},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(createDefaultTokenSecret("default"), createAdminSecret("default", "test-client"), server).Build()
+
+	clientObj := createBasicClient("default", "test-client", "", 0)
+	clientObj.Spec.Server.ServerRef = &frpv1alpha1.ClientSpec_Server_ServerRef{Name: "edge"}
+
+	config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	if config.Common.ServerAddress != "edge-frps.default.svc" {
+		t.Errorf("NewConfig() ServerAddress = %v, want %v", config.Common.ServerAddress, "edge-frps.default.svc")
+	}
+	if config.Common.ServerPort != 7100 {
+		t.Errorf("NewConfig() ServerPort = %v, want %v", config.Common.ServerPort, 7100)
+	}
+}
+
+func TestNewConfig_ServerRefProtocolPort(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = frpv1alpha1.AddToScheme(scheme)
+
+	server := &frpv1alpha1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge", Namespace: "default"},
+		Spec:       frpv1alpha1.ServerSpec{BindPort: 7000, KCPBindPort: 7001, QUICBindPort: 7002},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(createDefaultTokenSecret("default"), createAdminSecret("default", "test-client"), server).Build()
+
+	for protocol, want := range map[string]int{"tcp": 7000, "websocket": 7000, "kcp": 7001, "quic": 7002} {
+		clientObj := createBasicClient("default", "test-client", "", 0)
+		clientObj.Spec.Server.ServerRef = &frpv1alpha1.ClientSpec_Server_ServerRef{Name: "edge"}
+		clientObj.Spec.Server.Protocol = stringPtr(protocol)
+
+		config, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+		if err != nil {
+			t.Fatalf("NewConfig() unexpected error = %v", err)
+		}
+		if config.Common.ServerPort != want {
+			t.Errorf("NewConfig() %s ServerPort = %v, want %v", protocol, config.Common.ServerPort, want)
+		}
+	}
+
+	server.Spec.QUICBindPort = 0
+	fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(createDefaultTokenSecret("default"), createAdminSecret("default", "test-client"), server).Build()
+
+	clientObj := createBasicClient("default", "test-client", "", 0)
+	clientObj.Spec.Server.ServerRef = &frpv1alpha1.ClientSpec_Server_ServerRef{Name: "edge"}
+	clientObj.Spec.Server.Protocol = stringPtr("quic")
+	_, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	if !errors.IsBadRequest(err) {
+		t.Errorf("NewConfig() error = %v, want a BadRequest for a Server without quicBindPort", err)
+	}
+}
+
+func TestNewConfig_ServerRefNotFound(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = frpv1alpha1.AddToScheme(scheme)
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(createDefaultTokenSecret("default")).Build()
+
+	clientObj := createBasicClient("default", "test-client", "", 0)
+	clientObj.Spec.Server.ServerRef = &frpv1alpha1.ClientSpec_Server_ServerRef{Name: "missing"}
+
+	_, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	if err == nil {
+		t.Error("NewConfig() expected error for missing server, got nil")
+	}
+}
+
+func TestNewConfig_ServerAddressRequired(t *testing.T) {
+	fakeClient := createFakeClient(createDefaultTokenSecret("default")).Build()
+	clientObj := createBasicClient("default", "test-client", "", 0)
+
+	_, err := NewConfig(fakeClient, clientObj, nil, []frpv1alpha1.Upstream{}, []frpv1alpha1.Visitor{})
+	if err == nil {
+		t.Error("NewConfig() expected error for missing server address, got nil")
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestNewConfig_Replicas(t *testing.T) {
+	fakeClient := createFakeClient(createDefaultTokenSecret("default")).Build()
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+	clientObj.Spec.Replicas = int32Ptr(3)
+
+	upstreams := []frpv1alpha1.Upstream{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "tcp"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				TCP: &frpv1alpha1.UpstreamSpec_TCP{
+					Host:   "127.0.0.1",
+					Port:   8080,
+					Server: frpv1alpha1.UpstreamSpec_TCP_Server{Port: 8080},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				HTTP: &frpv1alpha1.UpstreamSpec_HTTP{
+					Host:          "127.0.0.1",
+					Port:          80,
+					CustomDomains: []string{"web.example.com"},
+				},
+			},
+		},
+	}
+
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	if config.Upstreams[0].Name != "tcp-{{ .Envs.FRPC_POD_NAME }}" {
+		t.Errorf("NewConfig() Upstreams[0].Name = %v, want per-replica name", config.Upstreams[0].Name)
+	}
+	if config.Upstreams[0].TCP.LoadBalancer == nil || config.Upstreams[0].TCP.LoadBalancer.Group != "tcp" {
+		t.Errorf("NewConfig() expected TCP load balancer group tcp, got %v", config.Upstreams[0].TCP.LoadBalancer)
+	}
+	if config.Upstreams[1].HTTP.LoadBalancer == nil || config.Upstreams[1].HTTP.LoadBalancer.Group != "web" {
+		t.Errorf("NewConfig() expected HTTP load balancer group web, got %v", config.Upstreams[1].HTTP.LoadBalancer)
+	}
+}
+
+func TestNewConfig_ReplicasKeepExplicitLoadBalancer(t *testing.T) {
+	fakeClient := createFakeClient(createDefaultTokenSecret("default")).Build()
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+	clientObj.Spec.Replicas = int32Ptr(2)
+
+	upstreams := []frpv1alpha1.Upstream{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "tcp"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				TCP: &frpv1alpha1.UpstreamSpec_TCP{
+					Host:         "127.0.0.1",
+					Port:         8080,
+					Server:       frpv1alpha1.UpstreamSpec_TCP_Server{Port: 8080},
+					LoadBalancer: &frpv1alpha1.LoadBalancer{Group: "shared"},
+				},
+			},
+		},
+	}
+
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	if config.Upstreams[0].TCP.LoadBalancer.Group != "shared" {
+		t.Errorf("NewConfig() LoadBalancer.Group = %v, want %v", config.Upstreams[0].TCP.LoadBalancer.Group, "shared")
+	}
+}
+
+func TestNewConfig_SingleReplicaKeepsNames(t *testing.T) {
+	fakeClient := createFakeClient(createDefaultTokenSecret("default")).Build()
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+	clientObj.Spec.Replicas = int32Ptr(1)
+
+	upstreams := []frpv1alpha1.Upstream{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "dns"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				UDP: &frpv1alpha1.UpstreamSpec_UDP{
+					Host:   "127.0.0.1",
+					Port:   53,
+					Server: frpv1alpha1.UpstreamSpec_UDP_Server{Port: 5353},
+				},
+			},
+		},
+	}
+
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+	if config.Upstreams[0].Name != "dns" {
+		t.Errorf("NewConfig() Upstreams[0].Name = %v, want %v", config.Upstreams[0].Name, "dns")
+	}
+}
+
+func TestNewAdminConfig(t *testing.T) {
+	adminSecret := createSecret("default", "admin-secret", map[string][]byte{
+		"username": []byte("admin"),
+		"password": []byte("secret"),
+	})
+	fakeClient := createFakeClient(adminSecret).Build()
+
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+	clientObj.Spec.Server.AdminServer = &frpv1alpha1.ClientSpec_Server_AdminServer{
+		Port: 7500,
+		Username: &frpv1alpha1.ClientSpec_Server_AdminServer_Username{
+			Secret: frpv1alpha1.Secret{Name: "admin-secret", Key: "username"},
+		},
+		Password: &frpv1alpha1.ClientSpec_Server_AdminServer_Password{
+			Secret: frpv1alpha1.Secret{Name: "admin-secret", Key: "password"},
+		},
+	}
+
+	config, err := NewAdminConfig(fakeClient, clientObj)
+	if err != nil {
+		t.Fatalf("NewAdminConfig() unexpected error = %v", err)
+	}
+
+	if config.Common.AdminPort != 7500 {
+		t.Errorf("NewAdminConfig() AdminPort = %v, want %v", config.Common.AdminPort, 7500)
+	}
+	if config.Common.AdminUsername != "admin" {
+		t.Errorf("NewAdminConfig() AdminUsername = %v, want %v", config.Common.AdminUsername, "admin")
+	}
+	if config.Common.AdminPassword != "secret" {
+		t.Errorf("NewAdminConfig() AdminPassword = %v, want %v", config.Common.AdminPassword, "secret")
+	}
+}
+
+func TestNewAdminConfig_Defaults(t *testing.T) {
+	fakeClient := createFakeClient().Build()
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+
+	config, err := NewAdminConfig(fakeClient, clientObj)
+	if err != nil {
+		t.Fatalf("NewAdminConfig() unexpected error = %v", err)
+	}
+
+	if config.Common.AdminPort != DEFAULT_ADMIN_PORT {
+		t.Errorf("NewAdminConfig() AdminPort = %v, want %v", config.Common.AdminPort, DEFAULT_ADMIN_PORT)
+	}
+	if config.Common.AdminUsername != DEFAULT_ADMIN_USERNAME {
+		t.Errorf("NewAdminConfig() AdminUsername = %v, want %v", config.Common.AdminUsername, DEFAULT_ADMIN_USERNAME)
+	}
+	if config.Common.AdminPassword != "generated-password" {
+		t.Errorf("NewAdminConfig() AdminPassword = %v, want %v", config.Common.AdminPassword, "generated-password")
+	}
+}
+
+func TestNewAdminConfig_GeneratedSecretNotFound(t *testing.T) {
+	fakeClient := createFakeClient().Build()
+	clientObj := createBasicClient("default", "other-client", "frp.example.com", 7000)
+
+	if _, err := NewAdminConfig(fakeClient, clientObj); err == nil {
+		t.Errorf("NewAdminConfig() expected error for missing admin secret")
+	}
+}
+
+func TestProxyName(t *testing.T) {
+	if got := ProxyName("web", "client-frpc-abc", 1); got != "web" {
+		t.Errorf("ProxyName() = %v, want %v", got, "web")
+	}
+	if got := ProxyName("web", "client-frpc-abc", 3); got != "web-client-frpc-abc" {
+		t.Errorf("ProxyName() = %v, want %v", got, "web-client-frpc-abc")
+	}
+}
+
+func TestNewConfig_CrossNamespaceBinding(t *testing.T) {
+	upstreamSecret := createSecret("team-a", "stcp-secret", map[string][]byte{
+		"key": []byte("team-a-key"),
+	})
+	visitorSecret := createSecret("team-b", "visitor-secret", map[string][]byte{
+		"key": []byte("team-b-key"),
+	})
+	fakeClient := createFakeClient(createDefaultTokenSecret("frp-system"), upstreamSecret, visitorSecret).Build()
+	clientObj := createBasicClient("frp-system", "edge", "frp.example.com", 7000)
+
+	upstreams := []frpv1alpha1.Upstream{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ssh", Namespace: "team-a"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				ClientRef: &frpv1alpha1.ClientRef{Name: "edge", Namespace: "frp-system"},
+				STCP: &frpv1alpha1.UpstreamSpec_STCP{
+					Host: "127.0.0.1",
+					Port: 22,
+					SecretKey: frpv1alpha1.UpstreamSpec_STCP_SecretKey{
+						Secret: frpv1alpha1.Secret{Name: "stcp-secret", Key: "key"},
+					},
+				},
+			},
+		},
+	}
+	visitors := []frpv1alpha1.Visitor{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ssh", Namespace: "team-b"},
+			Spec: frpv1alpha1.VisitorSpec{
+				ClientRef: &frpv1alpha1.ClientRef{Name: "edge", Namespace: "frp-system"},
+				STCP: &frpv1alpha1.VisitorSpec_STCP{
+					Host:       "127.0.0.1",
+					Port:       2222,
+					ServerName: "team-a.ssh",
+					ServerSecretKey: frpv1alpha1.VisitorSpec_STCP_ServerSecretKey{
+						Secret: frpv1alpha1.Secret{Name: "visitor-secret", Key: "key"},
+					},
+				},
+			},
+		},
+	}
+
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, visitors)
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	if config.Upstreams[0].Name != "team-a.ssh" {
+		t.Errorf("NewConfig() upstream.Name = %v, want %v", config.Upstreams[0].Name, "team-a.ssh")
+	}
+	if config.Upstreams[0].STCP.SecretKey != "team-a-key" {
+		t.Errorf("NewConfig() upstream.STCP.SecretKey = %v, want %v", config.Upstreams[0].STCP.SecretKey, "team-a-key")
+	}
+	if config.Visitors[0].Name != "team-b.ssh" {
+		t.Errorf("NewConfig() visitor.Name = %v, want %v", config.Visitors[0].Name, "team-b.ssh")
+	}
+	if config.Visitors[0].STCP.SecretKey != "team-b-key" {
+		t.Errorf("NewConfig() visitor.STCP.SecretKey = %v, want %v", config.Visitors[0].STCP.SecretKey, "team-b-key")
+	}
+}
+
+func TestDeploymentReplicas(t *testing.T) {
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+	clientObj.Spec.Replicas = int32Ptr(3)
+
+	if got := DeploymentReplicas(clientObj); got != 3 {
+		t.Errorf("DeploymentReplicas() = %v, want 3", got)
+	}
+
+	clientObj.Spec.Suspend = true
+	if got := DeploymentReplicas(clientObj); got != 0 {
+		t.Errorf("DeploymentReplicas() = %v, want 0 while suspended", got)
+	}
+	if got := Replicas(clientObj); got != 3 {
+		t.Errorf("Replicas() = %v, want 3 while suspended", got)
+	}
+}
+
+func TestNewConfig_HTTPSTLSSecret(t *testing.T) {
+	tlsSecret := createSecret("team-a", "web-tls", map[string][]byte{
+		corev1.TLSCertKey:       []byte("certificate"),
+		corev1.TLSPrivateKeyKey: []byte("private key"),
+	})
+	fakeClient := createFakeClient(createDefaultTokenSecret("default"), tlsSecret).Build()
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+
+	upstreams := []frpv1alpha1.Upstream{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				HTTPS: &frpv1alpha1.UpstreamSpec_HTTPS{
+					Host:          "web.team-a.svc",
+					Port:          80,
+					CustomDomains: []string{"web.example.com"},
+					TLSSecret:     "web-tls",
+				},
+			},
+		},
+	}
+
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{})
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	https := config.Upstreams[0].HTTPS
+	if https.CertFile != "/frp-config/tls.team-a.web-tls.crt" || https.KeyFile != "/frp-config/tls.team-a.web-tls.key" {
+		t.Errorf("NewConfig() cert files = %s, %s, want files of team-a/web-tls", https.CertFile, https.KeyFile)
+	}
+	if string(config.Files["tls.team-a.web-tls.crt"]) != "certificate" || string(config.Files["tls.team-a.web-tls.key"]) != "private key" {
+		t.Errorf("NewConfig() files = %v, want the certificate and key of web-tls", config.Files)
+	}
+
+	tlsSecret.Data = map[string][]byte{corev1.TLSCertKey: []byte("certificate")}
+	fakeClient = createFakeClient(createDefaultTokenSecret("default"), tlsSecret).Build()
+	if _, err := NewConfig(fakeClient, clientObj, nil, upstreams, []frpv1alpha1.Visitor{}); err == nil {
+		t.Errorf("NewConfig() expected an error for a TLS secret without private key")
+	}
+}
@@ -0,0 +1,198 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	servermodels "github.com/zufardhiyaulhaq/frp-operator/pkg/server/models"
+)
+
+const DEFAULT_PROBE_INTERVAL = 30
+const DEFAULT_PROBE_TIMEOUT = 3
+
+// FAILOVER_HISTORY_LIMIT is the number of failovers kept in the Client status
+const FAILOVER_HISTORY_LIMIT = 10
+
+// ServerCandidate is a server a Client can connect to with the endpoint frpc dials
+type ServerCandidate struct {
+	Server   frpv1alpha1.ClientSpec_Server
+	Endpoint string
+}
+
+// Servers returns the servers of a Client in order of preference, spec.server
+// first and then the failover servers
+func Servers(clientObject *frpv1alpha1.Client) []frpv1alpha1.ClientSpec_Server {
+	primary := clientObject.Spec.Server
+	servers := []frpv1alpha1.ClientSpec_Server{primary}
+	if clientObject.Spec.Failover == nil {
+		return servers
+	}
+
+	for _, failover := range clientObject.Spec.Failover.Servers {
+		server := primary
+		server.Host = failover.Host
+		server.Port = failover.Port
+		server.ServerRef = failover.ServerRef
+		if failover.Protocol != nil {
+			server.Protocol = failover.Protocol
+		}
+		if failover.Authentication != nil {
+			server.Authentication = *failover.Authentication
+		}
+		if failover.TLS != nil {
+			server.TLS = failover.TLS
+		}
+		if failover.Transport != nil {
+			server.Transport = failover.Transport
+		}
+		servers = append(servers, server)
+	}
+
+	return servers
+}
+
+// WithServer returns a copy of the Client that connects to the given server
+func WithServer(clientObject *frpv1alpha1.Client, server frpv1alpha1.ClientSpec_Server) *frpv1alpha1.Client {
+	serverClient := clientObject.DeepCopy()
+	serverClient.Spec.Server = *server.DeepCopy()
+
+	return serverClient
+}
+
+// ActiveServer returns the server frpc connects to, the failover server recorded
+// in status.activeServer or spec.server
+func ActiveServer(k8sclient client.Reader, clientObject *frpv1alpha1.Client) (frpv1alpha1.ClientSpec_Server, error) {
+	if clientObject.Spec.Failover == nil || clientObject.Status.ActiveServer == "" {
+		return clientObject.Spec.Server, nil
+	}
+
+	candidates, err := ServerCandidates(k8sclient, clientObject)
+	if err != nil {
+		return frpv1alpha1.ClientSpec_Server{}, err
+	}
+	for _, candidate := range candidates {
+		if candidate.Endpoint == clientObject.Status.ActiveServer {
+			return candidate.Server, nil
+		}
+	}
+
+	return clientObject.Spec.Server, nil
+}
+
+// ServerEndpoint returns the address and port frpc dials the server of a Client on
+func ServerEndpoint(k8sclient client.Reader, clientObject *frpv1alpha1.Client) (string, int, error) {
+	if clientObject.Spec.Server.ServerRef == nil {
+		return clientObject.Spec.Server.Host, clientObject.Spec.Server.Port, nil
+	}
+
+	server, err := ServerRef(k8sclient, clientObject)
+	if err != nil {
+		return "", 0, err
+	}
+
+	protocol := ""
+	if clientObject.Spec.Server.Protocol != nil {
+		protocol = *clientObject.Spec.Server.Protocol
+	}
+
+	port, err := serverRefPort(server, protocol)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return servermodels.Address(server), port, nil
+}
+
+// ServerCandidates resolves the endpoint of every server of a Client
+func ServerCandidates(k8sclient client.Reader, clientObject *frpv1alpha1.Client) ([]ServerCandidate, error) {
+	candidates := []ServerCandidate{}
+	for i, server := range Servers(clientObject) {
+		address, port, err := ServerEndpoint(k8sclient, WithServer(clientObject, server))
+		if err != nil && i > 0 && errors.IsNotFound(err) {
+			// a failover Server that doesn't exist can't be failed over to
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		candidates = append(candidates, ServerCandidate{
+			Server:   server,
+			Endpoint: net.JoinHostPort(address, strconv.Itoa(port)),
+		})
+	}
+
+	return candidates, nil
+}
+
+// SelectServer returns the first candidate whose probe passed, along with the
+// reason frpc leaves the active server. probeErrs holds the probe result of every
+// candidate. The active server is kept when no candidate passes the probe. Without
+// a known active server frpc would connect to the first candidate, so choosing
+// another one is a failover as well.
+func SelectServer(candidates []ServerCandidate, active string, probeErrs []error) (int, string) {
+	activeIndex := -1
+	for i, candidate := range candidates {
+		if candidate.Endpoint == active {
+			activeIndex = i
+		}
+	}
+
+	for i, candidate := range candidates {
+		if probeErrs[i] != nil {
+			continue
+		}
+
+		switch {
+		case i == activeIndex:
+			return i, ""
+		case activeIndex == -1 && i == 0:
+			return i, ""
+		case activeIndex == -1:
+			return i, fmt.Sprintf("server %s is unreachable: %v", candidates[0].Endpoint, probeErrs[0])
+		case activeIndex < i:
+			return i, fmt.Sprintf("server %s is unreachable: %v", active, probeErrs[activeIndex])
+		default:
+			return i, fmt.Sprintf("preferred server %s is reachable again", candidate.Endpoint)
+		}
+	}
+
+	if activeIndex == -1 {
+		return 0, ""
+	}
+	return activeIndex, ""
+}
+
+// RecordFailover prepends a failover to the history, keeping the latest
+// FAILOVER_HISTORY_LIMIT entries
+func RecordFailover(history []frpv1alpha1.ClientStatus_Failover, failover frpv1alpha1.ClientStatus_Failover) []frpv1alpha1.ClientStatus_Failover {
+	history = append([]frpv1alpha1.ClientStatus_Failover{failover}, history...)
+	if len(history) > FAILOVER_HISTORY_LIMIT {
+		history = history[:FAILOVER_HISTORY_LIMIT]
+	}
+
+	return history
+}
+
+// ProbeInterval returns the interval between probes of the failover servers
+func ProbeInterval(clientObject *frpv1alpha1.Client) time.Duration {
+	if clientObject.Spec.Failover == nil || clientObject.Spec.Failover.ProbeInterval == 0 {
+		return DEFAULT_PROBE_INTERVAL * time.Second
+	}
+
+	return time.Duration(clientObject.Spec.Failover.ProbeInterval) * time.Second
+}
+
+// ProbeTimeout returns the time a probe of a failover server waits for a connection
+func ProbeTimeout(clientObject *frpv1alpha1.Client) time.Duration {
+	if clientObject.Spec.Failover == nil || clientObject.Spec.Failover.ProbeTimeout == 0 {
+		return DEFAULT_PROBE_TIMEOUT * time.Second
+	}
+
+	return time.Duration(clientObject.Spec.Failover.ProbeTimeout) * time.Second
+}
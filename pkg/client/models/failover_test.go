@@ -0,0 +1,146 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func createFailoverClient() *frpv1alpha1.Client {
+	clientObj := createBasicClient("default", "test-client", "primary.example.com", 7000)
+	clientObj.Spec.Failover = &frpv1alpha1.ClientSpec_Failover{
+		Servers: []frpv1alpha1.ClientSpec_FailoverServer{
+			{Host: "secondary.example.com", Port: 7000},
+			{
+				Host: "tertiary.example.com",
+				Port: 443,
+				Authentication: &frpv1alpha1.ClientSpec_Server_Authentication{
+					Token: &frpv1alpha1.ClientSpec_Server_Authentication_Token{
+						Secret: frpv1alpha1.Secret{Name: "tertiary-token", Key: "token"},
+					},
+				},
+				Protocol: stringPtr("wss"),
+			},
+		},
+	}
+
+	return clientObj
+}
+
+func TestServers(t *testing.T) {
+	servers := Servers(createFailoverClient())
+	if len(servers) != 3 {
+		t.Fatalf("Servers() returned %d servers, want 3", len(servers))
+	}
+
+	if servers[0].Host != "primary.example.com" {
+		t.Errorf("Servers()[0].Host = %v, want primary.example.com", servers[0].Host)
+	}
+	if servers[1].Host != "secondary.example.com" || servers[1].Authentication.Token.Secret.Name != "token-secret" {
+		t.Errorf("Servers()[1] = %+v, want secondary.example.com with the primary authentication", servers[1])
+	}
+	if servers[2].Authentication.Token.Secret.Name != "tertiary-token" || servers[2].Protocol == nil || *servers[2].Protocol != "wss" {
+		t.Errorf("Servers()[2] = %+v, want its own authentication and protocol", servers[2])
+	}
+}
+
+func TestServerCandidates_SkipsMissingServerRef(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = frpv1alpha1.AddToScheme(scheme)
+	server := &frpv1alpha1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge", Namespace: "default"},
+		Spec:       frpv1alpha1.ServerSpec{BindPort: 7100},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(server).Build()
+
+	clientObj := createBasicClient("default", "test-client", "primary.example.com", 7000)
+	clientObj.Spec.Failover = &frpv1alpha1.ClientSpec_Failover{
+		Servers: []frpv1alpha1.ClientSpec_FailoverServer{
+			{ServerRef: &frpv1alpha1.ClientSpec_Server_ServerRef{Name: "missing"}},
+			{ServerRef: &frpv1alpha1.ClientSpec_Server_ServerRef{Name: "edge"}},
+		},
+	}
+
+	candidates, err := ServerCandidates(fakeClient, clientObj)
+	if err != nil {
+		t.Fatalf("ServerCandidates() unexpected error = %v", err)
+	}
+
+	endpoints := []string{}
+	for _, candidate := range candidates {
+		endpoints = append(endpoints, candidate.Endpoint)
+	}
+	want := "primary.example.com:7000,edge-frps.default.svc:7100"
+	if strings.Join(endpoints, ",") != want {
+		t.Errorf("ServerCandidates() = %v, want %v", endpoints, want)
+	}
+}
+
+func TestSelectServer(t *testing.T) {
+	candidates := []ServerCandidate{
+		{Endpoint: "primary:7000"},
+		{Endpoint: "secondary:7000"},
+		{Endpoint: "tertiary:7000"},
+	}
+
+	tests := []struct {
+		name       string
+		active     string
+		down       []string
+		want       int
+		wantReason string
+	}{
+		{name: "first reconcile", active: "", want: 0},
+		{name: "active primary healthy", active: "primary:7000", want: 0},
+		{name: "primary down", active: "primary:7000", down: []string{"primary:7000"}, want: 1, wantReason: "server primary:7000 is unreachable"},
+		{name: "primary and secondary down", active: "primary:7000", down: []string{"primary:7000", "secondary:7000"}, want: 2, wantReason: "unreachable"},
+		{name: "fail back to primary", active: "secondary:7000", want: 0, wantReason: "preferred server primary:7000 is reachable again"},
+		{name: "stay on secondary while primary down", active: "secondary:7000", down: []string{"primary:7000"}, want: 1},
+		{name: "all down keeps active", active: "secondary:7000", down: []string{"primary:7000", "secondary:7000", "tertiary:7000"}, want: 1},
+		{name: "removed active server", active: "removed:7000", down: []string{"primary:7000"}, want: 1, wantReason: "server primary:7000 is unreachable"},
+		{name: "primary down on first reconcile", active: "", down: []string{"primary:7000"}, want: 1, wantReason: "server primary:7000 is unreachable"},
+		{name: "all down on first reconcile", active: "", down: []string{"primary:7000", "secondary:7000", "tertiary:7000"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probeErrs := make([]error, len(candidates))
+			for i, candidate := range candidates {
+				for _, down := range tt.down {
+					if candidate.Endpoint == down {
+						probeErrs[i] = fmt.Errorf("connection refused")
+					}
+				}
+			}
+
+			got, reason := SelectServer(candidates, tt.active, probeErrs)
+			if got != tt.want {
+				t.Errorf("SelectServer() = %v, want %v", got, tt.want)
+			}
+			if tt.wantReason == "" && reason != "" || !strings.Contains(reason, tt.wantReason) {
+				t.Errorf("SelectServer() reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestRecordFailover(t *testing.T) {
+	history := []frpv1alpha1.ClientStatus_Failover{}
+	for i := 0; i < FAILOVER_HISTORY_LIMIT+2; i++ {
+		history = RecordFailover(history, frpv1alpha1.ClientStatus_Failover{To: fmt.Sprintf("server-%d", i)})
+	}
+
+	if len(history) != FAILOVER_HISTORY_LIMIT {
+		t.Fatalf("RecordFailover() kept %d failovers, want %d", len(history), FAILOVER_HISTORY_LIMIT)
+	}
+	if history[0].To != fmt.Sprintf("server-%d", FAILOVER_HISTORY_LIMIT+1) {
+		t.Errorf("RecordFailover() newest failover = %v, want the last recorded", history[0].To)
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// CONFIG_FILES_PATH is where the config Secret is mounted in the frpc pod, the
+// files the configuration references are stored in it next to the configuration
+const CONFIG_FILES_PATH = "/frp-config"
+
+// TLSFileKeys returns the config Secret keys of the certificate and the private key
+// of a kubernetes.io/tls Secret
+func TLSFileKeys(namespace string, name string) (string, string) {
+	prefix := fmt.Sprintf("tls.%s.%s", namespace, name)
+	return prefix + ".crt", prefix + ".key"
+}
+
+// FilesHash returns a short hash of the files stored next to the configuration.
+// frpc reads them when a proxy starts, a change rolls the pods instead of a reload
+// racing the kubelet updating the mounted Secret.
+func FilesHash(files map[string][]byte) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write(files[key])
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
@@ -0,0 +1,20 @@
+package models
+
+import "testing"
+
+func TestFilesHash(t *testing.T) {
+	if got := FilesHash(nil); got != "" {
+		t.Errorf("FilesHash() = %q, want empty without files", got)
+	}
+
+	files := map[string][]byte{"tls.default.web.crt": []byte("a"), "tls.default.web.key": []byte("b")}
+	hash := FilesHash(files)
+	if len(hash) != 16 {
+		t.Errorf("FilesHash() = %q, want 16 characters", hash)
+	}
+
+	files["tls.default.web.crt"] = []byte("renewed")
+	if FilesHash(files) == hash {
+		t.Errorf("FilesHash() unchanged after a file changed")
+	}
+}
@@ -0,0 +1,247 @@
+package models
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	servermodels "github.com/zufardhiyaulhaq/frp-operator/pkg/server/models"
+)
+
+// PortRequest is an Upstream that needs a remote port from a PortPool
+type PortRequest struct {
+	// Upstream is the namespaced name of the Upstream
+	Upstream string
+	// Port is the port found in the Upstream status, it is kept when still free
+	Port int
+}
+
+// PortPoolServer returns the address and bind port of the frps a PortPool
+// allocates the ports of
+func PortPoolServer(k8sclient client.Reader, portPool *frpv1alpha1.PortPool) (string, error) {
+	server := portPool.Spec.Server
+	if server.ServerRef != nil {
+		frpServer := &frpv1alpha1.Server{}
+		err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: server.ServerRef.Name, Namespace: portPool.Namespace}, frpServer)
+		if err != nil {
+			return "", err
+		}
+
+		return serverIdentity(servermodels.Address(frpServer), servermodels.BindPort(frpServer)), nil
+	}
+
+	return serverIdentity(server.Host, server.Port), nil
+}
+
+// ClientServer returns the address and bind port of the frps a Client connects
+// to, the bind port of a referenced Server whatever the transport protocol. A
+// Client with failover connects to its active server.
+func ClientServer(k8sclient client.Reader, clientObject *frpv1alpha1.Client) (string, error) {
+	server, err := ActiveServer(k8sclient, clientObject)
+	if err != nil {
+		return "", err
+	}
+	clientObject = WithServer(clientObject, server)
+
+	if clientObject.Spec.Server.ServerRef != nil {
+		server, err := ServerRef(k8sclient, clientObject)
+		if err != nil {
+			return "", err
+		}
+
+		return serverIdentity(servermodels.Address(server), servermodels.BindPort(server)), nil
+	}
+
+	return serverIdentity(clientObject.Spec.Server.Host, clientObject.Spec.Server.Port), nil
+}
+
+func serverIdentity(host string, port int) string {
+	if host == "" {
+		return ""
+	}
+	if port == 0 {
+		port = servermodels.DEFAULT_BIND_PORT
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// PortPoolMatches reports whether a PortPool covers the server of a Client. The
+// PortPool and the Client may live in different namespaces, a missing Server
+// matches nothing.
+func PortPoolMatches(k8sclient client.Reader, portPool *frpv1alpha1.PortPool, clientObject *frpv1alpha1.Client) (bool, error) {
+	poolServer, err := PortPoolServer(k8sclient, portPool)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	clientServer, err := ClientServer(k8sclient, clientObject)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return poolServer != "" && poolServer == clientServer, nil
+}
+
+// PortPoolFor returns the PortPool allocating the ports of a Client, the first one
+// by namespace and name when several PortPools cover its server, or nil
+func PortPoolFor(k8sclient client.Reader, clientObject *frpv1alpha1.Client, portPools []frpv1alpha1.PortPool) (*frpv1alpha1.PortPool, error) {
+	var selected *frpv1alpha1.PortPool
+	for i := range portPools {
+		matches, err := PortPoolMatches(k8sclient, &portPools[i], clientObject)
+		if err != nil {
+			return nil, err
+		}
+		if !matches {
+			continue
+		}
+		if selected == nil || portPoolKey(&portPools[i]) < portPoolKey(selected) {
+			selected = &portPools[i]
+		}
+	}
+
+	return selected, nil
+}
+
+func portPoolKey(portPool *frpv1alpha1.PortPool) string {
+	return portPool.Namespace + "/" + portPool.Name
+}
+
+// AllocatePorts allocates a port of the PortPool to every request. Ports found in the
+// PortPool or Upstream status are kept, other requests get the lowest free port.
+// Reserved ports are used by Upstreams with a fixed server.port. It returns the
+// allocations sorted by Upstream and the requests left without a port.
+func AllocatePorts(portPool *frpv1alpha1.PortPool, requests []PortRequest, reserved map[int]bool) ([]frpv1alpha1.PortPoolStatus_Allocation, []string) {
+	previous := map[string]int{}
+	for _, allocation := range portPool.Status.Allocations {
+		previous[allocation.Upstream] = allocation.Port
+	}
+
+	sorted := append([]PortRequest{}, requests...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Upstream < sorted[j].Upstream
+	})
+
+	used := map[int]bool{}
+	for port := range reserved {
+		used[port] = true
+	}
+
+	allocated := map[string]int{}
+	for _, request := range sorted {
+		port := previous[request.Upstream]
+		if port == 0 {
+			port = request.Port
+		}
+		if port == 0 || used[port] || !portInRanges(portPool.Spec.Ranges, port) {
+			continue
+		}
+
+		allocated[request.Upstream] = port
+		used[port] = true
+	}
+
+	unallocated := []string{}
+	for _, request := range sorted {
+		if _, ok := allocated[request.Upstream]; ok {
+			continue
+		}
+
+		port := freePort(portPool.Spec.Ranges, used)
+		if port == 0 {
+			unallocated = append(unallocated, request.Upstream)
+			continue
+		}
+
+		allocated[request.Upstream] = port
+		used[port] = true
+	}
+
+	allocations := []frpv1alpha1.PortPoolStatus_Allocation{}
+	for _, request := range sorted {
+		if port, ok := allocated[request.Upstream]; ok {
+			allocations = append(allocations, frpv1alpha1.PortPoolStatus_Allocation{
+				Port:     port,
+				Upstream: request.Upstream,
+			})
+		}
+	}
+
+	return allocations, unallocated
+}
+
+// AvailablePorts returns the number of ports of the ranges that are neither
+// allocated nor reserved
+func AvailablePorts(ranges []frpv1alpha1.ServerSpec_PortRange, allocations []frpv1alpha1.PortPoolStatus_Allocation, reserved map[int]bool) int {
+	used := map[int]bool{}
+	for port := range reserved {
+		used[port] = true
+	}
+	for _, allocation := range allocations {
+		used[allocation.Port] = true
+	}
+
+	ports := map[int]bool{}
+	for _, portRange := range ranges {
+		start, end := rangeBounds(portRange)
+		for port := start; port <= end; port++ {
+			if !used[port] {
+				ports[port] = true
+			}
+		}
+	}
+
+	return len(ports)
+}
+
+func freePort(ranges []frpv1alpha1.ServerSpec_PortRange, used map[int]bool) int {
+	for _, portRange := range ranges {
+		start, end := rangeBounds(portRange)
+		for port := start; port <= end; port++ {
+			if !used[port] {
+				return port
+			}
+		}
+	}
+
+	return 0
+}
+
+func portInRanges(ranges []frpv1alpha1.ServerSpec_PortRange, port int) bool {
+	for _, portRange := range ranges {
+		start, end := rangeBounds(portRange)
+		if port >= start && port <= end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rangeBounds returns the first and last port of a range, a single port is a
+// range of one port
+func rangeBounds(portRange frpv1alpha1.ServerSpec_PortRange) (int, int) {
+	if portRange.Single != 0 {
+		return portRange.Single, portRange.Single
+	}
+
+	start, end := portRange.Start, portRange.End
+	if start < 1 {
+		start = 1
+	}
+	if end > 65535 {
+		end = 65535
+	}
+
+	return start, end
+}
@@ -0,0 +1,246 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func createPortPool(name string, ranges ...frpv1alpha1.ServerSpec_PortRange) *frpv1alpha1.PortPool {
+	return &frpv1alpha1.PortPool{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: frpv1alpha1.PortPoolSpec{
+			Server: frpv1alpha1.PortPoolSpec_Server{Host: "192.168.0.1"},
+			Ranges: ranges,
+		},
+	}
+}
+
+func createPortPoolClient(name string, namespace string, host string, port int) *frpv1alpha1.Client {
+	return &frpv1alpha1.Client{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: frpv1alpha1.ClientSpec{
+			Server: frpv1alpha1.ClientSpec_Server{Host: host, Port: port},
+		},
+	}
+}
+
+func createPortPoolFakeClient(objects ...runtime.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = frpv1alpha1.AddToScheme(scheme)
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build()
+}
+
+func TestPortPoolMatches(t *testing.T) {
+	server := &frpv1alpha1.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "frps", Namespace: "default"},
+		Spec:       frpv1alpha1.ServerSpec{BindPort: 7100},
+	}
+	fakeClient := createPortPoolFakeClient(server)
+
+	hostClient := createPortPoolClient("client-01", "default", "192.168.0.1", 7000)
+	refClient := createPortPoolClient("client-02", "default", "", 0)
+	refClient.Spec.Server.ServerRef = &frpv1alpha1.ClientSpec_Server_ServerRef{Name: "frps"}
+	serviceClient := createPortPoolClient("client-03", "team-a", "frps-frps.default.svc", 7100)
+	otherNamespaceClient := createPortPoolClient("client-04", "team-a", "192.168.0.1", 0)
+	missingRefClient := createPortPoolClient("client-05", "team-a", "", 0)
+	missingRefClient.Spec.Server.ServerRef = &frpv1alpha1.ClientSpec_Server_ServerRef{Name: "frps"}
+	failoverClient := createPortPoolClient("client-06", "default", "192.168.0.9", 7000)
+	failoverClient.Spec.Failover = &frpv1alpha1.ClientSpec_Failover{
+		Servers: []frpv1alpha1.ClientSpec_FailoverServer{{ServerRef: &frpv1alpha1.ClientSpec_Server_ServerRef{Name: "frps"}}},
+	}
+	failedOverClient := failoverClient.DeepCopy()
+	failedOverClient.Status.ActiveServer = "frps-frps.default.svc:7100"
+
+	hostPool := createPortPool("host-pool")
+	otherPortPool := createPortPool("other-port-pool")
+	otherPortPool.Spec.Server.Port = 7443
+	refPool := createPortPool("ref-pool")
+	refPool.Spec.Server = frpv1alpha1.PortPoolSpec_Server{ServerRef: &frpv1alpha1.ClientSpec_Server_ServerRef{Name: "frps"}}
+
+	tests := []struct {
+		name     string
+		portPool *frpv1alpha1.PortPool
+		client   *frpv1alpha1.Client
+		want     bool
+	}{
+		{name: "host matches", portPool: hostPool, client: hostClient, want: true},
+		{name: "host matches default port", portPool: hostPool, client: otherNamespaceClient, want: true},
+		{name: "host doesn't match other port", portPool: otherPortPool, client: hostClient, want: false},
+		{name: "host doesn't match serverRef", portPool: hostPool, client: refClient, want: false},
+		{name: "serverRef matches", portPool: refPool, client: refClient, want: true},
+		{name: "serverRef matches service address", portPool: refPool, client: serviceClient, want: true},
+		{name: "serverRef doesn't match host", portPool: refPool, client: hostClient, want: false},
+		{name: "missing serverRef", portPool: refPool, client: missingRefClient, want: false},
+		{name: "failover client on its primary server", portPool: refPool, client: failoverClient, want: false},
+		{name: "failover client on its active server", portPool: refPool, client: failedOverClient, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PortPoolMatches(fakeClient, tt.portPool, tt.client)
+			if err != nil {
+				t.Fatalf("PortPoolMatches() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("PortPoolMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortPoolFor(t *testing.T) {
+	fakeClient := createPortPoolFakeClient()
+	clientObject := createPortPoolClient("client-01", "default", "192.168.0.1", 7000)
+
+	portPools := []frpv1alpha1.PortPool{*createPortPool("pool-b"), *createPortPool("pool-a")}
+	portPools[1].Spec.Server.Host = "192.168.0.2"
+	portPools = append(portPools, *createPortPool("pool-c"))
+
+	selected, err := PortPoolFor(fakeClient, clientObject, portPools)
+	if err != nil {
+		t.Fatalf("PortPoolFor() unexpected error = %v", err)
+	}
+	if selected == nil || selected.Name != "pool-b" {
+		t.Fatalf("PortPoolFor() = %v, want pool-b", selected)
+	}
+
+	clientObject.Spec.Server.Host = "192.168.0.3"
+	selected, err = PortPoolFor(fakeClient, clientObject, portPools)
+	if err != nil {
+		t.Fatalf("PortPoolFor() unexpected error = %v", err)
+	}
+	if selected != nil {
+		t.Errorf("PortPoolFor() = %v, want nil", selected.Name)
+	}
+}
+
+func TestPortPoolFor_CrossNamespace(t *testing.T) {
+	fakeClient := createPortPoolFakeClient()
+
+	ops := *createPortPool("shared")
+	ops.Namespace = "ops"
+	team := *createPortPool("shared")
+	team.Namespace = "team-b"
+	portPools := []frpv1alpha1.PortPool{team, ops}
+
+	clients := []*frpv1alpha1.Client{
+		createPortPoolClient("client-01", "team-a", "192.168.0.1", 7000),
+		createPortPoolClient("client-01", "team-b", "192.168.0.1", 0),
+	}
+
+	// Clients of different namespaces on the same server are allocated by one pool
+	requests := []PortRequest{}
+	for _, clientObject := range clients {
+		selected, err := PortPoolFor(fakeClient, clientObject, portPools)
+		if err != nil {
+			t.Fatalf("PortPoolFor() unexpected error = %v", err)
+		}
+		if selected == nil || selected.Namespace != "ops" {
+			t.Fatalf("PortPoolFor() = %v, want ops/shared", selected)
+		}
+		requests = append(requests, PortRequest{Upstream: clientObject.Namespace + "/nginx"})
+	}
+
+	ops.Spec.Ranges = []frpv1alpha1.ServerSpec_PortRange{{Start: 30000, End: 30009}}
+	allocations, _ := AllocatePorts(&ops, requests, map[int]bool{})
+
+	want := []frpv1alpha1.PortPoolStatus_Allocation{
+		{Port: 30000, Upstream: "team-a/nginx"},
+		{Port: 30001, Upstream: "team-b/nginx"},
+	}
+	if !reflect.DeepEqual(allocations, want) {
+		t.Errorf("AllocatePorts() allocations = %v, want %v", allocations, want)
+	}
+}
+
+func TestAllocatePorts(t *testing.T) {
+	portPool := createPortPool("pool", frpv1alpha1.ServerSpec_PortRange{Start: 30000, End: 30003})
+	portPool.Status.Allocations = []frpv1alpha1.PortPoolStatus_Allocation{
+		{Port: 30002, Upstream: "default/upstream-b"},
+		{Port: 30003, Upstream: "default/removed"},
+	}
+
+	requests := []PortRequest{
+		{Upstream: "default/upstream-c"},
+		{Upstream: "default/upstream-b"},
+		{Upstream: "default/upstream-a", Port: 30003},
+	}
+
+	allocations, unallocated := AllocatePorts(portPool, requests, map[int]bool{30000: true})
+
+	want := []frpv1alpha1.PortPoolStatus_Allocation{
+		{Port: 30003, Upstream: "default/upstream-a"},
+		{Port: 30002, Upstream: "default/upstream-b"},
+		{Port: 30001, Upstream: "default/upstream-c"},
+	}
+	if !reflect.DeepEqual(allocations, want) {
+		t.Errorf("AllocatePorts() allocations = %v, want %v", allocations, want)
+	}
+	if len(unallocated) != 0 {
+		t.Errorf("AllocatePorts() unallocated = %v, want none", unallocated)
+	}
+}
+
+func TestAllocatePorts_ReleasesTakenPorts(t *testing.T) {
+	portPool := createPortPool("pool", frpv1alpha1.ServerSpec_PortRange{Start: 30000, End: 30009})
+
+	requests := []PortRequest{
+		{Upstream: "default/upstream-a", Port: 30005},
+		{Upstream: "default/upstream-b", Port: 30005},
+		{Upstream: "default/upstream-c", Port: 40000},
+	}
+
+	allocations, _ := AllocatePorts(portPool, requests, map[int]bool{30000: true})
+
+	want := []frpv1alpha1.PortPoolStatus_Allocation{
+		{Port: 30005, Upstream: "default/upstream-a"},
+		{Port: 30001, Upstream: "default/upstream-b"},
+		{Port: 30002, Upstream: "default/upstream-c"},
+	}
+	if !reflect.DeepEqual(allocations, want) {
+		t.Errorf("AllocatePorts() allocations = %v, want %v", allocations, want)
+	}
+}
+
+func TestAllocatePorts_Exhausted(t *testing.T) {
+	portPool := createPortPool("pool",
+		frpv1alpha1.ServerSpec_PortRange{Single: 30000},
+		frpv1alpha1.ServerSpec_PortRange{Single: 30001},
+	)
+
+	requests := []PortRequest{
+		{Upstream: "default/upstream-a"},
+		{Upstream: "default/upstream-b"},
+		{Upstream: "default/upstream-c"},
+	}
+
+	allocations, unallocated := AllocatePorts(portPool, requests, map[int]bool{30001: true})
+
+	want := []frpv1alpha1.PortPoolStatus_Allocation{{Port: 30000, Upstream: "default/upstream-a"}}
+	if !reflect.DeepEqual(allocations, want) {
+		t.Errorf("AllocatePorts() allocations = %v, want %v", allocations, want)
+	}
+	if !reflect.DeepEqual(unallocated, []string{"default/upstream-b", "default/upstream-c"}) {
+		t.Errorf("AllocatePorts() unallocated = %v, want upstream-b and upstream-c", unallocated)
+	}
+}
+
+func TestAvailablePorts(t *testing.T) {
+	ranges := []frpv1alpha1.ServerSpec_PortRange{
+		{Start: 30000, End: 30009},
+		{Single: 30005},
+		{Single: 40000},
+	}
+	allocations := []frpv1alpha1.PortPoolStatus_Allocation{{Port: 30001, Upstream: "default/upstream-a"}}
+
+	if got := AvailablePorts(ranges, allocations, map[int]bool{40000: true}); got != 9 {
+		t.Errorf("AvailablePorts() = %v, want 9", got)
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+const DEFAULT_REVISION_HISTORY_LIMIT = 10
+
+// CONFIG_REVISION_LABEL holds the revision of a revision Secret, the hash of the
+// configuration it stores
+const CONFIG_REVISION_LABEL = "frp.zufardhiyaulhaq.com/config-revision"
+
+// CONFIG_REVISION_CLIENT_LABEL holds the name of the Client of a revision Secret
+const CONFIG_REVISION_CLIENT_LABEL = "frp.zufardhiyaulhaq.com/client"
+
+// RevisionSecretName returns the name of the Secret storing a revision of the
+// configuration of a client
+func RevisionSecretName(clientName string, revision string) string {
+	return clientName + "-frpc-config-" + revision
+}
+
+// RevisionHistoryLimit returns the number of revisions kept for a Client
+func RevisionHistoryLimit(clientObject *frpv1alpha1.Client) int {
+	if clientObject.Spec.RevisionHistoryLimit == nil {
+		return DEFAULT_REVISION_HISTORY_LIMIT
+	}
+
+	return int(*clientObject.Spec.RevisionHistoryLimit)
+}
+
+// ConfigGenerations returns the generation of the Client, Upstreams and Visitors
+// a configuration is rendered from, keyed by kind and namespaced name
+func ConfigGenerations(clientObject *frpv1alpha1.Client, upstreams []frpv1alpha1.Upstream, visitors []frpv1alpha1.Visitor) map[string]int64 {
+	generations := map[string]int64{
+		"Client/" + clientObject.Namespace + "/" + clientObject.Name: clientObject.Generation,
+	}
+	for _, upstream := range upstreams {
+		generations["Upstream/"+upstream.Namespace+"/"+upstream.Name] = upstream.Generation
+	}
+	for _, visitor := range visitors {
+		generations["Visitor/"+visitor.Namespace+"/"+visitor.Name] = visitor.Generation
+	}
+
+	return generations
+}
+
+// PruneRevisions returns the revision Secrets beyond the history limit, the
+// newest revisions and the revisions to keep aren't pruned
+func PruneRevisions(revisions []corev1.Secret, limit int, keep ...string) []corev1.Secret {
+	sorted := append([]corev1.Secret{}, revisions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].CreationTimestamp.Equal(&sorted[j].CreationTimestamp) {
+			return sorted[i].Name > sorted[j].Name
+		}
+		return sorted[j].CreationTimestamp.Before(&sorted[i].CreationTimestamp)
+	})
+
+	kept := map[string]bool{}
+	for _, revision := range keep {
+		if revision != "" {
+			kept[revision] = true
+		}
+	}
+
+	pruned := []corev1.Secret{}
+	count := 0
+	for _, revision := range sorted {
+		if kept[revision.Labels[CONFIG_REVISION_LABEL]] || count < limit {
+			count++
+			continue
+		}
+		pruned = append(pruned, revision)
+	}
+
+	return pruned
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+func createRevision(revision string, age time.Duration) corev1.Secret {
+	return corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              RevisionSecretName("client-01", revision),
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Add(-age)),
+			Labels:            map[string]string{CONFIG_REVISION_LABEL: revision},
+		},
+	}
+}
+
+func TestRevisionHistoryLimit(t *testing.T) {
+	clientObject := &frpv1alpha1.Client{}
+	if got := RevisionHistoryLimit(clientObject); got != DEFAULT_REVISION_HISTORY_LIMIT {
+		t.Errorf("RevisionHistoryLimit() = %v, want %v", got, DEFAULT_REVISION_HISTORY_LIMIT)
+	}
+
+	limit := int32(3)
+	clientObject.Spec.RevisionHistoryLimit = &limit
+	if got := RevisionHistoryLimit(clientObject); got != 3 {
+		t.Errorf("RevisionHistoryLimit() = %v, want 3", got)
+	}
+}
+
+func TestConfigGenerations(t *testing.T) {
+	clientObject := &frpv1alpha1.Client{
+		ObjectMeta: metav1.ObjectMeta{Name: "client-01", Namespace: "default", Generation: 4},
+	}
+	upstreams := []frpv1alpha1.Upstream{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps", Generation: 2}},
+	}
+	visitors := []frpv1alpha1.Visitor{
+		{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Generation: 7}},
+	}
+
+	generations := ConfigGenerations(clientObject, upstreams, visitors)
+	want := map[string]int64{
+		"Client/default/client-01": 4,
+		"Upstream/apps/web":        2,
+		"Visitor/default/db":       7,
+	}
+	if len(generations) != len(want) {
+		t.Fatalf("ConfigGenerations() = %v, want %v", generations, want)
+	}
+	for key, generation := range want {
+		if generations[key] != generation {
+			t.Errorf("ConfigGenerations() %s = %v, want %v", key, generations[key], generation)
+		}
+	}
+}
+
+func TestPruneRevisions(t *testing.T) {
+	revisions := []corev1.Secret{
+		createRevision("0000000000000003", 3*time.Hour),
+		createRevision("0000000000000001", 1*time.Hour),
+		createRevision("0000000000000004", 4*time.Hour),
+		createRevision("0000000000000002", 2*time.Hour),
+	}
+
+	pruned := PruneRevisions(revisions, 2)
+	if len(pruned) != 2 || pruned[0].Labels[CONFIG_REVISION_LABEL] != "0000000000000003" || pruned[1].Labels[CONFIG_REVISION_LABEL] != "0000000000000004" {
+		t.Errorf("PruneRevisions() = %v, want the two oldest revisions", pruned)
+	}
+
+	pruned = PruneRevisions(revisions, 2, "0000000000000004", "")
+	if len(pruned) != 1 || pruned[0].Labels[CONFIG_REVISION_LABEL] != "0000000000000003" {
+		t.Errorf("PruneRevisions() = %v, want the kept revision to stay", pruned)
+	}
+
+	if pruned := PruneRevisions(revisions, 10); len(pruned) != 0 {
+		t.Errorf("PruneRevisions() = %v, want none within the limit", pruned)
+	}
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CONFIG_VERIFY_PERIOD is how long the proxies of a reloaded configuration may take
+// to start before the configuration becomes the last known good one
+const CONFIG_VERIFY_PERIOD = 30 * time.Second
+
+// CONFIG_DIFF_LIMIT is the maximum length of a configuration diff in a condition
+const CONFIG_DIFF_LIMIT = 4096
+
+// CONFIG_DIFF_MAX_CELLS bounds the table of the line diff. Changed regions larger
+// than that are reported as every previous line removed and every next line added.
+const CONFIG_DIFF_MAX_CELLS = 1 << 18
+
+// secretConfigKeys are the configuration keys whose values are redacted from diffs
+var secretConfigKeys = []string{"token", "secret", "password", "groupkey"}
+
+// secretConfigTables are the configuration tables whose values are all redacted
+// from diffs, header values often carry credentials
+var secretConfigTables = []string{"headers"}
+
+// urlUserinfo matches the userinfo of a URL such as the proxyURL of a transport
+var urlUserinfo = regexp.MustCompile(`://[^/@"\s]*@`)
+
+// ConfigHash returns a short hash of a rendered configuration
+func ConfigHash(config string) string {
+	hash := sha256.Sum256([]byte(config))
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// ConfigDiff returns the lines removed from and added to a configuration, with
+// the values of tokens, secret keys, passwords, headers and URL credentials redacted
+func ConfigDiff(previous string, next string) string {
+	previousLines := configLines(previous)
+	nextLines := configLines(next)
+	previousRedacted := redactConfigLines(previousLines)
+	nextRedacted := redactConfigLines(nextLines)
+
+	// only the region between the common first and last lines is compared
+	prefix := 0
+	for prefix < len(previousLines) && prefix < len(nextLines) && previousLines[prefix] == nextLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(previousLines)-prefix && suffix < len(nextLines)-prefix &&
+		previousLines[len(previousLines)-1-suffix] == nextLines[len(nextLines)-1-suffix] {
+		suffix++
+	}
+	previousChanged := previousLines[prefix : len(previousLines)-suffix]
+	nextChanged := nextLines[prefix : len(nextLines)-suffix]
+
+	diff := []string{}
+	removed := func(i int) { diff = append(diff, "- "+previousRedacted[prefix+i]) }
+	added := func(j int) { diff = append(diff, "+ "+nextRedacted[prefix+j]) }
+
+	if (len(previousChanged)+1)*(len(nextChanged)+1) > CONFIG_DIFF_MAX_CELLS {
+		for i := range previousChanged {
+			removed(i)
+		}
+		for j := range nextChanged {
+			added(j)
+		}
+	} else {
+		diffLines(previousChanged, nextChanged, removed, added)
+	}
+
+	return truncateConfigDiff(strings.Join(diff, "\n"))
+}
+
+// truncateConfigDiff cuts a diff longer than CONFIG_DIFF_LIMIT after its last
+// complete line, or before the rune crossing the limit when the first line is
+// longer than that
+func truncateConfigDiff(diff string) string {
+	if len(diff) <= CONFIG_DIFF_LIMIT {
+		return diff
+	}
+
+	end := strings.LastIndexByte(diff[:CONFIG_DIFF_LIMIT+1], '\n')
+	if end <= 0 {
+		end = CONFIG_DIFF_LIMIT
+		for end > 0 && !utf8.RuneStart(diff[end]) {
+			end--
+		}
+	}
+
+	return diff[:end] + "\n..."
+}
+
+// diffLines calls removed and added for the lines outside the longest common
+// subsequence of previous and next, in order
+func diffLines(previous []string, next []string, removed func(int), added func(int)) {
+	// common[i*width+j] is the length of the longest common subsequence of
+	// previous[i:] and next[j:]
+	width := len(next) + 1
+	common := make([]int32, (len(previous)+1)*width)
+	for i := len(previous) - 1; i >= 0; i-- {
+		for j := len(next) - 1; j >= 0; j-- {
+			if previous[i] == next[j] {
+				common[i*width+j] = common[(i+1)*width+j+1] + 1
+			} else {
+				common[i*width+j] = max(common[(i+1)*width+j], common[i*width+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(previous) || j < len(next) {
+		switch {
+		case i < len(previous) && j < len(next) && previous[i] == next[j]:
+			i++
+			j++
+		case j == len(next) || (i < len(previous) && common[(i+1)*width+j] >= common[i*width+j+1]):
+			removed(i)
+			i++
+		default:
+			added(j)
+			j++
+		}
+	}
+}
+
+func configLines(config string) []string {
+	config = strings.TrimRight(config, "\n")
+	if config == "" {
+		return nil
+	}
+
+	return strings.Split(config, "\n")
+}
+
+// redactConfigLines returns the lines of a configuration with their secret values
+// redacted, a line is redacted by its key or by the table it is in
+func redactConfigLines(lines []string) []string {
+	redacted := make([]string, len(lines))
+	secretTable := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			table := strings.ToLower(strings.Trim(trimmed, "[]"))
+			secretTable = false
+			for _, secretTableName := range secretConfigTables {
+				if strings.Contains(table, secretTableName) {
+					secretTable = true
+				}
+			}
+			redacted[i] = line
+			continue
+		}
+
+		redacted[i] = redactConfigLine(line, secretTable)
+	}
+
+	return redacted
+}
+
+func redactConfigLine(line string, secretTable bool) string {
+	key, _, ok := strings.Cut(line, "=")
+	if !ok {
+		return line
+	}
+
+	if secretTable {
+		return strings.TrimRight(key, " ") + " = <redacted>"
+	}
+
+	name := strings.ToLower(strings.TrimSpace(key))
+	for _, secretKey := range secretConfigKeys {
+		if strings.Contains(name, secretKey) {
+			return strings.TrimRight(key, " ") + " = <redacted>"
+		}
+	}
+
+	return urlUserinfo.ReplaceAllString(line, "://<redacted>@")
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+// MAX_SCHEDULE_ACTIVATIONS bounds the overlapping window activations followed to
+// find when an open schedule closes
+const MAX_SCHEDULE_ACTIVATIONS = 1000
+
+// Schedule is the state of the schedule of an Upstream at a point in time
+type Schedule struct {
+	// Active reports whether the proxy is rendered
+	Active bool
+	// Expired reports whether spec.schedule.expiresAt passed
+	Expired bool
+	// NextTransition is when the proxy is next added or removed, zero when never
+	NextTransition time.Time
+}
+
+type scheduleWindow struct {
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// UpstreamSchedule returns whether the proxy of an Upstream is rendered at now and
+// when that changes next. Overlapping and adjacent windows are merged.
+func UpstreamSchedule(upstream *frpv1alpha1.Upstream, now time.Time) (Schedule, error) {
+	spec := upstream.Spec.Schedule
+	if spec == nil {
+		return Schedule{Active: true}, nil
+	}
+
+	if spec.ExpiresAt != nil && !now.Before(spec.ExpiresAt.Time) {
+		return Schedule{Expired: true}, nil
+	}
+
+	location, err := time.LoadLocation(spec.TimeZone)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("invalid time zone %s: %v", spec.TimeZone, err)
+	}
+	now = now.In(location)
+
+	windows := []scheduleWindow{}
+	for _, window := range spec.Windows {
+		schedule, err := cron.ParseStandard(window.Start)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("invalid window start %s: %v", window.Start, err)
+		}
+		windows = append(windows, scheduleWindow{schedule: schedule, duration: window.Duration.Duration})
+	}
+
+	result := Schedule{Active: len(windows) == 0}
+	if len(windows) > 0 {
+		end := scheduleEnd(windows, now)
+		if end.After(now) {
+			result.Active = true
+			result.NextTransition = end
+		} else {
+			result.NextTransition = scheduleStart(windows, now)
+		}
+	}
+
+	if spec.ExpiresAt != nil && (result.NextTransition.IsZero() || spec.ExpiresAt.Time.Before(result.NextTransition)) {
+		result.NextTransition = spec.ExpiresAt.Time
+	}
+
+	return result, nil
+}
+
+// scheduleEnd returns when the windows open at now close, now when none is open
+func scheduleEnd(windows []scheduleWindow, now time.Time) time.Time {
+	end := now
+	for i := 0; i < MAX_SCHEDULE_ACTIVATIONS; i++ {
+		extended := false
+		for _, window := range windows {
+			// the last activation of the window at or before end keeps it open past end
+			start := window.schedule.Next(end.Add(-window.duration))
+			if start.IsZero() || start.After(end) {
+				continue
+			}
+			if windowEnd := start.Add(window.duration); windowEnd.After(end) {
+				end = windowEnd
+				extended = true
+			}
+		}
+		if !extended {
+			break
+		}
+	}
+
+	return end
+}
+
+// scheduleStart returns when the next window opens after now, zero when none does
+func scheduleStart(windows []scheduleWindow, now time.Time) time.Time {
+	var start time.Time
+	for _, window := range windows {
+		next := window.schedule.Next(now)
+		if !next.IsZero() && (start.IsZero() || next.Before(start)) {
+			start = next
+		}
+	}
+
+	return start
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func createScheduledUpstream(schedule *frpv1alpha1.UpstreamSpec_Schedule) *frpv1alpha1.Upstream {
+	return &frpv1alpha1.Upstream{
+		ObjectMeta: metav1.ObjectMeta{Name: "support", Namespace: "default"},
+		Spec: frpv1alpha1.UpstreamSpec{
+			Client:   "client-01",
+			Schedule: schedule,
+		},
+	}
+}
+
+func createWindow(start string, duration time.Duration) frpv1alpha1.UpstreamSpec_Schedule_Window {
+	return frpv1alpha1.UpstreamSpec_Schedule_Window{Start: start, Duration: metav1.Duration{Duration: duration}}
+}
+
+func TestUpstreamSchedule(t *testing.T) {
+	businessHours := &frpv1alpha1.UpstreamSpec_Schedule{
+		Windows: []frpv1alpha1.UpstreamSpec_Schedule_Window{createWindow("0 9 * * 1-5", 8*time.Hour)},
+	}
+	expiring := businessHours.DeepCopy()
+	expiring.ExpiresAt = &metav1.Time{Time: time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name     string
+		schedule *frpv1alpha1.UpstreamSpec_Schedule
+		now      time.Time
+		want     Schedule
+	}{
+		{
+			name: "no schedule",
+			now:  time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC),
+			want: Schedule{Active: true},
+		},
+		{
+			name:     "inside window",
+			schedule: businessHours,
+			now:      time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC),
+			want:     Schedule{Active: true, NextTransition: time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "window opening",
+			schedule: businessHours,
+			now:      time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+			want:     Schedule{Active: true, NextTransition: time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "window closing",
+			schedule: businessHours,
+			now:      time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC),
+			want:     Schedule{NextTransition: time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "weekend",
+			schedule: businessHours,
+			now:      time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC),
+			want:     Schedule{NextTransition: time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "expires inside window",
+			schedule: expiring,
+			now:      time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC),
+			want:     Schedule{Active: true, NextTransition: time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "expired",
+			schedule: expiring,
+			now:      time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC),
+			want:     Schedule{Expired: true},
+		},
+		{
+			name:     "expiry without windows",
+			schedule: &frpv1alpha1.UpstreamSpec_Schedule{ExpiresAt: expiring.ExpiresAt},
+			now:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:     Schedule{Active: true, NextTransition: time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UpstreamSchedule(createScheduledUpstream(tt.schedule), tt.now)
+			if err != nil {
+				t.Fatalf("UpstreamSchedule() unexpected error = %v", err)
+			}
+			if got.Active != tt.want.Active || got.Expired != tt.want.Expired || !got.NextTransition.Equal(tt.want.NextTransition) {
+				t.Errorf("UpstreamSchedule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpstreamSchedule_MergesWindows(t *testing.T) {
+	upstream := createScheduledUpstream(&frpv1alpha1.UpstreamSpec_Schedule{
+		Windows: []frpv1alpha1.UpstreamSpec_Schedule_Window{
+			createWindow("0 22 * * *", 2*time.Hour),
+			createWindow("0 0 * * *", time.Hour),
+			createWindow("30 0 * * *", time.Hour),
+		},
+	})
+
+	got, err := UpstreamSchedule(upstream, time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("UpstreamSchedule() unexpected error = %v", err)
+	}
+
+	want := time.Date(2024, 1, 2, 1, 30, 0, 0, time.UTC)
+	if !got.Active || !got.NextTransition.Equal(want) {
+		t.Errorf("UpstreamSchedule() = %+v, want active until %v", got, want)
+	}
+}
+
+func TestUpstreamSchedule_TimeZone(t *testing.T) {
+	upstream := createScheduledUpstream(&frpv1alpha1.UpstreamSpec_Schedule{
+		Windows:  []frpv1alpha1.UpstreamSpec_Schedule_Window{createWindow("0 9 * * *", time.Hour)},
+		TimeZone: "Asia/Jakarta",
+	})
+
+	got, err := UpstreamSchedule(upstream, time.Date(2024, 1, 1, 2, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("UpstreamSchedule() unexpected error = %v", err)
+	}
+
+	want := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	if !got.Active || !got.NextTransition.Equal(want) {
+		t.Errorf("UpstreamSchedule() = %+v, want active until %v", got, want)
+	}
+}
+
+func TestUpstreamSchedule_Invalid(t *testing.T) {
+	upstream := createScheduledUpstream(&frpv1alpha1.UpstreamSpec_Schedule{
+		Windows: []frpv1alpha1.UpstreamSpec_Schedule_Window{createWindow("every day", time.Hour)},
+	})
+
+	if _, err := UpstreamSchedule(upstream, time.Now()); err == nil {
+		t.Errorf("UpstreamSchedule() expected an error for an invalid window start")
+	}
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"sort"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+// secretNames collects unique secret names in a stable order
+type secretNames map[string]struct{}
+
+func (s secretNames) add(name string) {
+	if name != "" {
+		s[name] = struct{}{}
+	}
+}
+
+func (s secretNames) addRef(ref *frpv1alpha1.SecretRef) {
+	if ref != nil {
+		s.add(ref.Secret.Name)
+	}
+}
+
+func (s secretNames) list() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ClientSecretNames returns the names of the secrets a client configuration reads,
+// including the secrets of its failover servers
+func ClientSecretNames(clientObject *frpv1alpha1.Client) []string {
+	names := secretNames{}
+
+	if adminServer := clientObject.Spec.Server.AdminServer; adminServer != nil {
+		if adminServer.Username != nil {
+			names.add(adminServer.Username.Secret.Name)
+		}
+		if adminServer.Password != nil {
+			names.add(adminServer.Password.Secret.Name)
+		}
+	}
+
+	for _, server := range Servers(clientObject) {
+		if server.Authentication.Token != nil {
+			names.add(server.Authentication.Token.Secret.Name)
+		}
+		if server.Authentication.OIDC != nil {
+			names.addRef(&server.Authentication.OIDC.ClientID)
+			names.addRef(&server.Authentication.OIDC.ClientSecret)
+		}
+
+		if server.TLS != nil {
+			names.addRef(server.TLS.CertFile)
+			names.addRef(server.TLS.KeyFile)
+			if server.TLS.TrustedCAFile != nil && server.TLS.TrustedCAFile.Secret != nil {
+				names.add(server.TLS.TrustedCAFile.Secret.Name)
+			}
+		}
+	}
+
+	return names.list()
+}
+
+// UpstreamSecretNames returns the names of the secrets an upstream configuration reads
+func UpstreamSecretNames(upstreamObject *frpv1alpha1.Upstream) []string {
+	names := secretNames{}
+	spec := upstreamObject.Spec
+
+	if spec.TCP != nil {
+		if spec.TCP.LoadBalancer != nil {
+			names.addRef(spec.TCP.LoadBalancer.GroupKey)
+		}
+		if spec.TCP.Plugin != nil {
+			names.addRef(spec.TCP.Plugin.Username)
+			names.addRef(spec.TCP.Plugin.Password)
+			names.addRef(spec.TCP.Plugin.HTTPUser)
+			names.addRef(spec.TCP.Plugin.HTTPPassword)
+		}
+	}
+	if spec.STCP != nil {
+		names.add(spec.STCP.SecretKey.Secret.Name)
+	}
+	if spec.XTCP != nil {
+		names.add(spec.XTCP.SecretKey.Secret.Name)
+	}
+	if spec.SUDP != nil {
+		names.add(spec.SUDP.SecretKey.Secret.Name)
+	}
+	if spec.HTTP != nil {
+		names.addRef(spec.HTTP.HTTPUser)
+		names.addRef(spec.HTTP.HTTPPassword)
+	}
+	if spec.HTTPS != nil {
+		names.add(spec.HTTPS.TLSSecret)
+	}
+
+	return names.list()
+}
+
+// VisitorSecretNames returns the names of the secrets a visitor configuration reads
+func VisitorSecretNames(visitorObject *frpv1alpha1.Visitor) []string {
+	names := secretNames{}
+
+	if visitorObject.Spec.STCP != nil {
+		names.add(visitorObject.Spec.STCP.ServerSecretKey.Secret.Name)
+	}
+	if visitorObject.Spec.XTCP != nil {
+		names.add(visitorObject.Spec.XTCP.ServerSecretKey.Secret.Name)
+	}
+	if visitorObject.Spec.SUDP != nil {
+		names.add(visitorObject.Spec.SUDP.ServerSecretKey.Secret.Name)
+	}
+
+	return names.list()
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClientSecretNames(t *testing.T) {
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+	clientObj.Spec.Server.AdminServer = &frpv1alpha1.ClientSpec_Server_AdminServer{
+		Port: 7400,
+		Username: &frpv1alpha1.ClientSpec_Server_AdminServer_Username{
+			Secret: frpv1alpha1.Secret{Name: "admin-secret", Key: "username"},
+		},
+		Password: &frpv1alpha1.ClientSpec_Server_AdminServer_Password{
+			Secret: frpv1alpha1.Secret{Name: "admin-secret", Key: "password"},
+		},
+	}
+	clientObj.Spec.Server.TLS = &frpv1alpha1.ClientSpec_Server_TLS{
+		Enable:   true,
+		CertFile: &frpv1alpha1.SecretRef{Secret: frpv1alpha1.Secret{Name: "tls-secret", Key: "tls.crt"}},
+		KeyFile:  &frpv1alpha1.SecretRef{Secret: frpv1alpha1.Secret{Name: "tls-secret", Key: "tls.key"}},
+		TrustedCAFile: &frpv1alpha1.ConfigMapOrSecretRef{
+			ConfigMap: &frpv1alpha1.ConfigMapRef{Name: "ca-configmap", Key: "ca.crt"},
+		},
+	}
+
+	want := []string{"admin-secret", "tls-secret", "token-secret"}
+	if got := ClientSecretNames(clientObj); !reflect.DeepEqual(got, want) {
+		t.Errorf("ClientSecretNames() = %v, want %v", got, want)
+	}
+}
+
+func TestClientSecretNames_OIDC(t *testing.T) {
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+	clientObj.Spec.Server.Authentication.Token = nil
+	clientObj.Spec.Server.Authentication.OIDC = &frpv1alpha1.ClientSpec_Server_Authentication_OIDC{
+		ClientID:     frpv1alpha1.SecretRef{Secret: frpv1alpha1.Secret{Name: "oidc-id", Key: "id"}},
+		ClientSecret: frpv1alpha1.SecretRef{Secret: frpv1alpha1.Secret{Name: "oidc-secret", Key: "secret"}},
+	}
+
+	want := []string{"oidc-id", "oidc-secret"}
+	if got := ClientSecretNames(clientObj); !reflect.DeepEqual(got, want) {
+		t.Errorf("ClientSecretNames() = %v, want %v", got, want)
+	}
+}
+
+func TestClientSecretNames_Failover(t *testing.T) {
+	clientObj := createFailoverClient()
+	clientObj.Spec.Failover.Servers[0].TLS = &frpv1alpha1.ClientSpec_Server_TLS{
+		Enable:   true,
+		CertFile: &frpv1alpha1.SecretRef{Secret: frpv1alpha1.Secret{Name: "secondary-tls", Key: "tls.crt"}},
+	}
+
+	want := []string{"secondary-tls", "tertiary-token", "token-secret"}
+	if got := ClientSecretNames(clientObj); !reflect.DeepEqual(got, want) {
+		t.Errorf("ClientSecretNames() = %v, want %v", got, want)
+	}
+}
+
+func TestUpstreamSecretNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		upstream frpv1alpha1.Upstream
+		want     []string
+	}{
+		{
+			name: "TCP with load balancer and plugin",
+			upstream: frpv1alpha1.Upstream{
+				ObjectMeta: metav1.ObjectMeta{Name: "tcp"},
+				Spec: frpv1alpha1.UpstreamSpec{
+					TCP: &frpv1alpha1.UpstreamSpec_TCP{
+						LoadBalancer: &frpv1alpha1.LoadBalancer{
+							Group:    "group",
+							GroupKey: &frpv1alpha1.SecretRef{Secret: frpv1alpha1.Secret{Name: "lb-secret", Key: "key"}},
+						},
+						Plugin: &frpv1alpha1.UpstreamPlugin{
+							Type:     "socks5",
+							Username: &frpv1alpha1.SecretRef{Secret: frpv1alpha1.Secret{Name: "plugin-secret", Key: "username"}},
+							Password: &frpv1alpha1.SecretRef{Secret: frpv1alpha1.Secret{Name: "plugin-secret", Key: "password"}},
+						},
+					},
+				},
+			},
+			want: []string{"lb-secret", "plugin-secret"},
+		},
+		{
+			name: "STCP secret key",
+			upstream: frpv1alpha1.Upstream{
+				ObjectMeta: metav1.ObjectMeta{Name: "stcp"},
+				Spec: frpv1alpha1.UpstreamSpec{
+					STCP: &frpv1alpha1.UpstreamSpec_STCP{
+						SecretKey: frpv1alpha1.UpstreamSpec_STCP_SecretKey{
+							Secret: frpv1alpha1.Secret{Name: "stcp-secret", Key: "key"},
+						},
+					},
+				},
+			},
+			want: []string{"stcp-secret"},
+		},
+		{
+			name: "HTTP basic auth",
+			upstream: frpv1alpha1.Upstream{
+				ObjectMeta: metav1.ObjectMeta{Name: "http"},
+				Spec: frpv1alpha1.UpstreamSpec{
+					HTTP: &frpv1alpha1.UpstreamSpec_HTTP{
+						HTTPUser:     &frpv1alpha1.SecretRef{Secret: frpv1alpha1.Secret{Name: "http-secret", Key: "user"}},
+						HTTPPassword: &frpv1alpha1.SecretRef{Secret: frpv1alpha1.Secret{Name: "http-secret", Key: "password"}},
+					},
+				},
+			},
+			want: []string{"http-secret"},
+		},
+		{
+			name: "UDP without secrets",
+			upstream: frpv1alpha1.Upstream{
+				ObjectMeta: metav1.ObjectMeta{Name: "udp"},
+				Spec: frpv1alpha1.UpstreamSpec{
+					UDP: &frpv1alpha1.UpstreamSpec_UDP{},
+				},
+			},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UpstreamSecretNames(&tt.upstream); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpstreamSecretNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisitorSecretNames(t *testing.T) {
+	visitor := &frpv1alpha1.Visitor{
+		ObjectMeta: metav1.ObjectMeta{Name: "visitor"},
+		Spec: frpv1alpha1.VisitorSpec{
+			XTCP: &frpv1alpha1.VisitorSpec_XTCP{
+				ServerSecretKey: frpv1alpha1.VisitorSpec_XTCP_ServerSecretKey{
+					Secret: frpv1alpha1.Secret{Name: "xtcp-secret", Key: "key"},
+				},
+			},
+		},
+	}
+
+	want := []string{"xtcp-secret"}
+	if got := VisitorSecretNames(visitor); !reflect.DeepEqual(got, want) {
+		t.Errorf("VisitorSecretNames() = %v, want %v", got, want)
+	}
+}
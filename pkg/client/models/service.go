@@ -0,0 +1,109 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+// ServicePortNotFoundError reports a serviceRef port the Service doesn't expose
+type ServicePortNotFoundError struct {
+	Service types.NamespacedName
+	Port    intstr.IntOrString
+}
+
+func (e *ServicePortNotFoundError) Error() string {
+	return fmt.Sprintf("Service %s has no port %s", e.Service, e.Port.String())
+}
+
+// IsServiceUnresolved reports whether an error of ResolveServiceRef is caused by a
+// missing Service or Service port, rather than by a failing API call
+func IsServiceUnresolved(err error) bool {
+	_, portNotFound := err.(*ServicePortNotFoundError)
+	return portNotFound || errors.IsNotFound(err)
+}
+
+// UpstreamServiceKey returns the namespaced name of the Service an Upstream
+// forwards to, and false when the Upstream has no serviceRef
+func UpstreamServiceKey(upstream *frpv1alpha1.Upstream) (types.NamespacedName, bool) {
+	ref := upstream.ServiceRef()
+	if ref == nil {
+		return types.NamespacedName{}, false
+	}
+
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = upstream.Namespace
+	}
+
+	return types.NamespacedName{Name: ref.Name, Namespace: namespace}, true
+}
+
+// ResolveServiceRef resolves the serviceRef of an Upstream into the host and port
+// frpc dials. The Service DNS name is used so frpc follows the Service even if it
+// is recreated, headless Services are dialed on the target port of their pods.
+func ResolveServiceRef(k8sclient client.Reader, upstream *frpv1alpha1.Upstream) (string, int, error) {
+	key, ok := UpstreamServiceKey(upstream)
+	if !ok {
+		return "", 0, nil
+	}
+
+	service := &corev1.Service{}
+	if err := k8sclient.Get(context.TODO(), key, service); err != nil {
+		return "", 0, err
+	}
+
+	host := fmt.Sprintf("%s.%s.svc", service.Name, service.Namespace)
+	ref := upstream.ServiceRef()
+
+	for _, port := range service.Spec.Ports {
+		if !servicePortMatches(port, ref.Port) {
+			continue
+		}
+
+		if service.Spec.ClusterIP == corev1.ClusterIPNone && port.TargetPort.Type == intstr.Int && port.TargetPort.IntValue() != 0 {
+			return host, port.TargetPort.IntValue(), nil
+		}
+
+		return host, int(port.Port), nil
+	}
+
+	return "", 0, &ServicePortNotFoundError{Service: key, Port: ref.Port}
+}
+
+func servicePortMatches(port corev1.ServicePort, ref intstr.IntOrString) bool {
+	if ref.Type == intstr.String {
+		return port.Name == ref.StrVal
+	}
+
+	return int(port.Port) == ref.IntValue()
+}
+
+// setLocalAddress sets the address frpc forwards the upstream to
+func (u *Upstream) setLocalAddress(host string, port int) {
+	switch u.Type {
+	case TCP:
+		u.TCP.Host, u.TCP.Port = host, port
+	case UDP:
+		u.UDP.Host, u.UDP.Port = host, port
+	case STCP:
+		u.STCP.Host, u.STCP.Port = host, port
+	case XTCP:
+		u.XTCP.Host, u.XTCP.Port = host, port
+	case HTTP:
+		u.HTTP.Host, u.HTTP.Port = host, port
+	case HTTPS:
+		u.HTTPS.Host, u.HTTPS.Port = host, port
+	case TCPMUX:
+		u.TCPMUX.Host, u.TCPMUX.Port = host, port
+	case SUDP:
+		u.SUDP.Host, u.SUDP.Port = host, port
+	}
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"testing"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func createService(namespace, name, clusterIP string, ports ...corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: clusterIP,
+			Ports:     ports,
+		},
+	}
+}
+
+func createServiceRefUpstream(namespace string, ref *frpv1alpha1.ServiceRef) *frpv1alpha1.Upstream {
+	return &frpv1alpha1.Upstream{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace},
+		Spec: frpv1alpha1.UpstreamSpec{
+			Client: "test-client",
+			TCP: &frpv1alpha1.UpstreamSpec_TCP{
+				ServiceRef: ref,
+				Server:     frpv1alpha1.UpstreamSpec_TCP_Server{Port: 8080},
+			},
+		},
+	}
+}
+
+func TestUpstreamServiceKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		ref    *frpv1alpha1.ServiceRef
+		want   types.NamespacedName
+		wantOk bool
+	}{
+		{
+			name:   "no serviceRef",
+			ref:    nil,
+			wantOk: false,
+		},
+		{
+			name:   "serviceRef without namespace",
+			ref:    &frpv1alpha1.ServiceRef{Name: "web", Port: intstr.FromInt(80)},
+			want:   types.NamespacedName{Name: "web", Namespace: "team-a"},
+			wantOk: true,
+		},
+		{
+			name:   "serviceRef in another namespace",
+			ref:    &frpv1alpha1.ServiceRef{Name: "web", Namespace: "team-b", Port: intstr.FromInt(80)},
+			want:   types.NamespacedName{Name: "web", Namespace: "team-b"},
+			wantOk: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := UpstreamServiceKey(createServiceRefUpstream("team-a", tt.ref))
+			if ok != tt.wantOk {
+				t.Fatalf("UpstreamServiceKey() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if got != tt.want {
+				t.Errorf("UpstreamServiceKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveServiceRef(t *testing.T) {
+	httpPort := corev1.ServicePort{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}
+	metricsPort := corev1.ServicePort{Name: "metrics", Port: 9090, TargetPort: intstr.FromString("metrics")}
+
+	tests := []struct {
+		name     string
+		services []*corev1.Service
+		ref      *frpv1alpha1.ServiceRef
+		wantHost string
+		wantPort int
+		wantErr  func(error) bool
+	}{
+		{
+			name:     "port by number",
+			services: []*corev1.Service{createService("team-a", "web", "10.0.0.10", httpPort, metricsPort)},
+			ref:      &frpv1alpha1.ServiceRef{Name: "web", Port: intstr.FromInt(9090)},
+			wantHost: "web.team-a.svc",
+			wantPort: 9090,
+		},
+		{
+			name:     "port by name",
+			services: []*corev1.Service{createService("team-a", "web", "10.0.0.10", httpPort, metricsPort)},
+			ref:      &frpv1alpha1.ServiceRef{Name: "web", Port: intstr.FromString("http")},
+			wantHost: "web.team-a.svc",
+			wantPort: 80,
+		},
+		{
+			name:     "service in another namespace",
+			services: []*corev1.Service{createService("team-b", "web", "10.0.0.10", httpPort)},
+			ref:      &frpv1alpha1.ServiceRef{Name: "web", Namespace: "team-b", Port: intstr.FromString("http")},
+			wantHost: "web.team-b.svc",
+			wantPort: 80,
+		},
+		{
+			name:     "headless service dials the target port",
+			services: []*corev1.Service{createService("team-a", "web", corev1.ClusterIPNone, httpPort)},
+			ref:      &frpv1alpha1.ServiceRef{Name: "web", Port: intstr.FromString("http")},
+			wantHost: "web.team-a.svc",
+			wantPort: 8080,
+		},
+		{
+			name:     "headless service with a named target port",
+			services: []*corev1.Service{createService("team-a", "web", corev1.ClusterIPNone, metricsPort)},
+			ref:      &frpv1alpha1.ServiceRef{Name: "web", Port: intstr.FromString("metrics")},
+			wantHost: "web.team-a.svc",
+			wantPort: 9090,
+		},
+		{
+			name:    "service not found",
+			ref:     &frpv1alpha1.ServiceRef{Name: "web", Port: intstr.FromInt(80)},
+			wantErr: errors.IsNotFound,
+		},
+		{
+			name:     "port not found",
+			services: []*corev1.Service{createService("team-a", "web", "10.0.0.10", httpPort)},
+			ref:      &frpv1alpha1.ServiceRef{Name: "web", Port: intstr.FromString("grpc")},
+			wantErr: func(err error) bool {
+				_, ok := err.(*ServicePortNotFoundError)
+				return ok
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder()
+			for _, service := range tt.services {
+				builder = builder.WithObjects(service)
+			}
+
+			host, port, err := ResolveServiceRef(builder.Build(), createServiceRefUpstream("team-a", tt.ref))
+			if tt.wantErr != nil {
+				if err == nil || !tt.wantErr(err) {
+					t.Fatalf("ResolveServiceRef() error = %v, want a matching error", err)
+				}
+				if !IsServiceUnresolved(err) {
+					t.Errorf("IsServiceUnresolved(%v) = false, want true", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ResolveServiceRef() unexpected error = %v", err)
+			}
+			if host != tt.wantHost {
+				t.Errorf("ResolveServiceRef() host = %v, want %v", host, tt.wantHost)
+			}
+			if port != tt.wantPort {
+				t.Errorf("ResolveServiceRef() port = %v, want %v", port, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestNewConfig_ServiceRefUpstreams(t *testing.T) {
+	fakeClient := createFakeClient(createDefaultTokenSecret("default")).
+		WithObjects(createService("default", "web", "10.0.0.10", corev1.ServicePort{Name: "http", Port: 80})).
+		Build()
+	clientObj := createBasicClient("default", "test-client", "frp.example.com", 7000)
+
+	upstreams := []frpv1alpha1.Upstream{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				Client: "test-client",
+				HTTP: &frpv1alpha1.UpstreamSpec_HTTP{
+					ServiceRef: &frpv1alpha1.ServiceRef{Name: "web", Port: intstr.FromString("http")},
+					Subdomain:  "web",
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "default"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				Client: "test-client",
+				TCP: &frpv1alpha1.UpstreamSpec_TCP{
+					ServiceRef: &frpv1alpha1.ServiceRef{Name: "missing", Port: intstr.FromInt(5432)},
+					Server:     frpv1alpha1.UpstreamSpec_TCP_Server{Port: 15432},
+				},
+			},
+		},
+	}
+
+	config, err := NewConfig(fakeClient, clientObj, nil, upstreams, nil)
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	if len(config.Upstreams) != 1 {
+		t.Fatalf("NewConfig() upstreams = %d, want 1, an unresolved serviceRef is skipped", len(config.Upstreams))
+	}
+	if config.Upstreams[0].HTTP.Host != "web.default.svc" {
+		t.Errorf("NewConfig() upstream.HTTP.Host = %v, want %v", config.Upstreams[0].HTTP.Host, "web.default.svc")
+	}
+	if config.Upstreams[0].HTTP.Port != 80 {
+		t.Errorf("NewConfig() upstream.HTTP.Port = %v, want %v", config.Upstreams[0].HTTP.Port, 80)
+	}
+}
@@ -0,0 +1,232 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+)
+
+// VirtualNet is the virtual network a Client joins, the Client registers an stcp
+// proxy with the virtual_net plugin and dials the proxy of every peer
+type VirtualNet struct {
+	// Address is the address of the Client with the prefix length, e.g. 100.86.0.1/24
+	Address   string
+	ProxyName string
+	SecretKey string
+	Peers     []VirtualNetPeer
+}
+
+type VirtualNetPeer struct {
+	ProxyName string
+	Address   string
+}
+
+// VirtualNetProxyName returns the name of the proxy a member of a VirtualNetwork
+// registers on the frp server
+func VirtualNetProxyName(virtualNetwork string, member string) string {
+	return "vnet-" + virtualNetwork + "-" + member
+}
+
+// AssignVirtualNetworkAddresses returns the address of every member of a
+// VirtualNetwork. Pinned addresses are used as is, the other members keep the
+// address found in the status and new members get the lowest free address.
+func AssignVirtualNetworkAddresses(virtualNetwork *frpv1alpha1.VirtualNetwork) ([]frpv1alpha1.VirtualNetworkStatus_Member, error) {
+	prefix, err := netip.ParsePrefix(virtualNetwork.Spec.CIDR)
+	if err != nil || !prefix.Addr().Is4() {
+		return nil, fmt.Errorf("cidr %q is not an IPv4 network", virtualNetwork.Spec.CIDR)
+	}
+	prefix = prefix.Masked()
+
+	used := map[netip.Addr]string{}
+	names := map[string]struct{}{}
+	clients := map[string]struct{}{}
+	for _, member := range virtualNetwork.Spec.Members {
+		if member.Name == "" {
+			return nil, fmt.Errorf("member name is required")
+		}
+		if _, ok := names[member.Name]; ok {
+			return nil, fmt.Errorf("member %q is declared more than once", member.Name)
+		}
+		names[member.Name] = struct{}{}
+
+		if member.Client != "" {
+			if _, ok := clients[member.Client]; ok {
+				return nil, fmt.Errorf("client %q joins the network more than once", member.Client)
+			}
+			clients[member.Client] = struct{}{}
+		}
+
+		if member.Address == "" {
+			if member.Client == "" {
+				return nil, fmt.Errorf("member %q without client requires an address", member.Name)
+			}
+			continue
+		}
+
+		address, err := netip.ParseAddr(member.Address)
+		if err != nil || !usableAddress(prefix, address) {
+			return nil, fmt.Errorf("address %q of member %q is not a host address of %s", member.Address, member.Name, prefix)
+		}
+		if other, ok := used[address]; ok {
+			return nil, fmt.Errorf("address %s is used by members %q and %q", address, other, member.Name)
+		}
+		used[address] = member.Name
+	}
+
+	// addresses of removed members are released
+	assigned := map[string]netip.Addr{}
+	for _, member := range virtualNetwork.Status.Members {
+		if _, ok := names[member.Name]; !ok {
+			continue
+		}
+		address, err := netip.ParseAddr(member.Address)
+		if err != nil || !usableAddress(prefix, address) {
+			continue
+		}
+		if _, ok := used[address]; ok {
+			continue
+		}
+		assigned[member.Name] = address
+	}
+
+	members := []frpv1alpha1.VirtualNetworkStatus_Member{}
+	next := prefix.Addr()
+	for _, member := range virtualNetwork.Spec.Members {
+		previous, ok := assigned[member.Name]
+		if _, taken := used[previous]; taken {
+			ok = false
+		}
+
+		var address netip.Addr
+		if member.Address != "" {
+			address = netip.MustParseAddr(member.Address)
+		} else if ok {
+			address = previous
+		} else {
+			for next = next.Next(); usableAddress(prefix, next); next = next.Next() {
+				if _, ok := used[next]; ok {
+					continue
+				}
+				if assignedTo(assigned, next) {
+					continue
+				}
+				break
+			}
+			if !usableAddress(prefix, next) {
+				return nil, fmt.Errorf("no free address left in %s for member %q", prefix, member.Name)
+			}
+			address = next
+		}
+
+		used[address] = member.Name
+		members = append(members, frpv1alpha1.VirtualNetworkStatus_Member{
+			Name:    member.Name,
+			Client:  member.Client,
+			Address: address.String(),
+		})
+	}
+
+	return members, nil
+}
+
+// usableAddress reports whether an address is a host address of the network,
+// the network and broadcast addresses are left out
+func usableAddress(prefix netip.Prefix, address netip.Addr) bool {
+	if !address.IsValid() || !prefix.Contains(address) || address == prefix.Addr() {
+		return false
+	}
+
+	return prefix.Bits() >= 31 || prefix.Contains(address.Next())
+}
+
+func assignedTo(assigned map[string]netip.Addr, address netip.Addr) bool {
+	for _, other := range assigned {
+		if other == address {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewVirtualNet returns the virtual network a Client joins, or nil when the Client
+// is no member of the VirtualNetworks or its address isn't assigned yet
+func NewVirtualNet(k8sclient client.Client, clientObject *frpv1alpha1.Client, virtualNetworks []frpv1alpha1.VirtualNetwork) (*VirtualNet, error) {
+	var virtualNetwork *frpv1alpha1.VirtualNetwork
+	var memberName string
+	for i := range virtualNetworks {
+		for _, member := range virtualNetworks[i].Spec.Members {
+			if member.Client != clientObject.Name {
+				continue
+			}
+			if virtualNetwork != nil {
+				return nil, errors.NewBadRequest(
+					fmt.Sprintf("client %q joins VirtualNetworks %q and %q, frpc supports a single virtual network",
+						clientObject.Name, virtualNetwork.Name, virtualNetworks[i].Name))
+			}
+			virtualNetwork = &virtualNetworks[i]
+			memberName = member.Name
+		}
+	}
+
+	if virtualNetwork == nil {
+		return nil, nil
+	}
+
+	if Replicas(clientObject) > 1 {
+		return nil, errors.NewBadRequest(
+			fmt.Sprintf("client %q joins VirtualNetwork %q, a virtual network address can't be shared by %d replicas",
+				clientObject.Name, virtualNetwork.Name, Replicas(clientObject)))
+	}
+
+	prefix, err := netip.ParsePrefix(virtualNetwork.Spec.CIDR)
+	if err != nil {
+		return nil, errors.NewBadRequest(fmt.Sprintf("VirtualNetwork %q: invalid cidr %q", virtualNetwork.Name, virtualNetwork.Spec.CIDR))
+	}
+
+	virtualNet := &VirtualNet{
+		ProxyName: VirtualNetProxyName(virtualNetwork.Name, memberName),
+	}
+	for _, member := range virtualNetwork.Status.Members {
+		if member.Name == memberName {
+			virtualNet.Address = fmt.Sprintf("%s/%d", member.Address, prefix.Bits())
+			continue
+		}
+
+		virtualNet.Peers = append(virtualNet.Peers, VirtualNetPeer{
+			ProxyName: VirtualNetProxyName(virtualNetwork.Name, member.Name),
+			Address:   member.Address,
+		})
+	}
+
+	if virtualNet.Address == "" {
+		return nil, nil
+	}
+
+	secret := &corev1.Secret{}
+	err = k8sclient.Get(context.TODO(), types.NamespacedName{Name: virtualNetwork.Spec.SecretKey.Secret.Name, Namespace: virtualNetwork.Namespace}, secret)
+	if err != nil {
+		return nil, err
+	}
+	secretKeyByte, ok := secret.Data[virtualNetwork.Spec.SecretKey.Secret.Key]
+	if !ok {
+		return nil, errors.NewBadRequest(fmt.Sprintf("key %s not found in secret %s",
+			virtualNetwork.Spec.SecretKey.Secret.Key,
+			virtualNetwork.Spec.SecretKey.Secret.Name))
+	}
+	virtualNet.SecretKey = string(secretKeyByte)
+
+	return virtualNet, nil
+}
+
+// VirtualNetworkSecretNames returns the names of the Secrets a VirtualNetwork reads
+func VirtualNetworkSecretNames(virtualNetwork *frpv1alpha1.VirtualNetwork) []string {
+	return []string{virtualNetwork.Spec.SecretKey.Secret.Name}
+}
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func createVirtualNetwork(cidr string, members ...frpv1alpha1.VirtualNetworkSpec_Member) *frpv1alpha1.VirtualNetwork {
+	return &frpv1alpha1.VirtualNetwork{
+		ObjectMeta: metav1.ObjectMeta{Name: "mesh", Namespace: "default"},
+		Spec: frpv1alpha1.VirtualNetworkSpec{
+			CIDR: cidr,
+			SecretKey: frpv1alpha1.VirtualNetworkSpec_SecretKey{
+				Secret: frpv1alpha1.Secret{Name: "vnet-secret", Key: "key"},
+			},
+			Members: members,
+		},
+	}
+}
+
+func memberAddresses(members []frpv1alpha1.VirtualNetworkStatus_Member) map[string]string {
+	addresses := map[string]string{}
+	for _, member := range members {
+		addresses[member.Name] = member.Address
+	}
+
+	return addresses
+}
+
+func TestAssignVirtualNetworkAddresses(t *testing.T) {
+	virtualNetwork := createVirtualNetwork("100.86.0.0/24",
+		frpv1alpha1.VirtualNetworkSpec_Member{Name: "cluster-a", Client: "client-01"},
+		frpv1alpha1.VirtualNetworkSpec_Member{Name: "cluster-b", Address: "100.86.0.1"},
+		frpv1alpha1.VirtualNetworkSpec_Member{Name: "cluster-c", Client: "client-02"},
+	)
+
+	members, err := AssignVirtualNetworkAddresses(virtualNetwork)
+	if err != nil {
+		t.Fatalf("AssignVirtualNetworkAddresses() unexpected error = %v", err)
+	}
+
+	want := map[string]string{"cluster-a": "100.86.0.2", "cluster-b": "100.86.0.1", "cluster-c": "100.86.0.3"}
+	got := memberAddresses(members)
+	for name, address := range want {
+		if got[name] != address {
+			t.Errorf("AssignVirtualNetworkAddresses() %s = %v, want %v", name, got[name], address)
+		}
+	}
+	if members[0].Client != "client-01" {
+		t.Errorf("AssignVirtualNetworkAddresses() client = %v, want client-01", members[0].Client)
+	}
+}
+
+func TestAssignVirtualNetworkAddresses_KeepsAssignedAddresses(t *testing.T) {
+	virtualNetwork := createVirtualNetwork("100.86.0.0/24",
+		frpv1alpha1.VirtualNetworkSpec_Member{Name: "cluster-a", Client: "client-01"},
+		frpv1alpha1.VirtualNetworkSpec_Member{Name: "cluster-c", Client: "client-02"},
+	)
+	virtualNetwork.Status.Members = []frpv1alpha1.VirtualNetworkStatus_Member{
+		{Name: "cluster-b", Address: "100.86.0.1"},
+		{Name: "cluster-c", Client: "client-02", Address: "100.86.0.5"},
+	}
+
+	members, err := AssignVirtualNetworkAddresses(virtualNetwork)
+	if err != nil {
+		t.Fatalf("AssignVirtualNetworkAddresses() unexpected error = %v", err)
+	}
+
+	got := memberAddresses(members)
+	if got["cluster-c"] != "100.86.0.5" {
+		t.Errorf("AssignVirtualNetworkAddresses() cluster-c = %v, want the assigned 100.86.0.5", got["cluster-c"])
+	}
+	if got["cluster-a"] != "100.86.0.1" {
+		t.Errorf("AssignVirtualNetworkAddresses() cluster-a = %v, want the released 100.86.0.1", got["cluster-a"])
+	}
+	if _, ok := got["cluster-b"]; ok {
+		t.Errorf("AssignVirtualNetworkAddresses() kept the removed member cluster-b")
+	}
+}
+
+func TestAssignVirtualNetworkAddresses_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		network *frpv1alpha1.VirtualNetwork
+		wantErr string
+	}{
+		{
+			name:    "invalid cidr",
+			network: createVirtualNetwork("100.86.0.0", frpv1alpha1.VirtualNetworkSpec_Member{Name: "a", Client: "client-01"}),
+			wantErr: "not an IPv4 network",
+		},
+		{
+			name: "duplicate member",
+			network: createVirtualNetwork("100.86.0.0/24",
+				frpv1alpha1.VirtualNetworkSpec_Member{Name: "a", Client: "client-01"},
+				frpv1alpha1.VirtualNetworkSpec_Member{Name: "a", Client: "client-02"}),
+			wantErr: "declared more than once",
+		},
+		{
+			name:    "remote member without address",
+			network: createVirtualNetwork("100.86.0.0/24", frpv1alpha1.VirtualNetworkSpec_Member{Name: "a"}),
+			wantErr: "requires an address",
+		},
+		{
+			name:    "address outside the network",
+			network: createVirtualNetwork("100.86.0.0/24", frpv1alpha1.VirtualNetworkSpec_Member{Name: "a", Address: "100.86.1.1"}),
+			wantErr: "not a host address",
+		},
+		{
+			name:    "broadcast address",
+			network: createVirtualNetwork("100.86.0.0/24", frpv1alpha1.VirtualNetworkSpec_Member{Name: "a", Address: "100.86.0.255"}),
+			wantErr: "not a host address",
+		},
+		{
+			name: "network exhausted",
+			network: createVirtualNetwork("100.86.0.0/30",
+				frpv1alpha1.VirtualNetworkSpec_Member{Name: "a", Client: "client-01"},
+				frpv1alpha1.VirtualNetworkSpec_Member{Name: "b", Client: "client-02"},
+				frpv1alpha1.VirtualNetworkSpec_Member{Name: "c", Client: "client-03"}),
+			wantErr: "no free address",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := AssignVirtualNetworkAddresses(tt.network)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("AssignVirtualNetworkAddresses() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewVirtualNet(t *testing.T) {
+	fakeClient := createFakeClient(createSecret("default", "vnet-secret", map[string][]byte{
+		"key": []byte("vnet-secret-key"),
+	})).Build()
+	clientObj := createBasicClient("default", "client-01", "frp.example.com", 7000)
+
+	virtualNetwork := createVirtualNetwork("100.86.0.0/24",
+		frpv1alpha1.VirtualNetworkSpec_Member{Name: "cluster-a", Client: "client-01"},
+		frpv1alpha1.VirtualNetworkSpec_Member{Name: "cluster-b", Address: "100.86.0.10"},
+	)
+	virtualNetwork.Status.Members = []frpv1alpha1.VirtualNetworkStatus_Member{
+		{Name: "cluster-a", Client: "client-01", Address: "100.86.0.1"},
+		{Name: "cluster-b", Address: "100.86.0.10"},
+	}
+
+	virtualNet, err := NewVirtualNet(fakeClient, clientObj, []frpv1alpha1.VirtualNetwork{*virtualNetwork})
+	if err != nil {
+		t.Fatalf("NewVirtualNet() unexpected error = %v", err)
+	}
+	if virtualNet == nil {
+		t.Fatal("NewVirtualNet() = nil, want the virtual network of the client")
+	}
+
+	if virtualNet.Address != "100.86.0.1/24" {
+		t.Errorf("NewVirtualNet() Address = %v, want 100.86.0.1/24", virtualNet.Address)
+	}
+	if virtualNet.ProxyName != "vnet-mesh-cluster-a" {
+		t.Errorf("NewVirtualNet() ProxyName = %v, want vnet-mesh-cluster-a", virtualNet.ProxyName)
+	}
+	if virtualNet.SecretKey != "vnet-secret-key" {
+		t.Errorf("NewVirtualNet() SecretKey = %v, want vnet-secret-key", virtualNet.SecretKey)
+	}
+	if len(virtualNet.Peers) != 1 || virtualNet.Peers[0].ProxyName != "vnet-mesh-cluster-b" || virtualNet.Peers[0].Address != "100.86.0.10" {
+		t.Errorf("NewVirtualNet() Peers = %v, want cluster-b at 100.86.0.10", virtualNet.Peers)
+	}
+}
+
+func TestNewVirtualNet_NotMember(t *testing.T) {
+	fakeClient := createFakeClient().Build()
+	clientObj := createBasicClient("default", "client-02", "frp.example.com", 7000)
+
+	virtualNetwork := createVirtualNetwork("100.86.0.0/24",
+		frpv1alpha1.VirtualNetworkSpec_Member{Name: "cluster-a", Client: "client-01"},
+	)
+
+	virtualNet, err := NewVirtualNet(fakeClient, clientObj, []frpv1alpha1.VirtualNetwork{*virtualNetwork})
+	if err != nil || virtualNet != nil {
+		t.Errorf("NewVirtualNet() = %v, %v, want nil for a client outside the network", virtualNet, err)
+	}
+}
+
+func TestNewVirtualNet_RejectReplicas(t *testing.T) {
+	fakeClient := createFakeClient().Build()
+	clientObj := createBasicClient("default", "client-01", "frp.example.com", 7000)
+	replicas := int32(2)
+	clientObj.Spec.Replicas = &replicas
+
+	virtualNetwork := createVirtualNetwork("100.86.0.0/24",
+		frpv1alpha1.VirtualNetworkSpec_Member{Name: "cluster-a", Client: "client-01"},
+	)
+
+	if _, err := NewVirtualNet(fakeClient, clientObj, []frpv1alpha1.VirtualNetwork{*virtualNetwork}); err == nil {
+		t.Error("NewVirtualNet() expected error for a client with 2 replicas")
+	}
+}
@@ -2,30 +2,59 @@ package status
 
 const (
 	// Client phases
-	ClientPhasePending = "Pending"
-	ClientPhaseRunning = "Running"
-	ClientPhaseFailed  = "Failed"
-	ClientPhaseUnknown = "Unknown"
+	ClientPhasePending   = "Pending"
+	ClientPhaseRunning   = "Running"
+	ClientPhaseFailed    = "Failed"
+	ClientPhaseSuspended = "Suspended"
+	ClientPhaseUnknown   = "Unknown"
 
 	// Upstream phases
-	UpstreamPhasePending = "Pending"
-	UpstreamPhaseActive  = "Active"
-	UpstreamPhaseFailed  = "Failed"
+	UpstreamPhasePending  = "Pending"
+	UpstreamPhaseActive   = "Active"
+	UpstreamPhaseFailed   = "Failed"
+	UpstreamPhaseDisabled = "Disabled"
+	UpstreamPhaseInactive = "Inactive"
+	UpstreamPhaseExpired  = "Expired"
 
 	// Visitor phases
-	VisitorPhasePending = "Pending"
-	VisitorPhaseActive  = "Active"
-	VisitorPhaseFailed  = "Failed"
+	VisitorPhasePending  = "Pending"
+	VisitorPhaseActive   = "Active"
+	VisitorPhaseFailed   = "Failed"
+	VisitorPhaseDisabled = "Disabled"
+
+	// VirtualNetwork phases
+	VirtualNetworkPhaseReady  = "Ready"
+	VirtualNetworkPhaseFailed = "Failed"
+
+	// PortPool phases
+	PortPoolPhaseReady     = "Ready"
+	PortPoolPhaseExhausted = "Exhausted"
 
 	// Condition types
 	ConditionTypeReady      = "Ready"
 	ConditionTypeConfigSync = "ConfigSynced"
+	ConditionTypeRollout    = "RolloutInProgress"
+
+	// ConditionTypeServiceResolved reports whether the serviceRef of an Upstream
+	// resolves to a Service port
+	ConditionTypeServiceResolved = "ServiceResolved"
 
 	// Condition reasons
-	ReasonPodCreated         = "PodCreated"
-	ReasonPodRunning         = "PodRunning"
-	ReasonPodFailed          = "PodFailed"
-	ReasonConfigMapUpdated   = "ConfigMapUpdated"
-	ReasonConfigReloaded     = "ConfigReloaded"
-	ReasonConfigReloadFailed = "ConfigReloadFailed"
+	ReasonPodCreated            = "PodCreated"
+	ReasonPodRunning            = "PodRunning"
+	ReasonPodFailed             = "PodFailed"
+	ReasonDeploymentCreated     = "DeploymentCreated"
+	ReasonDeploymentReady       = "DeploymentReady"
+	ReasonDeploymentFailed      = "DeploymentFailed"
+	ReasonSuspended             = "Suspended"
+	ReasonRolloutInProgress     = "RolloutInProgress"
+	ReasonRolloutComplete       = "RolloutComplete"
+	ReasonConfigMapUpdated      = "ConfigMapUpdated"
+	ReasonConfigReloaded        = "ConfigReloaded"
+	ReasonConfigReloadFailed    = "ConfigReloadFailed"
+	ReasonConfigRolledBack      = "ConfigRolledBack"
+	ReasonConfigRevisionInvalid = "ConfigRevisionInvalid"
+	ReasonServiceResolved       = "ServiceResolved"
+	ReasonServiceNotFound       = "ServiceNotFound"
+	ReasonServicePortMissing    = "ServicePortNotFound"
 )
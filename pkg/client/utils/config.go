@@ -0,0 +1,160 @@
+package utils
+
+// ClientConfig mirrors the frpc v1 TOML configuration. Only the options the
+// operator renders are modelled, every value is written by a TOML encoder so
+// user input can't break out of its key.
+type ClientConfig struct {
+	ServerAddr        string                 `toml:"serverAddr"`
+	ServerPort        int                    `toml:"serverPort"`
+	NatHoleStunServer string                 `toml:"natHoleStunServer,omitempty"`
+	Auth              *AuthClientConfig      `toml:"auth,omitempty"`
+	WebServer         WebServerConfig        `toml:"webServer"`
+	Transport         *ClientTransportConfig `toml:"transport,omitempty"`
+	FeatureGates      map[string]bool        `toml:"featureGates,omitempty"`
+	VirtualNet        *VirtualNetConfig      `toml:"virtualNet,omitempty"`
+	Proxies           []ProxyConfig          `toml:"proxies,omitempty"`
+	Visitors          []VisitorConfig        `toml:"visitors,omitempty"`
+}
+
+// VirtualNetConfig holds the address of the tun interface frpc creates for the
+// virtual_net plugin
+type VirtualNetConfig struct {
+	Address string `toml:"address"`
+}
+
+type AuthClientConfig struct {
+	Method string                `toml:"method"`
+	Token  string                `toml:"token,omitempty"`
+	OIDC   *AuthOIDCClientConfig `toml:"oidc,omitempty"`
+}
+
+type AuthOIDCClientConfig struct {
+	ClientID         string `toml:"clientID"`
+	ClientSecret     string `toml:"clientSecret"`
+	Audience         string `toml:"audience,omitempty"`
+	Scope            string `toml:"scope,omitempty"`
+	TokenEndpointURL string `toml:"tokenEndpointURL"`
+}
+
+type WebServerConfig struct {
+	Addr        string `toml:"addr"`
+	Port        int    `toml:"port"`
+	User        string `toml:"user"`
+	Password    string `toml:"password"`
+	PprofEnable bool   `toml:"pprofEnable,omitempty"`
+}
+
+type ClientTransportConfig struct {
+	Protocol             string            `toml:"protocol,omitempty"`
+	PoolCount            int               `toml:"poolCount,omitzero"`
+	TCPMux               *bool             `toml:"tcpMux,omitempty"`
+	DialServerTimeout    string            `toml:"dialServerTimeout,omitempty"`
+	DialServerKeepalive  string            `toml:"dialServerKeepalive,omitempty"`
+	ConnectServerLocalIP string            `toml:"connectServerLocalIP,omitempty"`
+	QUIC                 *QUICClientConfig `toml:"quic,omitempty"`
+	TLS                  *TLSClientConfig  `toml:"tls,omitempty"`
+}
+
+type QUICClientConfig struct {
+	KeepalivePeriod    int `toml:"keepalivePeriod,omitzero"`
+	MaxIdleTimeout     int `toml:"maxIdleTimeout,omitzero"`
+	MaxIncomingStreams int `toml:"maxIncomingStreams,omitzero"`
+}
+
+type TLSClientConfig struct {
+	Enable        *bool  `toml:"enable,omitempty"`
+	CertFile      string `toml:"certFile,omitempty"`
+	KeyFile       string `toml:"keyFile,omitempty"`
+	TrustedCaFile string `toml:"trustedCaFile,omitempty"`
+}
+
+// ProxyConfig is a [[proxies]] entry, it holds the options of every proxy type
+// and only the ones set for the proxy type are rendered
+type ProxyConfig struct {
+	Name              string             `toml:"name"`
+	Type              string             `toml:"type"`
+	Multiplexer       string             `toml:"multiplexer,omitempty"`
+	LocalIP           string             `toml:"localIP,omitempty"`
+	LocalPort         int                `toml:"localPort,omitzero"`
+	RemotePort        int                `toml:"remotePort,omitzero"`
+	SecretKey         string             `toml:"secretKey,omitempty"`
+	AllowUsers        []string           `toml:"allowUsers,omitempty"`
+	Subdomain         string             `toml:"subdomain,omitempty"`
+	CustomDomains     []string           `toml:"customDomains,omitempty"`
+	Locations         []string           `toml:"locations,omitempty"`
+	HostHeaderRewrite string             `toml:"hostHeaderRewrite,omitempty"`
+	HTTPUser          string             `toml:"httpUser,omitempty"`
+	HTTPPassword      string             `toml:"httpPassword,omitempty"`
+	RequestHeaders    *HeaderOperations  `toml:"requestHeaders,omitempty"`
+	ResponseHeaders   *HeaderOperations  `toml:"responseHeaders,omitempty"`
+	Transport         *ProxyTransport    `toml:"transport,omitempty"`
+	HealthCheck       *HealthCheckConfig `toml:"healthCheck,omitempty"`
+	LoadBalancer      *LoadBalancer      `toml:"loadBalancer,omitempty"`
+	Plugin            *ProxyPlugin       `toml:"plugin,omitempty"`
+}
+
+type ProxyTransport struct {
+	UseEncryption        *bool  `toml:"useEncryption,omitempty"`
+	UseCompression       *bool  `toml:"useCompression,omitempty"`
+	BandwidthLimit       string `toml:"bandwidthLimit,omitempty"`
+	BandwidthLimitMode   string `toml:"bandwidthLimitMode,omitempty"`
+	ProxyProtocolVersion string `toml:"proxyProtocolVersion,omitempty"`
+	ProxyURL             string `toml:"proxyURL,omitempty"`
+}
+
+type HealthCheckConfig struct {
+	Type            string `toml:"type"`
+	TimeoutSeconds  int    `toml:"timeoutSeconds,omitzero"`
+	MaxFailed       int    `toml:"maxFailed,omitzero"`
+	IntervalSeconds int    `toml:"intervalSeconds,omitzero"`
+	Path            string `toml:"path,omitempty"`
+}
+
+type LoadBalancer struct {
+	Group    string `toml:"group"`
+	GroupKey string `toml:"groupKey,omitempty"`
+}
+
+type HeaderOperations struct {
+	Set map[string]string `toml:"set,omitempty"`
+}
+
+// ProxyPlugin is the [proxies.plugin] table of a proxy
+type ProxyPlugin struct {
+	Type         string `toml:"type"`
+	Username     string `toml:"username,omitempty"`
+	Password     string `toml:"password,omitempty"`
+	HTTPUser     string `toml:"httpUser,omitempty"`
+	HTTPPassword string `toml:"httpPassword,omitempty"`
+	LocalPath    string `toml:"localPath,omitempty"`
+	StripPrefix  string `toml:"stripPrefix,omitempty"`
+	UnixPath     string `toml:"unixPath,omitempty"`
+	LocalAddr    string `toml:"localAddr,omitempty"`
+	CrtPath      string `toml:"crtPath,omitempty"`
+	KeyPath      string `toml:"keyPath,omitempty"`
+}
+
+// VisitorConfig is a [[visitors]] entry
+type VisitorConfig struct {
+	Name              string         `toml:"name"`
+	Type              string         `toml:"type"`
+	ServerName        string         `toml:"serverName"`
+	SecretKey         string         `toml:"secretKey,omitempty"`
+	BindAddr          string         `toml:"bindAddr,omitempty"`
+	BindPort          int            `toml:"bindPort,omitzero"`
+	KeepTunnelOpen    *bool          `toml:"keepTunnelOpen,omitempty"`
+	FallbackTo        string         `toml:"fallbackTo,omitempty"`
+	FallbackTimeoutMs int            `toml:"fallbackTimeoutMs,omitzero"`
+	NatHoleStun       *NatHoleStun   `toml:"natHoleStun,omitempty"`
+	Plugin            *VisitorPlugin `toml:"plugin,omitempty"`
+}
+
+// VisitorPlugin is the [visitors.plugin] table of a visitor
+type VisitorPlugin struct {
+	Type          string `toml:"type"`
+	DestinationIP string `toml:"destinationIP,omitempty"`
+}
+
+type NatHoleStun struct {
+	DisableAssistedAddrs bool `toml:"disableAssistedAddrs,omitempty"`
+}
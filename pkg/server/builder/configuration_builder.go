@@ -0,0 +1,111 @@
+package builder
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/server/models"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/server/utils"
+)
+
+type ConfigurationBuilder struct {
+	Config models.Config
+}
+
+func NewConfigurationBuilder() *ConfigurationBuilder {
+	return &ConfigurationBuilder{}
+}
+
+func (n *ConfigurationBuilder) SetConfig(config models.Config) *ConfigurationBuilder {
+	n.Config = config
+	return n
+}
+
+func (n *ConfigurationBuilder) Build() (string, error) {
+	var configurationBuffer bytes.Buffer
+	configurationBuffer.WriteString("# frps.toml\n")
+
+	encoder := toml.NewEncoder(&configurationBuffer)
+	encoder.Indent = ""
+
+	err := encoder.Encode(newServerConfig(n.Config))
+	if err != nil {
+		return "", err
+	}
+
+	// the encoder escapes newlines inside values, so every blank line is a
+	// separator between tables
+	var configuration []string
+	for _, data := range strings.Split(configurationBuffer.String(), "\n") {
+		if len(strings.TrimSpace(data)) != 0 {
+			configuration = append(configuration, data)
+		}
+	}
+
+	return strings.Join(configuration, "\n"), nil
+}
+
+func newServerConfig(config models.Config) utils.ServerConfig {
+	serverConfig := utils.ServerConfig{
+		BindAddr:              "0.0.0.0",
+		BindPort:              config.BindPort,
+		KCPBindPort:           config.KCPBindPort,
+		QUICBindPort:          config.QUICBindPort,
+		VhostHTTPPort:         config.VhostHTTPPort,
+		VhostHTTPSPort:        config.VhostHTTPSPort,
+		TCPMuxHTTPConnectPort: config.TCPMuxHTTPConnectPort,
+		SubdomainHost:         config.SubdomainHost,
+	}
+
+	for _, portRange := range config.AllowPorts {
+		serverConfig.AllowPorts = append(serverConfig.AllowPorts, utils.PortsRange{
+			Single: portRange.Single,
+			Start:  portRange.Start,
+			End:    portRange.End,
+		})
+	}
+
+	authentication := config.Authentication
+	if authentication.Type == models.TokenAuth {
+		serverConfig.Auth = &utils.AuthServerConfig{
+			Method: "token",
+			Token:  authentication.Token,
+		}
+	}
+
+	if authentication.Type == models.OIDCAuth {
+		serverConfig.Auth = &utils.AuthServerConfig{
+			Method: "oidc",
+			OIDC: &utils.AuthOIDCServerConfig{
+				Issuer:          authentication.OIDCIssuer,
+				Audience:        authentication.OIDCAudience,
+				SkipExpiryCheck: authentication.OIDCSkipExpiryCheck,
+				SkipIssuerCheck: authentication.OIDCSkipIssuerCheck,
+			},
+		}
+	}
+
+	if config.Dashboard != nil {
+		serverConfig.WebServer = &utils.WebServerConfig{
+			Addr:     "0.0.0.0",
+			Port:     config.Dashboard.Port,
+			User:     config.Dashboard.Username,
+			Password: config.Dashboard.Password,
+		}
+	}
+
+	if config.TLS != nil {
+		serverConfig.Transport = &utils.ServerTransportConfig{
+			TLS: &utils.TLSServerConfig{
+				Force:         config.TLS.Force,
+				CertFile:      config.TLS.CertFile,
+				KeyFile:       config.TLS.KeyFile,
+				TrustedCaFile: config.TLS.TrustedCAFile,
+			},
+		}
+	}
+
+	return serverConfig
+}
@@ -0,0 +1,165 @@
+package builder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/server/models"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/server/utils"
+)
+
+func TestConfigurationBuilder_Build(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         models.Config
+		wantContains   []string
+		wantNotContain []string
+	}{
+		{
+			name: "token authentication",
+			config: models.Config{
+				BindPort: 7000,
+				Authentication: models.Authentication{
+					Type:  models.TokenAuth,
+					Token: "my-token",
+				},
+			},
+			wantContains: []string{
+				`bindAddr = "0.0.0.0"`,
+				`bindPort = 7000`,
+				"[auth]\nmethod = \"token\"\ntoken = \"my-token\"",
+			},
+			wantNotContain: []string{
+				`vhostHTTPPort`,
+				`webServer`,
+				`transport`,
+			},
+		},
+		{
+			name: "oidc authentication",
+			config: models.Config{
+				BindPort: 7000,
+				Authentication: models.Authentication{
+					Type:                models.OIDCAuth,
+					OIDCIssuer:          "https://auth.example.com",
+					OIDCAudience:        "frps",
+					OIDCSkipExpiryCheck: true,
+				},
+			},
+			wantContains: []string{
+				"[auth]\nmethod = \"oidc\"",
+				"[auth.oidc]\nissuer = \"https://auth.example.com\"\naudience = \"frps\"\nskipExpiryCheck = true",
+			},
+			wantNotContain: []string{
+				`token`,
+				`skipIssuerCheck`,
+			},
+		},
+		{
+			name: "vhost, transports and allow ports",
+			config: models.Config{
+				BindPort:              7000,
+				KCPBindPort:           7000,
+				QUICBindPort:          7002,
+				VhostHTTPPort:         80,
+				VhostHTTPSPort:        443,
+				TCPMuxHTTPConnectPort: 1337,
+				SubdomainHost:         "frp.example.com",
+				AllowPorts: []models.PortRange{
+					{Start: 2000, End: 3000},
+					{Single: 3001},
+				},
+			},
+			wantContains: []string{
+				`kcpBindPort = 7000`,
+				`quicBindPort = 7002`,
+				`vhostHTTPPort = 80`,
+				`vhostHTTPSPort = 443`,
+				`tcpmuxHTTPConnectPort = 1337`,
+				`subdomainHost = "frp.example.com"`,
+				"[[allowPorts]]\nstart = 2000\nend = 3000\n[[allowPorts]]\nsingle = 3001",
+			},
+		},
+		{
+			name: "dashboard and tls",
+			config: models.Config{
+				BindPort: 7000,
+				Dashboard: &models.Dashboard{
+					Port:     7500,
+					Username: "admin",
+					Password: "secret",
+				},
+				TLS: &models.TLSConfig{
+					Force:    true,
+					CertFile: "/etc/frp/tls/tls.crt",
+					KeyFile:  "/etc/frp/tls/tls.key",
+				},
+			},
+			wantContains: []string{
+				"[webServer]\naddr = \"0.0.0.0\"\nport = 7500\nuser = \"admin\"\npassword = \"secret\"",
+				"[transport.tls]\nforce = true\ncertFile = \"/etc/frp/tls/tls.crt\"\nkeyFile = \"/etc/frp/tls/tls.key\"",
+			},
+			wantNotContain: []string{
+				`trustedCaFile`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewConfigurationBuilder().SetConfig(tt.config).Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+
+			for _, want := range tt.wantContains {
+				if !strings.Contains(got, want) {
+					t.Errorf("Build() output missing %q\nGot:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.wantNotContain {
+				if strings.Contains(got, notWant) {
+					t.Errorf("Build() output should not contain %q\nGot:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigurationBuilder_BuildEscapesValues(t *testing.T) {
+	config := models.Config{
+		BindPort:      7000,
+		SubdomainHost: "frp.example.com\"\nbindPort = 1",
+		Authentication: models.Authentication{
+			Type:  models.TokenAuth,
+			Token: "to\"ken\\",
+		},
+		Dashboard: &models.Dashboard{
+			Port:     7500,
+			Username: "admin",
+			Password: "pass\"\nwebServer.addr = \"::\"",
+		},
+	}
+
+	got, err := NewConfigurationBuilder().SetConfig(config).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	decoded := utils.ServerConfig{}
+	if _, err := toml.Decode(got, &decoded); err != nil {
+		t.Fatalf("Build() rendered invalid TOML: %v\nGot:\n%s", err, got)
+	}
+
+	if decoded.BindPort != 7000 || decoded.SubdomainHost != config.SubdomainHost {
+		t.Errorf("Build() bindPort = %d, subdomainHost = %q, want 7000 and %q", decoded.BindPort, decoded.SubdomainHost, config.SubdomainHost)
+	}
+	if decoded.Auth == nil || decoded.Auth.Token != config.Authentication.Token {
+		t.Errorf("Build() auth = %+v, want token %q", decoded.Auth, config.Authentication.Token)
+	}
+	if decoded.WebServer == nil || decoded.WebServer.Addr != "0.0.0.0" || decoded.WebServer.Password != config.Dashboard.Password {
+		t.Errorf("Build() webServer = %+v, want addr 0.0.0.0 and password %q", decoded.WebServer, config.Dashboard.Password)
+	}
+}
@@ -0,0 +1,246 @@
+package builder
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/server/models"
+)
+
+// ConfigHashAnnotation records the hash of the rendered frps configuration on the pod template,
+// frps can't reload its configuration so a change of the hash rolls the deployment
+const ConfigHashAnnotation = "frp.zufardhiyaulhaq.com/config-hash"
+
+type DeploymentBuilder struct {
+	Name           string
+	Namespace      string
+	Image          string
+	Config         models.Config
+	ConfigHash     string
+	PodTemplate    *frpv1alpha1.ClientSpec_PodTemplate
+	TLSSecret      string
+	TLSCAConfigMap string
+}
+
+func NewDeploymentBuilder() *DeploymentBuilder {
+	return &DeploymentBuilder{}
+}
+
+func (n *DeploymentBuilder) SetName(name string) *DeploymentBuilder {
+	n.Name = name
+	return n
+}
+
+func (n *DeploymentBuilder) SetNamespace(namespace string) *DeploymentBuilder {
+	n.Namespace = namespace
+	return n
+}
+
+func (n *DeploymentBuilder) SetImage(image string) *DeploymentBuilder {
+	n.Image = image
+	return n
+}
+
+func (n *DeploymentBuilder) SetConfig(config models.Config) *DeploymentBuilder {
+	n.Config = config
+	return n
+}
+
+func (n *DeploymentBuilder) SetConfigHash(configHash string) *DeploymentBuilder {
+	n.ConfigHash = configHash
+	return n
+}
+
+func (n *DeploymentBuilder) SetPodTemplate(podTemplate *frpv1alpha1.ClientSpec_PodTemplate) *DeploymentBuilder {
+	n.PodTemplate = podTemplate
+	return n
+}
+
+func (n *DeploymentBuilder) SetTLSSecret(tlsSecret string) *DeploymentBuilder {
+	n.TLSSecret = tlsSecret
+	return n
+}
+
+func (n *DeploymentBuilder) SetTLSCAConfigMap(tlsCAConfigMap string) *DeploymentBuilder {
+	n.TLSCAConfigMap = tlsCAConfigMap
+	return n
+}
+
+func (n *DeploymentBuilder) Build() (*appsv1.Deployment, error) {
+	labels := n.BuildLabels()
+	podLabels := n.BuildLabels()
+	annotations := map[string]string{
+		"sidecar.istio.io/inject": "false",
+		"linkerd.io/inject":       "disabled",
+		ConfigHashAnnotation:      n.ConfigHash,
+	}
+
+	// Merge PodTemplate labels and annotations
+	if n.PodTemplate != nil {
+		for k, v := range n.PodTemplate.Labels {
+			podLabels[k] = v
+		}
+		for k, v := range n.PodTemplate.Annotations {
+			annotations[k] = v
+		}
+	}
+
+	container := corev1.Container{
+		Name:    "frps",
+		Image:   n.Image,
+		Command: []string{"frps", "-c", "/frp/config.toml"},
+		Ports:   n.buildContainerPorts(),
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      n.Name + "-frps-config",
+				MountPath: "/frp",
+			},
+		},
+	}
+
+	if n.PodTemplate != nil && n.PodTemplate.Resources != nil {
+		container.Resources = *n.PodTemplate.Resources
+	}
+
+	podSpec := corev1.PodSpec{
+		Containers: []corev1.Container{container},
+		Volumes: []corev1.Volume{
+			{
+				Name: n.Name + "-frps-config",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: n.Name + "-frps-config",
+					},
+				},
+			},
+		},
+	}
+
+	// Add TLS volumes and mounts if configured
+	if n.TLSSecret != "" {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: "tls-certs",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: n.TLSSecret,
+				},
+			},
+		})
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      "tls-certs",
+			MountPath: "/etc/frp/tls",
+			ReadOnly:  true,
+		})
+	}
+
+	// Add TLS CA ConfigMap volume if configured separately
+	if n.TLSCAConfigMap != "" && n.TLSSecret == "" {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: "tls-ca",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: n.TLSCAConfigMap,
+					},
+				},
+			},
+		})
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      "tls-ca",
+			MountPath: "/etc/frp/tls",
+			ReadOnly:  true,
+		})
+	}
+
+	// Apply PodTemplate fields to pod spec
+	if n.PodTemplate != nil {
+		if n.PodTemplate.NodeSelector != nil {
+			podSpec.NodeSelector = n.PodTemplate.NodeSelector
+		}
+		if n.PodTemplate.Tolerations != nil {
+			podSpec.Tolerations = n.PodTemplate.Tolerations
+		}
+		if n.PodTemplate.Affinity != nil {
+			podSpec.Affinity = n.PodTemplate.Affinity
+		}
+		if n.PodTemplate.ServiceAccountName != "" {
+			podSpec.ServiceAccountName = n.PodTemplate.ServiceAccountName
+		}
+		if n.PodTemplate.ImagePullSecrets != nil {
+			podSpec.ImagePullSecrets = n.PodTemplate.ImagePullSecrets
+		}
+		if n.PodTemplate.PriorityClassName != "" {
+			podSpec.PriorityClassName = n.PodTemplate.PriorityClassName
+		}
+		if n.PodTemplate.SecurityContext != nil {
+			podSpec.SecurityContext = n.PodTemplate.SecurityContext
+		}
+	}
+
+	// frps keeps its proxies in memory, a single replica is recreated on change
+	replicas := int32(1)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      n.Name + "-frps",
+			Namespace: n.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: n.BuildLabels(),
+			},
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RecreateDeploymentStrategyType,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      podLabels,
+					Annotations: annotations,
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+
+	return deployment, nil
+}
+
+func (n *DeploymentBuilder) buildContainerPorts() []corev1.ContainerPort {
+	ports := []corev1.ContainerPort{
+		{Name: "bind", ContainerPort: int32(n.Config.BindPort), Protocol: corev1.ProtocolTCP},
+	}
+
+	if n.Config.KCPBindPort != 0 {
+		ports = append(ports, corev1.ContainerPort{Name: "kcp", ContainerPort: int32(n.Config.KCPBindPort), Protocol: corev1.ProtocolUDP})
+	}
+	if n.Config.QUICBindPort != 0 {
+		ports = append(ports, corev1.ContainerPort{Name: "quic", ContainerPort: int32(n.Config.QUICBindPort), Protocol: corev1.ProtocolUDP})
+	}
+	if n.Config.VhostHTTPPort != 0 {
+		ports = append(ports, corev1.ContainerPort{Name: "vhost-http", ContainerPort: int32(n.Config.VhostHTTPPort), Protocol: corev1.ProtocolTCP})
+	}
+	if n.Config.VhostHTTPSPort != 0 {
+		ports = append(ports, corev1.ContainerPort{Name: "vhost-https", ContainerPort: int32(n.Config.VhostHTTPSPort), Protocol: corev1.ProtocolTCP})
+	}
+	if n.Config.TCPMuxHTTPConnectPort != 0 {
+		ports = append(ports, corev1.ContainerPort{Name: "tcpmux", ContainerPort: int32(n.Config.TCPMuxHTTPConnectPort), Protocol: corev1.ProtocolTCP})
+	}
+	if n.Config.Dashboard != nil {
+		ports = append(ports, corev1.ContainerPort{Name: "dashboard", ContainerPort: int32(n.Config.Dashboard.Port), Protocol: corev1.ProtocolTCP})
+	}
+
+	return ports
+}
+
+func (n *DeploymentBuilder) BuildLabels() map[string]string {
+	var labels = map[string]string{
+		"app.kubernetes.io/name":       n.Name + "-frps",
+		"app.kubernetes.io/managed-by": "frp-operator",
+		"app.kubernetes.io/created-by": n.Name,
+	}
+
+	return labels
+}
@@ -0,0 +1,53 @@
+package builder
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretBuilder builds the Secret holding the rendered frps configuration.
+// The configuration embeds the authentication token, so it is never stored in a ConfigMap.
+type SecretBuilder struct {
+	Name      string
+	Namespace string
+	Config    string
+}
+
+func NewSecretBuilder() *SecretBuilder {
+	return &SecretBuilder{}
+}
+
+func (n *SecretBuilder) SetConfig(config string) *SecretBuilder {
+	n.Config = config
+	return n
+}
+
+func (n *SecretBuilder) SetName(name string) *SecretBuilder {
+	n.Name = name
+	return n
+}
+
+func (n *SecretBuilder) SetNamespace(namespace string) *SecretBuilder {
+	n.Namespace = namespace
+	return n
+}
+
+func (n *SecretBuilder) Build() (*corev1.Secret, error) {
+	data := make(map[string][]byte)
+	data["config.toml"] = []byte(n.Config)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      n.Name + "-frps-config",
+			Namespace: n.Namespace,
+			Labels: map[string]string{
+				"app":       n.Name,
+				"generated": "frp-operator",
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,115 @@
+package builder
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/server/models"
+)
+
+type ServiceBuilder struct {
+	Name      string
+	Namespace string
+	Config    models.Config
+	Service   *frpv1alpha1.ServerSpec_Service
+}
+
+func NewServiceBuilder() *ServiceBuilder {
+	return &ServiceBuilder{}
+}
+
+func (n *ServiceBuilder) SetName(name string) *ServiceBuilder {
+	n.Name = name
+	return n
+}
+
+func (n *ServiceBuilder) SetNamespace(namespace string) *ServiceBuilder {
+	n.Namespace = namespace
+	return n
+}
+
+func (n *ServiceBuilder) SetConfig(config models.Config) *ServiceBuilder {
+	n.Config = config
+	return n
+}
+
+func (n *ServiceBuilder) SetService(service *frpv1alpha1.ServerSpec_Service) *ServiceBuilder {
+	n.Service = service
+	return n
+}
+
+func (n *ServiceBuilder) Build() (*corev1.Service, error) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      n.Name + "-frps",
+			Namespace: n.Namespace,
+			Labels:    n.BuildLabels(),
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: n.BuildLabels(),
+			Ports:    []corev1.ServicePort{n.buildPort("bind", n.Config.BindPort, corev1.ProtocolTCP)},
+			Type:     corev1.ServiceTypeClusterIP,
+		},
+	}
+
+	if n.Service != nil {
+		if n.Service.Type != "" {
+			service.Spec.Type = n.Service.Type
+		}
+		if n.Service.Annotations != nil {
+			service.Annotations = n.Service.Annotations
+		}
+		if n.Service.LoadBalancerIP != "" {
+			service.Spec.LoadBalancerIP = n.Service.LoadBalancerIP
+		}
+	}
+
+	if n.Config.KCPBindPort != 0 {
+		service.Spec.Ports = append(service.Spec.Ports, n.buildPort("kcp", n.Config.KCPBindPort, corev1.ProtocolUDP))
+	}
+	if n.Config.QUICBindPort != 0 {
+		service.Spec.Ports = append(service.Spec.Ports, n.buildPort("quic", n.Config.QUICBindPort, corev1.ProtocolUDP))
+	}
+	if n.Config.VhostHTTPPort != 0 {
+		service.Spec.Ports = append(service.Spec.Ports, n.buildPort("vhost-http", n.Config.VhostHTTPPort, corev1.ProtocolTCP))
+	}
+	if n.Config.VhostHTTPSPort != 0 {
+		service.Spec.Ports = append(service.Spec.Ports, n.buildPort("vhost-https", n.Config.VhostHTTPSPort, corev1.ProtocolTCP))
+	}
+	if n.Config.TCPMuxHTTPConnectPort != 0 {
+		service.Spec.Ports = append(service.Spec.Ports, n.buildPort("tcpmux", n.Config.TCPMuxHTTPConnectPort, corev1.ProtocolTCP))
+	}
+	if n.Config.Dashboard != nil {
+		service.Spec.Ports = append(service.Spec.Ports, n.buildPort("dashboard", n.Config.Dashboard.Port, corev1.ProtocolTCP))
+	}
+
+	for _, proxyPort := range n.Config.ProxyPorts {
+		service.Spec.Ports = append(service.Spec.Ports, n.buildPort(proxyPort.Name, proxyPort.Port, proxyPort.Protocol))
+	}
+
+	return service, nil
+}
+
+func (n *ServiceBuilder) buildPort(name string, port int, protocol corev1.Protocol) corev1.ServicePort {
+	return corev1.ServicePort{
+		Name:     name,
+		Protocol: protocol,
+		Port:     int32(port),
+		TargetPort: intstr.IntOrString{
+			Type:   intstr.Int,
+			IntVal: int32(port),
+		},
+	}
+}
+
+func (n *ServiceBuilder) BuildLabels() map[string]string {
+	var labels = map[string]string{
+		"app.kubernetes.io/name":       n.Name + "-frps",
+		"app.kubernetes.io/managed-by": "frp-operator",
+		"app.kubernetes.io/created-by": n.Name,
+	}
+
+	return labels
+}
@@ -0,0 +1,81 @@
+package builder
+
+import (
+	"testing"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"github.com/zufardhiyaulhaq/frp-operator/pkg/server/models"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestServiceBuilder_Build(t *testing.T) {
+	config := models.Config{
+		BindPort:      7000,
+		KCPBindPort:   7000,
+		VhostHTTPPort: 80,
+		ProxyPorts: models.ProxyPorts{
+			{Name: "tcp-8080", Port: 8080, Protocol: corev1.ProtocolTCP},
+		},
+	}
+
+	service, err := NewServiceBuilder().
+		SetName("edge").
+		SetNamespace("default").
+		SetConfig(config).
+		SetService(&frpv1alpha1.ServerSpec_Service{
+			Type:        corev1.ServiceTypeLoadBalancer,
+			Annotations: map[string]string{"example.com/lb": "external"},
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if service.Name != "edge-frps" {
+		t.Errorf("Expected service name edge-frps, got %s", service.Name)
+	}
+	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		t.Errorf("Expected service type LoadBalancer, got %s", service.Spec.Type)
+	}
+	if service.Annotations["example.com/lb"] != "external" {
+		t.Errorf("Expected service annotation example.com/lb=external")
+	}
+
+	want := []struct {
+		name     string
+		port     int32
+		protocol corev1.Protocol
+	}{
+		{"bind", 7000, corev1.ProtocolTCP},
+		{"kcp", 7000, corev1.ProtocolUDP},
+		{"vhost-http", 80, corev1.ProtocolTCP},
+		{"tcp-8080", 8080, corev1.ProtocolTCP},
+	}
+	if len(service.Spec.Ports) != len(want) {
+		t.Fatalf("Expected %d ports, got %d", len(want), len(service.Spec.Ports))
+	}
+	for i, w := range want {
+		got := service.Spec.Ports[i]
+		if got.Name != w.name || got.Port != w.port || got.Protocol != w.protocol {
+			t.Errorf("Port[%d] = %s/%d/%s, want %s/%d/%s", i, got.Name, got.Port, got.Protocol, w.name, w.port, w.protocol)
+		}
+	}
+}
+
+func TestServiceBuilder_DefaultsToClusterIP(t *testing.T) {
+	service, err := NewServiceBuilder().
+		SetName("edge").
+		SetNamespace("default").
+		SetConfig(models.Config{BindPort: 7000}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if service.Spec.Type != corev1.ServiceTypeClusterIP {
+		t.Errorf("Expected service type ClusterIP, got %s", service.Spec.Type)
+	}
+	if service.Spec.Selector["app.kubernetes.io/name"] != "edge-frps" {
+		t.Errorf("Expected selector app.kubernetes.io/name=edge-frps")
+	}
+}
@@ -0,0 +1,264 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type AuthenticationType int64
+
+const DEFAULT_BIND_PORT = 7000
+const DEFAULT_DASHBOARD_PORT = 7500
+
+const (
+	NoAuth    AuthenticationType = iota // 0 - no authentication
+	TokenAuth AuthenticationType = iota // 1 - token authentication
+	OIDCAuth  AuthenticationType = iota // 2 - OIDC authentication
+)
+
+type Config struct {
+	BindPort              int
+	KCPBindPort           int
+	QUICBindPort          int
+	VhostHTTPPort         int
+	VhostHTTPSPort        int
+	TCPMuxHTTPConnectPort int
+	SubdomainHost         string
+	AllowPorts            []PortRange
+	Authentication        Authentication
+	Dashboard             *Dashboard
+	TLS                   *TLSConfig
+	ProxyPorts            ProxyPorts
+}
+
+type PortRange struct {
+	Single int
+	Start  int
+	End    int
+}
+
+type Authentication struct {
+	Type                AuthenticationType
+	Token               string
+	OIDCIssuer          string
+	OIDCAudience        string
+	OIDCSkipExpiryCheck bool
+	OIDCSkipIssuerCheck bool
+}
+
+type Dashboard struct {
+	Port     int
+	Username string
+	Password string
+}
+
+type TLSConfig struct {
+	Force         bool
+	CertFile      string
+	KeyFile       string
+	TrustedCAFile string
+}
+
+// ProxyPort is a remote port requested by a TCP or UDP upstream of a client
+// connected to this server, it needs to be exposed on the server Service
+type ProxyPort struct {
+	Name     string
+	Port     int
+	Protocol corev1.Protocol
+}
+
+type ProxyPorts []ProxyPort
+
+func (p ProxyPorts) Len() int {
+	return len(p)
+}
+func (p ProxyPorts) Less(i, j int) bool {
+	if p[i].Port == p[j].Port {
+		return p[i].Protocol < p[j].Protocol
+	}
+	return p[i].Port < p[j].Port
+}
+func (p ProxyPorts) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+
+// Address returns the in-cluster address of the Service that exposes the server
+func Address(server *frpv1alpha1.Server) string {
+	return server.Name + "-frps." + server.Namespace + ".svc"
+}
+
+// BindPort returns the port frps listens on for frpc connections
+func BindPort(server *frpv1alpha1.Server) int {
+	if server.Spec.BindPort == 0 {
+		return DEFAULT_BIND_PORT
+	}
+	return server.Spec.BindPort
+}
+
+// SecretNames returns the names of the Secrets the configuration of a Server reads
+func SecretNames(server *frpv1alpha1.Server) []string {
+	var names []string
+	if server.Spec.Authentication.Token != nil {
+		names = append(names, server.Spec.Authentication.Token.Secret.Name)
+	}
+	if dashboard := server.Spec.Dashboard; dashboard != nil {
+		if dashboard.Username != nil {
+			names = append(names, dashboard.Username.Secret.Name)
+		}
+		if dashboard.Password != nil {
+			names = append(names, dashboard.Password.Secret.Name)
+		}
+	}
+
+	return names
+}
+
+func readSecret(k8sclient client.Client, namespace string, ref frpv1alpha1.Secret) (string, error) {
+	secret := &corev1.Secret{}
+	err := k8sclient.Get(context.TODO(), types.NamespacedName{Name: ref.Name, Namespace: namespace}, secret)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", errors.NewBadRequest(fmt.Sprintf("key %s not found in secret %s", ref.Key, ref.Name))
+	}
+
+	return string(value), nil
+}
+
+func NewConfig(k8sclient client.Client,
+	serverObject *frpv1alpha1.Server,
+	upstreamObjects []frpv1alpha1.Upstream,
+) (Config, error) {
+	config := Config{
+		BindPort:              BindPort(serverObject),
+		KCPBindPort:           serverObject.Spec.KCPBindPort,
+		QUICBindPort:          serverObject.Spec.QUICBindPort,
+		VhostHTTPPort:         serverObject.Spec.VhostHTTPPort,
+		VhostHTTPSPort:        serverObject.Spec.VhostHTTPSPort,
+		TCPMuxHTTPConnectPort: serverObject.Spec.TCPMuxHTTPConnectPort,
+		SubdomainHost:         serverObject.Spec.SubdomainHost,
+	}
+
+	for _, allowPort := range serverObject.Spec.AllowPorts {
+		if allowPort.Single == 0 && (allowPort.Start == 0 || allowPort.End < allowPort.Start) {
+			return config, errors.NewBadRequest("allowPorts entry must set single or a valid start and end")
+		}
+		config.AllowPorts = append(config.AllowPorts, PortRange{
+			Single: allowPort.Single,
+			Start:  allowPort.Start,
+			End:    allowPort.End,
+		})
+	}
+
+	// Validate authentication - exactly one method must be specified
+	if serverObject.Spec.Authentication.Token == nil && serverObject.Spec.Authentication.OIDC == nil {
+		return config, errors.NewBadRequest("either token or oidc authentication is required")
+	}
+	if serverObject.Spec.Authentication.Token != nil && serverObject.Spec.Authentication.OIDC != nil {
+		return config, errors.NewBadRequest("only one authentication method (token or oidc) can be specified")
+	}
+
+	if serverObject.Spec.Authentication.Token != nil {
+		token, err := readSecret(k8sclient, serverObject.Namespace, serverObject.Spec.Authentication.Token.Secret)
+		if err != nil {
+			return config, err
+		}
+
+		config.Authentication.Type = TokenAuth
+		config.Authentication.Token = token
+	}
+
+	if serverObject.Spec.Authentication.OIDC != nil {
+		config.Authentication.Type = OIDCAuth
+		config.Authentication.OIDCIssuer = serverObject.Spec.Authentication.OIDC.Issuer
+		config.Authentication.OIDCAudience = serverObject.Spec.Authentication.OIDC.Audience
+		config.Authentication.OIDCSkipExpiryCheck = serverObject.Spec.Authentication.OIDC.SkipExpiryCheck
+		config.Authentication.OIDCSkipIssuerCheck = serverObject.Spec.Authentication.OIDC.SkipIssuerCheck
+	}
+
+	if serverObject.Spec.Dashboard != nil {
+		config.Dashboard = &Dashboard{
+			Port: serverObject.Spec.Dashboard.Port,
+		}
+		if config.Dashboard.Port == 0 {
+			config.Dashboard.Port = DEFAULT_DASHBOARD_PORT
+		}
+
+		if serverObject.Spec.Dashboard.Username != nil {
+			username, err := readSecret(k8sclient, serverObject.Namespace, serverObject.Spec.Dashboard.Username.Secret)
+			if err != nil {
+				return config, err
+			}
+			config.Dashboard.Username = username
+		}
+
+		if serverObject.Spec.Dashboard.Password != nil {
+			password, err := readSecret(k8sclient, serverObject.Namespace, serverObject.Spec.Dashboard.Password.Secret)
+			if err != nil {
+				return config, err
+			}
+			config.Dashboard.Password = password
+		}
+	}
+
+	// Handle TLS configuration, files are mounted by the deployment builder
+	if serverObject.Spec.TLS != nil {
+		config.TLS = &TLSConfig{
+			Force: serverObject.Spec.TLS.Force,
+		}
+
+		if serverObject.Spec.TLS.CertFile != nil {
+			config.TLS.CertFile = "/etc/frp/tls/tls.crt"
+		}
+
+		if serverObject.Spec.TLS.KeyFile != nil {
+			config.TLS.KeyFile = "/etc/frp/tls/tls.key"
+		}
+
+		if serverObject.Spec.TLS.TrustedCAFile != nil {
+			config.TLS.TrustedCAFile = "/etc/frp/tls/ca.crt"
+		}
+	}
+
+	// Collect remote ports of TCP and UDP upstreams so they can be exposed
+	seen := make(map[string]bool)
+	for _, upstreamObject := range upstreamObjects {
+		var proxyPort ProxyPort
+
+		if upstreamObject.Spec.TCP != nil {
+			proxyPort = ProxyPort{Port: upstreamObject.RemotePort(), Protocol: corev1.ProtocolTCP}
+		} else if upstreamObject.Spec.UDP != nil {
+			proxyPort = ProxyPort{Port: upstreamObject.RemotePort(), Protocol: corev1.ProtocolUDP}
+		} else {
+			continue
+		}
+
+		if proxyPort.Port == 0 {
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%d", proxyPort.Protocol, proxyPort.Port)
+		if seen[key] {
+			continue // load balancer groups share the same remote port
+		}
+		seen[key] = true
+
+		proxyPort.Name = fmt.Sprintf("%s-%d", strings.ToLower(string(proxyPort.Protocol)), proxyPort.Port)
+		config.ProxyPorts = append(config.ProxyPorts, proxyPort)
+	}
+	sort.Sort(config.ProxyPorts)
+
+	return config, nil
+}
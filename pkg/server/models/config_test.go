@@ -0,0 +1,234 @@
+package models
+
+import (
+	"testing"
+
+	frpv1alpha1 "github.com/zufardhiyaulhaq/frp-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Helper to create a fake client with secrets
+func createFakeClient(secrets ...*corev1.Secret) *fake.ClientBuilder {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	objects := make([]runtime.Object, len(secrets))
+	for i, s := range secrets {
+		objects[i] = s
+	}
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...)
+}
+
+// Helper to create a basic server object
+func createBasicServer(namespace, name string) *frpv1alpha1.Server {
+	return &frpv1alpha1.Server{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: frpv1alpha1.ServerSpec{
+			Authentication: frpv1alpha1.ServerSpec_Authentication{
+				Token: &frpv1alpha1.ServerSpec_Authentication_Token{
+					Secret: frpv1alpha1.Secret{
+						Name: "token-secret",
+						Key:  "token",
+					},
+				},
+			},
+		},
+	}
+}
+
+func createDefaultTokenSecret(namespace string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "token-secret",
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"token": []byte("test-token"),
+		},
+	}
+}
+
+func TestAddress(t *testing.T) {
+	server := createBasicServer("edge", "frps")
+
+	if got := Address(server); got != "frps-frps.edge.svc" {
+		t.Errorf("Address() = %v, want %v", got, "frps-frps.edge.svc")
+	}
+}
+
+func TestBindPort(t *testing.T) {
+	server := createBasicServer("default", "frps")
+	if got := BindPort(server); got != DEFAULT_BIND_PORT {
+		t.Errorf("BindPort() = %v, want %v", got, DEFAULT_BIND_PORT)
+	}
+
+	server.Spec.BindPort = 7100
+	if got := BindPort(server); got != 7100 {
+		t.Errorf("BindPort() = %v, want %v", got, 7100)
+	}
+}
+
+func TestSecretNames(t *testing.T) {
+	server := createBasicServer("default", "frps")
+	server.Spec.Dashboard = &frpv1alpha1.ServerSpec_Dashboard{
+		Username: &frpv1alpha1.SecretRef{Secret: frpv1alpha1.Secret{Name: "dashboard-user", Key: "username"}},
+		Password: &frpv1alpha1.SecretRef{Secret: frpv1alpha1.Secret{Name: "dashboard-password", Key: "password"}},
+	}
+
+	got := SecretNames(server)
+	if len(got) != 3 || got[0] != "token-secret" || got[1] != "dashboard-user" || got[2] != "dashboard-password" {
+		t.Errorf("SecretNames() = %v, want [token-secret dashboard-user dashboard-password]", got)
+	}
+}
+
+func TestNewConfig_TokenAuthentication(t *testing.T) {
+	fakeClient := createFakeClient(createDefaultTokenSecret("default")).Build()
+	server := createBasicServer("default", "frps")
+	server.Spec.VhostHTTPPort = 8080
+
+	config, err := NewConfig(fakeClient, server, nil)
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	if config.BindPort != DEFAULT_BIND_PORT {
+		t.Errorf("NewConfig() BindPort = %v, want %v", config.BindPort, DEFAULT_BIND_PORT)
+	}
+	if config.VhostHTTPPort != 8080 {
+		t.Errorf("NewConfig() VhostHTTPPort = %v, want %v", config.VhostHTTPPort, 8080)
+	}
+	if config.Authentication.Type != TokenAuth {
+		t.Errorf("NewConfig() Authentication.Type = %v, want %v", config.Authentication.Type, TokenAuth)
+	}
+	if config.Authentication.Token != "test-token" {
+		t.Errorf("NewConfig() Authentication.Token = %v, want %v", config.Authentication.Token, "test-token")
+	}
+}
+
+func TestNewConfig_OIDCAuthentication(t *testing.T) {
+	fakeClient := createFakeClient().Build()
+	server := createBasicServer("default", "frps")
+	server.Spec.Authentication.Token = nil
+	server.Spec.Authentication.OIDC = &frpv1alpha1.ServerSpec_Authentication_OIDC{
+		Issuer:   "https://auth.example.com",
+		Audience: "frps",
+	}
+
+	config, err := NewConfig(fakeClient, server, nil)
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	if config.Authentication.Type != OIDCAuth {
+		t.Errorf("NewConfig() Authentication.Type = %v, want %v", config.Authentication.Type, OIDCAuth)
+	}
+	if config.Authentication.OIDCIssuer != "https://auth.example.com" {
+		t.Errorf("NewConfig() OIDCIssuer = %v, want %v", config.Authentication.OIDCIssuer, "https://auth.example.com")
+	}
+}
+
+func TestNewConfig_AuthenticationRequired(t *testing.T) {
+	fakeClient := createFakeClient().Build()
+	server := createBasicServer("default", "frps")
+	server.Spec.Authentication.Token = nil
+
+	_, err := NewConfig(fakeClient, server, nil)
+	if err == nil {
+		t.Error("NewConfig() expected error for missing authentication, got nil")
+	}
+}
+
+func TestNewConfig_TokenSecretNotFound(t *testing.T) {
+	fakeClient := createFakeClient().Build()
+	server := createBasicServer("default", "frps")
+
+	_, err := NewConfig(fakeClient, server, nil)
+	if err == nil {
+		t.Error("NewConfig() expected error for missing secret, got nil")
+	}
+}
+
+func TestNewConfig_InvalidAllowPorts(t *testing.T) {
+	fakeClient := createFakeClient(createDefaultTokenSecret("default")).Build()
+	server := createBasicServer("default", "frps")
+	server.Spec.AllowPorts = []frpv1alpha1.ServerSpec_PortRange{
+		{Start: 3000, End: 2000},
+	}
+
+	_, err := NewConfig(fakeClient, server, nil)
+	if err == nil {
+		t.Error("NewConfig() expected error for invalid allowPorts, got nil")
+	}
+}
+
+func TestNewConfig_ProxyPorts(t *testing.T) {
+	fakeClient := createFakeClient(createDefaultTokenSecret("default")).Build()
+	server := createBasicServer("default", "frps")
+
+	upstreams := []frpv1alpha1.Upstream{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-2"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				TCP: &frpv1alpha1.UpstreamSpec_TCP{
+					Host:   "web",
+					Port:   80,
+					Server: frpv1alpha1.UpstreamSpec_TCP_Server{Port: 8080},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				TCP: &frpv1alpha1.UpstreamSpec_TCP{
+					Host:   "web",
+					Port:   80,
+					Server: frpv1alpha1.UpstreamSpec_TCP_Server{Port: 8080},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "dns"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				UDP: &frpv1alpha1.UpstreamSpec_UDP{
+					Host:   "dns",
+					Port:   53,
+					Server: frpv1alpha1.UpstreamSpec_UDP_Server{Port: 5353},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "site"},
+			Spec: frpv1alpha1.UpstreamSpec{
+				HTTP: &frpv1alpha1.UpstreamSpec_HTTP{
+					Host: "site",
+					Port: 80,
+				},
+			},
+		},
+	}
+
+	config, err := NewConfig(fakeClient, server, upstreams)
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+
+	want := ProxyPorts{
+		{Name: "udp-5353", Port: 5353, Protocol: corev1.ProtocolUDP},
+		{Name: "tcp-8080", Port: 8080, Protocol: corev1.ProtocolTCP},
+	}
+	if len(config.ProxyPorts) != len(want) {
+		t.Fatalf("NewConfig() ProxyPorts = %v, want %v", config.ProxyPorts, want)
+	}
+	for i := range want {
+		if config.ProxyPorts[i] != want[i] {
+			t.Errorf("NewConfig() ProxyPorts[%d] = %v, want %v", i, config.ProxyPorts[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package status
+
+const (
+	// Server phases
+	ServerPhasePending = "Pending"
+	ServerPhaseRunning = "Running"
+	ServerPhaseFailed  = "Failed"
+
+	// Condition types
+	ConditionTypeReady = "Ready"
+
+	// Condition reasons
+	ReasonDeploymentCreated     = "DeploymentCreated"
+	ReasonDeploymentAvailable   = "DeploymentAvailable"
+	ReasonDeploymentUnavailable = "DeploymentUnavailable"
+	ReasonConfigInvalid         = "ConfigInvalid"
+)
@@ -0,0 +1,57 @@
+package utils
+
+// ServerConfig mirrors the frps v1 TOML configuration. Only the options the
+// operator renders are modelled, every value is written by a TOML encoder so
+// user input can't break out of its key.
+type ServerConfig struct {
+	BindAddr              string                 `toml:"bindAddr"`
+	BindPort              int                    `toml:"bindPort"`
+	KCPBindPort           int                    `toml:"kcpBindPort,omitzero"`
+	QUICBindPort          int                    `toml:"quicBindPort,omitzero"`
+	VhostHTTPPort         int                    `toml:"vhostHTTPPort,omitzero"`
+	VhostHTTPSPort        int                    `toml:"vhostHTTPSPort,omitzero"`
+	TCPMuxHTTPConnectPort int                    `toml:"tcpmuxHTTPConnectPort,omitzero"`
+	SubdomainHost         string                 `toml:"subdomainHost,omitempty"`
+	AllowPorts            []PortsRange           `toml:"allowPorts,omitempty"`
+	Auth                  *AuthServerConfig      `toml:"auth,omitempty"`
+	WebServer             *WebServerConfig       `toml:"webServer,omitempty"`
+	Transport             *ServerTransportConfig `toml:"transport,omitempty"`
+}
+
+// PortsRange is a single port or a range of ports clients may bind
+type PortsRange struct {
+	Single int `toml:"single,omitzero"`
+	Start  int `toml:"start,omitzero"`
+	End    int `toml:"end,omitzero"`
+}
+
+type AuthServerConfig struct {
+	Method string                `toml:"method"`
+	Token  string                `toml:"token,omitempty"`
+	OIDC   *AuthOIDCServerConfig `toml:"oidc,omitempty"`
+}
+
+type AuthOIDCServerConfig struct {
+	Issuer          string `toml:"issuer"`
+	Audience        string `toml:"audience,omitempty"`
+	SkipExpiryCheck bool   `toml:"skipExpiryCheck,omitempty"`
+	SkipIssuerCheck bool   `toml:"skipIssuerCheck,omitempty"`
+}
+
+type WebServerConfig struct {
+	Addr     string `toml:"addr"`
+	Port     int    `toml:"port"`
+	User     string `toml:"user,omitempty"`
+	Password string `toml:"password,omitempty"`
+}
+
+type ServerTransportConfig struct {
+	TLS *TLSServerConfig `toml:"tls,omitempty"`
+}
+
+type TLSServerConfig struct {
+	Force         bool   `toml:"force"`
+	CertFile      string `toml:"certFile,omitempty"`
+	KeyFile       string `toml:"keyFile,omitempty"`
+	TrustedCaFile string `toml:"trustedCaFile,omitempty"`
+}